@@ -0,0 +1,16 @@
+// Package main have the logic necessary to deploy the SES feedback handler
+package main
+
+import (
+	"modak/send-notification/v1/internal/di"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	feedbackHandler, err := di.InitializeFeedback()
+	if err != nil {
+		panic("fatal err: " + err.Error())
+	}
+	lambda.Start(feedbackHandler.Handle)
+}
@@ -0,0 +1,137 @@
+// Package main have the local server of the local profile, it serves the routes of the lambda function
+// over HTTP against DynamoDB Local and a fake SES
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"modak/send-notification/v1/internal/config"
+	"modak/send-notification/v1/internal/di"
+	"modak/send-notification/v1/internal/infraestructure"
+	"modak/send-notification/v1/internal/local"
+	"modak/send-notification/v1/internal/repositories"
+	"modak/send-notification/v1/internal/uc"
+)
+
+// startTimeout time that DynamoDB Local has to start, the containers usually start with the server
+const startTimeout = 30 * time.Second
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// run prepare the tables and the rules and serve the routes until the context is canceled
+func run(ctx context.Context) error {
+	cfg, err := config.Load(os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Profile != config.ProfileLocal {
+		return errors.New("the local server only runs with PROFILE=local")
+	}
+
+	dynamoClient, err := infraestructure.NewDynamoV2Provider(
+		infraestructure.NewConfigProvider(cfg.AWS.SessionConfig()),
+		&infraestructure.DynamoConfig{Endpoint: cfg.AWS.DynamoDBEndpoint},
+	).DynamoClient()
+	if err != nil {
+		return err
+	}
+
+	if err := createTables(ctx, dynamoClient.(infraestructure.DynamoTablesAPI), cfg.Tables); err != nil {
+		return err
+	}
+
+	if cfg.Local.SeedRules != "" {
+		// The server reads the rules with its own cache, so this one is disabled
+		rules := repositories.NewCachedRateLimitRulesRepository(
+			repositories.NewRateLimitRulesRepository(dynamoClient, cfg.Tables.RateLimitRules, cfg.Tables.RateLimitRulesHistory),
+			0,
+			0,
+		)
+
+		seeded, err := local.SeedRules(ctx, uc.NewManageRulesUC(rules, rules), cfg.Local.SeedRules)
+		if err != nil {
+			return err
+		}
+
+		for _, notificationType := range seeded {
+			fmt.Printf("Created the rule of %s\n", notificationType)
+		}
+	}
+
+	if cfg.Local.SeedAPIKey != "" {
+		apiKeys := repositories.NewAPIKeyRepository(dynamoClient, cfg.Tables.APIKeys)
+
+		created, err := local.SeedAPIKey(ctx, apiKeys, cfg.Local.SeedAPIKey)
+		if err != nil {
+			return err
+		}
+
+		if created {
+			fmt.Println("Created the API key of the local client")
+		}
+	}
+
+	router, err := di.Initialize()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Local.Address,
+		Handler:           local.NewServer(router.Handle),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Listening on http://%s, emails are sent to %s\n", cfg.Local.Address, cfg.AWS.SESEndpoint)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// createTables create the tables, retrying while DynamoDB Local starts
+func createTables(ctx context.Context, client infraestructure.DynamoTablesAPI, tables config.Tables) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	for {
+		created, err := local.CreateTables(ctx, client, tables)
+		for _, table := range created {
+			fmt.Printf("Created the table %s\n", table)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("DynamoDB Local is not available: %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package main have the command line tool to operate the rules, the quotas and the cache of the rate limiter
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"modak/send-notification/v1/internal/config"
+	"modak/send-notification/v1/internal/infraestructure"
+	"modak/send-notification/v1/internal/repositories"
+	"modak/send-notification/v1/internal/uc"
+)
+
+// List of storage backends
+const (
+	// backendDynamoDB the tables of the lambda functions
+	backendDynamoDB = "dynamodb"
+	// backendFile every table in a JSON file, for offline use
+	backendFile = "file"
+	// backendMemory every table in memory, lost when the command ends
+	backendMemory = "memory"
+)
+
+// offlineEnv values of the offline backends for the required configuration that is not set, the tables are
+// not used and the emails are written to stdout
+var offlineEnv = map[string]string{
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME":         "offline",
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_HISTORY_TABLE_NAME": "offline",
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME":         "offline",
+	"DYNAMODB_NOTIFICATION_RECIPIENT_PROFILES_TABLE_NAME":       "offline",
+	"DYNAMODB_NOTIFICATION_RECIPIENT_PREFERENCES_TABLE_NAME":    "offline",
+	"DYNAMODB_NOTIFICATION_SUPPRESSION_LIST_TABLE_NAME":         "offline",
+	"DYNAMODB_NOTIFICATION_API_KEYS_TABLE_NAME":                 "offline",
+	"SENDER_FROM_ADDRESS":                                       "ratelimitctl@localhost",
+	"UNSUBSCRIBE_SIGNING_SECRET":                                "ratelimitctl-offline",
+	"UNSUBSCRIBE_BASE_URL":                                      "http://localhost/v1/unsubscribe",
+}
+
+// backend repositories and clients of one storage backend
+type backend struct {
+	rules        uc.RateLimitRulesRepositoryInterface
+	cache        uc.RateLimitCacheRepositoryInterface
+	profiles     uc.RecipientProfileRepositoryInterface
+	preferences  uc.RecipientPreferencesRepositoryInterface
+	suppressions uc.SuppressionRepositoryInterface
+	apiKeys      uc.APIKeyRepositoryInterface
+	ses          infraestructure.SESAPI
+	config       *config.Config
+}
+
+// newBackend build the repositories of the backend, the offline backends write the emails to stdout
+func newBackend(name, file string, stdout io.Writer) (*backend, error) {
+	switch name {
+	case backendDynamoDB:
+		cfg, err := config.Load(os.Getenv)
+		if err != nil {
+			return nil, err
+		}
+
+		awsSession := infraestructure.NewSessionProvider(cfg.AWS.SessionConfig())
+		awsConfig := infraestructure.NewConfigProvider(cfg.AWS.SessionConfig())
+
+		dynamoConfig := &infraestructure.DynamoConfig{Endpoint: cfg.AWS.DynamoDBEndpoint}
+		sesConfig := &infraestructure.SESConfig{Endpoint: cfg.AWS.SESEndpoint}
+
+		// Same clients as the lambda functions, aws-sdk-go-v2 unless the config selects aws-sdk-go
+		dynamoProvider := infraestructure.NewDynamoV2Provider(awsConfig, dynamoConfig)
+		sesProvider := infraestructure.NewSESV2Provider(awsConfig, sesConfig)
+		if cfg.AWS.SDKVersion == config.SDKV1 {
+			dynamoProvider = infraestructure.NewDynamoProvider(awsSession, dynamoConfig)
+			sesProvider = infraestructure.NewSESProvider(awsSession, sesConfig)
+		}
+
+		dynamoClient, err := dynamoProvider.DynamoClient()
+		if err != nil {
+			return nil, err
+		}
+
+		sesClient, err := sesProvider.SESClient()
+		if err != nil {
+			return nil, err
+		}
+
+		return &backend{
+			rules: repositories.NewRateLimitRulesRepository(
+				dynamoClient,
+				cfg.Tables.RateLimitRules,
+				cfg.Tables.RateLimitRulesHistory,
+			),
+			cache:       repositories.NewRateLimitCacheRepository(dynamoClient, cfg.Tables.RateLimitCache),
+			profiles:    repositories.NewRecipientProfileRepository(dynamoClient, cfg.Tables.RecipientProfiles),
+			preferences: repositories.NewRecipientPreferencesRepository(dynamoClient, cfg.Tables.RecipientPreferences),
+			suppressions: repositories.NewSuppressionRepository(
+				dynamoClient,
+				cfg.Tables.SuppressionList,
+				cfg.RecipientNormalizer,
+			),
+			apiKeys: repositories.NewAPIKeyRepository(dynamoClient, cfg.Tables.APIKeys),
+			ses:     sesClient,
+			config:  cfg,
+		}, nil
+	case backendFile, backendMemory:
+		cfg, err := config.Load(func(name string) string {
+			return envOrDefault(name, offlineEnv[name])
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		path := file
+		if name == backendMemory {
+			path = ""
+		}
+
+		store, err := repositories.NewMemoryStore(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return &backend{
+			rules:        repositories.NewMemoryRateLimitRulesRepository(store),
+			cache:        repositories.NewMemoryRateLimitCacheRepository(store),
+			profiles:     repositories.NewMemoryRecipientProfileRepository(store),
+			preferences:  repositories.NewMemoryRecipientPreferencesRepository(store),
+			suppressions: repositories.NewMemorySuppressionRepository(store, cfg.RecipientNormalizer),
+			apiKeys:      repositories.NewMemoryAPIKeyRepository(store),
+			ses:          infraestructure.NewWriterSES(stdout),
+			config:       cfg,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend '%s', expected %s, %s or %s", name, backendDynamoDB, backendFile, backendMemory)
+	}
+}
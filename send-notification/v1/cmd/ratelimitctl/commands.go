@@ -0,0 +1,588 @@
+// Package main have the command line tool to operate the rules, the quotas and the cache of the rate limiter
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+	"modak/send-notification/v1/internal/repositories"
+	"modak/send-notification/v1/internal/services"
+	"modak/send-notification/v1/internal/uc"
+
+	"sigs.k8s.io/yaml"
+)
+
+// cli commands of the tool over one backend
+type cli struct {
+	backend       *backend
+	manageRulesUC *uc.ManageRulesUC
+	manageQuotaUC *uc.ManageQuotaUC
+	manageKeysUC  *uc.ManageAPIKeysUC
+	stdout        io.Writer
+}
+
+// execute run one command with its arguments
+func (c *cli) execute(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "rules":
+		if len(args) == 0 {
+			return errUsage
+		}
+
+		return c.rules(ctx, args[0], args[1:])
+	case "keys":
+		if len(args) == 0 {
+			return errUsage
+		}
+
+		return c.keys(ctx, args[0], args[1:])
+	case "usage":
+		return c.usage(ctx, args)
+	case "reset":
+		return c.reset(ctx, args)
+	case "send":
+		return c.send(ctx, args)
+	default:
+		return fmt.Errorf("unknown command '%s': %w", command, errUsage)
+	}
+}
+
+// rules run one of the subcommands about the rules
+func (c *cli) rules(ctx context.Context, subcommand string, args []string) error {
+	switch subcommand {
+	case "list":
+		rules, err := c.manageRulesUC.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		return c.printRules(rules)
+	case "get":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		rule, err := c.manageRulesUC.Get(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		return c.printYAML(rule)
+	case "set":
+		return c.setRule(ctx, args)
+	case "delete":
+		return c.deleteRule(ctx, args)
+	case "history":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		history, err := c.manageRulesUC.History(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		return c.printYAML(internal.RuleHistoryResponseBody{History: history})
+	case "export":
+		return c.exportRules(ctx, args)
+	case "import":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		return c.importRules(ctx, args[0])
+	default:
+		return fmt.Errorf("unknown rules command '%s': %w", subcommand, errUsage)
+	}
+}
+
+// setRule create the rule of a type or change the attributes given by flags of its current rule
+func (c *cli) setRule(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	notificationType := args[0]
+
+	flags := c.flagSet("rules set <type>")
+	limit := flags.Int("limit", 0, "maximum number of notifications per recipient inside the window")
+	intervalInMinutes := flags.Int("interval-in-minutes", 0, "size of the rolling window in minutes")
+	interval := flags.String("interval", "", "Go or ISO-8601 duration of the rolling window")
+	windowAlignment := flags.String("window-alignment", "", "rolling, calendar_day or calendar_week")
+	timezone := flags.String("timezone", "", "IANA timezone of the calendar windows")
+	quietHours := flags.String("quiet-hours", "", "HH:MM-HH:MM local time of the recipient, \"none\" to remove them")
+	quietHoursExempt := flags.Bool("quiet-hours-exempt", false, "send the type even inside quiet hours")
+	algorithm := flags.String("algorithm", "", "algorithm used to count the notifications")
+	dedupWindow := flags.String("dedup-window", "", "Go or ISO-8601 duration where the same message is sent once")
+	senderFrom := flags.String("sender-from", "", "from address of the emails of the type, \"none\" to remove the sender")
+	senderName := flags.String("sender-name", "", "display name of the sender of the emails of the type")
+	senderReplyTo := flags.String("sender-reply-to", "", "reply-to address of the emails of the type")
+	priorityDefault := flags.String("priority-default", "", "priority of the notifications of the type without one")
+	priorityMax := flags.String("priority-max", "", "highest priority the notifications of the type can ask for")
+	priorityBorrow := flags.Int("priority-borrow", 0, "notifications over the limit that high priority can use")
+	version := flags.Int("version", -1, "version that is replaced, the current one by default")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	current, err := c.backend.rules.GetByType(ctx, notificationType)
+	if err != nil {
+		return err
+	}
+
+	rule := internal.RateLimitRule{Type: notificationType}
+	if current != nil {
+		rule = *current
+	}
+
+	var parseErr error
+
+	// Only the attributes given by flags are changed
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "limit":
+			rule.NotificationsLimit = *limit
+		case "interval-in-minutes":
+			rule.IntervalInMinutes = *intervalInMinutes
+		case "interval":
+			rule.Interval = *interval
+		case "window-alignment":
+			rule.WindowAlignment = *windowAlignment
+		case "timezone":
+			rule.Timezone = *timezone
+		case "quiet-hours":
+			rule.QuietHours = nil
+			if *quietHours != "none" {
+				rule.QuietHours, parseErr = internal.ParseQuietHours(*quietHours)
+			}
+		case "quiet-hours-exempt":
+			rule.QuietHoursExempt = *quietHoursExempt
+		case "algorithm":
+			rule.Algorithm = *algorithm
+		case "dedup-window":
+			rule.DedupWindow = *dedupWindow
+		case "sender-from":
+			rule.Sender = setSender(rule.Sender, f.Name, *senderFrom)
+		case "sender-name":
+			rule.Sender = setSender(rule.Sender, f.Name, *senderName)
+		case "sender-reply-to":
+			rule.Sender = setSender(rule.Sender, f.Name, *senderReplyTo)
+		case "priority-default":
+			rule.Priority = setPriority(rule.Priority, func(p *internal.PriorityPolicy) { p.Default = *priorityDefault })
+		case "priority-max":
+			rule.Priority = setPriority(rule.Priority, func(p *internal.PriorityPolicy) { p.Max = *priorityMax })
+		case "priority-borrow":
+			rule.Priority = setPriority(rule.Priority, func(p *internal.PriorityPolicy) { p.Borrow = *priorityBorrow })
+		case "version":
+			rule.Version = *version
+		}
+	})
+
+	if parseErr != nil {
+		return parseErr
+	}
+
+	var saved *internal.RateLimitRule
+
+	if current == nil {
+		saved, err = c.manageRulesUC.Create(ctx, rule)
+	} else {
+		saved, err = c.manageRulesUC.Update(ctx, rule)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.printYAML(saved)
+}
+
+// deleteRule delete the rule of a type
+func (c *cli) deleteRule(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	flags := c.flagSet("rules delete <type>")
+	version := flags.Int("version", -1, "version that is deleted, the current one by default")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *version < 0 {
+		current, err := c.manageRulesUC.Get(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		*version = current.Version
+	}
+
+	if err := c.manageRulesUC.Delete(ctx, args[0], *version); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Rule of type %s deleted\n", args[0])
+
+	return nil
+}
+
+// exportRules write every rule as YAML to stdout or to a file
+func (c *cli) exportRules(ctx context.Context, args []string) error {
+	flags := c.flagSet("rules export")
+	output := flags.String("o", "", "file where the rules are written, stdout by default")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	rules, err := c.manageRulesUC.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(internal.RulesResponseBody{Rules: rules})
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = c.stdout.Write(content)
+
+		return err
+	}
+
+	return os.WriteFile(*output, content, 0o600)
+}
+
+// importRules create the rules of a YAML file, the rules of types that already have one replace the current version
+func (c *cli) importRules(ctx context.Context, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file internal.RulesResponseBody
+
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	// Every rule is validated before writing any of them
+	for _, rule := range file.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid rule of type '%s': %w", rule.Type, err)
+		}
+	}
+
+	for _, rule := range file.Rules {
+		current, err := c.backend.rules.GetByType(ctx, rule.Type)
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			_, err = c.manageRulesUC.Create(ctx, rule)
+			fmt.Fprintf(c.stdout, "created %s\n", rule.Type)
+		} else {
+			rule.Version = current.Version
+			_, err = c.manageRulesUC.Update(ctx, rule)
+			fmt.Fprintf(c.stdout, "updated %s\n", rule.Type)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keys run one of the subcommands about the API keys of the clients
+func (c *cli) keys(ctx context.Context, subcommand string, args []string) error {
+	switch subcommand {
+	case "create":
+		return c.createKey(ctx, args)
+	case "list":
+		apiKeys, err := c.manageKeysUC.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		table := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "ID\tTENANT\tTYPES\tRATE LIMIT\tDISABLED\tCREATED")
+
+		for _, apiKey := range apiKeys {
+			fmt.Fprintf(
+				table, "%s\t%s\t%s\t%s\t%t\t%s\n",
+				apiKey.ID, apiKey.Tenant, strings.Join(apiKey.AllowedTypes, ","), apiKey.RateLimit, apiKey.Disabled,
+				apiKey.CreatedAt.Format(time.RFC3339),
+			)
+		}
+
+		return table.Flush()
+	case "disable":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		if err := c.manageKeysUC.Disable(ctx, args[0]); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Disabled %s\n", args[0])
+
+		return nil
+	default:
+		return fmt.Errorf("unknown keys command '%s': %w", subcommand, errUsage)
+	}
+}
+
+// createKey create an API key and print it, it can not be shown again because only its hash is kept
+func (c *cli) createKey(ctx context.Context, args []string) error {
+	flags := c.flagSet("keys create")
+	apiKey := internal.APIKey{}
+	flags.StringVar(&apiKey.Tenant, "tenant", "", "tenant of the client, the global rules when it is empty")
+	types := flags.String("types", internal.AnyType, "comma separated types the client can send, * for every type")
+	flags.StringVar(&apiKey.RateLimit, "rate-limit", "", "requests of the client like 100/1m, the default when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	for _, notificationType := range strings.Split(*types, ",") {
+		if notificationType = strings.TrimSpace(notificationType); notificationType != "" {
+			apiKey.AllowedTypes = append(apiKey.AllowedTypes, notificationType)
+		}
+	}
+
+	key, created, err := c.manageKeysUC.Create(ctx, apiKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Created %s, the key is only shown now:\n%s\n", created.ID, key)
+
+	return nil
+}
+
+// usage show the notifications sent to a recipient and the remaining quota per type
+func (c *cli) usage(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	usages, err := c.manageQuotaUC.Usage(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TYPE\tLIMIT\tUSED\tREMAINING\tWINDOW START")
+
+	for _, usage := range usages {
+		fmt.Fprintf(
+			table, "%s\t%d\t%d\t%d\t%s\n",
+			usage.Type, usage.Limit, usage.Used, usage.Remaining, usage.WindowStart.Format(time.RFC3339),
+		)
+	}
+
+	return table.Flush()
+}
+
+// reset start again the window of a recipient
+func (c *cli) reset(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	flags := c.flagSet("reset <email>")
+	notificationType := flags.String("type", "", "type whose window is reset, every type by default")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	deleted, err := c.manageQuotaUC.Reset(ctx, args[0], *notificationType)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Removed %d notifications of %s\n", deleted, args[0])
+
+	return nil
+}
+
+// send send a test notification through the rate limiter, it uses quota like any other notification
+func (c *cli) send(ctx context.Context, args []string) error {
+	flags := c.flagSet("send")
+	notification := internal.Notification{}
+	flags.StringVar(&notification.Type, "type", "", "notification type")
+	flags.StringVar(&notification.Recipient, "recipient", "", "recipient email")
+	flags.StringVar(&notification.Message, "message", "Test notification sent by ratelimitctl", "message")
+	flags.StringVar(&notification.Priority, "priority", "", "critical, high, normal or low, the default of the type")
+	tenant := flags.String("tenant", "", "tenant of the notification, its rules, quotas and sender are used")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if notification.Type == "" || notification.Recipient == "" {
+		return errUsage
+	}
+
+	ctx = internal.WithRequestMetadata(ctx, internal.RequestMetadata{Tenant: *tenant})
+
+	validation, err := uc.NewValidateRateLimitUC(
+		c.backend.rules,
+		c.backend.cache,
+		c.backend.profiles,
+		c.backend.preferences,
+		c.backend.config.DefaultQuietHours,
+		c.backend.config.RecipientNormalizer,
+		c.backend.config.RecipientCap,
+		infraestructure.NewLogrusProvider().Logger(),
+	).Handle(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	if !validation.Allowed {
+		fmt.Fprintf(c.stdout, "Not sent: %s\n", validation.Reason)
+
+		return nil
+	}
+
+	result, err := uc.NewSendNotificationUC(
+		services.NewEmailService(c.backend.ses, c.backend.config.SESConfigurationSet),
+		services.NewUnsubscribeLinkService(
+			c.backend.config.Unsubscribe.BaseURL,
+			c.backend.config.Unsubscribe.SigningSecret,
+			c.backend.config.Unsubscribe.LinkTTL,
+		),
+		c.backend.suppressions,
+		c.backend.rules,
+		// The notifications of the command have no attachments, so they do not need the object store
+		services.NewAttachmentService(nil, c.backend.config.Handler.MaxAttachmentsSize),
+		c.backend.config.Sender,
+	).Handle(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	if !result.Sent {
+		fmt.Fprintf(c.stdout, "Not sent: %s\n", result.Reason)
+
+		return nil
+	}
+
+	fmt.Fprintf(c.stdout, "Sent %s to %s, message ID %s\n", notification.Type, notification.Recipient, result.MessageID)
+
+	return nil
+}
+
+// setSender change an attribute of the sender of a rule given by a flag, the sender is removed when the
+// from address is "none"
+func setSender(sender *internal.Sender, flagName, value string) *internal.Sender {
+	if flagName == "sender-from" && value == "none" {
+		return nil
+	}
+
+	changed := internal.Sender{}
+	if sender != nil {
+		changed = *sender
+	}
+
+	switch flagName {
+	case "sender-from":
+		changed.FromAddress = value
+	case "sender-name":
+		changed.DisplayName = value
+	case "sender-reply-to":
+		changed.ReplyTo = value
+	}
+
+	return &changed
+}
+
+// setPriority apply a change of a flag to the priority policy of a rule, the other attributes are kept
+func setPriority(policy *internal.PriorityPolicy, change func(policy *internal.PriorityPolicy)) *internal.PriorityPolicy {
+	changed := internal.PriorityPolicy{}
+	if policy != nil {
+		changed = *policy
+	}
+
+	change(&changed)
+
+	return &changed
+}
+
+// printRules write the rules as a table
+func (c *cli) printRules(rules []internal.RateLimitRule) error {
+	table := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TYPE\tLIMIT\tINTERVAL\tWINDOW\tTIMEZONE\tVERSION")
+
+	for _, rule := range rules {
+		interval := rule.Interval
+		if interval == "" && rule.IntervalInMinutes > 0 {
+			interval = strconv.Itoa(rule.IntervalInMinutes) + "m"
+		}
+
+		windowAlignment := rule.WindowAlignment
+		if windowAlignment == "" {
+			windowAlignment = internal.WindowAlignmentRolling
+		}
+
+		fmt.Fprintf(
+			table, "%s\t%d\t%s\t%s\t%s\t%d\n",
+			rule.Type, rule.NotificationsLimit, interval, windowAlignment, rule.Timezone, rule.Version,
+		)
+	}
+
+	return table.Flush()
+}
+
+// printYAML write the value as YAML
+func (c *cli) printYAML(value interface{}) error {
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.stdout.Write(content)
+
+	return err
+}
+
+// flagSet flags of a command that report the errors instead of exiting
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stdout)
+
+	return flags
+}
+
+// newCLI commands over the given backend
+func newCLI(b *backend, stdout io.Writer) *cli {
+	// Every execution runs one command, so the rules are not cached
+	rules := repositories.NewCachedRateLimitRulesRepository(b.rules, 0, 0)
+
+	return &cli{
+		backend:       b,
+		manageRulesUC: uc.NewManageRulesUC(rules, rules),
+		manageQuotaUC: uc.NewManageQuotaUC(b.rules, b.cache, b.profiles, b.config.RecipientNormalizer),
+		manageKeysUC:  uc.NewManageAPIKeysUC(b.apiKeys),
+		stdout:        stdout,
+	}
+}
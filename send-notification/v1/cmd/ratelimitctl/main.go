@@ -0,0 +1,92 @@
+// Package main have the command line tool to operate the rules, the quotas and the cache of the rate limiter
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// usage help of the tool
+const usage = `Usage: ratelimitctl [-backend dynamodb|file|memory] [-file path] <command> [arguments]
+
+Commands:
+  rules list                          list every rule
+  rules get <type>                    show the rule of a type
+  rules set <type> [flags]            create or edit the rule of a type, run "rules set -h" for the flags
+  rules delete <type> [-version n]    delete the rule of a type
+  rules history <type>                show the previous versions of the rule of a type
+  rules export [-o file]              write every rule as YAML
+  rules import <file>                 create or replace the rules of a YAML file
+  keys create [-tenant t] [-types News,Status] [-rate-limit 100/1m]
+                                      create the API key of a client, the key is only shown once
+  keys list                           list the API keys without the keys
+  keys disable <id>                   reject the requests of an API key
+  usage <email>                       show the notifications sent to a recipient and the remaining quota per type
+  reset <email> [-type type]          start again the window of a recipient, for one type or for every type
+  send -type t -recipient r -message m [-tenant t]
+                                      send a test notification through the rate limiter
+
+The dynamodb backend uses the same environment variables as the lambda functions, the file backend keeps
+every table in a JSON file and the memory backend only lives during the execution.
+`
+
+// errUsage the command line is not valid
+var errUsage = errors.New("invalid command line, run ratelimitctl -h for the usage")
+
+func main() {
+	// An interrupt cancels the requests in flight instead of killing them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// run execute the command of the arguments writing its output
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ratelimitctl", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() { fmt.Fprint(stdout, usage) }
+
+	backendName := flags.String("backend", envOrDefault("RATELIMITCTL_BACKEND", backendDynamoDB), "storage backend")
+	file := flags.String("file", envOrDefault("RATELIMITCTL_FILE", "ratelimit.json"), "JSON file of the file backend")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	b, err := newBackend(*backendName, *file, stdout)
+	if err != nil {
+		return err
+	}
+
+	return newCLI(b, stdout).execute(ctx, flags.Arg(0), flags.Args()[1:])
+}
+
+// envOrDefault value of an environment variable, the default when it is empty
+func envOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
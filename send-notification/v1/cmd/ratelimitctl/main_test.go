@@ -0,0 +1,186 @@
+// Package main have the command line tool to operate the rules, the quotas and the cache of the rate limiter
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand execute the tool over the file backend of the test returning its output
+func runCommand(t *testing.T, file string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+
+	err := run(context.Background(), append([]string{"-backend", backendFile, "-file", file}, args...), &stdout)
+
+	return stdout.String(), err
+}
+
+func TestRun_Rules(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ratelimit.json")
+
+	_, err := runCommand(t, file, "rules", "set", "News", "-limit", "1", "-window-alignment", "calendar_day")
+	assert.NoError(t, err)
+
+	_, err = runCommand(t, file, "rules", "set", "News", "-timezone", "America/Bogota")
+	assert.NoError(t, err)
+
+	out, err := runCommand(t, file, "rules", "get", "News")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "notifications_limit: 1")
+	assert.Contains(t, out, "timezone: America/Bogota")
+	assert.Contains(t, out, "version: 2")
+
+	_, err = runCommand(t, file, "rules", "set", "News", "-limit", "5", "-version", "1")
+	assert.ErrorContains(t, err, "was changed")
+
+	_, err = runCommand(t, file, "rules", "set", "Status", "-limit", "-1", "-interval", "10s")
+	assert.ErrorContains(t, err, "notifications_limit must not be negative")
+
+	out, err = runCommand(t, file, "rules", "history", "News")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "operation: update")
+
+	export := filepath.Join(dir, "rules.yaml")
+	_, err = runCommand(t, file, "rules", "export", "-o", export)
+	assert.NoError(t, err)
+
+	// The exported rules are imported in a new store
+	out, err = runCommand(t, filepath.Join(dir, "other.json"), "rules", "import", export)
+	assert.NoError(t, err)
+	assert.Equal(t, "created News\n", out)
+
+	out, err = runCommand(t, filepath.Join(dir, "other.json"), "rules", "list")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "calendar_day  America/Bogota")
+
+	assert.NoError(t, os.WriteFile(export, []byte("rules:\n- type: News\n  notifications_limit: \"3\"\n"), 0o600))
+	_, err = runCommand(t, file, "rules", "import", export)
+	assert.Error(t, err)
+
+	_, err = runCommand(t, file, "rules", "delete", "News")
+	assert.NoError(t, err)
+
+	out, err = runCommand(t, file, "rules", "list")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+}
+
+func TestRun_Quota(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	_, err := runCommand(t, file, "rules", "set", "Status", "-limit", "2", "-interval", "1m")
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		out, err := runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com")
+		assert.NoError(t, err)
+		assert.Contains(t, out, "Sent Status to user@example.com")
+		assert.Contains(t, out, "List-Unsubscribe: <http://localhost/v1/unsubscribe?token=")
+	}
+
+	out, err := runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Not sent: rate_limited\n", out)
+
+	// The tenants have their own quota of the global rule
+	out, err = runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com", "-tenant", "acme")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Sent Status to user@example.com")
+
+	out, err = runCommand(t, file, "usage", "user@example.com")
+	assert.NoError(t, err)
+	assert.Regexp(t, `Status\s+2\s+2\s+0\s+`, out)
+
+	out, err = runCommand(t, file, "reset", "user@example.com", "-type", "Status")
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed 2 notifications of user@example.com\n", out)
+
+	out, err = runCommand(t, file, "usage", "user@example.com")
+	assert.NoError(t, err)
+	assert.Regexp(t, `Status\s+2\s+0\s+2\s+`, out)
+}
+
+func TestRun_InvalidCommandLine(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	_, err := runCommand(t, file)
+	assert.ErrorIs(t, err, errUsage)
+
+	_, err = runCommand(t, file, "rules", "rename")
+	assert.ErrorIs(t, err, errUsage)
+
+	err = run(context.Background(), []string{"-backend", "postgres", "rules", "list"}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unknown backend 'postgres'")
+}
+
+func TestRun_Sender(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	t.Setenv("SENDER_FROM_ADDRESS", "")
+	t.Setenv("SENDER_ALLOWED_IDENTITIES", "")
+
+	_, err := runCommand(t, file, "rules", "set", "Status", "-limit", "5", "-interval", "1m")
+	assert.NoError(t, err)
+
+	out, err := runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "From: ratelimitctl@localhost\r\n")
+
+	_, err = runCommand(t, file, "rules", "set", "Status", "-sender-from", "status@example.org", "-sender-name", "Status")
+	assert.NoError(t, err)
+
+	out, err = runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Not sent: sender_not_allowed\n", out)
+
+	t.Setenv("SENDER_ALLOWED_IDENTITIES", "example.org")
+
+	out, err = runCommand(t, file, "send", "-type", "Status", "-recipient", "user@example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "From: \"Status\" <status@example.org>\r\n")
+
+	_, err = runCommand(t, file, "rules", "set", "Status", "-sender-reply-to", "help")
+	assert.ErrorContains(t, err, "sender reply_to 'help' is not an email address")
+}
+
+func TestRun_Keys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	out, err := runCommand(
+		t, file, "keys", "create", "-tenant", "acme", "-types", "News, Status", "-rate-limit", "100/1m",
+	)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^Created [0-9a-f]{16}, the key is only shown now:\nmk_[0-9a-f]{16}_[0-9a-f]{64}\n$`, out)
+
+	id := strings.Fields(out)[1]
+	id = strings.TrimSuffix(id, ",")
+	key := strings.TrimSpace(strings.Split(out, "\n")[1])
+
+	out, err = runCommand(t, file, "keys", "list")
+	assert.NoError(t, err)
+	assert.Regexp(t, id+`\s+acme\s+News,Status\s+100/1m\s+false\s+`, out)
+	assert.NotContains(t, out, key)
+
+	_, err = runCommand(t, file, "keys", "create", "-rate-limit", "100")
+	assert.ErrorContains(t, err, "invalid request rate '100'")
+
+	out, err = runCommand(t, file, "keys", "disable", id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Disabled "+id+"\n", out)
+
+	out, err = runCommand(t, file, "keys", "list")
+	assert.NoError(t, err)
+	assert.Regexp(t, id+`\s+acme\s+News,Status\s+100/1m\s+true\s+`, out)
+
+	_, err = runCommand(t, file, "keys", "disable", "0000000000000000")
+	assert.ErrorContains(t, err, "does not exist")
+}
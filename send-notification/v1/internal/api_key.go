@@ -0,0 +1,165 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader header with the API key of the requests that send notifications
+const APIKeyHeader = "X-Api-Key"
+
+// apiKeyPrefix prefix of the API keys, it tells them apart from other secrets in the logs and the secret scanners
+const apiKeyPrefix = "mk_"
+
+// AnyType allowed type of the clients that can send notifications of every type
+const AnyType = "*"
+
+// DefaultClientRateLimit requests that a client can send in a window when its API key has no rate limit
+const DefaultClientRateLimit = "600/1m"
+
+// Client caller of the API that sends notifications, authenticated by its API key or its bearer token
+type Client struct {
+	// ID public identifier of the client, its requests are counted with it
+	ID string
+	// Tenant of the notifications of the client, empty for the clients without tenant
+	Tenant string
+	// AllowedTypes types of the notifications that the client can send, AnyType for every type
+	AllowedTypes []string
+}
+
+// Allows check if the client can send notifications of the type, the types are compared ignoring the case because
+// the scopes of the tokens are usually lowercase
+func (c Client) Allows(notificationType string) bool {
+	for _, allowedType := range c.AllowedTypes {
+		if allowedType == AnyType || strings.EqualFold(allowedType, notificationType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIKey item of the NotificationAPIKeys table, only the hash of the key is stored so the keys can not be read
+// from the table
+type APIKey struct {
+	// PK KEY#<hash>, set by the repository
+	PK string `dynamodbav:"pk" json:"-"`
+	// Hash of the key of HashAPIKey, the requests are authenticated with it
+	Hash         string   `dynamodbav:"hash" json:"-"`
+	ID           string   `dynamodbav:"id" json:"id"`
+	Tenant       string   `dynamodbav:"tenant,omitempty" json:"tenant,omitempty"`
+	AllowedTypes []string `dynamodbav:"allowed_types" json:"allowed_types"`
+	// RateLimit requests that the client can send in a window with the format "limit/window" like 100/1m, empty to
+	// use the default of the service
+	RateLimit string    `dynamodbav:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	Disabled  bool      `dynamodbav:"disabled,omitempty" json:"disabled,omitempty"`
+	CreatedAt time.Time `dynamodbav:"created_at" json:"created_at"`
+}
+
+// Validate check the tenant, the allowed types and the rate limit of the key, every problem found is returned in
+// one error
+func (k APIKey) Validate() error {
+	var problems []string
+
+	if k.Tenant != "" && !ValidTenant(k.Tenant) {
+		problems = append(problems, fmt.Sprintf(
+			"tenant '%s' must have between 1 and 64 letters, digits, '_', '.' or '-'", k.Tenant,
+		))
+	}
+
+	if len(k.AllowedTypes) == 0 {
+		problems = append(problems, "allowed_types must have at least one type, '*' for every type")
+	}
+
+	for _, allowedType := range k.AllowedTypes {
+		if allowedType != AnyType && !notificationTypeRegexp.MatchString(allowedType) {
+			problems = append(problems, fmt.Sprintf("invalid allowed type '%s'", allowedType))
+		}
+	}
+
+	if k.RateLimit != "" {
+		if _, err := ParseRequestRate(k.RateLimit); err != nil {
+			problems = append(problems, "rate_limit: "+err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Client client authenticated by the key
+func (k APIKey) Client() Client {
+	return Client{ID: k.ID, Tenant: k.Tenant, AllowedTypes: k.AllowedTypes}
+}
+
+// NewAPIKeyValue generate a random API key with the format mk_<id>_<secret>, the id is returned too
+func NewAPIKeyValue() (string, string, error) {
+	random := make([]byte, 40)
+
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	id := hex.EncodeToString(random[:8])
+
+	return apiKeyPrefix + id + "_" + hex.EncodeToString(random[8:]), id, nil
+}
+
+// HashAPIKey hash of an API key, the keys are random so a hash without salt is enough to not store them
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// RequestRate maximum number of requests of a client in a fixed window
+type RequestRate struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled check if the requests are limited
+func (r RequestRate) Enabled() bool {
+	return r.Limit > 0
+}
+
+// ParseRequestRate parse a rate with the format "limit/window" like 100/1m, empty to not limit the requests
+func ParseRequestRate(value string) (RequestRate, error) {
+	if value == "" {
+		return RequestRate{}, nil
+	}
+
+	limit, window, ok := parseLimitWindow(value)
+	if !ok {
+		return RequestRate{}, fmt.Errorf("invalid request rate '%s', expected limit/window like 100/1m", value)
+	}
+
+	return RequestRate{Limit: limit, Window: window}, nil
+}
+
+// parseLimitWindow parse a positive limit and a positive duration with the format "limit/window"
+func parseLimitWindow(value string) (int, time.Duration, bool) {
+	limit, window, found := strings.Cut(value, "/")
+
+	number, err := strconv.Atoi(limit)
+	if !found || err != nil || number <= 0 {
+		return 0, 0, false
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return 0, 0, false
+	}
+
+	return number, duration, true
+}
@@ -0,0 +1,95 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIKey_Validate test for this method
+func TestAPIKey_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiKey  APIKey
+		wantErr string
+	}{
+		{
+			name:   "key of a tenant",
+			apiKey: APIKey{Tenant: "acme", AllowedTypes: []string{"News", "Status"}, RateLimit: "100/1m"},
+		},
+		{
+			name:   "key of every type",
+			apiKey: APIKey{AllowedTypes: []string{AnyType}},
+		},
+		{
+			name:   "every problem is reported",
+			apiKey: APIKey{Tenant: "acme:news", AllowedTypes: []string{"TYPE#News"}, RateLimit: "100"},
+			wantErr: "tenant 'acme:news' must have between 1 and 64 letters, digits, '_', '.' or '-'; " +
+				"invalid allowed type 'TYPE#News'; " +
+				"rate_limit: invalid request rate '100', expected limit/window like 100/1m",
+		},
+		{
+			name:    "without allowed types",
+			apiKey:  APIKey{Tenant: "acme"},
+			wantErr: "allowed_types must have at least one type, '*' for every type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.apiKey.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestClient_Allows test for this method
+func TestClient_Allows(t *testing.T) {
+	client := Client{AllowedTypes: []string{"News", "Status"}}
+
+	assert.True(t, client.Allows("News"))
+	assert.False(t, client.Allows("Marketing"))
+	assert.True(t, Client{AllowedTypes: []string{AnyType}}.Allows("Marketing"))
+	assert.False(t, Client{}.Allows("News"))
+}
+
+// TestNewAPIKeyValue test for this function and HashAPIKey
+func TestNewAPIKeyValue(t *testing.T) {
+	key, id, err := NewAPIKeyValue()
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile("^mk_"+id+"_[0-9a-f]{64}$"), key)
+	assert.Len(t, id, 16)
+
+	other, _, err := NewAPIKeyValue()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	assert.Len(t, HashAPIKey(key), 64)
+	assert.Equal(t, HashAPIKey(key), HashAPIKey(key))
+	assert.NotEqual(t, HashAPIKey(key), HashAPIKey(other))
+}
+
+// TestParseRequestRate test for this function
+func TestParseRequestRate(t *testing.T) {
+	rate, err := ParseRequestRate("100/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, RequestRate{Limit: 100, Window: time.Minute}, rate)
+
+	rate, err = ParseRequestRate("")
+	assert.NoError(t, err)
+	assert.False(t, rate.Enabled())
+
+	for _, value := range []string{"100", "0/1m", "100/0s", "100/minute"} {
+		_, err := ParseRequestRate(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,145 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxAttachmentsSize maximum size in bytes of the decoded attachments of one notification, the base64
+// encoding of the email adds a third so it stays below the 40 MB that SES accepts
+const DefaultMaxAttachmentsSize = 10 * 1024 * 1024
+
+// MaxAttachmentsPerNotification maximum number of attachments of one notification
+const MaxAttachmentsPerNotification = 10
+
+// objectPathScheme scheme of the paths of the attachments kept in the object store
+const objectPathScheme = "s3://"
+
+// maxFilenameLength maximum length of the filename of an attachment
+const maxFilenameLength = 255
+
+// ErrInvalidAttachment the attachment can not be loaded or the attachments are larger than the limit
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// Attachment file sent with a notification, its content is in Content encoded in base64 or in the object of
+// ObjectPath like s3://bucket/key
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content,omitempty"`
+	ObjectPath  string `json:"object_path,omitempty"`
+}
+
+// EmailAttachment file attached to an email with its decoded content
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ParseObjectPath bucket and key of a path of the object store like s3://bucket/key
+func ParseObjectPath(path string) (string, string, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(path, objectPathScheme), "/")
+	if !strings.HasPrefix(path, objectPathScheme) || !ok || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid object path '%s', expected s3://bucket/key", path)
+	}
+
+	return bucket, key, nil
+}
+
+// validateAttachments check the attachments of the notification and that their content fits maxSize, the size
+// of the objects is checked when they are loaded
+func (n Notification) validateAttachments(pointer string, maxSize int) []FieldError {
+	if len(n.Attachments) > MaxAttachmentsPerNotification {
+		return []FieldError{{
+			Pointer: pointer + "/attachments",
+			Detail: fmt.Sprintf("The notification has %d attachments, the maximum is %d",
+				len(n.Attachments), MaxAttachmentsPerNotification),
+		}}
+	}
+
+	var fields []FieldError
+
+	size := 0
+
+	for i, attachment := range n.Attachments {
+		attachmentPointer := fmt.Sprintf("%s/attachments/%d", pointer, i)
+
+		if detail := filenameProblem(attachment.Filename); detail != "" {
+			fields = append(fields, FieldError{Pointer: attachmentPointer + "/filename", Detail: detail})
+		}
+
+		if !validContentType(attachment.ContentType) {
+			fields = append(fields, FieldError{
+				Pointer: attachmentPointer + "/content_type",
+				Detail:  "The content type must be a media type like application/pdf or text/calendar; method=REQUEST",
+			})
+		}
+
+		switch {
+		case (attachment.Content == "") == (attachment.ObjectPath == ""):
+			fields = append(fields, FieldError{
+				Pointer: attachmentPointer,
+				Detail:  "The attachment must have either a content or an object path",
+			})
+		case attachment.ObjectPath != "":
+			if _, _, err := ParseObjectPath(attachment.ObjectPath); err != nil {
+				fields = append(fields, FieldError{
+					Pointer: attachmentPointer + "/object_path",
+					Detail:  "The object path must be like s3://bucket/key",
+				})
+			}
+		default:
+			data, err := base64.StdEncoding.DecodeString(attachment.Content)
+			if err != nil {
+				fields = append(fields, FieldError{
+					Pointer: attachmentPointer + "/content",
+					Detail:  "The content must be encoded in base64",
+				})
+
+				continue
+			}
+
+			size += len(data)
+		}
+	}
+
+	if size > maxSize {
+		fields = append(fields, FieldError{
+			Pointer: pointer + "/attachments",
+			Detail:  fmt.Sprintf("The attachments have %d bytes, the maximum is %d", size, maxSize),
+		})
+	}
+
+	return fields
+}
+
+// validContentType check that the content type is a media type like text/calendar with optional parameters
+func validContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && strings.Contains(mediaType, "/")
+}
+
+// filenameProblem problem of the filename of an attachment, empty when it is a name without directories nor
+// control characters
+func filenameProblem(filename string) string {
+	if strings.TrimSpace(filename) == "" {
+		return "The filename is required"
+	}
+
+	if len(filename) > maxFilenameLength {
+		return fmt.Sprintf("The filename must have at most %d characters", maxFilenameLength)
+	}
+
+	if strings.ContainsAny(filename, `/\`) || strings.IndexFunc(filename, unicode.IsControl) >= 0 {
+		return "The filename can not have directories nor control characters"
+	}
+
+	return ""
+}
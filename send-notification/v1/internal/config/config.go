@@ -0,0 +1,521 @@
+// Package config loads the configuration of the lambda functions and the tools
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"sigs.k8s.io/yaml"
+)
+
+// List of versions of the AWS SDK used by the clients
+const (
+	// SDKV1 aws-sdk-go
+	SDKV1 = "v1"
+	// SDKV2 aws-sdk-go-v2, the default
+	SDKV2 = "v2"
+)
+
+// ProfileLocal profile that runs the handlers against DynamoDB Local and a fake SES, see profileDefaults
+const ProfileLocal = "local"
+
+// profileDefaults defaults of each profile, the values that are set in the environment or the file override them
+var profileDefaults = map[string]map[string]string{
+	ProfileLocal: {
+		"AWS_ENDPOINT_URL_DYNAMODB":                                 "http://localhost:8000",
+		"AWS_ENDPOINT_URL_SESV2":                                    "http://localhost:8005",
+		"AWS_ACCESS_KEY_ID":                                         "local",
+		"AWS_SECRET_ACCESS_KEY":                                     "local",
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME":         "NotificationRateLimitRules",
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_HISTORY_TABLE_NAME": "NotificationRateLimitRulesHistory",
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME":         "NotificationRateLimitCache",
+		"DYNAMODB_NOTIFICATION_RECIPIENT_PROFILES_TABLE_NAME":       "NotificationRecipientProfiles",
+		"DYNAMODB_NOTIFICATION_RECIPIENT_PREFERENCES_TABLE_NAME":    "NotificationRecipientPreferences",
+		"DYNAMODB_NOTIFICATION_SUPPRESSION_LIST_TABLE_NAME":         "NotificationSuppressionList",
+		"DYNAMODB_NOTIFICATION_API_KEYS_TABLE_NAME":                 "NotificationAPIKeys",
+		"SENDER_FROM_ADDRESS":                                       "notifications@localhost",
+		"UNSUBSCRIBE_SIGNING_SECRET":                                "local-signing-secret",
+		"UNSUBSCRIBE_BASE_URL":                                      "http://localhost:3000/v1/unsubscribe",
+		"LOCAL_ADDRESS":                                             "localhost:3000",
+		"LOCAL_SEED_RULES":                                          "local/rules.yaml",
+		"LOCAL_SEED_API_KEY":                                        "local-api-key",
+	},
+}
+
+// defaultRegion region of the AWS clients when AWS_REGION is not set
+const defaultRegion = "us-east-1"
+
+// defaultRulesCacheTTL time that the rules are cached when RULES_CACHE_TTL is not set
+const defaultRulesCacheTTL = 30 * time.Second
+
+// defaultJWKSCacheTTL time that the JWKS of the bearer tokens is cached when JWT_JWKS_CACHE_TTL is not set
+const defaultJWKSCacheTTL = 10 * time.Minute
+
+// defaultUnsubscribeLinkTTL time that the unsubscribe links are valid when UNSUBSCRIBE_LINK_TTL is not set
+const defaultUnsubscribeLinkTTL = 90 * 24 * time.Hour
+
+// Config configuration of the lambda functions
+type Config struct {
+	// Profile set of defaults, empty for the deployed functions or ProfileLocal
+	Profile             string
+	AWS                 AWS
+	Tables              Tables
+	RulesCache          RulesCache
+	Handler             internal.HandlerConfig
+	Sender              internal.SenderConfig
+	SESConfigurationSet string
+	Unsubscribe         Unsubscribe
+	DefaultQuietHours   *internal.QuietHours
+	RecipientNormalizer internal.RecipientNormalizer
+	RecipientCap        internal.RecipientCap
+	// ClientRateLimit requests that a client can send in a window when its API key has no rate limit
+	ClientRateLimit internal.RequestRate
+	JWT             JWT
+	Local           Local
+}
+
+// AWS configuration of the AWS clients
+type AWS struct {
+	// SDKVersion SDK of the clients, SDKV1 or SDKV2
+	SDKVersion string
+	Region     string
+	// Endpoint override of the endpoint of every service, empty to resolve it from the region
+	Endpoint string
+	// DynamoDBEndpoint override of the DynamoDB endpoint, empty to use Endpoint
+	DynamoDBEndpoint string
+	// SESEndpoint override of the SES endpoint, empty to use Endpoint
+	SESEndpoint string
+	// S3Endpoint override of the S3 endpoint of the attachments, empty to use Endpoint
+	S3Endpoint string
+	// AccessKeyID and SecretAccessKey static credentials of the local profile, empty to use the default chain
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// SessionConfig config of the AWS session and of the config of the aws-sdk-go-v2 clients
+func (a AWS) SessionConfig() *infraestructure.SessionConfig {
+	return &infraestructure.SessionConfig{
+		Endpoint:        a.Endpoint,
+		Region:          a.Region,
+		AccessKeyID:     a.AccessKeyID,
+		SecretAccessKey: a.SecretAccessKey,
+	}
+}
+
+// Tables names of the DynamoDB tables
+type Tables struct {
+	RateLimitRules        string
+	RateLimitRulesHistory string
+	RateLimitCache        string
+	RecipientProfiles     string
+	RecipientPreferences  string
+	SuppressionList       string
+	APIKeys               string
+}
+
+// RulesCache time that the rules and the types without rule are cached, 0 disables each cache
+type RulesCache struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// Unsubscribe configuration of the unsubscribe links
+type Unsubscribe struct {
+	BaseURL       string
+	SigningSecret string
+	LinkTTL       time.Duration
+}
+
+// JWT verification of the bearer tokens of the requests that send notifications
+type JWT struct {
+	// JWKS URL or path of the file of the JWKS with the keys of the issuer, empty to not accept bearer tokens
+	JWKS string
+	// Audience that the tokens must have in their aud claim
+	Audience string
+	// Issuer that the tokens must have in their iss claim, empty to accept every issuer of the keys
+	Issuer string
+	// JWKSCacheTTL time that the keys of the JWKS are used before loading it again
+	JWKSCacheTTL time.Duration
+}
+
+// Local configuration of the local server of the local profile
+type Local struct {
+	// Address where the local server listens
+	Address string
+	// SeedRules YAML file with the rules created on start for the types without rule, like the one of
+	// ratelimitctl rules export, empty to not create any
+	SeedRules string
+	// SeedAPIKey API key created on start that can send every type, empty to not create it
+	SeedAPIKey string
+}
+
+// Load the configuration, getenv reads the environment variables. When CONFIG_FILE is set it is a YAML or JSON
+// object with the same names as the environment variables, and the environment variables that are set override
+// it. Every problem found is returned in one error so the function fails on startup instead of on a request
+func Load(getenv func(string) string) (*Config, error) {
+	l := &loader{getenv: getenv, read: map[string]bool{}}
+
+	if path := getenv("CONFIG_FILE"); path != "" {
+		if err := l.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	profile := l.profile()
+
+	config := &Config{
+		Profile: profile,
+		AWS: AWS{
+			SDKVersion:       l.oneOf("AWS_SDK_VERSION", SDKV2, SDKV1, SDKV2),
+			Region:           l.string("AWS_REGION", defaultRegion),
+			Endpoint:         l.string("AWS_ENDPOINT_URL", ""),
+			DynamoDBEndpoint: l.string("AWS_ENDPOINT_URL_DYNAMODB", ""),
+			SESEndpoint:      l.string("AWS_ENDPOINT_URL_SESV2", ""),
+			S3Endpoint:       l.string("AWS_ENDPOINT_URL_S3", ""),
+		},
+		Tables: Tables{
+			RateLimitRules:        l.required("DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME"),
+			RateLimitRulesHistory: l.required("DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_HISTORY_TABLE_NAME"),
+			RateLimitCache:        l.required("DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME"),
+			RecipientProfiles:     l.required("DYNAMODB_NOTIFICATION_RECIPIENT_PROFILES_TABLE_NAME"),
+			RecipientPreferences:  l.required("DYNAMODB_NOTIFICATION_RECIPIENT_PREFERENCES_TABLE_NAME"),
+			SuppressionList:       l.required("DYNAMODB_NOTIFICATION_SUPPRESSION_LIST_TABLE_NAME"),
+			APIKeys:               l.required("DYNAMODB_NOTIFICATION_API_KEYS_TABLE_NAME"),
+		},
+		RulesCache: RulesCache{
+			TTL:         l.duration("RULES_CACHE_TTL", defaultRulesCacheTTL),
+			NegativeTTL: l.duration("RULES_CACHE_NEGATIVE_TTL", defaultRulesCacheTTL),
+		},
+		Handler: internal.HandlerConfig{
+			Workers:            l.positiveInt("SEND_WORKERS", internal.DefaultSendWorkers),
+			MaxNotifications:   l.positiveInt("MAX_NOTIFICATIONS_PER_REQUEST", internal.DefaultMaxNotificationsPerRequest),
+			MaxRecipients:      l.positiveInt("MAX_RECIPIENTS_PER_REQUEST", internal.DefaultMaxRecipientsPerRequest),
+			DeadlineMargin:     l.duration("REQUEST_DEADLINE_MARGIN", internal.DefaultRequestDeadlineMargin),
+			MaxMessageSize:     l.positiveInt("MAX_MESSAGE_SIZE", internal.DefaultMaxMessageSize),
+			MaxAttachmentsSize: l.positiveInt("MAX_ATTACHMENTS_SIZE", internal.DefaultMaxAttachmentsSize),
+		},
+		SESConfigurationSet: l.string("SES_CONFIGURATION_SET", ""),
+		Unsubscribe: Unsubscribe{
+			BaseURL:       l.string("UNSUBSCRIBE_BASE_URL", ""),
+			SigningSecret: l.required("UNSUBSCRIBE_SIGNING_SECRET"),
+			LinkTTL:       l.duration("UNSUBSCRIBE_LINK_TTL", defaultUnsubscribeLinkTTL),
+		},
+	}
+
+	if profile == ProfileLocal {
+		config.AWS.AccessKeyID = l.string("AWS_ACCESS_KEY_ID", "")
+		config.AWS.SecretAccessKey = l.string("AWS_SECRET_ACCESS_KEY", "")
+		config.Local = Local{
+			Address:    l.string("LOCAL_ADDRESS", ""),
+			SeedRules:  l.string("LOCAL_SEED_RULES", ""),
+			SeedAPIKey: l.string("LOCAL_SEED_API_KEY", ""),
+		}
+	}
+
+	config.Handler.MessageSizeByType = l.messageSizes("MESSAGE_SIZE_BY_TYPE")
+	config.Handler.Tenants = l.tenants("TENANT_HEADER", "TENANT_API_KEYS")
+	config.Sender = l.sender()
+	config.DefaultQuietHours = l.quietHours("DEFAULT_QUIET_HOURS")
+	config.RecipientNormalizer = l.recipientNormalizer("RECIPIENT_NORMALIZATION")
+	config.RecipientCap = l.recipientCap("RECIPIENT_CAP", "RECIPIENT_CAP_LOW_PRIORITY_PERCENT")
+	config.ClientRateLimit = l.requestRate("CLIENT_RATE_LIMIT", internal.DefaultClientRateLimit)
+	config.JWT = l.jwt()
+	l.checkUnknown()
+
+	if len(l.problems) > 0 {
+		return nil, errors.New("invalid configuration: " + strings.Join(l.problems, "; "))
+	}
+
+	return config, nil
+}
+
+// loader values of the configuration and the problems found reading them
+type loader struct {
+	getenv   func(string) string
+	file     map[string]string
+	defaults map[string]string
+	read     map[string]bool
+	problems []string
+}
+
+// loadFile read the values of the configuration file, its values are strings, numbers or booleans
+func (l *loader) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: CONFIG_FILE: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("invalid configuration: CONFIG_FILE '%s': %w", path, err)
+	}
+
+	l.file = make(map[string]string, len(values))
+
+	for name, value := range values {
+		switch value := value.(type) {
+		case nil:
+		case string:
+			l.file[name] = value
+		case float64:
+			l.file[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			l.file[name] = strconv.FormatBool(value)
+		default:
+			l.problems = append(l.problems, fmt.Sprintf("%s in CONFIG_FILE must be a string, a number or a boolean", name))
+		}
+	}
+
+	return nil
+}
+
+// profile read PROFILE, the following values take the defaults of the profile
+func (l *loader) profile() string {
+	profile := l.string("PROFILE", "")
+	if profile == "" {
+		return ""
+	}
+
+	defaults, ok := profileDefaults[profile]
+	if !ok {
+		l.problems = append(l.problems, fmt.Sprintf("invalid PROFILE '%s', expected %s", profile, ProfileLocal))
+
+		return ""
+	}
+
+	l.defaults = defaults
+
+	return profile
+}
+
+// string value of the environment variable, then of the file, then the default of the profile and then
+// the given default
+func (l *loader) string(name, defaultValue string) string {
+	l.read[name] = true
+
+	if value := l.getenv(name); value != "" {
+		return value
+	}
+
+	if value := l.file[name]; value != "" {
+		return value
+	}
+
+	if value := l.defaults[name]; value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// required value that can not be empty
+func (l *loader) required(name string) string {
+	value := l.string(name, "")
+	if value == "" {
+		l.problems = append(l.problems, name+" is required")
+	}
+
+	return value
+}
+
+// oneOf value that must be one of the given values
+func (l *loader) oneOf(name, defaultValue string, values ...string) string {
+	value := l.string(name, defaultValue)
+
+	for _, allowed := range values {
+		if value == allowed {
+			return value
+		}
+	}
+
+	l.problems = append(l.problems, fmt.Sprintf("invalid %s '%s', expected %s", name, value, strings.Join(values, " or ")))
+
+	return defaultValue
+}
+
+// duration value with the format of Go durations, it can not be negative
+func (l *loader) duration(name string, defaultValue time.Duration) time.Duration {
+	value := l.string(name, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		l.problems = append(l.problems, fmt.Sprintf("invalid %s '%s', expected a duration like 30s", name, value))
+
+		return defaultValue
+	}
+
+	return duration
+}
+
+// positiveInt value that must be a positive number
+func (l *loader) positiveInt(name string, defaultValue int) int {
+	value := l.string(name, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		l.problems = append(l.problems, fmt.Sprintf("invalid %s '%s', expected a positive number", name, value))
+
+		return defaultValue
+	}
+
+	return number
+}
+
+// sender senders of the emails, SENDER_FROM_ADDRESS is required, TENANT_SENDERS is a JSON object with the
+// sender of each tenant and SENDER_ALLOWED_IDENTITIES a comma separated list of addresses and domains
+func (l *loader) sender() internal.SenderConfig {
+	defaultSender := internal.Sender{
+		FromAddress: l.required("SENDER_FROM_ADDRESS"),
+		DisplayName: l.string("SENDER_DISPLAY_NAME", ""),
+		ReplyTo:     l.string("SENDER_REPLY_TO", ""),
+	}
+	tenants := l.string("TENANT_SENDERS", "")
+	allowedIdentities := l.string("SENDER_ALLOWED_IDENTITIES", "")
+
+	if defaultSender.FromAddress == "" {
+		return internal.SenderConfig{}
+	}
+
+	senders, err := internal.ParseSenderConfig(defaultSender, tenants, allowedIdentities)
+	if err != nil {
+		l.problems = append(l.problems, err.Error())
+	}
+
+	return senders
+}
+
+// messageSizes maximum size of the messages of each type with the format {"Status": 1024}, nil when the value is empty
+func (l *loader) messageSizes(name string) map[string]int {
+	sizes, err := internal.ParseMessageSizes(l.string(name, ""))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %s", name, err))
+	}
+
+	return sizes
+}
+
+// tenants how the tenant of the requests is identified, the header internal.DefaultTenantHeader by default can only
+// repeat the tenant of the credentials. The tenants of the API Gateway API keys are not supported anymore, the
+// tenant is the one of the API key or the token of the request
+func (l *loader) tenants(headerName, apiKeysName string) internal.TenantConfig {
+	if l.string(apiKeysName, "") != "" {
+		l.problems = append(l.problems, fmt.Sprintf(
+			"%s is not supported, set the tenant of the API keys or the tenant claim of the tokens", apiKeysName,
+		))
+	}
+
+	return internal.TenantConfig{Header: l.string(headerName, internal.DefaultTenantHeader)}
+}
+
+// recipientNormalizer normalization of the recipients of each provider, internal.DefaultRecipientNormalization
+// when the value is empty
+func (l *loader) recipientNormalizer(name string) internal.RecipientNormalizer {
+	normalizer, err := internal.ParseRecipientNormalizer(l.string(name, internal.DefaultRecipientNormalization))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %s", name, err))
+	}
+
+	return normalizer
+}
+
+// quietHours quiet hours with the format "HH:MM-HH:MM", nil when the value is empty
+func (l *loader) quietHours(name string) *internal.QuietHours {
+	quietHours, err := internal.ParseQuietHours(l.string(name, ""))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %s", name, err))
+	}
+
+	return quietHours
+}
+
+// recipientCap cap of every notification to a recipient with the format "limit/window", disabled when the value
+// is empty. The low priority notifications use internal.DefaultLowPriorityPercent of it by default
+func (l *loader) recipientCap(name, lowPriorityName string) internal.RecipientCap {
+	lowPriorityPercent := l.positiveInt(lowPriorityName, internal.DefaultLowPriorityPercent)
+
+	recipientCap, err := internal.ParseRecipientCap(l.string(name, ""), lowPriorityPercent)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %s", name, err))
+	}
+
+	return recipientCap
+}
+
+// requestRate rate of the requests with the format "limit/window", "none" to not limit the requests
+func (l *loader) requestRate(name, defaultValue string) internal.RequestRate {
+	value := l.string(name, defaultValue)
+	if value == "none" {
+		return internal.RequestRate{}
+	}
+
+	rate, err := internal.ParseRequestRate(value)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %s", name, err))
+	}
+
+	return rate
+}
+
+// jwt verification of the bearer tokens, JWT_JWKS is an http or https URL or the path of a file and JWT_AUDIENCE
+// is required with it. The bearer tokens are not accepted when JWT_JWKS is empty
+func (l *loader) jwt() JWT {
+	jwt := JWT{
+		JWKS:         l.string("JWT_JWKS", ""),
+		Audience:     l.string("JWT_AUDIENCE", ""),
+		Issuer:       l.string("JWT_ISSUER", ""),
+		JWKSCacheTTL: l.duration("JWT_JWKS_CACHE_TTL", defaultJWKSCacheTTL),
+	}
+
+	if jwt.JWKS == "" {
+		if jwt.Audience != "" || jwt.Issuer != "" {
+			l.problems = append(l.problems, "JWT_AUDIENCE and JWT_ISSUER need JWT_JWKS")
+		}
+
+		return jwt
+	}
+
+	if strings.Contains(jwt.JWKS, "://") &&
+		!strings.HasPrefix(jwt.JWKS, "http://") && !strings.HasPrefix(jwt.JWKS, "https://") {
+		l.problems = append(
+			l.problems,
+			fmt.Sprintf("invalid JWT_JWKS '%s', expected an http or https URL or the path of a file", jwt.JWKS),
+		)
+	}
+
+	if jwt.Audience == "" {
+		l.problems = append(l.problems, "JWT_AUDIENCE is required with JWT_JWKS")
+	}
+
+	return jwt
+}
+
+// checkUnknown report the names of the file that are not part of the configuration, usually a typo
+func (l *loader) checkUnknown() {
+	var unknown []string
+
+	for name := range l.file {
+		if !l.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		l.problems = append(l.problems, fmt.Sprintf("unknown setting %s in CONFIG_FILE", name))
+	}
+}
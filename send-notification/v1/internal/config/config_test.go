@@ -0,0 +1,304 @@
+// Package config loads the configuration of the lambda functions and the tools
+package config
+
+import (
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requiredEnv environment with the values that are required
+var requiredEnv = map[string]string{
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME":         "rules",
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_HISTORY_TABLE_NAME": "rules-history",
+	"DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME":         "cache",
+	"DYNAMODB_NOTIFICATION_RECIPIENT_PROFILES_TABLE_NAME":       "profiles",
+	"DYNAMODB_NOTIFICATION_RECIPIENT_PREFERENCES_TABLE_NAME":    "preferences",
+	"DYNAMODB_NOTIFICATION_SUPPRESSION_LIST_TABLE_NAME":         "suppressions",
+	"DYNAMODB_NOTIFICATION_API_KEYS_TABLE_NAME":                 "api-keys",
+	"SENDER_FROM_ADDRESS":                                       "notifications@example.com",
+	"UNSUBSCRIBE_SIGNING_SECRET":                                "secret",
+}
+
+// env getenv of the required environment with the given values, an empty value unsets the variable
+func env(values map[string]string) func(string) string {
+	merged := map[string]string{}
+	for name, value := range requiredEnv {
+		merged[name] = value
+	}
+
+	for name, value := range values {
+		merged[name] = value
+	}
+
+	return func(name string) string {
+		return merged[name]
+	}
+}
+
+// TestLoad test for this function
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		getenv    func(string) string
+		want      func(t *testing.T, config *Config)
+		wantError string
+	}{
+		{
+			name:   "defaults",
+			getenv: env(nil),
+			want: func(t *testing.T, config *Config) {
+				assert.Equal(t, AWS{SDKVersion: SDKV2, Region: "us-east-1"}, config.AWS)
+				assert.Equal(t, Tables{
+					RateLimitRules:        "rules",
+					RateLimitRulesHistory: "rules-history",
+					RateLimitCache:        "cache",
+					RecipientProfiles:     "profiles",
+					RecipientPreferences:  "preferences",
+					SuppressionList:       "suppressions",
+					APIKeys:               "api-keys",
+				}, config.Tables)
+				assert.Equal(t, RulesCache{TTL: 30 * time.Second, NegativeTTL: 30 * time.Second}, config.RulesCache)
+				assert.Equal(t, internal.HandlerConfig{
+					Workers:            internal.DefaultSendWorkers,
+					MaxNotifications:   internal.DefaultMaxNotificationsPerRequest,
+					MaxRecipients:      internal.DefaultMaxRecipientsPerRequest,
+					DeadlineMargin:     internal.DefaultRequestDeadlineMargin,
+					MaxMessageSize:     internal.DefaultMaxMessageSize,
+					MaxAttachmentsSize: internal.DefaultMaxAttachmentsSize,
+					Tenants:            internal.TenantConfig{Header: internal.DefaultTenantHeader},
+				}, config.Handler)
+				assert.Equal(t, internal.SenderConfig{
+					Default: internal.Sender{FromAddress: "notifications@example.com"},
+				}, config.Sender)
+				assert.Equal(t, Unsubscribe{SigningSecret: "secret", LinkTTL: 90 * 24 * time.Hour}, config.Unsubscribe)
+				assert.Nil(t, config.DefaultQuietHours)
+				assert.Equal(t, "username@gmail.com", config.RecipientNormalizer.Normalize("User.Name+news@gmail.com"))
+				assert.Equal(t, internal.RequestRate{Limit: 600, Window: time.Minute}, config.ClientRateLimit)
+				assert.Equal(t, JWT{JWKSCacheTTL: 10 * time.Minute}, config.JWT)
+			},
+		},
+		{
+			name: "every value from the environment",
+			getenv: env(map[string]string{
+				"AWS_SDK_VERSION":                    "v1",
+				"AWS_REGION":                         "eu-west-1",
+				"AWS_ENDPOINT_URL":                   "http://localhost:4566",
+				"AWS_ENDPOINT_URL_SESV2":             "http://localhost:8005",
+				"AWS_ENDPOINT_URL_S3":                "http://localhost:9000",
+				"RULES_CACHE_TTL":                    "0",
+				"RULES_CACHE_NEGATIVE_TTL":           "5m",
+				"SEND_WORKERS":                       "4",
+				"MAX_NOTIFICATIONS_PER_REQUEST":      "100",
+				"MAX_RECIPIENTS_PER_REQUEST":         "1000",
+				"REQUEST_DEADLINE_MARGIN":            "500ms",
+				"MAX_MESSAGE_SIZE":                   "1024",
+				"MESSAGE_SIZE_BY_TYPE":               `{"News": 65536}`,
+				"MAX_ATTACHMENTS_SIZE":               "2048",
+				"SENDER_DISPLAY_NAME":                "Modak",
+				"SENDER_ALLOWED_IDENTITIES":          "example.org",
+				"SES_CONFIGURATION_SET":              "notifications",
+				"UNSUBSCRIBE_BASE_URL":               "https://example.com/v1/unsubscribe",
+				"UNSUBSCRIBE_LINK_TTL":               "720h",
+				"DEFAULT_QUIET_HOURS":                "22:00-07:00",
+				"RECIPIENT_NORMALIZATION":            `{"*": {"strip_plus": true}}`,
+				"RECIPIENT_CAP":                      "20/24h",
+				"RECIPIENT_CAP_LOW_PRIORITY_PERCENT": "50",
+				"TENANT_HEADER":                      "X-Product",
+				"CLIENT_RATE_LIMIT":                  "none",
+				"JWT_JWKS":                           "https://auth.example.com/.well-known/jwks.json",
+				"JWT_AUDIENCE":                       "notifications",
+				"JWT_ISSUER":                         "https://auth.example.com",
+				"JWT_JWKS_CACHE_TTL":                 "1h",
+			}),
+			want: func(t *testing.T, config *Config) {
+				assert.Equal(t, AWS{
+					SDKVersion:  SDKV1,
+					Region:      "eu-west-1",
+					Endpoint:    "http://localhost:4566",
+					SESEndpoint: "http://localhost:8005",
+					S3Endpoint:  "http://localhost:9000",
+				}, config.AWS)
+				assert.Equal(t, RulesCache{TTL: 0, NegativeTTL: 5 * time.Minute}, config.RulesCache)
+				assert.Equal(t, internal.HandlerConfig{
+					Workers:            4,
+					MaxNotifications:   100,
+					MaxRecipients:      1000,
+					DeadlineMargin:     500 * time.Millisecond,
+					MaxMessageSize:     1024,
+					MessageSizeByType:  map[string]int{"News": 65536},
+					MaxAttachmentsSize: 2048,
+					Tenants:            internal.TenantConfig{Header: "X-Product"},
+				}, config.Handler)
+				assert.Equal(t, internal.SenderConfig{
+					Default:           internal.Sender{FromAddress: "notifications@example.com", DisplayName: "Modak"},
+					AllowedIdentities: []string{"example.org"},
+				}, config.Sender)
+				assert.Equal(t, "notifications", config.SESConfigurationSet)
+				assert.Equal(t, "https://example.com/v1/unsubscribe", config.Unsubscribe.BaseURL)
+				assert.Equal(t, 30*24*time.Hour, config.Unsubscribe.LinkTTL)
+				assert.Equal(t, &internal.QuietHours{Start: "22:00", End: "07:00"}, config.DefaultQuietHours)
+				assert.Equal(t, internal.RecipientNormalizer{Providers: map[string]internal.AddressNormalization{
+					internal.AnyProvider: {StripPlus: true},
+				}}, config.RecipientNormalizer)
+				assert.Equal(t, internal.RecipientCap{
+					Limit:              20,
+					Window:             24 * time.Hour,
+					LowPriorityPercent: 50,
+				}, config.RecipientCap)
+				assert.Equal(t, internal.RequestRate{}, config.ClientRateLimit)
+				assert.Equal(t, JWT{
+					JWKS:         "https://auth.example.com/.well-known/jwks.json",
+					Audience:     "notifications",
+					Issuer:       "https://auth.example.com",
+					JWKSCacheTTL: time.Hour,
+				}, config.JWT)
+			},
+		},
+		{
+			name: "file overridden by the environment",
+			getenv: func(name string) string {
+				return map[string]string{
+					"CONFIG_FILE":                "testdata/config.yaml",
+					"SEND_WORKERS":               "8",
+					"UNSUBSCRIBE_SIGNING_SECRET": "env-secret",
+				}[name]
+			},
+			want: func(t *testing.T, config *Config) {
+				assert.Equal(t, AWS{
+					SDKVersion:       SDKV1,
+					Region:           "us-east-1",
+					DynamoDBEndpoint: "http://localhost:8000",
+				}, config.AWS)
+				assert.Equal(t, "NotificationRateLimitRules", config.Tables.RateLimitRules)
+				assert.Equal(t, 8, config.Handler.Workers)
+				assert.Equal(t, time.Minute, config.RulesCache.TTL)
+				assert.Equal(t, "Modak", config.Sender.Default.DisplayName)
+				assert.Equal(t, "env-secret", config.Unsubscribe.SigningSecret)
+				assert.Equal(t, &internal.QuietHours{Start: "22:00", End: "07:00"}, config.DefaultQuietHours)
+			},
+		},
+		{
+			name: "local profile",
+			getenv: func(name string) string {
+				return map[string]string{
+					"PROFILE":       "local",
+					"LOCAL_ADDRESS": "0.0.0.0:3000",
+				}[name]
+			},
+			want: func(t *testing.T, config *Config) {
+				assert.Equal(t, ProfileLocal, config.Profile)
+				assert.Equal(t, AWS{
+					SDKVersion:       SDKV2,
+					Region:           "us-east-1",
+					DynamoDBEndpoint: "http://localhost:8000",
+					SESEndpoint:      "http://localhost:8005",
+					AccessKeyID:      "local",
+					SecretAccessKey:  "local",
+				}, config.AWS)
+				assert.Equal(t, "NotificationRateLimitCache", config.Tables.RateLimitCache)
+				assert.Equal(t, "notifications@localhost", config.Sender.Default.FromAddress)
+				assert.Equal(t, "http://localhost:3000/v1/unsubscribe", config.Unsubscribe.BaseURL)
+				assert.Equal(t, Local{
+					Address:    "0.0.0.0:3000",
+					SeedRules:  "local/rules.yaml",
+					SeedAPIKey: "local-api-key",
+				}, config.Local)
+			},
+		},
+		{
+			name: "static credentials only in the local profile",
+			getenv: env(map[string]string{
+				"AWS_ACCESS_KEY_ID":     "key",
+				"AWS_SECRET_ACCESS_KEY": "secret",
+				"LOCAL_ADDRESS":         "0.0.0.0:3000",
+			}),
+			want: func(t *testing.T, config *Config) {
+				assert.Empty(t, config.Profile)
+				assert.Empty(t, config.AWS.AccessKeyID)
+				assert.Empty(t, config.AWS.SecretAccessKey)
+				assert.Equal(t, Local{}, config.Local)
+			},
+		},
+		{
+			name:      "unknown profile",
+			getenv:    env(map[string]string{"PROFILE": "staging"}),
+			wantError: "invalid PROFILE 'staging', expected local",
+		},
+		{
+			name:      "missing required values",
+			getenv:    func(string) string { return "" },
+			wantError: "DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME is required",
+		},
+		{
+			name: "every invalid value in one error",
+			getenv: env(map[string]string{
+				"AWS_SDK_VERSION":         "v3",
+				"RULES_CACHE_TTL":         "-1s",
+				"SEND_WORKERS":            "0",
+				"REQUEST_DEADLINE_MARGIN": "soon",
+				"SENDER_FROM_ADDRESS":     "Modak <notifications@example.com>",
+				"DEFAULT_QUIET_HOURS":     "22:00",
+				"MESSAGE_SIZE_BY_TYPE":    "News=1",
+				"RECIPIENT_NORMALIZATION": "gmail.com",
+				"RECIPIENT_CAP":           "20",
+				"TENANT_API_KEYS":         `{"a1b2c3d4e5": "acme"}`,
+				"CLIENT_RATE_LIMIT":       "100",
+				"JWT_JWKS":                "ftp://auth.example.com/jwks.json",
+				"JWT_JWKS_CACHE_TTL":      "-1m",
+			}),
+			wantError: "invalid configuration: invalid AWS_SDK_VERSION 'v3', expected v1 or v2; " +
+				"invalid RULES_CACHE_TTL '-1s', expected a duration like 30s; " +
+				"invalid SEND_WORKERS '0', expected a positive number; " +
+				"invalid REQUEST_DEADLINE_MARGIN 'soon', expected a duration like 30s; " +
+				"MESSAGE_SIZE_BY_TYPE: invalid message sizes: invalid character 'N' looking for beginning of value; " +
+				"TENANT_API_KEYS is not supported, set the tenant of the API keys or the tenant claim of the tokens; " +
+				"sender from_address 'Modak <notifications@example.com>' is not an email address; " +
+				"DEFAULT_QUIET_HOURS: invalid quiet hours '22:00', expected HH:MM-HH:MM; " +
+				"RECIPIENT_NORMALIZATION: invalid recipient normalization: invalid character 'g' looking for beginning of value; " +
+				"RECIPIENT_CAP: invalid recipient cap '20', expected limit/window like 20/24h; " +
+				"CLIENT_RATE_LIMIT: invalid request rate '100', expected limit/window like 100/1m; " +
+				"invalid JWT_JWKS_CACHE_TTL '-1m', expected a duration like 30s; " +
+				"invalid JWT_JWKS 'ftp://auth.example.com/jwks.json', " +
+				"expected an http or https URL or the path of a file; " +
+				"JWT_AUDIENCE is required with JWT_JWKS",
+		},
+		{
+			name:      "nested value of the file",
+			getenv:    env(map[string]string{"CONFIG_FILE": "testdata/unknown.yaml"}),
+			wantError: "SENDER_FROM_ADDRESS in CONFIG_FILE must be a string, a number or a boolean",
+		},
+		{
+			name:      "unknown name in the file",
+			getenv:    env(map[string]string{"CONFIG_FILE": "testdata/unknown.yaml"}),
+			wantError: "unknown setting SEND_WORKER in CONFIG_FILE",
+		},
+		{
+			name:      "missing file",
+			getenv:    env(map[string]string{"CONFIG_FILE": "testdata/missing.yaml"}),
+			wantError: "CONFIG_FILE",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Load(tt.getenv)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				assert.Nil(t, got)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.want(t, got)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// RecordFeedbackUCInterface interface for this use case record SES feedback
+type RecordFeedbackUCInterface interface {
+	Handle(ctx context.Context, feedback SESFeedback) error
+}
+
+// FeedbackHandler declaration of the handler of the SES bounce and complaint notifications published to SNS
+type FeedbackHandler struct {
+	recordFeedbackUC RecordFeedbackUCInterface
+	logger           infraestructure.LoggerInterface
+}
+
+// Handle main method to record the SES feedback of every SNS record
+func (h *FeedbackHandler) Handle(ctx context.Context, event events.SNSEvent) error {
+	// Init logger with light ECS specification
+	logger := h.logger.WithFields(
+		"@timestamp", time.Now().Format(time.RFC3339),
+		"file", "cx_feedback_handler",
+		"method", "Handle",
+	)
+
+	for _, record := range event.Records {
+		var feedback SESFeedback
+
+		// A malformed message would fail on every retry, it is logged and skipped
+		err := json.Unmarshal([]byte(record.SNS.Message), &feedback)
+		if err != nil {
+			logger.Errorf("error: malformed SES feedback %s: %v", record.SNS.MessageID, err)
+
+			continue
+		}
+
+		err = h.recordFeedbackUC.Handle(ctx, feedback)
+		if err != nil {
+			logger.Errorf("error: ", err)
+
+			return err
+		}
+
+		logger.Infof("SES %s feedback of message %s processed", feedback.Type(), feedback.Mail.MessageID)
+	}
+
+	return nil
+}
+
+// NewFeedbackHandler Initialize FeedbackHandler
+func NewFeedbackHandler(
+	recordFeedbackUC RecordFeedbackUCInterface,
+	logger infraestructure.LoggerInterface,
+) *FeedbackHandler {
+	return &FeedbackHandler{
+		recordFeedbackUC: recordFeedbackUC,
+		logger:           logger,
+	}
+}
@@ -0,0 +1,96 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRecordFeedbackUC struct {
+	handleFunc func(feedback SESFeedback) error
+}
+
+func (m *mockRecordFeedbackUC) Handle(_ context.Context, feedback SESFeedback) error {
+	return m.handleFunc(feedback)
+}
+
+// snsEventFromFixtures SNS event with one record per recorded SES notification
+func snsEventFromFixtures(t *testing.T, fixtures ...string) events.SNSEvent {
+	var event events.SNSEvent
+
+	for _, fixture := range fixtures {
+		message, err := os.ReadFile("testdata/" + fixture)
+		if err != nil {
+			t.Fatalf("reading fixture %s: %v", fixture, err)
+		}
+
+		event.Records = append(event.Records, events.SNSEventRecord{
+			SNS: events.SNSEntity{MessageID: fixture, Message: string(message)},
+		})
+	}
+
+	return event
+}
+
+func TestFeedbackHandler_Handle(t *testing.T) {
+	var received []SESFeedback
+
+	recordFeedbackUC := &mockRecordFeedbackUC{
+		handleFunc: func(feedback SESFeedback) error {
+			received = append(received, feedback)
+
+			return nil
+		},
+	}
+
+	event := snsEventFromFixtures(
+		t,
+		"ses_bounce_permanent.json",
+		"ses_complaint.json",
+		"ses_delivery.json",
+		"ses_event_complaint.json",
+	)
+	event.Records = append(event.Records, events.SNSEventRecord{SNS: events.SNSEntity{Message: "not json"}})
+
+	err := NewFeedbackHandler(recordFeedbackUC, &mockLogger{}).Handle(context.Background(), event)
+	assert.NoError(t, err)
+
+	if assert.Len(t, received, 4) {
+		assert.Equal(t, "Bounce", received[0].NotificationType)
+		assert.Equal(t, "Permanent", received[0].Bounce.BounceType)
+		assert.Equal(t, "General", received[0].Bounce.BounceSubType)
+		assert.Equal(t, "bounce@simulator.amazonses.com", received[0].Bounce.BouncedRecipients[0].EmailAddress)
+		assert.Equal(t, "0100018b33c0e9a7-8f3e0a2b-7c4d-4b6e-a1f2-3d4e5f6a7b8c-000000", received[0].Mail.MessageID)
+
+		assert.Equal(t, "Complaint", received[1].NotificationType)
+		assert.Equal(t, "abuse", received[1].Complaint.ComplaintFeedbackType)
+		assert.Equal(t, "complaint@simulator.amazonses.com", received[1].Complaint.ComplainedRecipients[0].EmailAddress)
+
+		assert.Equal(t, "Delivery", received[2].NotificationType)
+		assert.Nil(t, received[2].Bounce)
+		assert.Nil(t, received[2].Complaint)
+
+		// The events of the configuration set have the same complaint
+		assert.Equal(t, "Complaint", received[3].Type())
+		assert.Equal(t, "complaint@simulator.amazonses.com", received[3].Complaint.ComplainedRecipients[0].EmailAddress)
+	}
+}
+
+func TestFeedbackHandler_Handle_Error(t *testing.T) {
+	recordFeedbackUC := &mockRecordFeedbackUC{
+		handleFunc: func(feedback SESFeedback) error {
+			return errors.New("database error")
+		},
+	}
+
+	err := NewFeedbackHandler(recordFeedbackUC, &mockLogger{}).Handle(
+		context.Background(),
+		snsEventFromFixtures(t, "ses_complaint.json"),
+	)
+	assert.Error(t, err)
+}
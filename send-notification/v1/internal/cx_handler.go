@@ -2,7 +2,10 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -14,23 +17,74 @@ import (
 
 // ValidateRateLimitUCInterface interface for this use case validate rate limit
 type ValidateRateLimitUCInterface interface {
-	Handle(notification Notification) (bool, error)
+	HandleBatch(ctx context.Context, notifications []Notification) ([]ValidationResult, error)
+	Release(ctx context.Context, reservations []Reservation) error
 }
 
 // SendNotificationUCInterface interface for this use case validate rate limit
 type SendNotificationUCInterface interface {
-	Handle(notification Notification) error
+	Handle(ctx context.Context, notification Notification) (SendResult, error)
+	SenderAllowed(ctx context.Context, notificationType string) (bool, error)
+}
+
+// AuthenticateClientUCInterface interface for this use case authenticate client
+type AuthenticateClientUCInterface interface {
+	Handle(ctx context.Context, credentials Credentials) (Client, error)
+}
+
+// Default limits of the requests that send notifications
+const (
+	// DefaultSendWorkers notifications sent at the same time by one request
+	DefaultSendWorkers = 10
+	// DefaultMaxNotificationsPerRequest maximum number of notifications of one request
+	DefaultMaxNotificationsPerRequest = 500
+	// DefaultMaxRecipientsPerRequest maximum number of recipients of all the notifications of one request
+	DefaultMaxRecipientsPerRequest = 500
+	// DefaultRequestDeadlineMargin time reserved before the lambda deadline to write the response
+	DefaultRequestDeadlineMargin = 2 * time.Second
+)
+
+// HandlerConfig limits of the requests that send notifications, the zero values take the defaults
+type HandlerConfig struct {
+	Workers          int
+	MaxNotifications int
+	// MaxRecipients maximum number of recipients of all the notifications of one request, each one is checked,
+	// rate limited and sent on its own
+	MaxRecipients  int
+	DeadlineMargin time.Duration
+	// MaxMessageSize maximum size in bytes of the messages of the types that are not in MessageSizeByType
+	MaxMessageSize int
+	// MessageSizeByType maximum size in bytes of the messages of each type
+	MessageSizeByType map[string]int
+	// MaxAttachmentsSize maximum size in bytes of the decoded attachments of one notification
+	MaxAttachmentsSize int
+	// Tenants how the tenant of the requests is identified
+	Tenants TenantConfig
+}
+
+// messageSize maximum size in bytes of the messages of the type
+func (c HandlerConfig) messageSize(notificationType string) int {
+	if size, ok := c.MessageSizeByType[notificationType]; ok {
+		return size
+	}
+
+	return c.MaxMessageSize
 }
 
 // Handler declaration of handler struct used in this file
 type Handler struct {
-	validateRateLimitUC ValidateRateLimitUCInterface
-	sendNotificationUC  SendNotificationUCInterface
-	logger              infraestructure.LoggerInterface
+	authenticateClientUC AuthenticateClientUCInterface
+	validateRateLimitUC  ValidateRateLimitUCInterface
+	sendNotificationUC   SendNotificationUCInterface
+	config               HandlerConfig
+	logger               infraestructure.LoggerInterface
 }
 
 // Handle main method controller to execute this lambda function
-func (h *Handler) Handle(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) Handle(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
 	// Init logger with light ECS specification
 	logger := h.logger.WithFields(
 		"@timestamp", time.Now().Format(time.RFC3339),
@@ -38,62 +92,240 @@ func (h *Handler) Handle(event events.APIGatewayProxyRequest) (events.APIGateway
 		"method", "Handle",
 	)
 
-	var requestBody RequestBody
+	// The credentials are checked and counted before reading the body, so the requests over the rate limit of the
+	// client cost as little as possible
+	client, err := h.authenticateClientUC.Handle(ctx, credentials(event))
+	if err != nil {
+		logger.Errorf("error: ", err)
 
-	err := json.Unmarshal([]byte(event.Body), &requestBody)
+		return responseError(err)
+	}
+
+	// The rules, the quotas and the sender of the notifications are the ones of the tenant
+	tenant, err := h.config.Tenants.Resolve(event, client)
 	if err != nil {
 		logger.Errorf("error: ", err)
 
 		return responseError(err)
 	}
 
-	var sent, failed []Notification
+	requestBody, err := decodeRequestBody(event.Body)
+	if err != nil {
+		logger.Errorf("error: ", err)
 
-	// Create channels to handle concurrency
-	sentChannel := make(chan Notification, len(requestBody.Notifications))
-	failedChannel := make(chan Notification, len(requestBody.Notifications))
-	errorsChannel := make(chan error, len(requestBody.Notifications))
-
-	// Process notifications concurrently
-	for _, notification := range requestBody.Notifications {
-		go func(notification Notification) {
-			canSend, err := h.validateRateLimitUC.Handle(notification)
-			if err != nil {
-				errorsChannel <- err
+		return responseError(err)
+	}
 
-				return
-			}
+	if len(requestBody.Notifications) > h.config.MaxNotifications {
+		err := &GeneralError{
+			Code: CodeRequestError,
+			ID:   IDRequestTooLarge,
+			Message: fmt.Sprintf(
+				"The request has %d notifications, the maximum is %d",
+				len(requestBody.Notifications), h.config.MaxNotifications,
+			),
+			StatusCode: http.StatusRequestEntityTooLarge,
+		}
+		logger.Errorf("error: ", err)
 
-			if !canSend {
-				failedChannel <- notification
+		return responseError(err)
+	}
+
+	if recipients := requestBody.recipientCount(); recipients > h.config.MaxRecipients {
+		err := &GeneralError{
+			Code: CodeRequestError,
+			ID:   IDRequestTooLarge,
+			Message: fmt.Sprintf(
+				"The request has %d recipients, the maximum is %d",
+				recipients, h.config.MaxRecipients,
+			),
+			StatusCode: http.StatusRequestEntityTooLarge,
+		}
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	if err := requestBody.Validate(h.config); err != nil {
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	// The quota of the notifications not sent is released even after the request context is done
+	releaseCtx := ctx
+
+	// The work stops before the lambda deadline so there is time to write the response
+	ctx, cancel := h.requestContext(ctx)
+	defer cancel()
+
+	ctx = WithRequestMetadata(ctx, RequestMetadata{RequestID: event.RequestContext.RequestID, Tenant: tenant})
+
+	var sent []SentNotification
+
+	var failed []FailedNotification
+
+	if ctx.Err() != nil {
+		failed = notProcessed(requestBody.Notifications)
+
+		return h.response(logger, sent, failed)
+	}
+
+	// The notifications of the types that the client can not send, or whose sender is not allowed, do not use
+	// any quota
+	notifications, failed := authorize(client, requestBody.Notifications)
+
+	notifications, rejected, err := h.checkSenders(ctx, notifications)
+	failed = append(failed, rejected...)
+
+	// The rate limit of the whole request is planned at once, so the notifications to the same recipient
+	// can not use the same quota. Every recipient of a notification is limited on its own
+	var results []ValidationResult
+	if err == nil && len(notifications) > 0 {
+		results, err = h.validateRateLimitUC.HandleBatch(ctx, recipientNotifications(notifications))
+	}
 
-				return
+	if err != nil && ctx.Err() != nil {
+		// The request was cancelled or reached the deadline while validating, nothing was sent and the quota
+		// recorded before the error was released
+		logger.Errorf("error: ", err)
+
+		return h.response(logger, sent, append(failed, notProcessed(notifications)...))
+	}
+
+	if err != nil {
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	var allowed []delivery
+
+	for _, notification := range notifications {
+		recipients := notification.Recipients()
+		d := delivery{notification: notification}
+
+		var rejection ValidationResult
+
+		for _, recipient := range recipients {
+			result := results[0]
+			results = results[1:]
+
+			if result.Allowed {
+				d.recipients = append(d.recipients, recipient)
+				d.reservations = append(d.reservations, result.Reservation)
+
+				continue
 			}
 
-			err = h.sendNotificationUC.Handle(notification)
-			if err != nil {
-				errorsChannel <- err
+			rejection = result
+			d.rejected = append(d.rejected, RecipientResult{
+				Recipient:     recipient,
+				Reason:        result.Reason,
+				NextAllowedAt: result.NextAllowedAt,
+			})
+		}
+
+		// Only the allowed recipients receive the notification
+		if len(d.recipients) > 0 {
+			allowed = append(allowed, d)
+
+			continue
+		}
+
+		if len(recipients) > 1 {
+			rejection = ValidationResult{Reason: rejectionReason(d.rejected)}
+		}
+
+		failed = append(failed, FailedNotification{
+			Notification:  notification,
+			Reason:        rejection.Reason,
+			NextAllowedAt: rejection.NextAllowedAt,
+			Recipients:    d.recipientResults(nil, ""),
+		})
+	}
 
-				return
+	// Create channels to handle concurrency
+	jobsChannel := make(chan delivery)
+	sentChannel := make(chan SentNotification, len(allowed))
+	failedChannel := make(chan FailedNotification, len(allowed))
+
+	// Send the allowed notifications with a bounded number of workers
+	for i := 0; i < h.config.Workers && i < len(allowed); i++ {
+		go func() {
+			for d := range jobsChannel {
+				// The notifications not started before the deadline are returned without sending them
+				if ctx.Err() != nil {
+					h.release(releaseCtx, logger, d.unsent(SendResult{}))
+					failedChannel <- d.failed(RejectionReasonNotProcessed, nil)
+
+					continue
+				}
+
+				sendResult, err := h.sendNotificationUC.Handle(ctx, d.notification.WithRecipients(d.recipients))
+				if err != nil && ctx.Err() != nil {
+					// The send was interrupted by the cancellation, the caller can retry it
+					h.release(releaseCtx, logger, d.unsent(SendResult{}))
+					failedChannel <- d.failed(RejectionReasonNotProcessed, nil)
+
+					continue
+				}
+
+				// The error of one notification does not stop the others, the caller can retry it
+				if err != nil {
+					logger.Errorf("error: ", err)
+					h.release(releaseCtx, logger, d.unsent(SendResult{}))
+					failedChannel <- d.failed(RejectionReasonSendFailed, nil)
+
+					continue
+				}
+
+				// The recipients that did not receive it, like the ones of an email that failed after another one
+				// was sent, get their quota back
+				h.release(releaseCtx, logger, d.unsent(sendResult))
+
+				if !sendResult.Sent {
+					failedChannel <- d.failed(sendResult.Reason, sendResult.Recipients)
+
+					continue
+				}
+
+				sentChannel <- SentNotification{
+					Notification: d.notification,
+					MessageID:    sendResult.MessageID,
+					Recipients:   d.recipientResults(sendResult.Recipients, ""),
+				}
 			}
-			sentChannel <- notification
-		}(notification)
+		}()
 	}
 
+	go func() {
+		defer close(jobsChannel)
+
+		for _, d := range allowed {
+			jobsChannel <- d
+		}
+	}()
+
 	// Collect results
-	for i := 0; i < len(requestBody.Notifications); i++ {
+	for i := 0; i < len(allowed); i++ {
 		select {
 		case notification := <-sentChannel:
 			sent = append(sent, notification)
 		case notification := <-failedChannel:
 			failed = append(failed, notification)
-		case err := <-errorsChannel:
-			logger.Errorf("error: ", err)
-
-			return responseError(err)
 		}
 	}
 
+	return h.response(logger, sent, failed)
+}
+
+// response body with the notifications sent and failed
+func (h *Handler) response(
+	logger infraestructure.LoggerInterface,
+	sent []SentNotification,
+	failed []FailedNotification,
+) (events.APIGatewayProxyResponse, error) {
 	responseBody := ResponseBody{
 		Sent:   sent,
 		Failed: failed,
@@ -114,6 +346,203 @@ func (h *Handler) Handle(event events.APIGatewayProxyRequest) (events.APIGateway
 	}, nil
 }
 
+// release give back the quota and the messages recorded for the recipients that did not receive a notification,
+// the errors are only logged because the outcome of the notification does not change
+func (h *Handler) release(ctx context.Context, logger infraestructure.LoggerInterface, reservations []Reservation) {
+	if len(reservations) == 0 {
+		return
+	}
+
+	if err := h.validateRateLimitUC.Release(ctx, reservations); err != nil {
+		logger.Errorf("error releasing the quota of the notifications not sent: ", err)
+	}
+}
+
+// requestContext context of the request that expires the deadline margin before the lambda deadline
+func (h *Handler) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-h.config.DeadlineMargin))
+}
+
+// delivery notification with the recipients allowed by the rate limiter and the outcome of the rejected ones
+type delivery struct {
+	notification Notification
+	recipients   []Recipient
+	// reservations quota and message recorded for each allowed recipient, in the order of the recipients
+	reservations []*Reservation
+	rejected     []RecipientResult
+}
+
+// unsent reservations of the allowed recipients that did not receive the notification, all of them when it was
+// not sent and else the ones whose outcome is not sent
+func (d delivery) unsent(sendResult SendResult) []Reservation {
+	unsentRecipients := map[Recipient]bool{}
+
+	for _, result := range sendResult.Recipients {
+		if !result.Sent {
+			unsentRecipients[result.Recipient] = true
+		}
+	}
+
+	var reservations []Reservation
+
+	for i, reservation := range d.reservations {
+		if reservation != nil && (!sendResult.Sent || unsentRecipients[d.recipients[i]]) {
+			reservations = append(reservations, *reservation)
+		}
+	}
+
+	return reservations
+}
+
+// failed notification that was not sent to any recipient, the recipients without an outcome get the reason
+func (d delivery) failed(reason string, sendResults []RecipientResult) FailedNotification {
+	return FailedNotification{
+		Notification: d.notification,
+		Reason:       reason,
+		Recipients:   d.recipientResults(sendResults, reason),
+	}
+}
+
+// recipientResults outcome of every recipient in the order of the notification, the recipients without an outcome
+// get the reason. The notifications to one recipient do not report it
+func (d delivery) recipientResults(sendResults []RecipientResult, reason string) []RecipientResult {
+	if !d.notification.HasRecipientList() {
+		return nil
+	}
+
+	outcomes := make(map[Recipient]RecipientResult, len(d.rejected)+len(sendResults))
+
+	for _, result := range append(append([]RecipientResult{}, d.rejected...), sendResults...) {
+		outcomes[result.Recipient] = result
+	}
+
+	recipients := d.notification.Recipients()
+	results := make([]RecipientResult, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		result, ok := outcomes[recipient]
+		if !ok {
+			result = RecipientResult{Recipient: recipient, Reason: reason}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// authorize split the notifications that the client can send from the ones of the types that it can not send,
+// which are returned as failed
+func authorize(client Client, notifications []Notification) ([]Notification, []FailedNotification) {
+	var authorized []Notification
+
+	var forbidden []FailedNotification
+
+	for _, notification := range notifications {
+		if client.Allows(notification.Type) {
+			authorized = append(authorized, notification)
+
+			continue
+		}
+
+		forbidden = append(forbidden, FailedNotification{
+			Notification: notification,
+			Reason:       RejectionReasonForbidden,
+		})
+	}
+
+	return authorized, forbidden
+}
+
+// checkSenders split the notifications whose sender is allowed from the others, which are returned as failed.
+// The sender is checked once per type
+func (h *Handler) checkSenders(
+	ctx context.Context,
+	notifications []Notification,
+) ([]Notification, []FailedNotification, error) {
+	var allowed []Notification
+
+	var rejected []FailedNotification
+
+	allowedTypes := map[string]bool{}
+
+	for _, notification := range notifications {
+		senderAllowed, ok := allowedTypes[notification.Type]
+		if !ok {
+			var err error
+
+			senderAllowed, err = h.sendNotificationUC.SenderAllowed(ctx, notification.Type)
+			if err != nil {
+				return notifications, nil, err
+			}
+
+			allowedTypes[notification.Type] = senderAllowed
+		}
+
+		if senderAllowed {
+			allowed = append(allowed, notification)
+
+			continue
+		}
+
+		rejected = append(rejected, FailedNotification{
+			Notification: notification,
+			Reason:       RejectionReasonSenderNotAllowed,
+		})
+	}
+
+	return allowed, rejected, nil
+}
+
+// recipientNotifications notifications to each recipient of the notifications, in the order of the request
+func recipientNotifications(notifications []Notification) []Notification {
+	var single []Notification
+
+	for _, notification := range notifications {
+		for _, recipient := range notification.Recipients() {
+			single = append(single, Notification{
+				Type:      notification.Type,
+				Recipient: recipient.Address,
+				Message:   notification.Message,
+				Priority:  notification.Priority,
+			})
+		}
+	}
+
+	return single
+}
+
+// rejectionReason reason of a notification whose recipients were all rejected, the reason they share or
+// RejectionReasonRecipientsRejected
+func rejectionReason(rejected []RecipientResult) string {
+	for _, result := range rejected[1:] {
+		if result.Reason != rejected[0].Reason {
+			return RejectionReasonRecipientsRejected
+		}
+	}
+
+	return rejected[0].Reason
+}
+
+// notProcessed failed notifications for the notifications that were not processed
+func notProcessed(notifications []Notification) []FailedNotification {
+	failed := make([]FailedNotification, 0, len(notifications))
+
+	for _, notification := range notifications {
+		failed = append(failed, FailedNotification{
+			Notification: notification,
+			Reason:       RejectionReasonNotProcessed,
+		})
+	}
+
+	return failed
+}
+
 // responseError return response according error type
 func responseError(err error) (events.APIGatewayProxyResponse, error) {
 	var lambdaError error
@@ -132,6 +561,23 @@ func responseError(err error) (events.APIGatewayProxyResponse, error) {
 			Detail: err.Error(),
 		})
 
+		httpStatusCode = e.StatusCode
+	case *ValidationError:
+		for _, field := range e.Fields {
+			jsonError := ErrorJSONAPI{
+				Status: strconv.Itoa(e.StatusCode),
+				Code:   CodeRequestError,
+				ID:     IDRequestInvalidParameter,
+				Title:  GeneralErrorTitle,
+				Detail: field.Detail,
+			}
+			if field.Pointer != "" {
+				jsonError.Source = &ErrorSourceJSONAPI{Pointer: field.Pointer}
+			}
+
+			errors.Add(jsonError)
+		}
+
 		httpStatusCode = e.StatusCode
 	default:
 		lambdaError = e
@@ -149,21 +595,71 @@ func responseError(err error) (events.APIGatewayProxyResponse, error) {
 
 	errorsResponse, _ := json.Marshal(errors)
 
-	return events.APIGatewayProxyResponse{
+	response := events.APIGatewayProxyResponse{
 		StatusCode: httpStatusCode,
 		Body:       string(errorsResponse),
-	}, lambdaError
+	}
+
+	// The seconds are rounded up so the client does not retry before the time given
+	if e, ok := err.(*GeneralError); ok && e.RetryAfter > 0 {
+		response.Headers = map[string]string{
+			"Retry-After": strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))),
+		}
+	}
+
+	return response, lambdaError
+}
+
+// jsonResponse response with the body encoded as JSON
+func jsonResponse(statusCode int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return responseError(err)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(jsonData),
+	}, nil
 }
 
 // NewHandler Initialize Handle
 func NewHandler(
+	authenticateClientUC AuthenticateClientUCInterface,
 	validateRateLimitUC ValidateRateLimitUCInterface,
 	sendNotificationUC SendNotificationUCInterface,
+	config HandlerConfig,
 	logger infraestructure.LoggerInterface,
 ) *Handler {
+	if config.Workers <= 0 {
+		config.Workers = DefaultSendWorkers
+	}
+
+	if config.MaxNotifications <= 0 {
+		config.MaxNotifications = DefaultMaxNotificationsPerRequest
+	}
+
+	if config.MaxRecipients <= 0 {
+		config.MaxRecipients = DefaultMaxRecipientsPerRequest
+	}
+
+	if config.DeadlineMargin <= 0 {
+		config.DeadlineMargin = DefaultRequestDeadlineMargin
+	}
+
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = DefaultMaxMessageSize
+	}
+
+	if config.MaxAttachmentsSize <= 0 {
+		config.MaxAttachmentsSize = DefaultMaxAttachmentsSize
+	}
+
 	return &Handler{
-		validateRateLimitUC: validateRateLimitUC,
-		sendNotificationUC:  sendNotificationUC,
-		logger:              logger,
+		authenticateClientUC: authenticateClientUC,
+		validateRateLimitUC:  validateRateLimitUC,
+		sendNotificationUC:   sendNotificationUC,
+		config:               config,
+		logger:               logger,
 	}
 }
@@ -2,9 +2,16 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"modak/send-notification/v1/internal/infraestructure"
 
@@ -12,20 +19,68 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// mockAuthenticateClientUC mock of the authentication, without handleFunc every request is of a client that can
+// send every type
+type mockAuthenticateClientUC struct {
+	handleFunc func(credentials Credentials) (Client, error)
+}
+
+func (m *mockAuthenticateClientUC) Handle(_ context.Context, credentials Credentials) (Client, error) {
+	if m.handleFunc == nil {
+		return Client{ID: "client-1", AllowedTypes: []string{AnyType}}, nil
+	}
+
+	return m.handleFunc(credentials)
+}
+
+// mockValidateRateLimitUC mock of the rate limiter, without releaseFunc the reservations are released
 type mockValidateRateLimitUC struct {
-	handleFunc func(notification Notification) (bool, error)
+	handleFunc  func(notification Notification) (ValidationResult, error)
+	releaseFunc func(reservations []Reservation) error
 }
 
-func (m *mockValidateRateLimitUC) Handle(notification Notification) (bool, error) {
-	return m.handleFunc(notification)
+func (m *mockValidateRateLimitUC) Release(_ context.Context, reservations []Reservation) error {
+	if m.releaseFunc == nil {
+		return nil
+	}
+
+	return m.releaseFunc(reservations)
 }
 
+func (m *mockValidateRateLimitUC) HandleBatch(
+	_ context.Context,
+	notifications []Notification,
+) ([]ValidationResult, error) {
+	results := make([]ValidationResult, 0, len(notifications))
+
+	for _, notification := range notifications {
+		result, err := m.handleFunc(notification)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// mockSendNotificationUC mock of the sends, without senderAllowedFunc every sender is allowed
 type mockSendNotificationUC struct {
-	handleFunc func(notification Notification) error
+	handleFunc        func(ctx context.Context, notification Notification) (SendResult, error)
+	senderAllowedFunc func(notificationType string) (bool, error)
+}
+
+func (m *mockSendNotificationUC) Handle(ctx context.Context, notification Notification) (SendResult, error) {
+	return m.handleFunc(ctx, notification)
 }
 
-func (m *mockSendNotificationUC) Handle(notification Notification) error {
-	return m.handleFunc(notification)
+func (m *mockSendNotificationUC) SenderAllowed(_ context.Context, notificationType string) (bool, error) {
+	if m.senderAllowedFunc == nil {
+		return true, nil
+	}
+
+	return m.senderAllowedFunc(notificationType)
 }
 
 type mockLogger struct{}
@@ -50,15 +105,15 @@ func TestHandler_Handle(t *testing.T) {
 			eventBody:      "{",
 			validateRateUC: &mockValidateRateLimitUC{},
 			sendNotifUC:    &mockSendNotificationUC{},
-			wantStatusCode: http.StatusInternalServerError,
-			wantErr:        true,
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        false,
 		},
 		{
 			name:      "validate rate limit error",
 			eventBody: `{"notifications":[{"type":"test","recipient":"test@example.com","message":"Hello"}]}`,
 			validateRateUC: &mockValidateRateLimitUC{
-				handleFunc: func(notification Notification) (bool, error) {
-					return false, errors.New("rate limit error")
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					return ValidationResult{}, errors.New("rate limit error")
 				},
 			},
 			sendNotifUC:    &mockSendNotificationUC{},
@@ -69,8 +124,8 @@ func TestHandler_Handle(t *testing.T) {
 			name:      "validate rate limit returns canSend=false",
 			eventBody: `{"notifications":[{"type":"test","recipient":"test@example.com","message":"Hello"}]}`,
 			validateRateUC: &mockValidateRateLimitUC{
-				handleFunc: func(notification Notification) (bool, error) {
-					return false, nil
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					return ValidationResult{Reason: RejectionReasonRateLimited}, nil
 				},
 			},
 			sendNotifUC:    &mockSendNotificationUC{},
@@ -78,31 +133,31 @@ func TestHandler_Handle(t *testing.T) {
 			wantErr:        false,
 		},
 		{
-			name:      "send notification error",
+			name:      "send notification error is a failed notification",
 			eventBody: `{"notifications":[{"type":"test","recipient":"test@example.com","message":"Hello"}]}`,
 			validateRateUC: &mockValidateRateLimitUC{
-				handleFunc: func(notification Notification) (bool, error) {
-					return true, nil
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					return ValidationResult{Allowed: true}, nil
 				},
 			},
 			sendNotifUC: &mockSendNotificationUC{
-				handleFunc: func(notification Notification) error {
-					return errors.New("send notification error")
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					return SendResult{}, errors.New("send notification error")
 				},
 			},
-			wantStatusCode: http.StatusInternalServerError,
-			wantErr:        true,
+			wantStatusCode: http.StatusOK,
+			wantErr:        false,
 		}, {
 			name:      "successful notification send",
 			eventBody: `{"notifications":[{"type":"test","recipient":"test@example.com","message":"Hello"}]}`,
 			validateRateUC: &mockValidateRateLimitUC{
-				handleFunc: func(notification Notification) (bool, error) {
-					return true, nil
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					return ValidationResult{Allowed: true}, nil
 				},
 			},
 			sendNotifUC: &mockSendNotificationUC{
-				handleFunc: func(notification Notification) error {
-					return nil
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					return SendResult{Sent: true}, nil
 				},
 			},
 			wantStatusCode: http.StatusOK,
@@ -112,13 +167,13 @@ func TestHandler_Handle(t *testing.T) {
 			name:      "general error",
 			eventBody: `{"notifications":[{"type":"test","recipient":"test@example.com","message":"Hello"}]}`,
 			validateRateUC: &mockValidateRateLimitUC{
-				handleFunc: func(notification Notification) (bool, error) {
-					return false, nil
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					return ValidationResult{Reason: RejectionReasonRateLimited}, nil
 				},
 			},
 			sendNotifUC: &mockSendNotificationUC{
-				handleFunc: func(notification Notification) error {
-					return &GeneralError{
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					return SendResult{}, &GeneralError{
 						Code:       CodeGeneralError,
 						ID:         IDGeneralError,
 						Message:    "An unexpected error occurred",
@@ -133,11 +188,17 @@ func TestHandler_Handle(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			h := NewHandler(tt.validateRateUC, tt.sendNotifUC, &mockLogger{})
+			h := NewHandler(
+				&mockAuthenticateClientUC{},
+				tt.validateRateUC,
+				tt.sendNotifUC,
+				HandlerConfig{},
+				&mockLogger{},
+			)
 			event := events.APIGatewayProxyRequest{
 				Body: tt.eventBody,
 			}
-			resp, err := h.Handle(event)
+			resp, err := h.Handle(context.Background(), event)
 			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
 			if tt.wantErr {
 				assert.NotNil(t, err)
@@ -147,3 +208,1041 @@ func TestHandler_Handle(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_Handle_Validation(t *testing.T) {
+	tests := []struct {
+		name           string
+		eventBody      string
+		config         HandlerConfig
+		wantStatusCode int
+		wantErrors     []ErrorJSONAPI
+	}{
+		{
+			name:           "empty body",
+			eventBody:      "",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrors: []ErrorJSONAPI{{
+				Detail: "The body is not a valid JSON document: unexpected end of JSON input",
+			}},
+		},
+		{
+			name:           "value of the wrong type",
+			eventBody:      `{"notifications":{"type":"News"}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrors: []ErrorJSONAPI{{
+				Detail: "Invalid value, expected a []internal.Notification but got a object",
+				Source: &ErrorSourceJSONAPI{Pointer: "/notifications"},
+			}},
+		},
+		{
+			name:           "no notifications",
+			eventBody:      `{"notifications":[]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{{
+				Detail: "The request must have at least one notification",
+				Source: &ErrorSourceJSONAPI{Pointer: "/notifications"},
+			}},
+		},
+		{
+			name: "every invalid attribute",
+			eventBody: `{"notifications":[` +
+				`{"type":"News","recipient":"user@example.com","message":"Hello"},` +
+				`{"type":"","recipient":"User <user@example.com>","message":"  "},` +
+				`{"type":"News#1","recipient":"user","message":"Hello\u0000"}]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{
+				{
+					Detail: "The type must have between 1 and 64 letters, digits, '_', '.' or '-'",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/type"},
+				},
+				{
+					Detail: "The recipient must be an email address like user@example.com",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/recipient"},
+				},
+				{
+					Detail: "The message is required",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/message"},
+				},
+				{
+					Detail: "The type must have between 1 and 64 letters, digits, '_', '.' or '-'",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/2/type"},
+				},
+				{
+					Detail: "The recipient must be an email address like user@example.com",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/2/recipient"},
+				},
+				{
+					Detail: "The message has the control character U+0000",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/2/message"},
+				},
+			},
+		},
+		{
+			name: "invalid recipient lists",
+			eventBody: `{"notifications":[` +
+				`{"type":"News","recipient":"user@example.com","to":["other@example.com"],"message":"Hello"},` +
+				`{"type":"News","to":["user@example.com","user"],"cc":["User@example.com"],"message":"Hello"}]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{
+				{
+					Detail: "The recipient can not be used with the lists to, cc and bcc",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/recipient"},
+				},
+				{
+					Detail: "The recipient must be an email address like user@example.com",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/to/1"},
+				},
+				{
+					Detail: "The recipient is already in the lists of the notification",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/cc/0"},
+				},
+			},
+		},
+		{
+			name: "invalid attachments",
+			eventBody: `{"notifications":[{"type":"News","recipient":"user@example.com","message":"Hello","attachments":[` +
+				`{"filename":"invite.ics","content_type":"text/calendar; method=REQUEST","content":"QkVHSU46VkNBTEVOREFS"},` +
+				`{"filename":"reports/october.pdf","content_type":"application/pdf","object_path":"reports/october.pdf"},` +
+				`{"filename":"notes.txt","content_type":"text","content":"not base64!"},` +
+				`{"filename":"empty.txt","content_type":"text/plain"}]}]}`,
+			config:         HandlerConfig{MaxAttachmentsSize: 10},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{
+				{
+					Detail: "The filename can not have directories nor control characters",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments/1/filename"},
+				},
+				{
+					Detail: "The object path must be like s3://bucket/key",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments/1/object_path"},
+				},
+				{
+					Detail: "The content type must be a media type like application/pdf or text/calendar; method=REQUEST",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments/2/content_type"},
+				},
+				{
+					Detail: "The content must be encoded in base64",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments/2/content"},
+				},
+				{
+					Detail: "The attachment must have either a content or an object path",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments/3"},
+				},
+				{
+					Detail: "The attachments have 15 bytes, the maximum is 10",
+					Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/attachments"},
+				},
+			},
+		},
+		{
+			name:           "unknown priority",
+			eventBody:      `{"notifications":[{"type":"News","recipient":"user@example.com","message":"Hi","priority":"urgent"}]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{{
+				Detail: "The priority must be critical, high, normal or low",
+				Source: &ErrorSourceJSONAPI{Pointer: "/notifications/0/priority"},
+			}},
+		},
+		{
+			name: "message larger than the limit of its type",
+			eventBody: `{"notifications":[` +
+				`{"type":"News","recipient":"user@example.com","message":"Hello world"},` +
+				`{"type":"Status","recipient":"user@example.com","message":"Hello world"}]}`,
+			config:         HandlerConfig{MaxMessageSize: 5, MessageSizeByType: map[string]int{"News": 20}},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantErrors: []ErrorJSONAPI{{
+				Detail: "The message has 11 bytes, the maximum of its type is 5",
+				Source: &ErrorSourceJSONAPI{Pointer: "/notifications/1/message"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(
+				&mockAuthenticateClientUC{},
+				&mockValidateRateLimitUC{},
+				&mockSendNotificationUC{},
+				tt.config,
+				&mockLogger{},
+			)
+
+			resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.eventBody})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			var got ErrorsJSONAPI
+			assert.NoError(t, json.Unmarshal([]byte(resp.Body), &got))
+
+			for i := range tt.wantErrors {
+				tt.wantErrors[i].ID = IDRequestInvalidParameter
+				tt.wantErrors[i].Status = fmt.Sprint(tt.wantStatusCode)
+				tt.wantErrors[i].Code = CodeRequestError
+				tt.wantErrors[i].Title = GeneralErrorTitle
+			}
+
+			assert.Equal(t, tt.wantErrors, got.Errors)
+		})
+	}
+}
+
+func TestParseMessageSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{name: "size of each type", value: `{"Status": 1024, "News": 65536}`, want: map[string]int{"Status": 1024, "News": 65536}},
+		{name: "not a JSON object", value: "Status=1024", wantErr: true},
+		{name: "size not positive", value: `{"Status": 0}`, wantErr: true},
+		{name: "invalid type", value: `{"Status#1": 10}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMessageSizes(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandler_Handle_Suppressed(t *testing.T) {
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+			return SendResult{Reason: RejectionReasonSuppressed}, nil
+		},
+	}
+
+	h := NewHandler(&mockAuthenticateClientUC{}, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"News","recipient":"bounce@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sent":null,"failed":[{"type":"News","recipient":"bounce@example.com","message":"Hello","reason":"suppressed"}]}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_Release(t *testing.T) {
+	eventBody := `{"notifications":[` +
+		`{"type":"News","recipient":"sent@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"bounce@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"sender@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"error@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"limited@example.com","message":"Hello"}]}`
+
+	// Every notification keeps its outcome whether its quota could be released or not
+	wantBody := `{"sent":[{"type":"News","recipient":"sent@example.com","message":"Hello","message_id":"message-1"}],` +
+		`"failed":[` +
+		`{"type":"News","recipient":"limited@example.com","message":"Hello","reason":"rate_limited"},` +
+		`{"type":"News","recipient":"bounce@example.com","message":"Hello","reason":"suppressed"},` +
+		`{"type":"News","recipient":"sender@example.com","message":"Hello","reason":"sender_not_allowed"},` +
+		`{"type":"News","recipient":"error@example.com","message":"Hello","reason":"send_failed"}]}`
+
+	tests := []struct {
+		name         string
+		releaseErr   error
+		wantReleased []string
+	}{
+		{
+			name:         "the quota of the notifications not sent is released",
+			wantReleased: []string{"bounce@example.com", "error@example.com", "sender@example.com"},
+		},
+		{
+			name:         "the errors releasing do not change the response",
+			releaseErr:   errors.New("AccessDeniedException: not authorized to perform dynamodb:DeleteItem"),
+			wantReleased: []string{"bounce@example.com", "error@example.com", "sender@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			var released []string
+
+			validateRateUC := &mockValidateRateLimitUC{
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					if notification.Recipient == "limited@example.com" {
+						return ValidationResult{Reason: RejectionReasonRateLimited}, nil
+					}
+
+					return ValidationResult{
+						Allowed:     true,
+						Reservation: &Reservation{ContentKey: notification.Recipient},
+					}, nil
+				},
+				releaseFunc: func(reservations []Reservation) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					for _, reservation := range reservations {
+						released = append(released, reservation.ContentKey)
+					}
+
+					return tt.releaseErr
+				},
+			}
+			sendNotifUC := &mockSendNotificationUC{
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					switch notification.Recipient {
+					case "bounce@example.com":
+						return SendResult{Reason: RejectionReasonSuppressed}, nil
+					case "sender@example.com":
+						return SendResult{Reason: RejectionReasonSenderNotAllowed}, nil
+					case "error@example.com":
+						return SendResult{}, errors.New("send notification error")
+					}
+
+					return SendResult{Sent: true, MessageID: "message-1"}, nil
+				},
+			}
+
+			// One worker keeps the failed notifications in the order of the request
+			h := NewHandler(
+				&mockAuthenticateClientUC{},
+				validateRateUC,
+				sendNotifUC,
+				HandlerConfig{Workers: 1},
+				&mockLogger{},
+			)
+			resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{Body: eventBody})
+
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.JSONEq(t, wantBody, resp.Body)
+
+			sort.Strings(released)
+			assert.Equal(t, tt.wantReleased, released)
+		})
+	}
+}
+
+func TestHandler_Handle_SendFailed(t *testing.T) {
+	var released []string
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true, Reservation: &Reservation{ContentKey: notification.Recipient}}, nil
+		},
+		releaseFunc: func(reservations []Reservation) error {
+			for _, reservation := range reservations {
+				released = append(released, reservation.ContentKey)
+			}
+
+			return nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+			if notification.Recipient == "error@example.com" {
+				return SendResult{}, errors.New("send notification error")
+			}
+
+			return SendResult{Sent: true, MessageID: "message-1"}, nil
+		},
+	}
+
+	h := NewHandler(
+		&mockAuthenticateClientUC{},
+		validateRateUC,
+		sendNotifUC,
+		HandlerConfig{Workers: 1},
+		&mockLogger{},
+	)
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"News","recipient":"error@example.com","message":"Hello"},` +
+			`{"type":"News","recipient":"a@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sent":[{"type":"News","recipient":"a@example.com","message":"Hello","message_id":"message-1"}],`+
+			`"failed":[{"type":"News","recipient":"error@example.com","message":"Hello","reason":"send_failed"}]}`,
+		resp.Body,
+	)
+	assert.Equal(t, []string{"error@example.com"}, released)
+}
+
+func TestHandler_Handle_Release_Recipients(t *testing.T) {
+	var released []string
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true, Reservation: &Reservation{ContentKey: notification.Recipient}}, nil
+		},
+		releaseFunc: func(reservations []Reservation) error {
+			for _, reservation := range reservations {
+				released = append(released, reservation.ContentKey)
+			}
+
+			return nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+			// The email of the cc failed after the one of the to was sent
+			return SendResult{
+				Sent: true,
+				Recipients: []RecipientResult{
+					{Recipient: Recipient{Address: "a@example.com", Kind: RecipientKindTo}, Sent: true},
+					{
+						Recipient: Recipient{Address: "bounce@example.com", Kind: RecipientKindTo},
+						Reason:    RejectionReasonSuppressed,
+					},
+					{
+						Recipient: Recipient{Address: "b@example.com", Kind: RecipientKindCc},
+						Reason:    RejectionReasonNotProcessed,
+					},
+				},
+			}, nil
+		},
+	}
+
+	h := NewHandler(&mockAuthenticateClientUC{}, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"News","to":["a@example.com","bounce@example.com"],` +
+			`"cc":["b@example.com"],"message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"bounce@example.com", "b@example.com"}, released)
+}
+
+func TestHandler_Handle_Recipients(t *testing.T) {
+	nextAllowedAt := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			if notification.HasRecipientList() {
+				return ValidationResult{}, fmt.Errorf("notification to several recipients: %v", notification)
+			}
+
+			switch notification.Recipient {
+			case "limited@example.com":
+				return ValidationResult{Reason: RejectionReasonRateLimited, NextAllowedAt: &nextAllowedAt}, nil
+			case "opted-out@example.com":
+				return ValidationResult{Reason: RejectionReasonOptedOut}, nil
+			}
+
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+			// Only the recipients allowed by the rate limit are sent
+			if len(notification.To) != 1 || len(notification.Cc) != 0 || len(notification.Bcc) != 1 {
+				return SendResult{}, fmt.Errorf("unexpected recipients: %v", notification)
+			}
+
+			return SendResult{Sent: true, MessageID: "message-1", Recipients: []RecipientResult{
+				{Recipient: Recipient{Address: "user@example.com", Kind: RecipientKindTo}, Sent: true, MessageID: "message-1"},
+				{Recipient: Recipient{Address: "hidden@example.com", Kind: RecipientKindBcc}, Sent: true, MessageID: "message-1"},
+			}}, nil
+		},
+	}
+
+	h := NewHandler(&mockAuthenticateClientUC{}, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[` +
+			`{"type":"News","to":["user@example.com"],"cc":["limited@example.com"],"bcc":["hidden@example.com"],` +
+			`"message":"Hello"},` +
+			`{"type":"News","to":["limited@example.com","opted-out@example.com"],"message":"Hello"},` +
+			`{"type":"News","cc":["limited@example.com"],"message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sent":[{"type":"News","to":["user@example.com"],"cc":["limited@example.com"],"bcc":["hidden@example.com"],`+
+			`"message":"Hello","message_id":"message-1","recipients":[`+
+			`{"address":"user@example.com","kind":"to","sent":true,"message_id":"message-1"},`+
+			`{"address":"limited@example.com","kind":"cc","sent":false,"reason":"rate_limited",`+
+			`"next_allowed_at":"2023-10-15T13:00:00Z"},`+
+			`{"address":"hidden@example.com","kind":"bcc","sent":true,"message_id":"message-1"}]}],`+
+			`"failed":[{"type":"News","to":["limited@example.com","opted-out@example.com"],"message":"Hello",`+
+			`"reason":"recipients_rejected","recipients":[`+
+			`{"address":"limited@example.com","kind":"to","sent":false,"reason":"rate_limited",`+
+			`"next_allowed_at":"2023-10-15T13:00:00Z"},`+
+			`{"address":"opted-out@example.com","kind":"to","sent":false,"reason":"opted_out"}]},`+
+			`{"type":"News","cc":["limited@example.com"],"message":"Hello","reason":"rate_limited",`+
+			`"next_allowed_at":"2023-10-15T13:00:00Z","recipients":[`+
+			`{"address":"limited@example.com","kind":"cc","sent":false,"reason":"rate_limited",`+
+			`"next_allowed_at":"2023-10-15T13:00:00Z"}]}]}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_MessageID(t *testing.T) {
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(ctx context.Context, notification Notification) (SendResult, error) {
+			return SendResult{Sent: true, MessageID: "message-of-" + RequestMetadataFromContext(ctx).RequestID}, nil
+		},
+	}
+
+	h := NewHandler(&mockAuthenticateClientUC{}, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "request-1"},
+		Body:           `{"notifications":[{"type":"News","recipient":"test@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sent":[{"type":"News","recipient":"test@example.com","message":"Hello","message_id":"message-of-request-1"}],`+
+			`"failed":null}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_Tenant(t *testing.T) {
+	var tenants []string
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(ctx context.Context, notification Notification) (SendResult, error) {
+			tenants = append(tenants, RequestMetadataFromContext(ctx).Tenant)
+
+			return SendResult{Sent: true}, nil
+		},
+	}
+
+	// The key of acme has its tenant and the other keys have none
+	authenticateClientUC := &mockAuthenticateClientUC{
+		handleFunc: func(credentials Credentials) (Client, error) {
+			client := Client{ID: "client-1", AllowedTypes: []string{AnyType}}
+			if credentials.APIKey == "key-acme" {
+				client.Tenant = "acme"
+			}
+
+			return client, nil
+		},
+	}
+
+	h := NewHandler(authenticateClientUC, validateRateUC, sendNotifUC, HandlerConfig{
+		Tenants: TenantConfig{Header: DefaultTenantHeader},
+	}, &mockLogger{})
+	body := `{"notifications":[{"type":"News","recipient":"test@example.com","message":"Hello"}]}`
+
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{APIKeyHeader: "key-acme", "X-Tenant-ID": "acme"},
+		Body:    body,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{APIKeyHeader: "key-acme", "X-Tenant-ID": "globex"},
+		Body:    body,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, resp.Body, IDRequestTenantForbidden)
+
+	// A client without tenant can not choose one with the header
+	resp, err = h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{APIKeyHeader: "key-other", "X-Tenant-ID": "globex"},
+		Body:    body,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, resp.Body, IDRequestTenantForbidden)
+	assert.Equal(t, []string{"acme"}, tenants)
+}
+
+func TestHandler_Handle_Authentication(t *testing.T) {
+	body := `{"notifications":[{"type":"News","recipient":"test@example.com","message":"Hello"}]}`
+
+	tests := []struct {
+		name           string
+		authenticate   func(credentials Credentials) (Client, error)
+		wantStatusCode int
+		wantHeaders    map[string]string
+		wantID         string
+	}{
+		{
+			name: "credentials not valid",
+			authenticate: func(credentials Credentials) (Client, error) {
+				return Client{}, &GeneralError{
+					Code:       CodeRequestError,
+					ID:         IDRequestUnauthorized,
+					Message:    "The API key is not valid",
+					StatusCode: http.StatusUnauthorized,
+				}
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantID:         IDRequestUnauthorized,
+		},
+		{
+			name: "client over its rate limit",
+			authenticate: func(credentials Credentials) (Client, error) {
+				return Client{}, &GeneralError{
+					Code:       CodeRequestError,
+					ID:         IDRequestRateLimited,
+					Message:    "The client sent more than 10 requests in 1m0s",
+					StatusCode: http.StatusTooManyRequests,
+					RetryAfter: 1500 * time.Millisecond,
+				}
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantHeaders:    map[string]string{"Retry-After": "2"},
+			wantID:         IDRequestRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Credentials
+
+			authenticateClientUC := &mockAuthenticateClientUC{
+				handleFunc: func(credentials Credentials) (Client, error) {
+					got = credentials
+
+					return tt.authenticate(credentials)
+				},
+			}
+
+			h := NewHandler(
+				authenticateClientUC,
+				&mockValidateRateLimitUC{},
+				&mockSendNotificationUC{},
+				HandlerConfig{},
+				&mockLogger{},
+			)
+			resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+				Headers: map[string]string{"x-api-key": "mk_key", "Authorization": "Bearer eyJ.eyJ.sig"},
+				Body:    body,
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, Credentials{APIKey: "mk_key", BearerToken: "eyJ.eyJ.sig"}, got)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+			assert.Equal(t, tt.wantHeaders, resp.Headers)
+			assert.Contains(t, resp.Body, tt.wantID)
+		})
+	}
+}
+
+func TestHandler_Handle_Forbidden(t *testing.T) {
+	var validated []string
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			validated = append(validated, notification.Type)
+
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(ctx context.Context, notification Notification) (SendResult, error) {
+			if RequestMetadataFromContext(ctx).Tenant != "acme" {
+				return SendResult{}, errors.New("unexpected tenant")
+			}
+
+			return SendResult{Sent: true}, nil
+		},
+	}
+	authenticateClientUC := &mockAuthenticateClientUC{
+		handleFunc: func(credentials Credentials) (Client, error) {
+			client := TokenClaims{Subject: "billing-service", Scopes: []string{"notifications:send:news"}}.Client()
+			client.Tenant = "acme"
+
+			return client, nil
+		},
+	}
+
+	h := NewHandler(authenticateClientUC, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"Marketing","recipient":"test@example.com","message":"Hello"},` +
+			`{"type":"News","recipient":"test@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"News"}, validated)
+	assert.JSONEq(
+		t,
+		`{"sent":[{"type":"News","recipient":"test@example.com","message":"Hello"}],`+
+			`"failed":[{"type":"Marketing","recipient":"test@example.com","message":"Hello","reason":"forbidden"}]}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_SenderNotAllowed(t *testing.T) {
+	var validated []string
+
+	var checked []string
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			validated = append(validated, notification.Type)
+
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+	sendNotifUC := &mockSendNotificationUC{
+		handleFunc: func(ctx context.Context, notification Notification) (SendResult, error) {
+			return SendResult{Sent: true}, nil
+		},
+		senderAllowedFunc: func(notificationType string) (bool, error) {
+			checked = append(checked, notificationType)
+
+			return notificationType != "Marketing", nil
+		},
+	}
+
+	h := NewHandler(&mockAuthenticateClientUC{}, validateRateUC, sendNotifUC, HandlerConfig{}, &mockLogger{})
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"Marketing","recipient":"a@example.com","message":"Hello"},` +
+			`{"type":"News","recipient":"a@example.com","message":"Hello"},` +
+			`{"type":"Marketing","recipient":"b@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Marketing", "News"}, checked)
+	assert.Equal(t, []string{"News"}, validated)
+	assert.JSONEq(
+		t,
+		`{"sent":[{"type":"News","recipient":"a@example.com","message":"Hello"}],"failed":[`+
+			`{"type":"Marketing","recipient":"a@example.com","message":"Hello","reason":"sender_not_allowed"},`+
+			`{"type":"Marketing","recipient":"b@example.com","message":"Hello","reason":"sender_not_allowed"}]}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_FailedReason(t *testing.T) {
+	nextAllowedAt := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+	validateRateUC := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{
+				Reason:        RejectionReasonQuietHours,
+				NextAllowedAt: &nextAllowedAt,
+			}, nil
+		},
+	}
+
+	h := NewHandler(
+		&mockAuthenticateClientUC{},
+		validateRateUC,
+		&mockSendNotificationUC{},
+		HandlerConfig{},
+		&mockLogger{},
+	)
+	resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"notifications":[{"type":"Marketing","recipient":"test@example.com","message":"Hello"}]}`,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sent":null,"failed":[{"type":"Marketing","recipient":"test@example.com","message":"Hello",`+
+			`"reason":"quiet_hours","next_allowed_at":"2023-10-15T13:00:00Z"}]}`,
+		resp.Body,
+	)
+}
+
+func TestHandler_Handle_MaxRecipients(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "recipients of every notification up to the maximum",
+			body: `{"notifications":[` +
+				`{"type":"News","to":["a@example.com","b@example.com"],"cc":["c@example.com"],"message":"Hello"},` +
+				`{"type":"News","recipient":"d@example.com","message":"Hello"}]}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "more recipients than allowed",
+			body: `{"notifications":[` +
+				`{"type":"News","to":["a@example.com","b@example.com"],"bcc":["c@example.com"],"message":"Hello"},` +
+				`{"type":"News","to":["d@example.com","e@example.com"],"message":"Hello"}]}`,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+			wantBody: `{"errors":[{"id":"ID_REQUEST_TOO_LARGE","status":"413","code":"CODE_REQUEST_ERROR",` +
+				`"title":"Error","detail":"The request has 5 recipients, the maximum is 4"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validated := 0
+
+			validateRateUC := &mockValidateRateLimitUC{
+				handleFunc: func(notification Notification) (ValidationResult, error) {
+					validated++
+
+					return ValidationResult{Allowed: true}, nil
+				},
+			}
+			sendNotifUC := &mockSendNotificationUC{
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					return SendResult{Sent: true}, nil
+				},
+			}
+
+			h := NewHandler(
+				&mockAuthenticateClientUC{},
+				validateRateUC,
+				sendNotifUC,
+				HandlerConfig{MaxRecipients: 4},
+				&mockLogger{},
+			)
+			resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			if tt.wantBody != "" {
+				// Nothing of the request is processed
+				assert.JSONEq(t, tt.wantBody, resp.Body)
+				assert.Zero(t, validated)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_Limits(t *testing.T) {
+	body := func(count int) string {
+		notifications := make([]Notification, 0, count)
+		for i := 0; i < count; i++ {
+			notifications = append(notifications, Notification{
+				Type:      "News",
+				Recipient: fmt.Sprintf("user%d@example.com", i),
+				Message:   "Hello",
+			})
+		}
+
+		content, _ := json.Marshal(RequestBody{Notifications: notifications})
+
+		return string(content)
+	}
+
+	allowAll := &mockValidateRateLimitUC{
+		handleFunc: func(notification Notification) (ValidationResult, error) {
+			return ValidationResult{Allowed: true}, nil
+		},
+	}
+
+	tests := []struct {
+		name             string
+		notifications    int
+		config           HandlerConfig
+		deadline         time.Duration
+		sendDelay        time.Duration
+		wantStatusCode   int
+		wantSent         int
+		wantNotProcessed int
+		wantMaxInFlight  int32
+	}{
+		{
+			name:           "more notifications than allowed",
+			notifications:  4,
+			config:         HandlerConfig{MaxNotifications: 3},
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:            "sends are bounded by the workers",
+			notifications:   20,
+			config:          HandlerConfig{Workers: 3},
+			sendDelay:       5 * time.Millisecond,
+			wantStatusCode:  http.StatusOK,
+			wantSent:        20,
+			wantMaxInFlight: 3,
+		},
+		{
+			name:             "deadline inside the margin processes nothing",
+			notifications:    5,
+			config:           HandlerConfig{DeadlineMargin: time.Minute},
+			deadline:         30 * time.Second,
+			wantStatusCode:   http.StatusOK,
+			wantNotProcessed: 5,
+		},
+		{
+			name:             "notifications not started before the deadline are not processed",
+			notifications:    6,
+			config:           HandlerConfig{Workers: 2, DeadlineMargin: time.Millisecond},
+			deadline:         101 * time.Millisecond,
+			sendDelay:        300 * time.Millisecond,
+			wantStatusCode:   http.StatusOK,
+			wantSent:         2,
+			wantNotProcessed: 4,
+			wantMaxInFlight:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, maxInFlight int32
+
+			sendNotifUC := &mockSendNotificationUC{
+				handleFunc: func(_ context.Context, notification Notification) (SendResult, error) {
+					current := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+
+					for {
+						previous := atomic.LoadInt32(&maxInFlight)
+						if current <= previous || atomic.CompareAndSwapInt32(&maxInFlight, previous, current) {
+							break
+						}
+					}
+
+					time.Sleep(tt.sendDelay)
+
+					return SendResult{Sent: true}, nil
+				},
+			}
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			h := NewHandler(&mockAuthenticateClientUC{}, allowAll, sendNotifUC, tt.config, &mockLogger{})
+			resp, err := h.Handle(ctx, events.APIGatewayProxyRequest{Body: body(tt.notifications)})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			var responseBody ResponseBody
+			assert.NoError(t, json.Unmarshal([]byte(resp.Body), &responseBody))
+			assert.Len(t, responseBody.Sent, tt.wantSent)
+			assert.Len(t, responseBody.Failed, tt.wantNotProcessed)
+
+			for _, failed := range responseBody.Failed {
+				assert.Equal(t, RejectionReasonNotProcessed, failed.Reason)
+			}
+
+			assert.Equal(t, tt.wantMaxInFlight, atomic.LoadInt32(&maxInFlight))
+		})
+	}
+}
+
+func TestHandler_Handle_Cancelled(t *testing.T) {
+	eventBody := `{"notifications":[` +
+		`{"type":"News","recipient":"user0@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"user1@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"user2@example.com","message":"Hello"},` +
+		`{"type":"News","recipient":"user3@example.com","message":"Hello"}]}`
+
+	t.Run("sends in flight and not started are not processed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var started int32
+
+		var released int32
+
+		allowAll := &mockValidateRateLimitUC{
+			handleFunc: func(notification Notification) (ValidationResult, error) {
+				reservation := &Reservation{ContentKey: notification.Recipient}
+
+				return ValidationResult{Allowed: true, Reservation: reservation}, nil
+			},
+			releaseFunc: func(reservations []Reservation) error {
+				atomic.AddInt32(&released, int32(len(reservations)))
+
+				return nil
+			},
+		}
+		sendNotifUC := &mockSendNotificationUC{
+			handleFunc: func(ctx context.Context, notification Notification) (SendResult, error) {
+				// The request is cancelled once every worker is sending
+				if atomic.AddInt32(&started, 1) == 2 {
+					cancel()
+				}
+
+				<-ctx.Done()
+
+				return SendResult{}, ctx.Err()
+			},
+		}
+
+		h := NewHandler(&mockAuthenticateClientUC{}, allowAll, sendNotifUC, HandlerConfig{Workers: 2}, &mockLogger{})
+		resp, err := h.Handle(ctx, events.APIGatewayProxyRequest{Body: eventBody})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var responseBody ResponseBody
+		assert.NoError(t, json.Unmarshal([]byte(resp.Body), &responseBody))
+		assert.Empty(t, responseBody.Sent)
+		assert.Len(t, responseBody.Failed, 4)
+
+		for _, failed := range responseBody.Failed {
+			assert.Equal(t, RejectionReasonNotProcessed, failed.Reason)
+		}
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&started))
+		assert.Equal(t, int32(4), atomic.LoadInt32(&released))
+	})
+
+	t.Run("validation interrupted by the cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		validateRateUC := &mockValidateRateLimitUC{
+			handleFunc: func(notification Notification) (ValidationResult, error) {
+				cancel()
+
+				return ValidationResult{}, context.Canceled
+			},
+		}
+
+		h := NewHandler(
+			&mockAuthenticateClientUC{},
+			validateRateUC,
+			&mockSendNotificationUC{},
+			HandlerConfig{},
+			&mockLogger{},
+		)
+		resp, err := h.Handle(ctx, events.APIGatewayProxyRequest{Body: eventBody})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var responseBody ResponseBody
+		assert.NoError(t, json.Unmarshal([]byte(resp.Body), &responseBody))
+		assert.Empty(t, responseBody.Sent)
+		assert.Len(t, responseBody.Failed, 4)
+	})
+}
@@ -0,0 +1,229 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ManageRulesUCInterface interface for this use case manage rules
+type ManageRulesUCInterface interface {
+	List(ctx context.Context) ([]RateLimitRule, error)
+	Get(ctx context.Context, notificationType string) (*RateLimitRule, error)
+	Create(ctx context.Context, rule RateLimitRule) (*RateLimitRule, error)
+	Update(ctx context.Context, rule RateLimitRule) (*RateLimitRule, error)
+	Delete(ctx context.Context, notificationType string, version int) error
+	History(ctx context.Context, notificationType string) ([]RateLimitRuleHistory, error)
+}
+
+// RulesHandler declaration of the admin handler of the rate limit rules
+type RulesHandler struct {
+	manageRulesUC ManageRulesUCInterface
+	logger        infraestructure.LoggerInterface
+}
+
+// List get every rule
+func (h *RulesHandler) List(
+	ctx context.Context,
+	_ events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	rules, err := h.manageRulesUC.List(ctx)
+	if err != nil {
+		h.loggerFor("List").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusOK, RulesResponseBody{Rules: rules})
+}
+
+// Get get the rule of the type of the path
+func (h *RulesHandler) Get(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	notificationType, err := pathType(event)
+	if err != nil {
+		return responseError(err)
+	}
+
+	rule, err := h.manageRulesUC.Get(ctx, notificationType)
+	if err != nil {
+		h.loggerFor("Get").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusOK, rule)
+}
+
+// Create save the rule of the body, its type must not have a rule yet
+func (h *RulesHandler) Create(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	rule, err := decodeRule(event.Body)
+	if err != nil {
+		return responseError(err)
+	}
+
+	created, err := h.manageRulesUC.Create(ctx, rule)
+	if err != nil {
+		h.loggerFor("Create").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusCreated, created)
+}
+
+// Update replace the rule of the type of the path, the body must have the version that is replaced
+func (h *RulesHandler) Update(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	notificationType, err := pathType(event)
+	if err != nil {
+		return responseError(err)
+	}
+
+	rule, err := decodeRule(event.Body)
+	if err != nil {
+		return responseError(err)
+	}
+
+	if rule.Type != "" && rule.Type != notificationType {
+		return responseError(invalidParameterError(
+			fmt.Sprintf("The type of the body '%s' does not match the type of the path '%s'", rule.Type, notificationType),
+		))
+	}
+
+	rule.Type = notificationType
+
+	updated, err := h.manageRulesUC.Update(ctx, rule)
+	if err != nil {
+		h.loggerFor("Update").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusOK, updated)
+}
+
+// Delete remove the rule of the type of the path, the query parameter version must be the version that is deleted
+func (h *RulesHandler) Delete(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	notificationType, err := pathType(event)
+	if err != nil {
+		return responseError(err)
+	}
+
+	version, err := strconv.Atoi(event.QueryStringParameters["version"])
+	if err != nil || version < 0 {
+		return responseError(invalidParameterError("The query parameter version is required and must be a number"))
+	}
+
+	err = h.manageRulesUC.Delete(ctx, notificationType, version)
+	if err != nil {
+		h.loggerFor("Delete").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+}
+
+// History get the previous versions of the rule of the type of the path
+func (h *RulesHandler) History(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	notificationType, err := pathType(event)
+	if err != nil {
+		return responseError(err)
+	}
+
+	history, err := h.manageRulesUC.History(ctx, notificationType)
+	if err != nil {
+		h.loggerFor("History").Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusOK, RuleHistoryResponseBody{History: history})
+}
+
+// loggerFor Init logger with light ECS specification for a method of this handler
+func (h *RulesHandler) loggerFor(method string) infraestructure.LoggerInterface {
+	return h.logger.WithFields(
+		"@timestamp", time.Now().Format(time.RFC3339),
+		"file", "cx_rules_handler",
+		"method", method,
+	)
+}
+
+// decodeRule decode a rule rejecting unknown attributes and values of the wrong type, e.g. a limit "3"
+func decodeRule(body string) (RateLimitRule, error) {
+	var rule RateLimitRule
+
+	decoder := json.NewDecoder(bytes.NewBufferString(body))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&rule)
+	if err == nil {
+		return rule, nil
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return rule, invalidParameterError(
+			fmt.Sprintf("Invalid value for '%s', expected a %s but got a %s", typeError.Field, typeError.Type, typeError.Value),
+		)
+	}
+
+	return rule, invalidParameterError("Invalid rule: " + err.Error())
+}
+
+// pathType notification type of the path
+func pathType(event events.APIGatewayProxyRequest) (string, error) {
+	notificationType, err := url.PathUnescape(event.PathParameters["type"])
+	if err != nil || notificationType == "" {
+		return "", invalidParameterError("Invalid type in the path")
+	}
+
+	return notificationType, nil
+}
+
+// invalidParameterError error for the requests with a malformed parameter
+func invalidParameterError(message string) error {
+	return &GeneralError{
+		Code:       CodeRequestError,
+		ID:         IDRequestInvalidParameter,
+		Message:    message,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// NewRulesHandler Initialize RulesHandler
+func NewRulesHandler(
+	manageRulesUC ManageRulesUCInterface,
+	logger infraestructure.LoggerInterface,
+) *RulesHandler {
+	return &RulesHandler{
+		manageRulesUC: manageRulesUC,
+		logger:        logger,
+	}
+}
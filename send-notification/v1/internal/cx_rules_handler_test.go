@@ -0,0 +1,235 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockManageRulesUC struct {
+	listFunc    func() ([]RateLimitRule, error)
+	getFunc     func(notificationType string) (*RateLimitRule, error)
+	createFunc  func(rule RateLimitRule) (*RateLimitRule, error)
+	updateFunc  func(rule RateLimitRule) (*RateLimitRule, error)
+	deleteFunc  func(notificationType string, version int) error
+	historyFunc func(notificationType string) ([]RateLimitRuleHistory, error)
+}
+
+func (m *mockManageRulesUC) List(_ context.Context) ([]RateLimitRule, error) {
+	return m.listFunc()
+}
+
+func (m *mockManageRulesUC) Get(_ context.Context, notificationType string) (*RateLimitRule, error) {
+	return m.getFunc(notificationType)
+}
+
+func (m *mockManageRulesUC) Create(_ context.Context, rule RateLimitRule) (*RateLimitRule, error) {
+	return m.createFunc(rule)
+}
+
+func (m *mockManageRulesUC) Update(_ context.Context, rule RateLimitRule) (*RateLimitRule, error) {
+	return m.updateFunc(rule)
+}
+
+func (m *mockManageRulesUC) Delete(_ context.Context, notificationType string, version int) error {
+	return m.deleteFunc(notificationType, version)
+}
+
+func (m *mockManageRulesUC) History(_ context.Context, notificationType string) ([]RateLimitRuleHistory, error) {
+	return m.historyFunc(notificationType)
+}
+
+func TestRulesHandler_Create(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantBody       string
+		wantDetail     string
+	}{
+		{
+			name:           "created",
+			body:           `{"type":"News","notifications_limit":1,"interval":"P1D"}`,
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `{"type":"News","notifications_limit":1,"interval":"P1D","version":1}`,
+		},
+		{
+			name:           "limit as a string",
+			body:           `{"type":"News","notifications_limit":"3","interval_in_minutes":1440}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantDetail:     "notifications_limit",
+		},
+		{
+			name:           "unknown attribute",
+			body:           `{"type":"News","notifications_limt":3,"interval_in_minutes":1440}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantDetail:     "notifications_limt",
+		},
+		{
+			name:           "malformed body",
+			body:           `{"type":`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewRulesHandler(&mockManageRulesUC{
+				createFunc: func(rule RateLimitRule) (*RateLimitRule, error) {
+					rule.Version = 1
+
+					return &rule, nil
+				},
+			}, &mockLogger{})
+
+			resp, err := h.Create(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, resp.Body)
+			}
+
+			assert.Contains(t, resp.Body, tt.wantDetail)
+		})
+	}
+}
+
+func TestRulesHandler_Update(t *testing.T) {
+	tests := []struct {
+		name           string
+		pathType       string
+		body           string
+		useCaseErr     error
+		wantStatusCode int
+	}{
+		{
+			name:           "updated",
+			pathType:       "News",
+			body:           `{"notifications_limit":2,"interval_in_minutes":60,"version":1}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "type of the body does not match the path",
+			pathType:       "News",
+			body:           `{"type":"Status","notifications_limit":2,"interval_in_minutes":60,"version":1}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:     "version conflict",
+			pathType: "News",
+			body:     `{"notifications_limit":2,"interval_in_minutes":60,"version":1}`,
+			useCaseErr: &GeneralError{
+				Code:       CodeRuleError,
+				ID:         IDRuleVersionConflict,
+				StatusCode: http.StatusConflict,
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewRulesHandler(&mockManageRulesUC{
+				updateFunc: func(rule RateLimitRule) (*RateLimitRule, error) {
+					assert.Equal(t, tt.pathType, rule.Type)
+					assert.Equal(t, 1, rule.Version)
+
+					if tt.useCaseErr != nil {
+						return nil, tt.useCaseErr
+					}
+
+					rule.Version++
+
+					return &rule, nil
+				},
+			}, &mockLogger{})
+
+			resp, err := h.Update(context.Background(), events.APIGatewayProxyRequest{
+				PathParameters: map[string]string{"type": tt.pathType},
+				Body:           tt.body,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestRulesHandler_Delete(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          map[string]string
+		wantStatusCode int
+	}{
+		{
+			name:           "deleted",
+			query:          map[string]string{"version": "2"},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "missing version",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewRulesHandler(&mockManageRulesUC{
+				deleteFunc: func(notificationType string, version int) error {
+					assert.Equal(t, "News", notificationType)
+					assert.Equal(t, 2, version)
+
+					return nil
+				},
+			}, &mockLogger{})
+
+			resp, err := h.Delete(context.Background(), events.APIGatewayProxyRequest{
+				PathParameters:        map[string]string{"type": "News"},
+				QueryStringParameters: tt.query,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestRulesHandler_Reads(t *testing.T) {
+	h := NewRulesHandler(&mockManageRulesUC{
+		listFunc: func() ([]RateLimitRule, error) {
+			return []RateLimitRule{{PK: "TYPE#News", Type: "News", NotificationsLimit: 1, IntervalInMinutes: 1440}}, nil
+		},
+		getFunc: func(notificationType string) (*RateLimitRule, error) {
+			return nil, &GeneralError{Code: CodeRuleError, ID: IDRuleNotFound, StatusCode: http.StatusNotFound}
+		},
+		historyFunc: func(notificationType string) ([]RateLimitRuleHistory, error) {
+			return []RateLimitRuleHistory{{
+				RateLimitRule: RateLimitRule{Type: notificationType, NotificationsLimit: 1, Version: 1},
+				ReplacedAt:    "2023-10-15T13:00:00Z",
+				Operation:     RuleOperationUpdate,
+			}}, nil
+		},
+	}, &mockLogger{})
+
+	resp, err := h.List(context.Background(), events.APIGatewayProxyRequest{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"rules":[{"type":"News","notifications_limit":1,"interval_in_minutes":1440,"version":0}]}`, resp.Body)
+
+	resp, err = h.Get(
+		context.Background(),
+		events.APIGatewayProxyRequest{PathParameters: map[string]string{"type": "Missing"}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = h.History(
+		context.Background(),
+		events.APIGatewayProxyRequest{PathParameters: map[string]string{"type": "News"}},
+	)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"history":[{"type":"News","notifications_limit":1,"version":1,`+
+		`"replaced_at":"2023-10-15T13:00:00Z","operation":"update"}]}`, resp.Body)
+}
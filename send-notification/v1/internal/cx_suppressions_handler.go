@@ -0,0 +1,116 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// defaultSuppressionsPageSize size of the pages of the suppressions list when the limit is not given
+const defaultSuppressionsPageSize = 50
+
+// ManageSuppressionsUCInterface interface for this use case manage suppressions
+type ManageSuppressionsUCInterface interface {
+	List(ctx context.Context, limit int, cursor string) ([]Suppression, string, error)
+	Remove(ctx context.Context, email string) error
+}
+
+// SuppressionsHandler declaration of the admin handler of the suppression list
+type SuppressionsHandler struct {
+	manageSuppressionsUC ManageSuppressionsUCInterface
+	logger               infraestructure.LoggerInterface
+}
+
+// List get one page of the suppressed addresses, the query parameters limit and cursor control the pagination
+func (h *SuppressionsHandler) List(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	// Init logger with light ECS specification
+	logger := h.logger.WithFields(
+		"@timestamp", time.Now().Format(time.RFC3339),
+		"file", "cx_suppressions_handler",
+		"method", "List",
+	)
+
+	limit := defaultSuppressionsPageSize
+
+	if value, ok := event.QueryStringParameters["limit"]; ok {
+		var err error
+
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return responseError(&GeneralError{
+				Code:       CodeRequestError,
+				ID:         IDRequestInvalidParameter,
+				Message:    fmt.Sprintf("Invalid limit '%s', it must be a positive number", value),
+				StatusCode: http.StatusBadRequest,
+			})
+		}
+	}
+
+	suppressions, nextCursor, err := h.manageSuppressionsUC.List(ctx, limit, event.QueryStringParameters["cursor"])
+	if err != nil {
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	return jsonResponse(http.StatusOK, SuppressionsResponseBody{
+		Suppressions: suppressions,
+		NextCursor:   nextCursor,
+	})
+}
+
+// Remove delete the address of the path from the suppression list
+func (h *SuppressionsHandler) Remove(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	// Init logger with light ECS specification
+	logger := h.logger.WithFields(
+		"@timestamp", time.Now().Format(time.RFC3339),
+		"file", "cx_suppressions_handler",
+		"method", "Remove",
+	)
+
+	email, err := url.PathUnescape(event.PathParameters["email"])
+	if err != nil || email == "" {
+		return responseError(&GeneralError{
+			Code:       CodeRequestError,
+			ID:         IDRequestInvalidParameter,
+			Message:    "Invalid email in the path",
+			StatusCode: http.StatusBadRequest,
+		})
+	}
+
+	err = h.manageSuppressionsUC.Remove(ctx, email)
+	if err != nil {
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	logger.Infof("Address removed from the suppression list")
+
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+}
+
+// NewSuppressionsHandler Initialize SuppressionsHandler
+func NewSuppressionsHandler(
+	manageSuppressionsUC ManageSuppressionsUCInterface,
+	logger infraestructure.LoggerInterface,
+) *SuppressionsHandler {
+	return &SuppressionsHandler{
+		manageSuppressionsUC: manageSuppressionsUC,
+		logger:               logger,
+	}
+}
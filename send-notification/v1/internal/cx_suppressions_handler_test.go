@@ -0,0 +1,153 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockManageSuppressionsUC struct {
+	listFunc   func(limit int, cursor string) ([]Suppression, string, error)
+	removeFunc func(email string) error
+}
+
+func (m *mockManageSuppressionsUC) List(_ context.Context, limit int, cursor string) ([]Suppression, string, error) {
+	return m.listFunc(limit, cursor)
+}
+
+func (m *mockManageSuppressionsUC) Remove(_ context.Context, email string) error {
+	return m.removeFunc(email)
+}
+
+func TestSuppressionsHandler_List(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          map[string]string
+		listErr        error
+		wantLimit      int
+		wantStatusCode int
+		wantBody       string
+		wantErr        bool
+	}{
+		{
+			name:           "default page",
+			wantLimit:      defaultSuppressionsPageSize,
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"suppressions":[{"email":"bounce@example.com","reason":"bounce","detail":"General",` +
+				`"created_at":"2023-10-15T13:00:00Z"}],"next_cursor":"bounce@example.com"}`,
+		},
+		{
+			name:           "custom limit and cursor",
+			query:          map[string]string{"limit": "10", "cursor": "a@example.com"},
+			wantLimit:      10,
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"suppressions":[{"email":"bounce@example.com","reason":"bounce","detail":"General",` +
+				`"created_at":"2023-10-15T13:00:00Z"}],"next_cursor":"bounce@example.com"}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          map[string]string{"limit": "-1"},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "unexpected error",
+			listErr:        errors.New("database error"),
+			wantLimit:      defaultSuppressionsPageSize,
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manageSuppressionsUC := &mockManageSuppressionsUC{
+				listFunc: func(limit int, cursor string) ([]Suppression, string, error) {
+					assert.Equal(t, tt.wantLimit, limit)
+					assert.Equal(t, tt.query["cursor"], cursor)
+
+					if tt.listErr != nil {
+						return nil, "", tt.listErr
+					}
+
+					return []Suppression{{
+						PK:        "EMAIL#bounce@example.com",
+						Email:     "bounce@example.com",
+						Reason:    SuppressionReasonBounce,
+						Detail:    "General",
+						CreatedAt: "2023-10-15T13:00:00Z",
+					}}, "bounce@example.com", nil
+				},
+			}
+
+			h := NewSuppressionsHandler(manageSuppressionsUC, &mockLogger{})
+			resp, err := h.List(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: tt.query})
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, resp.Body)
+			}
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSuppressionsHandler_Remove(t *testing.T) {
+	tests := []struct {
+		name           string
+		pathEmail      string
+		removeErr      error
+		wantEmail      string
+		wantStatusCode int
+	}{
+		{
+			name:           "removed",
+			pathEmail:      "bounce%40example.com",
+			wantEmail:      "bounce@example.com",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:      "not suppressed",
+			pathEmail: "other@example.com",
+			wantEmail: "other@example.com",
+			removeErr: &GeneralError{
+				Code:       CodeSuppressionError,
+				ID:         IDSuppressionNotFound,
+				StatusCode: http.StatusNotFound,
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "missing email",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manageSuppressionsUC := &mockManageSuppressionsUC{
+				removeFunc: func(email string) error {
+					assert.Equal(t, tt.wantEmail, email)
+
+					return tt.removeErr
+				},
+			}
+
+			h := NewSuppressionsHandler(manageSuppressionsUC, &mockLogger{})
+			resp, err := h.Remove(context.Background(), events.APIGatewayProxyRequest{
+				PathParameters: map[string]string{"email": tt.pathEmail},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"time"
+
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// unsubscribeConfirmationPage page shown by the link of the email body, the opt-out is only recorded with the POST
+// so link scanners of the email providers can not unsubscribe the recipient
+const unsubscribeConfirmationPage = `<!DOCTYPE html>
+<html><body>
+<form method="post" action="?token=%s">
+<p>Do you want to stop receiving these emails?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+</body></html>`
+
+// unsubscribedPage page shown once the opt-out is recorded
+const unsubscribedPage = `<!DOCTYPE html>
+<html><body><p>You have been unsubscribed.</p></body></html>`
+
+// UnsubscribeUCInterface interface for this use case unsubscribe
+type UnsubscribeUCInterface interface {
+	Handle(ctx context.Context, token string) error
+}
+
+// UnsubscribeHandler declaration of the handler of the one-click unsubscribe links
+type UnsubscribeHandler struct {
+	unsubscribeUC UnsubscribeUCInterface
+	logger        infraestructure.LoggerInterface
+}
+
+// Handle GET shows the confirmation page, POST (RFC 8058 one-click) records the opt-out
+func (h *UnsubscribeHandler) Handle(
+	ctx context.Context,
+	event events.APIGatewayProxyRequest,
+) (events.APIGatewayProxyResponse, error) {
+	// Init logger with light ECS specification
+	logger := h.logger.WithFields(
+		"@timestamp", time.Now().Format(time.RFC3339),
+		"file", "cx_unsubscribe_handler",
+		"method", "Handle",
+	)
+
+	token := event.QueryStringParameters["token"]
+
+	if event.HTTPMethod == http.MethodGet {
+		page := fmt.Sprintf(unsubscribeConfirmationPage, html.EscapeString(url.QueryEscape(token)))
+
+		return htmlResponse(http.StatusOK, page), nil
+	}
+
+	err := h.unsubscribeUC.Handle(ctx, token)
+	if err != nil {
+		logger.Errorf("error: ", err)
+
+		return responseError(err)
+	}
+
+	logger.Infof("Unsubscribe recorded successfully")
+
+	return htmlResponse(http.StatusOK, unsubscribedPage), nil
+}
+
+// htmlResponse response with an HTML body
+func htmlResponse(statusCode int, body string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "text/html; charset=UTF-8"},
+		Body:       body,
+	}
+}
+
+// NewUnsubscribeHandler Initialize UnsubscribeHandler
+func NewUnsubscribeHandler(
+	unsubscribeUC UnsubscribeUCInterface,
+	logger infraestructure.LoggerInterface,
+) *UnsubscribeHandler {
+	return &UnsubscribeHandler{
+		unsubscribeUC: unsubscribeUC,
+		logger:        logger,
+	}
+}
@@ -0,0 +1,104 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockUnsubscribeUC struct {
+	handleFunc func(token string) error
+}
+
+func (m *mockUnsubscribeUC) Handle(_ context.Context, token string) error {
+	return m.handleFunc(token)
+}
+
+func TestUnsubscribeHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		unsubscribeUC  *mockUnsubscribeUC
+		wantStatusCode int
+		wantErr        bool
+		wantBody       string
+	}{
+		{
+			name:   "confirmation page does not unsubscribe",
+			method: http.MethodGet,
+			unsubscribeUC: &mockUnsubscribeUC{
+				handleFunc: func(token string) error {
+					return errors.New("must not be called")
+				},
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `action="?token=a%2Bb%3C%3E"`,
+		},
+		{
+			name:   "one-click unsubscribe",
+			method: http.MethodPost,
+			unsubscribeUC: &mockUnsubscribeUC{
+				handleFunc: func(token string) error {
+					if token != "a+b<>" {
+						return errors.New("unexpected token")
+					}
+
+					return nil
+				},
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       "You have been unsubscribed",
+		},
+		{
+			name:   "invalid token",
+			method: http.MethodPost,
+			unsubscribeUC: &mockUnsubscribeUC{
+				handleFunc: func(token string) error {
+					return &GeneralError{
+						Code:       CodeUnsubscribeError,
+						ID:         IDUnsubscribeTokenInvalid,
+						Message:    "Invalid unsubscribe link",
+						StatusCode: http.StatusBadRequest,
+					}
+				},
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       IDUnsubscribeTokenInvalid,
+		},
+		{
+			name:   "unexpected error",
+			method: http.MethodPost,
+			unsubscribeUC: &mockUnsubscribeUC{
+				handleFunc: func(token string) error {
+					return errors.New("unexpected error")
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewUnsubscribeHandler(tt.unsubscribeUC, &mockLogger{})
+			resp, err := h.Handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:            tt.method,
+				QueryStringParameters: map[string]string{"token": "a+b<>"},
+			})
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+			assert.True(t, strings.Contains(resp.Body, tt.wantBody), resp.Body)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ContentKeyPrefix prefix of the partition key of the messages sent, kept in the cache table apart from the
+// partitions of the notifications counted by the rate limit
+const ContentKeyPrefix = "DEDUP#"
+
+// ContentKey key of a message of a type sent to a recipient, the hash of the three of them so the key has a fixed
+// size and the messages are not stored. The recipient must be normalized so its variants share the key
+func ContentKey(notificationType, recipient, message string) string {
+	hash := sha256.New()
+
+	// The length of each part keeps ("ab", "c") and ("a", "bc") from having the same hash
+	for _, part := range []string{notificationType, recipient, message} {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return ContentKeyPrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// DedupWindowDuration get the duration of the dedup window of the rule, 0 when the type is not deduplicated
+func (r RateLimitRule) DedupWindowDuration() (time.Duration, error) {
+	if r.DedupWindow == "" {
+		return 0, nil
+	}
+
+	return ParseInterval(r.DedupWindow)
+}
@@ -0,0 +1,22 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestContentKey test for this function
+func TestContentKey(t *testing.T) {
+	key := ContentKey("Status", "user@example.com", "Order shipped")
+
+	assert.True(t, strings.HasPrefix(key, ContentKeyPrefix))
+	assert.Len(t, key, len(ContentKeyPrefix)+64)
+	assert.Equal(t, key, ContentKey("Status", "user@example.com", "Order shipped"))
+	assert.NotEqual(t, key, ContentKey("News", "user@example.com", "Order shipped"))
+	assert.NotEqual(t, key, ContentKey("Status", "other@example.com", "Order shipped"))
+	assert.NotEqual(t, key, ContentKey("Status", "user@example.com", "Order delivered"))
+	assert.NotEqual(t, ContentKey("ab", "c", ""), ContentKey("a", "bc", ""))
+}
@@ -4,15 +4,22 @@ package di
 import (
 	"os"
 
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/config"
 	"modak/send-notification/v1/internal/infraestructure"
 	"modak/send-notification/v1/internal/repositories"
 	"modak/send-notification/v1/internal/services"
 	"modak/send-notification/v1/internal/uc"
 )
 
+// newConfigProvider provider for the configuration, it is loaded from the environment and CONFIG_FILE
+func newConfigProvider() (*config.Config, error) {
+	return config.Load(os.Getenv)
+}
+
 // newAWSSessionProvider provider to aws session
-func newAWSSessionProvider() infraestructure.SessionProvider {
-	return infraestructure.NewSessionProvider(&infraestructure.SessionConfig{})
+func newAWSSessionProvider(cfg *config.Config) infraestructure.SessionProvider {
+	return infraestructure.NewSessionProvider(cfg.AWS.SessionConfig())
 }
 
 // newLoggerProvider provider for logger
@@ -20,55 +27,200 @@ func newLoggerProvider() infraestructure.LoggerInterface {
 	return infraestructure.NewLogrusProvider().Logger()
 }
 
-// newDynamoDBProvider dynamo db provider
-func newDynamoDBProvider(awsSession infraestructure.SessionProvider) infraestructure.DynamoAPI {
-	dynamoProvider := infraestructure.NewDynamoProvider(awsSession, &infraestructure.DynamoConfig{})
+// newValidateRateLimitLoggerProvider provider for the logger of the validation of the rate limit, it has its own
+// logger because the fields added to a logger are kept for the next logs
+func newValidateRateLimitLoggerProvider() uc.LoggerInterface {
+	return infraestructure.NewLogrusProvider().Logger().WithFields("file", "validate_rate_limit_uc")
+}
 
-	dynamoClient, err := dynamoProvider.DynamoClient()
-	if err != nil {
-		panic(err)
+// newAWSConfigProvider provider to the config of the aws-sdk-go-v2 clients
+func newAWSConfigProvider(cfg *config.Config) infraestructure.ConfigProvider {
+	return infraestructure.NewConfigProvider(cfg.AWS.SessionConfig())
+}
+
+// newDynamoDBProvider dynamo db provider, the clients use aws-sdk-go-v2 unless the config selects aws-sdk-go
+func newDynamoDBProvider(
+	awsSession infraestructure.SessionProvider,
+	awsConfig infraestructure.ConfigProvider,
+	cfg *config.Config,
+) (infraestructure.DynamoAPI, error) {
+	dynamoConfig := &infraestructure.DynamoConfig{Endpoint: cfg.AWS.DynamoDBEndpoint}
+
+	dynamoProvider := infraestructure.NewDynamoV2Provider(awsConfig, dynamoConfig)
+	if cfg.AWS.SDKVersion == config.SDKV1 {
+		dynamoProvider = infraestructure.NewDynamoProvider(awsSession, dynamoConfig)
 	}
 
-	return dynamoClient
+	return dynamoProvider.DynamoClient()
 }
 
 // newSESProvider creates and returns an Amazon SES client.
-func newSESProvider(awsSession infraestructure.SessionProvider) infraestructure.SESAPI {
-	sesProvider := infraestructure.NewSESProvider(awsSession, &infraestructure.SESConfig{})
+func newSESProvider(
+	awsSession infraestructure.SessionProvider,
+	awsConfig infraestructure.ConfigProvider,
+	cfg *config.Config,
+) (infraestructure.SESAPI, error) {
+	sesConfig := &infraestructure.SESConfig{Endpoint: cfg.AWS.SESEndpoint}
 
-	sesClient, err := sesProvider.SESClient()
-	if err != nil {
-		panic(err)
+	sesProvider := infraestructure.NewSESV2Provider(awsConfig, sesConfig)
+	if cfg.AWS.SDKVersion == config.SDKV1 {
+		sesProvider = infraestructure.NewSESProvider(awsSession, sesConfig)
 	}
 
-	return sesClient
+	return sesProvider.SESClient()
+}
+
+// newS3Provider creates and returns an Amazon S3 client for the attachments.
+func newS3Provider(
+	awsSession infraestructure.SessionProvider,
+	awsConfig infraestructure.ConfigProvider,
+	cfg *config.Config,
+) (infraestructure.S3API, error) {
+	s3Config := &infraestructure.S3Config{Endpoint: cfg.AWS.S3Endpoint}
+
+	s3Provider := infraestructure.NewS3V2Provider(awsConfig, s3Config)
+	if cfg.AWS.SDKVersion == config.SDKV1 {
+		s3Provider = infraestructure.NewS3Provider(awsSession, s3Config)
+	}
+
+	return s3Provider.S3Client()
 }
 
 // newRateLimitRulesRepositoryProvider provider for this repository
 func newRateLimitRulesRepositoryProvider(
 	dynamoProvider infraestructure.DynamoAPI,
-) uc.RateLimitRulesRepositoryInterface {
+	cfg *config.Config,
+) *repositories.RateLimitRulesRepository {
 	return repositories.NewRateLimitRulesRepository(
 		dynamoProvider,
-		os.Getenv("DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME"),
+		cfg.Tables.RateLimitRules,
+		cfg.Tables.RateLimitRulesHistory,
+	)
+}
+
+// newCachedRateLimitRulesRepositoryProvider provider for the cache of the rules
+func newCachedRateLimitRulesRepositoryProvider(
+	rateLimitRulesRepository *repositories.RateLimitRulesRepository,
+	cfg *config.Config,
+) *repositories.CachedRateLimitRulesRepository {
+	return repositories.NewCachedRateLimitRulesRepository(
+		rateLimitRulesRepository,
+		cfg.RulesCache.TTL,
+		cfg.RulesCache.NegativeTTL,
 	)
 }
 
 // newRateLimitCacheRepositoryProvider provider for this repository
 func newRateLimitCacheRepositoryProvider(
 	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
 ) uc.RateLimitCacheRepositoryInterface {
-	return repositories.NewRateLimitCacheRepository(
+	return repositories.NewRateLimitCacheRepository(dynamoProvider, cfg.Tables.RateLimitCache)
+}
+
+// newClientRequestsRepositoryProvider provider for the counters of the requests of the clients, they are kept in
+// the cache table
+func newClientRequestsRepositoryProvider(
+	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
+) uc.ClientRequestsRepositoryInterface {
+	return repositories.NewRateLimitCacheRepository(dynamoProvider, cfg.Tables.RateLimitCache)
+}
+
+// newAPIKeyRepositoryProvider provider for this repository
+func newAPIKeyRepositoryProvider(
+	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
+) uc.APIKeyRepositoryInterface {
+	return repositories.NewAPIKeyRepository(dynamoProvider, cfg.Tables.APIKeys)
+}
+
+// newRecipientProfileRepositoryProvider provider for this repository
+func newRecipientProfileRepositoryProvider(
+	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
+) uc.RecipientProfileRepositoryInterface {
+	return repositories.NewRecipientProfileRepository(dynamoProvider, cfg.Tables.RecipientProfiles)
+}
+
+// newRecipientPreferencesRepositoryProvider provider for this repository
+func newRecipientPreferencesRepositoryProvider(
+	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
+) uc.RecipientPreferencesRepositoryInterface {
+	return repositories.NewRecipientPreferencesRepository(dynamoProvider, cfg.Tables.RecipientPreferences)
+}
+
+// newSuppressionRepositoryProvider provider for this repository
+func newSuppressionRepositoryProvider(
+	dynamoProvider infraestructure.DynamoAPI,
+	cfg *config.Config,
+) uc.SuppressionRepositoryInterface {
+	return repositories.NewSuppressionRepository(
 		dynamoProvider,
-		os.Getenv("DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME"),
+		cfg.Tables.SuppressionList,
+		cfg.RecipientNormalizer,
 	)
 }
 
-// newEmailServiceProvider provider for this service
+// newUnsubscribeLinkServiceProvider provider for this service
+func newUnsubscribeLinkServiceProvider(cfg *config.Config) uc.UnsubscribeLinkServiceInterface {
+	return services.NewUnsubscribeLinkService(
+		cfg.Unsubscribe.BaseURL,
+		cfg.Unsubscribe.SigningSecret,
+		cfg.Unsubscribe.LinkTTL,
+	)
+}
+
+// newDefaultQuietHoursProvider quiet hours applied to the types without their own quiet hours
+func newDefaultQuietHoursProvider(cfg *config.Config) *internal.QuietHours {
+	return cfg.DefaultQuietHours
+}
+
+// newRecipientCapProvider cap of every notification to a recipient
+func newRecipientCapProvider(cfg *config.Config) internal.RecipientCap {
+	return cfg.RecipientCap
+}
+
+// newClientRateLimitProvider rate limit of the clients whose API key has none
+func newClientRateLimitProvider(cfg *config.Config) internal.RequestRate {
+	return cfg.ClientRateLimit
+}
+
+// newTokenVerifierProvider provider for the verifier of the bearer tokens, nil when no JWKS is configured
+func newTokenVerifierProvider(cfg *config.Config) uc.TokenVerifierInterface {
+	if cfg.JWT.JWKS == "" {
+		return nil
+	}
+
+	return services.NewJWTVerifier(cfg.JWT.JWKS, cfg.JWT.Audience, cfg.JWT.Issuer, cfg.JWT.JWKSCacheTTL)
+}
+
+// newRecipientNormalizerProvider normalization of the recipients in the keys of the rate limit
+func newRecipientNormalizerProvider(cfg *config.Config) internal.RecipientNormalizer {
+	return cfg.RecipientNormalizer
+}
+
+// newSenderConfigProvider provider for the senders of the emails
+func newSenderConfigProvider(cfg *config.Config) internal.SenderConfig {
+	return cfg.Sender
+}
+
+// newHandlerConfigProvider provider for the limits of the requests that send notifications
+func newHandlerConfigProvider(cfg *config.Config) internal.HandlerConfig {
+	return cfg.Handler
+}
+
+// newAttachmentServiceProvider provider for this service, the attachments of one notification fit in the limit of
+// the handler
+func newAttachmentServiceProvider(s3Provider infraestructure.S3API, cfg *config.Config) uc.AttachmentServiceInterface {
+	return services.NewAttachmentService(s3Provider, cfg.Handler.MaxAttachmentsSize)
+}
+
+// newEmailServiceProvider provider for this service, the configuration set receives the events of the emails
 func newEmailServiceProvider(
 	sesProvider infraestructure.SESAPI,
+	cfg *config.Config,
 ) uc.EmailServiceInterface {
-	return services.NewEmailService(
-		sesProvider,
-	)
+	return services.NewEmailService(sesProvider, cfg.SESConfigurationSet)
 }
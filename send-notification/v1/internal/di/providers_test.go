@@ -2,10 +2,12 @@
 package di
 
 import (
-	"os"
 	"reflect"
 	"testing"
+	"time"
 
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/config"
 	"modak/send-notification/v1/internal/infraestructure"
 	"modak/send-notification/v1/internal/repositories"
 	"modak/send-notification/v1/internal/services"
@@ -13,21 +15,96 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/sesv2"
 )
 
+// testConfig configuration of the tests of the providers
+var testConfig = &config.Config{
+	AWS: config.AWS{SDKVersion: config.SDKV2, Region: "us-east-1"},
+	Tables: config.Tables{
+		RateLimitRules:        "prod-notification-rate-limit-rules",
+		RateLimitRulesHistory: "prod-notification-rate-limit-rules-history",
+		RateLimitCache:        "prod-notification-rate-limit-cache",
+		RecipientProfiles:     "prod-notification-recipient-profiles",
+		RecipientPreferences:  "prod-notification-recipient-preferences",
+		SuppressionList:       "prod-notification-suppression-list",
+		APIKeys:               "prod-notification-api-keys",
+	},
+	RulesCache:          config.RulesCache{TTL: 30 * time.Second, NegativeTTL: time.Minute},
+	SESConfigurationSet: "notifications",
+}
+
+// Test_newConfigProvider tests for this provider
+func Test_newConfigProvider(t *testing.T) {
+	required := map[string]string{
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME":         "rules",
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_HISTORY_TABLE_NAME": "rules-history",
+		"DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME":         "cache",
+		"DYNAMODB_NOTIFICATION_RECIPIENT_PROFILES_TABLE_NAME":       "profiles",
+		"DYNAMODB_NOTIFICATION_RECIPIENT_PREFERENCES_TABLE_NAME":    "preferences",
+		"DYNAMODB_NOTIFICATION_SUPPRESSION_LIST_TABLE_NAME":         "suppressions",
+		"DYNAMODB_NOTIFICATION_API_KEYS_TABLE_NAME":                 "api-keys",
+		"SENDER_FROM_ADDRESS":                                       "notifications@example.com",
+		"UNSUBSCRIBE_SIGNING_SECRET":                                "secret",
+	}
+
+	tests := []struct {
+		name    string
+		missing string
+		wantErr bool
+	}{
+		{name: "every required variable"},
+		{name: "missing table", missing: "DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME", wantErr: true},
+		{name: "missing signing secret", missing: "UNSUBSCRIBE_SIGNING_SECRET", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+
+			for name, value := range required {
+				if name == tt.missing {
+					value = ""
+				}
+
+				t.Setenv(name, value)
+			}
+
+			got, err := newConfigProvider()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newConfigProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got.Tables.RateLimitCache != "cache" {
+				t.Errorf("newConfigProvider() cache table = %v, want cache", got.Tables.RateLimitCache)
+			}
+		})
+	}
+}
+
 // Test_newAWSSessionProvider tests for this provider
 func Test_newAWSSessionProvider(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name string
+		cfg  *config.Config
 		want infraestructure.SessionProvider
 	}{
 		{
 			name: "ok",
-			want: infraestructure.NewSessionProvider(&infraestructure.SessionConfig{}),
+			cfg:  testConfig,
+			want: infraestructure.NewSessionProvider(&infraestructure.SessionConfig{Region: "us-east-1"}),
+		},
+		{
+			name: "endpoint override",
+			cfg:  &config.Config{AWS: config.AWS{Region: "us-east-1", Endpoint: "http://localhost:4566"}},
+			want: infraestructure.NewSessionProvider(&infraestructure.SessionConfig{
+				Region:   "us-east-1",
+				Endpoint: "http://localhost:4566",
+			}),
 		},
 	}
 
@@ -35,7 +112,7 @@ func Test_newAWSSessionProvider(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got := newAWSSessionProvider(); !reflect.DeepEqual(got, tt.want) {
+			if got := newAWSSessionProvider(tt.cfg); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("newAWSSessionProvider() = %v, want %v", got, tt.want)
 			}
 		})
@@ -46,43 +123,23 @@ func Test_newAWSSessionProvider(t *testing.T) {
 func Test_newDynamoDBProvider(t *testing.T) {
 	t.Parallel()
 
-	type args struct {
-		awsSession infraestructure.SessionProvider
-	}
-
 	tests := []struct {
-		name string
-		args args
-		want func(a args) infraestructure.DynamoAPI
+		name       string
+		sdkVersion string
 	}{
-		{
-			name: "success",
-			args: args{
-				awsSession: infraestructure.NewSessionProvider(&infraestructure.SessionConfig{Region: "us-east-1"}),
-			},
-			want: func(a args) infraestructure.DynamoAPI {
-				dynamoProvider := infraestructure.NewDynamoProvider(a.awsSession, &infraestructure.DynamoConfig{})
-				dynamoClient, err := dynamoProvider.DynamoClient()
-				if err != nil {
-					panic(err)
-				}
-				return dynamoClient
-			},
-		},
+		{name: "aws-sdk-go-v2", sdkVersion: config.SDKV2},
+		{name: "aws-sdk-go", sdkVersion: config.SDKV1},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got := newDynamoDBProvider(
-				infraestructure.NewSessionProvider(&infraestructure.SessionConfig{Region: "us-east-1"}),
-			); reflect.DeepEqual(got, tt.want) {
-				t.Errorf(
-					"newDynamoDBProvider() = %v, want %v",
-					got,
-					tt.want(tt.args),
-				)
+			cfg := &config.Config{AWS: config.AWS{SDKVersion: tt.sdkVersion, Region: "us-east-1"}}
+
+			got, err := newDynamoDBProvider(newAWSSessionProvider(cfg), newAWSConfigProvider(cfg), cfg)
+			if err != nil || got == nil {
+				t.Errorf("newDynamoDBProvider() = %v, error %v", got, err)
 			}
 		})
 	}
@@ -91,9 +148,13 @@ func Test_newDynamoDBProvider(t *testing.T) {
 // mockSESProvider mock for ses provider
 type mockSESProvider struct{}
 
-// SendEmail mock for the method SendEmail
-func (m *mockSESProvider) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
-	return &ses.SendEmailOutput{}, nil
+// SendEmailWithContext mock for the method SendEmailWithContext
+func (m *mockSESProvider) SendEmailWithContext(
+	_ aws.Context,
+	_ *sesv2.SendEmailInput,
+	_ ...request.Option,
+) (*sesv2.SendEmailOutput, error) {
+	return &sesv2.SendEmailOutput{}, nil
 }
 
 // Test_newEmailServiceProvider tests for this provider
@@ -115,7 +176,7 @@ func Test_newEmailServiceProvider(t *testing.T) {
 				sesProvider: &mockSESProvider{},
 			},
 			want: func(a args) uc.EmailServiceInterface {
-				return services.NewEmailService(a.sesProvider)
+				return services.NewEmailService(a.sesProvider, "notifications")
 			},
 		},
 	}
@@ -124,7 +185,7 @@ func Test_newEmailServiceProvider(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got := newEmailServiceProvider(tt.args.sesProvider); !reflect.DeepEqual(got, tt.want(tt.args)) {
+			if got := newEmailServiceProvider(tt.args.sesProvider, testConfig); !reflect.DeepEqual(got, tt.want(tt.args)) {
 				t.Errorf("newEmailServiceProvider() = %v, want %v", got, tt.want(tt.args))
 			}
 		})
@@ -161,14 +222,6 @@ func Test_newLoggerProvider(t *testing.T) {
 func Test_newRateLimitCacheRepositoryProvider(t *testing.T) {
 	t.Parallel()
 
-	err := os.Setenv(
-		"DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME",
-		"prod-notification-rate-limit-cache",
-	)
-	if err != nil {
-		panic(err)
-	}
-
 	type args struct {
 		dynamoProvider infraestructure.DynamoAPI
 	}
@@ -181,15 +234,10 @@ func Test_newRateLimitCacheRepositoryProvider(t *testing.T) {
 		{
 			name: "success",
 			args: args{
-				dynamoProvider: newDynamoDBProvider(
-					infraestructure.NewSessionProvider(&infraestructure.SessionConfig{}),
-				),
+				dynamoProvider: &infraestructure.DynamoV2Client{},
 			},
 			want: func(a args) uc.RateLimitCacheRepositoryInterface {
-				return repositories.NewRateLimitCacheRepository(
-					a.dynamoProvider,
-					os.Getenv("DYNAMODB_NOTIFICATION_RATE_LIMIT_CACHE_TABLE_NAME"),
-				)
+				return repositories.NewRateLimitCacheRepository(a.dynamoProvider, "prod-notification-rate-limit-cache")
 			},
 		},
 	}
@@ -200,6 +248,7 @@ func Test_newRateLimitCacheRepositoryProvider(t *testing.T) {
 
 			if got := newRateLimitCacheRepositoryProvider(
 				tt.args.dynamoProvider,
+				testConfig,
 			); !reflect.DeepEqual(got, tt.want(tt.args)) {
 				t.Errorf("newRateLimitCacheRepositoryProvider() = %v, want %v", got, tt.want(tt.args))
 			}
@@ -211,14 +260,6 @@ func Test_newRateLimitCacheRepositoryProvider(t *testing.T) {
 func Test_newRateLimitRulesRepositoryProvider(t *testing.T) {
 	t.Parallel()
 
-	err := os.Setenv(
-		"DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME",
-		"prod-notification-rate-limit-rules",
-	)
-	if err != nil {
-		panic(err)
-	}
-
 	type args struct {
 		dynamoProvider infraestructure.DynamoAPI
 	}
@@ -226,19 +267,18 @@ func Test_newRateLimitRulesRepositoryProvider(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want func(a args) uc.RateLimitRulesRepositoryInterface
+		want func(a args) *repositories.RateLimitRulesRepository
 	}{
 		{
 			name: "success",
 			args: args{
-				dynamoProvider: newDynamoDBProvider(
-					infraestructure.NewSessionProvider(&infraestructure.SessionConfig{}),
-				),
+				dynamoProvider: &infraestructure.DynamoV2Client{},
 			},
-			want: func(a args) uc.RateLimitRulesRepositoryInterface {
+			want: func(a args) *repositories.RateLimitRulesRepository {
 				return repositories.NewRateLimitRulesRepository(
 					a.dynamoProvider,
-					os.Getenv("DYNAMODB_NOTIFICATION_RATE_LIMIT_RULES_TABLE_NAME"),
+					"prod-notification-rate-limit-rules",
+					"prod-notification-rate-limit-rules-history",
 				)
 			},
 		},
@@ -250,6 +290,7 @@ func Test_newRateLimitRulesRepositoryProvider(t *testing.T) {
 
 			if got := newRateLimitRulesRepositoryProvider(
 				tt.args.dynamoProvider,
+				testConfig,
 			); !reflect.DeepEqual(got, tt.want(tt.args)) {
 				t.Errorf("newRateLimitRulesRepositoryProvider() = %v, want %v", got, tt.want(tt.args))
 			}
@@ -273,6 +314,7 @@ func Test_newSESProvider(t *testing.T) {
 
 	type args struct {
 		awsSession infraestructure.SessionProvider
+		sdkVersion string
 	}
 
 	tests := []struct {
@@ -281,9 +323,18 @@ func Test_newSESProvider(t *testing.T) {
 		want bool
 	}{
 		{
-			name: "success",
+			name: "aws-sdk-go-v2",
 			args: args{
 				awsSession: &mockSessionProvider{},
+				sdkVersion: config.SDKV2,
+			},
+			want: true,
+		},
+		{
+			name: "aws-sdk-go",
+			args: args{
+				awsSession: &mockSessionProvider{},
+				sdkVersion: config.SDKV1,
 			},
 			want: true,
 		},
@@ -293,10 +344,49 @@ func Test_newSESProvider(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := newSESProvider(tt.args.awsSession)
-			if (got != nil) != tt.want {
-				t.Errorf("newSESProvider() implemented SESAPI = %v, want %v", (got != nil), tt.want)
+			cfg := &config.Config{AWS: config.AWS{SDKVersion: tt.args.sdkVersion, SESEndpoint: "http://localhost:8005"}}
+
+			got, err := newSESProvider(tt.args.awsSession, newAWSConfigProvider(cfg), cfg)
+			if (got != nil && err == nil) != tt.want {
+				t.Errorf("newSESProvider() implemented SESAPI = %v, error %v, want %v", (got != nil), err, tt.want)
 			}
 		})
 	}
 }
+
+// Test_newCachedRateLimitRulesRepositoryProvider Tests for this provider
+func Test_newCachedRateLimitRulesRepositoryProvider(t *testing.T) {
+	t.Parallel()
+
+	if got := newCachedRateLimitRulesRepositoryProvider(
+		repositories.NewRateLimitRulesRepository(nil, "", ""),
+		testConfig,
+	); got == nil {
+		t.Errorf("newCachedRateLimitRulesRepositoryProvider() = %v, want a repository", got)
+	}
+}
+
+// Test_newHandlerConfigProvider Tests for this provider
+func Test_newHandlerConfigProvider(t *testing.T) {
+	t.Parallel()
+
+	want := internal.HandlerConfig{Workers: 4, MaxNotifications: 100, DeadlineMargin: 500 * time.Millisecond}
+
+	if got := newHandlerConfigProvider(&config.Config{Handler: want}); !reflect.DeepEqual(got, want) {
+		t.Errorf("newHandlerConfigProvider() = %v, want %v", got, want)
+	}
+}
+
+// Test_newTokenVerifierProvider Tests for this provider
+func Test_newTokenVerifierProvider(t *testing.T) {
+	t.Parallel()
+
+	if got := newTokenVerifierProvider(testConfig); got != nil {
+		t.Errorf("newTokenVerifierProvider() = %v, want nil without JWKS", got)
+	}
+
+	cfg := &config.Config{JWT: config.JWT{JWKS: "jwks.json", Audience: "notifications"}}
+	if _, ok := newTokenVerifierProvider(cfg).(*services.JWTVerifier); !ok {
+		t.Errorf("newTokenVerifierProvider() = %v, want a JWT verifier", newTokenVerifierProvider(cfg))
+	}
+}
@@ -11,7 +11,13 @@ import (
 )
 
 // Initialize method to initialize wire
-func Initialize() (*internal.Handler, error) {
+func Initialize() (*internal.Router, error) {
 	wire.Build(stdSet)
-	return &internal.Handler{}, nil
+	return &internal.Router{}, nil
+}
+
+// InitializeFeedback method to initialize wire for the SES feedback handler
+func InitializeFeedback() (*internal.FeedbackHandler, error) {
+	wire.Build(feedbackSet)
+	return &internal.FeedbackHandler{}, nil
 }
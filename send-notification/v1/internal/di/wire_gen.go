@@ -14,16 +14,74 @@ import (
 // Injectors from wire.go:
 
 // Initialize method to initialize wire
-func Initialize() (*internal.Handler, error) {
-	sessionProvider := newAWSSessionProvider()
-	dynamoAPI := newDynamoDBProvider(sessionProvider)
-	rateLimitRulesRepositoryInterface := newRateLimitRulesRepositoryProvider(dynamoAPI)
-	rateLimitCacheRepositoryInterface := newRateLimitCacheRepositoryProvider(dynamoAPI)
-	validateRateLimitUC := uc.NewValidateRateLimitUC(rateLimitRulesRepositoryInterface, rateLimitCacheRepositoryInterface)
-	sesapi := newSESProvider(sessionProvider)
-	emailServiceInterface := newEmailServiceProvider(sesapi)
-	sendNotificationUC := uc.NewSendNotificationUC(emailServiceInterface)
+func Initialize() (*internal.Router, error) {
+	config, err := newConfigProvider()
+	if err != nil {
+		return nil, err
+	}
+	sessionProvider := newAWSSessionProvider(config)
+	configProvider := newAWSConfigProvider(config)
+	dynamoAPI, err := newDynamoDBProvider(sessionProvider, configProvider, config)
+	if err != nil {
+		return nil, err
+	}
+	apiKeyRepositoryInterface := newAPIKeyRepositoryProvider(dynamoAPI, config)
+	clientRequestsRepositoryInterface := newClientRequestsRepositoryProvider(dynamoAPI, config)
+	tokenVerifierInterface := newTokenVerifierProvider(config)
+	requestRate := newClientRateLimitProvider(config)
+	authenticateClientUC := uc.NewAuthenticateClientUC(apiKeyRepositoryInterface, clientRequestsRepositoryInterface, tokenVerifierInterface, requestRate)
+	rateLimitRulesRepository := newRateLimitRulesRepositoryProvider(dynamoAPI, config)
+	cachedRateLimitRulesRepository := newCachedRateLimitRulesRepositoryProvider(rateLimitRulesRepository, config)
+	rateLimitCacheRepositoryInterface := newRateLimitCacheRepositoryProvider(dynamoAPI, config)
+	recipientProfileRepositoryInterface := newRecipientProfileRepositoryProvider(dynamoAPI, config)
+	recipientPreferencesRepositoryInterface := newRecipientPreferencesRepositoryProvider(dynamoAPI, config)
+	quietHours := newDefaultQuietHoursProvider(config)
+	recipientNormalizer := newRecipientNormalizerProvider(config)
+	recipientCap := newRecipientCapProvider(config)
+	loggerInterface := newValidateRateLimitLoggerProvider()
+	validateRateLimitUC := uc.NewValidateRateLimitUC(cachedRateLimitRulesRepository, rateLimitCacheRepositoryInterface, recipientProfileRepositoryInterface, recipientPreferencesRepositoryInterface, quietHours, recipientNormalizer, recipientCap, loggerInterface)
+	sesapi, err := newSESProvider(sessionProvider, configProvider, config)
+	if err != nil {
+		return nil, err
+	}
+	emailServiceInterface := newEmailServiceProvider(sesapi, config)
+	unsubscribeLinkServiceInterface := newUnsubscribeLinkServiceProvider(config)
+	suppressionRepositoryInterface := newSuppressionRepositoryProvider(dynamoAPI, config)
+	s3API, err := newS3Provider(sessionProvider, configProvider, config)
+	if err != nil {
+		return nil, err
+	}
+	attachmentServiceInterface := newAttachmentServiceProvider(s3API, config)
+	senderConfig := newSenderConfigProvider(config)
+	sendNotificationUC := uc.NewSendNotificationUC(emailServiceInterface, unsubscribeLinkServiceInterface, suppressionRepositoryInterface, cachedRateLimitRulesRepository, attachmentServiceInterface, senderConfig)
+	handlerConfig := newHandlerConfigProvider(config)
+	infraestructureLoggerInterface := newLoggerProvider()
+	handler := internal.NewHandler(authenticateClientUC, validateRateLimitUC, sendNotificationUC, handlerConfig, infraestructureLoggerInterface)
+	unsubscribeUC := uc.NewUnsubscribeUC(unsubscribeLinkServiceInterface, recipientPreferencesRepositoryInterface, recipientNormalizer)
+	unsubscribeHandler := internal.NewUnsubscribeHandler(unsubscribeUC, infraestructureLoggerInterface)
+	manageSuppressionsUC := uc.NewManageSuppressionsUC(suppressionRepositoryInterface)
+	suppressionsHandler := internal.NewSuppressionsHandler(manageSuppressionsUC, infraestructureLoggerInterface)
+	manageRulesUC := uc.NewManageRulesUC(cachedRateLimitRulesRepository, cachedRateLimitRulesRepository)
+	rulesHandler := internal.NewRulesHandler(manageRulesUC, infraestructureLoggerInterface)
+	router := internal.NewRouter(handler, unsubscribeHandler, suppressionsHandler, rulesHandler)
+	return router, nil
+}
+
+// InitializeFeedback method to initialize wire for the SES feedback handler
+func InitializeFeedback() (*internal.FeedbackHandler, error) {
+	config, err := newConfigProvider()
+	if err != nil {
+		return nil, err
+	}
+	sessionProvider := newAWSSessionProvider(config)
+	configProvider := newAWSConfigProvider(config)
+	dynamoAPI, err := newDynamoDBProvider(sessionProvider, configProvider, config)
+	if err != nil {
+		return nil, err
+	}
+	suppressionRepositoryInterface := newSuppressionRepositoryProvider(dynamoAPI, config)
+	recordFeedbackUC := uc.NewRecordFeedbackUC(suppressionRepositoryInterface)
 	loggerInterface := newLoggerProvider()
-	handler := internal.NewHandler(validateRateLimitUC, sendNotificationUC, loggerInterface)
-	return handler, nil
+	feedbackHandler := internal.NewFeedbackHandler(recordFeedbackUC, loggerInterface)
+	return feedbackHandler, nil
 }
@@ -6,23 +6,70 @@ package di
 
 import (
 	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/repositories"
 	"modak/send-notification/v1/internal/uc"
 
 	"github.com/google/wire"
 )
 
 var stdSet = wire.NewSet(
+	newConfigProvider,
 	newAWSSessionProvider,
+	newAWSConfigProvider,
 	newLoggerProvider,
+	newValidateRateLimitLoggerProvider,
 	newDynamoDBProvider,
 	newSESProvider,
+	newS3Provider,
 	internal.NewHandler,
+	newHandlerConfigProvider,
+	internal.NewUnsubscribeHandler,
+	internal.NewSuppressionsHandler,
+	internal.NewRulesHandler,
+	internal.NewRouter,
 	newRateLimitRulesRepositoryProvider,
+	newCachedRateLimitRulesRepositoryProvider,
+	wire.Bind(new(uc.RateLimitRulesRepositoryInterface), new(*repositories.CachedRateLimitRulesRepository)),
+	wire.Bind(new(uc.RulesCacheInterface), new(*repositories.CachedRateLimitRulesRepository)),
 	newRateLimitCacheRepositoryProvider,
+	newClientRequestsRepositoryProvider,
+	newAPIKeyRepositoryProvider,
+	newRecipientProfileRepositoryProvider,
+	newRecipientPreferencesRepositoryProvider,
+	newSuppressionRepositoryProvider,
+	newUnsubscribeLinkServiceProvider,
+	newDefaultQuietHoursProvider,
+	newRecipientNormalizerProvider,
+	newRecipientCapProvider,
+	newClientRateLimitProvider,
+	newTokenVerifierProvider,
+	newSenderConfigProvider,
 	newEmailServiceProvider,
+	newAttachmentServiceProvider,
 
+	uc.NewAuthenticateClientUC,
+	wire.Bind(new(internal.AuthenticateClientUCInterface), new(*uc.AuthenticateClientUC)),
 	uc.NewValidateRateLimitUC,
 	wire.Bind(new(internal.ValidateRateLimitUCInterface), new(*uc.ValidateRateLimitUC)),
 	uc.NewSendNotificationUC,
 	wire.Bind(new(internal.SendNotificationUCInterface), new(*uc.SendNotificationUC)),
+	uc.NewUnsubscribeUC,
+	wire.Bind(new(internal.UnsubscribeUCInterface), new(*uc.UnsubscribeUC)),
+	uc.NewManageSuppressionsUC,
+	wire.Bind(new(internal.ManageSuppressionsUCInterface), new(*uc.ManageSuppressionsUC)),
+	uc.NewManageRulesUC,
+	wire.Bind(new(internal.ManageRulesUCInterface), new(*uc.ManageRulesUC)),
+)
+
+var feedbackSet = wire.NewSet(
+	newConfigProvider,
+	newAWSSessionProvider,
+	newAWSConfigProvider,
+	newLoggerProvider,
+	newDynamoDBProvider,
+	internal.NewFeedbackHandler,
+	newSuppressionRepositoryProvider,
+
+	uc.NewRecordFeedbackUC,
+	wire.Bind(new(internal.RecordFeedbackUCInterface), new(*uc.RecordFeedbackUC)),
 )
@@ -1,6 +1,8 @@
 // Package internal have all the main logic
 package internal
 
+import "time"
+
 // List for code errors related to notifications
 const (
 	// CodeGeneralError Unexpected errors code
@@ -13,8 +15,51 @@ const (
 	CodeNotificationError string = "CODE_NOTIFICATION_ERROR"
 	// IDNotificationTypeNotImplemented this identifier is used when a type is not implemented
 	IDNotificationTypeNotImplemented string = "ID_NOTIFICATION_NOT_IMPLEMENTED"
+	// IDNotificationRuleInvalid this identifier is used when the rule of a type can not be applied
+	IDNotificationRuleInvalid string = "ID_NOTIFICATION_RULE_INVALID"
 	// IDNotificationEmailNotSent this identifier is used when an email was not sent
 	IDNotificationEmailNotSent string = "ID_NOTIFICATION_EMAIL_NOT_SENT"
+	// CodeUnsubscribeError this code represents a problem with an unsubscribe request
+	CodeUnsubscribeError string = "CODE_UNSUBSCRIBE_ERROR"
+	// IDUnsubscribeTokenInvalid this identifier is used when the unsubscribe link was not signed by us
+	IDUnsubscribeTokenInvalid string = "ID_UNSUBSCRIBE_TOKEN_INVALID"
+	// CodeSuppressionError this code represents a problem with the suppression list
+	CodeSuppressionError string = "CODE_SUPPRESSION_ERROR"
+	// IDSuppressionNotFound this identifier is used when the address is not in the suppression list
+	IDSuppressionNotFound string = "ID_SUPPRESSION_NOT_FOUND"
+	// CodeRuleError this code represents a problem managing the rate limit rules
+	CodeRuleError string = "CODE_RULE_ERROR"
+	// IDRuleNotFound this identifier is used when the type has no rule
+	IDRuleNotFound string = "ID_RULE_NOT_FOUND"
+	// IDRuleInvalid this identifier is used when the rule does not pass the validation
+	IDRuleInvalid string = "ID_RULE_INVALID"
+	// IDRuleAlreadyExists this identifier is used when creating a rule for a type that already has one
+	IDRuleAlreadyExists string = "ID_RULE_ALREADY_EXISTS"
+	// IDRuleVersionConflict this identifier is used when the rule was changed since the version given was read
+	IDRuleVersionConflict string = "ID_RULE_VERSION_CONFLICT"
+	// CodeRequestError this code represents a malformed request
+	CodeRequestError string = "CODE_REQUEST_ERROR"
+	// IDRequestInvalidParameter this identifier is used when a parameter of the request is not valid
+	IDRequestInvalidParameter string = "ID_REQUEST_INVALID_PARAMETER"
+	// IDRequestTooLarge this identifier is used when the request has more notifications than allowed
+	IDRequestTooLarge string = "ID_REQUEST_TOO_LARGE"
+	// IDRequestTenantForbidden this identifier is used when the request names a tenant that is not the one of its
+	// API key
+	IDRequestTenantForbidden string = "ID_REQUEST_TENANT_FORBIDDEN"
+	// IDRequestUnauthorized this identifier is used when the request has no API key or its API key is not valid
+	IDRequestUnauthorized string = "ID_REQUEST_UNAUTHORIZED"
+	// IDRequestRateLimited this identifier is used when the client sent more requests than its rate limit
+	IDRequestRateLimited string = "ID_REQUEST_RATE_LIMITED"
+	// CodeAPIKeyError this code represents a problem managing the API keys
+	CodeAPIKeyError string = "CODE_API_KEY_ERROR"
+	// IDAPIKeyNotFound this identifier is used when no API key has the ID given
+	IDAPIKeyNotFound string = "ID_API_KEY_NOT_FOUND"
+	// IDAPIKeyInvalid this identifier is used when the API key does not pass the validation
+	IDAPIKeyInvalid string = "ID_API_KEY_INVALID"
+	// CodeRouteError this code represents a request to a route that does not exist
+	CodeRouteError string = "CODE_ROUTE_ERROR"
+	// IDRouteNotFound this identifier is used when no handler serves the method and path
+	IDRouteNotFound string = "ID_ROUTE_NOT_FOUND"
 )
 
 // GeneralError for unexpected errors
@@ -24,6 +69,8 @@ type GeneralError struct {
 	Message       string
 	StatusCode    int
 	OriginalError error
+	// RetryAfter time until the request can be sent again, it is returned in the Retry-After header
+	RetryAfter time.Duration
 }
 
 // Error get the error message
@@ -38,6 +85,14 @@ type ErrorJSONAPI struct {
 	Code   string `json:"code"`
 	Title  string `json:"title"`
 	Detail string `json:"detail"`
+	// Source attribute of the request that caused the error, nil when the error is not about one attribute
+	Source *ErrorSourceJSONAPI `json:"source,omitempty"`
+}
+
+// ErrorSourceJSONAPI attribute of the request that caused an error
+type ErrorSourceJSONAPI struct {
+	// Pointer JSON pointer (RFC 6901) of the attribute, e.g. /notifications/0/recipient
+	Pointer string `json:"pointer"`
 }
 
 // ErrorsJSONAPIProvider interface to add or get errors
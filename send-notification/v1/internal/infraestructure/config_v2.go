@@ -0,0 +1,63 @@
+package infraestructure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// ConfigProvider interface for the configuration of the aws-sdk-go-v2 clients.
+type ConfigProvider interface {
+	Config() (aws.Config, error)
+}
+
+// AWSConfig attributes required for ConfigProvider.
+type AWSConfig struct {
+	once   sync.Once
+	config aws.Config
+	err    error
+	params *SessionConfig
+}
+
+// Config load the shared configuration once, the calls are retried in adaptive mode. The endpoint of
+// the session config is the base endpoint of every service, when it is empty the endpoints are resolved
+// from AWS_ENDPOINT_URL, AWS_ENDPOINT_URL_<SERVICE> or the region.
+func (c *AWSConfig) Config() (aws.Config, error) {
+	c.once.Do(func() {
+		options := []func(*config.LoadOptions) error{
+			config.WithRetryMode(aws.RetryModeAdaptive),
+		}
+
+		if c.params.Region != "" {
+			options = append(options, config.WithRegion(c.params.Region))
+		}
+
+		if c.params.Endpoint != "" {
+			options = append(options, config.WithBaseEndpoint(c.params.Endpoint))
+		}
+
+		if c.params.AccessKeyID != "" {
+			options = append(options, config.WithCredentialsProvider(
+				credentials.NewStaticCredentialsProvider(c.params.AccessKeyID, c.params.SecretAccessKey, ""),
+			))
+		}
+
+		if c.params.CredentialsFile != "" {
+			options = append(options, config.WithSharedCredentialsFiles([]string{c.params.CredentialsFile}))
+		}
+
+		c.config, c.err = config.LoadDefaultConfig(context.Background(), options...)
+	})
+
+	return c.config, c.err
+}
+
+// NewConfigProvider instantiate new ConfigProvider.
+func NewConfigProvider(params *SessionConfig) ConfigProvider {
+	return &AWSConfig{
+		params: params,
+	}
+}
@@ -1,12 +1,51 @@
 package infraestructure
 
-import "github.com/aws/aws-sdk-go/service/dynamodb"
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
 
-// DynamoAPI interface for DynamoDB methods.
+// DynamoAPI interface for DynamoDB methods, every call is canceled with its context.
 type DynamoAPI interface {
-	GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
-	PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
-	Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	GetItemWithContext(
+		ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option,
+	) (*dynamodb.GetItemOutput, error)
+	BatchGetItemWithContext(
+		ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option,
+	) (*dynamodb.BatchGetItemOutput, error)
+	PutItemWithContext(
+		ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option,
+	) (*dynamodb.PutItemOutput, error)
+	QueryWithContext(
+		ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option,
+	) (*dynamodb.QueryOutput, error)
+	UpdateItemWithContext(
+		ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option,
+	) (*dynamodb.UpdateItemOutput, error)
+	DeleteItemWithContext(
+		ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option,
+	) (*dynamodb.DeleteItemOutput, error)
+	ScanWithContext(
+		ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option,
+	) (*dynamodb.ScanOutput, error)
+	TransactWriteItemsWithContext(
+		ctx aws.Context, input *dynamodb.TransactWriteItemsInput, opts ...request.Option,
+	) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// DynamoTablesAPI interface for the DynamoDB methods that manage the tables, used by the local profile to
+// create them. The clients of DynamoProvider implement it.
+type DynamoTablesAPI interface {
+	DescribeTableWithContext(
+		ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option,
+	) (*dynamodb.DescribeTableOutput, error)
+	CreateTableWithContext(
+		ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option,
+	) (*dynamodb.CreateTableOutput, error)
+	UpdateTimeToLiveWithContext(
+		ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option,
+	) (*dynamodb.UpdateTimeToLiveOutput, error)
 }
 
 // DynamoProvider interface for Dynamo client.
@@ -15,7 +54,10 @@ type DynamoProvider interface {
 }
 
 // DynamoConfig struct with config for Dynamo.
-type DynamoConfig struct{}
+type DynamoConfig struct {
+	// Endpoint override of the DynamoDB endpoint, empty to use the endpoint of the session
+	Endpoint string
+}
 
 // Dynamo attributes required for DynamoProvider.
 type Dynamo struct {
@@ -31,12 +73,21 @@ func (d *Dynamo) DynamoClient() (DynamoAPI, error) {
 		if err != nil {
 			return nil, err
 		}
-		d.client = dynamodb.New(dynamoSession)
+		d.client = dynamodb.New(dynamoSession, d.config.awsConfig())
 	}
 
 	return d.client, nil
 }
 
+// awsConfig config of the client, the endpoint of the session unless it is overridden.
+func (c *DynamoConfig) awsConfig() *aws.Config {
+	if c == nil || c.Endpoint == "" {
+		return &aws.Config{}
+	}
+
+	return &aws.Config{Endpoint: aws.String(c.Endpoint)}
+}
+
 // NewDynamoProvider instantiate new NewDynamoProvider.
 func NewDynamoProvider(session SessionProvider, config *DynamoConfig) DynamoProvider {
 	return &Dynamo{
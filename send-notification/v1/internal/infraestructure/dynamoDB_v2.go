@@ -0,0 +1,165 @@
+package infraestructure
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// dynamoV2API methods of the aws-sdk-go-v2 DynamoDB client used by DynamoV2Client.
+type dynamoV2API interface {
+	GetItem(
+		ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.GetItemOutput, error)
+	BatchGetItem(
+		ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.BatchGetItemOutput, error)
+	PutItem(
+		ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.PutItemOutput, error)
+	Query(
+		ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.QueryOutput, error)
+	UpdateItem(
+		ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(
+		ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.DeleteItemOutput, error)
+	Scan(
+		ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(
+		ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.TransactWriteItemsOutput, error)
+	DescribeTable(
+		ctx context.Context, input *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.DescribeTableOutput, error)
+	CreateTable(
+		ctx context.Context, input *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.CreateTableOutput, error)
+	UpdateTimeToLive(
+		ctx context.Context, input *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options),
+	) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// DynamoV2Client DynamoAPI over the aws-sdk-go-v2 client, the inputs, outputs and errors are translated
+// from and into the aws-sdk-go shapes by the adapters of each call so the repositories do not change.
+type DynamoV2Client struct {
+	client dynamoV2API
+}
+
+// GetItemWithContext get only one element from dynamoDB
+func (c *DynamoV2Client) GetItemWithContext(
+	ctx aws.Context, input *dynamodbv1.GetItemInput, _ ...request.Option,
+) (*dynamodbv1.GetItemOutput, error) {
+	return invokeV2(ctx, input, getItemToV2, c.client.GetItem, getItemFromV2)
+}
+
+// BatchGetItemWithContext get several elements from dynamoDB
+func (c *DynamoV2Client) BatchGetItemWithContext(
+	ctx aws.Context, input *dynamodbv1.BatchGetItemInput, _ ...request.Option,
+) (*dynamodbv1.BatchGetItemOutput, error) {
+	return invokeV2(ctx, input, batchGetItemToV2, c.client.BatchGetItem, batchGetItemFromV2)
+}
+
+// PutItemWithContext insert a new item into dynamoDB
+func (c *DynamoV2Client) PutItemWithContext(
+	ctx aws.Context, input *dynamodbv1.PutItemInput, _ ...request.Option,
+) (*dynamodbv1.PutItemOutput, error) {
+	return invokeV2(ctx, input, putItemToV2, c.client.PutItem, putItemFromV2)
+}
+
+// QueryWithContext get elements from dynamo given a query
+func (c *DynamoV2Client) QueryWithContext(
+	ctx aws.Context, input *dynamodbv1.QueryInput, _ ...request.Option,
+) (*dynamodbv1.QueryOutput, error) {
+	return invokeV2(ctx, input, queryToV2, c.client.Query, queryFromV2)
+}
+
+// UpdateItemWithContext update the attributes of one element in dynamoDB
+func (c *DynamoV2Client) UpdateItemWithContext(
+	ctx aws.Context, input *dynamodbv1.UpdateItemInput, _ ...request.Option,
+) (*dynamodbv1.UpdateItemOutput, error) {
+	return invokeV2(ctx, input, updateItemToV2, c.client.UpdateItem, updateItemFromV2)
+}
+
+// DeleteItemWithContext delete one element from dynamoDB
+func (c *DynamoV2Client) DeleteItemWithContext(
+	ctx aws.Context, input *dynamodbv1.DeleteItemInput, _ ...request.Option,
+) (*dynamodbv1.DeleteItemOutput, error) {
+	return invokeV2(ctx, input, deleteItemToV2, c.client.DeleteItem, deleteItemFromV2)
+}
+
+// ScanWithContext read every element of a table from dynamoDB
+func (c *DynamoV2Client) ScanWithContext(
+	ctx aws.Context, input *dynamodbv1.ScanInput, _ ...request.Option,
+) (*dynamodbv1.ScanOutput, error) {
+	return invokeV2(ctx, input, scanToV2, c.client.Scan, scanFromV2)
+}
+
+// TransactWriteItemsWithContext write several elements of dynamoDB in one transaction
+func (c *DynamoV2Client) TransactWriteItemsWithContext(
+	ctx aws.Context, input *dynamodbv1.TransactWriteItemsInput, _ ...request.Option,
+) (*dynamodbv1.TransactWriteItemsOutput, error) {
+	return invokeV2(ctx, input, transactWriteItemsToV2, c.client.TransactWriteItems, transactWriteItemsFromV2)
+}
+
+// DescribeTableWithContext get the status and the schema of a table
+func (c *DynamoV2Client) DescribeTableWithContext(
+	ctx aws.Context, input *dynamodbv1.DescribeTableInput, _ ...request.Option,
+) (*dynamodbv1.DescribeTableOutput, error) {
+	return invokeV2(ctx, input, describeTableToV2, c.client.DescribeTable, describeTableFromV2)
+}
+
+// CreateTableWithContext create a table
+func (c *DynamoV2Client) CreateTableWithContext(
+	ctx aws.Context, input *dynamodbv1.CreateTableInput, _ ...request.Option,
+) (*dynamodbv1.CreateTableOutput, error) {
+	return invokeV2(ctx, input, createTableToV2, c.client.CreateTable, createTableFromV2)
+}
+
+// UpdateTimeToLiveWithContext enable or disable the expiration of the items of a table
+func (c *DynamoV2Client) UpdateTimeToLiveWithContext(
+	ctx aws.Context, input *dynamodbv1.UpdateTimeToLiveInput, _ ...request.Option,
+) (*dynamodbv1.UpdateTimeToLiveOutput, error) {
+	return invokeV2(ctx, input, updateTimeToLiveToV2, c.client.UpdateTimeToLive, updateTimeToLiveFromV2)
+}
+
+// DynamoV2 attributes required for DynamoProvider over aws-sdk-go-v2.
+type DynamoV2 struct {
+	client *DynamoV2Client
+	config ConfigProvider
+	params *DynamoConfig
+}
+
+// DynamoClient create a new aws-sdk-go-v2 client for DynamoDB.
+func (d *DynamoV2) DynamoClient() (DynamoAPI, error) {
+	if d.client == nil {
+		awsConfig, err := d.config.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		d.client = &DynamoV2Client{
+			client: dynamodb.NewFromConfig(awsConfig, func(options *dynamodb.Options) {
+				if d.params != nil && d.params.Endpoint != "" {
+					options.BaseEndpoint = aws.String(d.params.Endpoint)
+				}
+			}),
+		}
+	}
+
+	return d.client, nil
+}
+
+// NewDynamoV2Provider instantiate new DynamoProvider over aws-sdk-go-v2.
+func NewDynamoV2Provider(config ConfigProvider, params *DynamoConfig) DynamoProvider {
+	return &DynamoV2{
+		config: config,
+		params: params,
+	}
+}
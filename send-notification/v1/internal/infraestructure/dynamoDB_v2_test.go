@@ -0,0 +1,518 @@
+package infraestructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAWSEnv environment of a client of aws-sdk-go-v2 with static credentials that only uses the given endpoint
+func fakeAWSEnv(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "testdata/missing")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "testdata/missing")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ENDPOINT_URL_DYNAMODB", "")
+	t.Setenv("AWS_ENDPOINT_URL_SESV2", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+}
+
+// fakeDynamoDB server that answers every operation of the DynamoDB JSON protocol with the given status and body
+type fakeDynamoDB struct {
+	operation string
+	request   map[string]interface{}
+	status    int
+	response  string
+}
+
+// ServeHTTP record the operation and its request
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.operation = strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+
+	body, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(body, &f.request)
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(f.status)
+	_, _ = w.Write([]byte(f.response))
+}
+
+// newDynamoV2Client client of aws-sdk-go-v2 that sends every call to the server
+func newDynamoV2Client(t *testing.T, server *httptest.Server) DynamoAPI {
+	fakeAWSEnv(t)
+
+	client, err := NewDynamoV2Provider(
+		NewConfigProvider(&SessionConfig{}),
+		&DynamoConfig{Endpoint: server.URL},
+	).DynamoClient()
+	require.NoError(t, err)
+
+	return client
+}
+
+// TestDynamoV2Client_GetItemWithContext test that the attribute values are translated both ways
+func TestDynamoV2Client_GetItemWithContext(t *testing.T) {
+	fake := &fakeDynamoDB{
+		status: http.StatusOK,
+		response: `{"Item":{"type":{"S":"News"},"limit":{"N":"3"},"exempt":{"BOOL":true},` +
+			`"tags":{"SS":["a","b"]},"quiet_hours":{"M":{"start":{"S":"22:00"},"days":{"L":[{"N":"1"},{"NULL":true}]}}}}}`,
+	}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	output, err := newDynamoV2Client(t, server).GetItemWithContext(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String("rules"),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"type": {S: aws.String("News")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "GetItem", fake.operation)
+	assert.Equal(t, map[string]interface{}{
+		"TableName":      "rules",
+		"ConsistentRead": true,
+		"Key":            map[string]interface{}{"type": map[string]interface{}{"S": "News"}},
+	}, fake.request)
+
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{
+		"type":   {S: aws.String("News")},
+		"limit":  {N: aws.String("3")},
+		"exempt": {BOOL: aws.Bool(true)},
+		"tags":   {SS: aws.StringSlice([]string{"a", "b"})},
+		"quiet_hours": {M: map[string]*dynamodb.AttributeValue{
+			"start": {S: aws.String("22:00")},
+			"days":  {L: []*dynamodb.AttributeValue{{N: aws.String("1")}, {NULL: aws.Bool(true)}}},
+		}},
+	}, output.Item)
+}
+
+// TestDynamoV2Client_CreateTableWithContext test the key schema and the description of the table
+func TestDynamoV2Client_CreateTableWithContext(t *testing.T) {
+	fake := &fakeDynamoDB{
+		status:   http.StatusOK,
+		response: `{"TableDescription":{"TableName":"cache","TableStatus":"ACTIVE","CreationDateTime":1700000000}}`,
+	}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	output, err := newDynamoV2Client(t, server).(DynamoTablesAPI).CreateTableWithContext(
+		context.Background(),
+		&dynamodb.CreateTableInput{
+			TableName:   aws.String("cache"),
+			BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			},
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("pk"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "CreateTable", fake.operation)
+	assert.Equal(t, "PAY_PER_REQUEST", fake.request["BillingMode"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"AttributeName": "pk", "KeyType": "HASH"}}, fake.request["KeySchema"])
+	assert.Equal(t, dynamodb.TableStatusActive, aws.StringValue(output.TableDescription.TableStatus))
+	assert.Equal(t, int64(1700000000), output.TableDescription.CreationDateTime.Unix())
+}
+
+// TestDynamoV2Client_QueryWithContext test the enums, the numbers and the pagination keys
+func TestDynamoV2Client_QueryWithContext(t *testing.T) {
+	fake := &fakeDynamoDB{
+		status:   http.StatusOK,
+		response: `{"Count":2,"ScannedCount":2,"LastEvaluatedKey":{"pk":{"S":"News#test@example.com"}}}`,
+	}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	output, err := newDynamoV2Client(t, server).QueryWithContext(context.Background(), &dynamodb.QueryInput{
+		TableName:              aws.String("cache"),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		Select:                 aws.String(dynamodb.SelectCount),
+		Limit:                  aws.Int64(5),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk": {S: aws.String("News#test@example.com")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Query", fake.operation)
+	assert.Equal(t, "COUNT", fake.request["Select"])
+	assert.Equal(t, float64(5), fake.request["Limit"])
+	assert.Equal(t, int64(2), aws.Int64Value(output.Count))
+	assert.Equal(t, "News#test@example.com", aws.StringValue(output.LastEvaluatedKey["pk"].S))
+}
+
+// TestDynamoV2Client_Adapters test that the adapter of each call sends every parameter it translates and reads
+// every attribute of the output
+func TestDynamoV2Client_Adapters(t *testing.T) {
+	key := map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("News#test@example.com")}}
+	keyJSON := map[string]interface{}{"pk": map[string]interface{}{"S": "News#test@example.com"}}
+	names := map[string]*string{"#count": aws.String("count")}
+	namesJSON := map[string]interface{}{"#count": "count"}
+	values := map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}}
+	valuesJSON := map[string]interface{}{":one": map[string]interface{}{"N": "1"}}
+
+	tests := []struct {
+		name        string
+		response    string
+		call        func(client DynamoAPI) (interface{}, error)
+		wantRequest map[string]interface{}
+		wantOutput  interface{}
+	}{
+		{
+			name:     "UpdateItem",
+			response: `{"Attributes":{"count":{"N":"2"}}}`,
+			call: func(client DynamoAPI) (interface{}, error) {
+				return client.UpdateItemWithContext(context.Background(), &dynamodb.UpdateItemInput{
+					TableName:                           aws.String("cache"),
+					Key:                                 key,
+					UpdateExpression:                    aws.String("ADD #count :one"),
+					ConditionExpression:                 aws.String("attribute_exists(pk)"),
+					ExpressionAttributeNames:            names,
+					ExpressionAttributeValues:           values,
+					ReturnValues:                        aws.String(dynamodb.ReturnValueAllNew),
+					ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
+				})
+			},
+			wantRequest: map[string]interface{}{
+				"TableName":                           "cache",
+				"Key":                                 keyJSON,
+				"UpdateExpression":                    "ADD #count :one",
+				"ConditionExpression":                 "attribute_exists(pk)",
+				"ExpressionAttributeNames":            namesJSON,
+				"ExpressionAttributeValues":           valuesJSON,
+				"ReturnValues":                        "ALL_NEW",
+				"ReturnValuesOnConditionCheckFailure": "ALL_OLD",
+			},
+			wantOutput: &dynamodb.UpdateItemOutput{
+				Attributes: map[string]*dynamodb.AttributeValue{"count": {N: aws.String("2")}},
+			},
+		},
+		{
+			name:     "DeleteItem",
+			response: `{"Attributes":{"pk":{"S":"News#test@example.com"}}}`,
+			call: func(client DynamoAPI) (interface{}, error) {
+				return client.DeleteItemWithContext(context.Background(), &dynamodb.DeleteItemInput{
+					TableName:                 aws.String("cache"),
+					Key:                       key,
+					ConditionExpression:       aws.String("#count = :one"),
+					ExpressionAttributeNames:  names,
+					ExpressionAttributeValues: values,
+					ReturnValues:              aws.String(dynamodb.ReturnValueAllOld),
+				})
+			},
+			wantRequest: map[string]interface{}{
+				"TableName":                 "cache",
+				"Key":                       keyJSON,
+				"ConditionExpression":       "#count = :one",
+				"ExpressionAttributeNames":  namesJSON,
+				"ExpressionAttributeValues": valuesJSON,
+				"ReturnValues":              "ALL_OLD",
+			},
+			wantOutput: &dynamodb.DeleteItemOutput{Attributes: key},
+		},
+		{
+			name:     "Scan",
+			response: `{"Items":[{"pk":{"S":"News#test@example.com"}}],"Count":1,"ScannedCount":3}`,
+			call: func(client DynamoAPI) (interface{}, error) {
+				return client.ScanWithContext(context.Background(), &dynamodb.ScanInput{
+					TableName:                 aws.String("cache"),
+					IndexName:                 aws.String("by-type"),
+					FilterExpression:          aws.String("#count > :one"),
+					ProjectionExpression:      aws.String("pk"),
+					ExpressionAttributeNames:  names,
+					ExpressionAttributeValues: values,
+					ExclusiveStartKey:         key,
+					ConsistentRead:            aws.Bool(true),
+					Limit:                     aws.Int64(10),
+					Segment:                   aws.Int64(1),
+					TotalSegments:             aws.Int64(4),
+				})
+			},
+			wantRequest: map[string]interface{}{
+				"TableName":                 "cache",
+				"IndexName":                 "by-type",
+				"FilterExpression":          "#count > :one",
+				"ProjectionExpression":      "pk",
+				"ExpressionAttributeNames":  namesJSON,
+				"ExpressionAttributeValues": valuesJSON,
+				"ExclusiveStartKey":         keyJSON,
+				"ConsistentRead":            true,
+				"Limit":                     float64(10),
+				"Segment":                   float64(1),
+				"TotalSegments":             float64(4),
+			},
+			wantOutput: &dynamodb.ScanOutput{
+				Items:        []map[string]*dynamodb.AttributeValue{key},
+				Count:        aws.Int64(1),
+				ScannedCount: aws.Int64(3),
+			},
+		},
+		{
+			name: "BatchGetItem",
+			response: `{"Responses":{"rules":[{"pk":{"S":"News#test@example.com"}}]},` +
+				`"UnprocessedKeys":{"rules":{"Keys":[{"pk":{"S":"Status"}}],"ConsistentRead":true}}}`,
+			call: func(client DynamoAPI) (interface{}, error) {
+				return client.BatchGetItemWithContext(context.Background(), &dynamodb.BatchGetItemInput{
+					RequestItems: map[string]*dynamodb.KeysAndAttributes{
+						"rules": {
+							Keys:                     []map[string]*dynamodb.AttributeValue{key},
+							ConsistentRead:           aws.Bool(true),
+							ProjectionExpression:     aws.String("#count"),
+							ExpressionAttributeNames: names,
+						},
+					},
+				})
+			},
+			wantRequest: map[string]interface{}{
+				"RequestItems": map[string]interface{}{
+					"rules": map[string]interface{}{
+						"Keys":                     []interface{}{keyJSON},
+						"ConsistentRead":           true,
+						"ProjectionExpression":     "#count",
+						"ExpressionAttributeNames": namesJSON,
+					},
+				},
+			},
+			wantOutput: &dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]*dynamodb.AttributeValue{"rules": {key}},
+				UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{
+					"rules": {
+						Keys: []map[string]*dynamodb.AttributeValue{
+							{"pk": {S: aws.String("Status")}},
+						},
+						ConsistentRead: aws.Bool(true),
+					},
+				},
+			},
+		},
+		{
+			name:     "TransactWriteItems",
+			response: `{}`,
+			call: func(client DynamoAPI) (interface{}, error) {
+				return client.TransactWriteItemsWithContext(context.Background(), &dynamodb.TransactWriteItemsInput{
+					ClientRequestToken: aws.String("request-1"),
+					TransactItems: []*dynamodb.TransactWriteItem{
+						{Update: &dynamodb.Update{
+							TableName:                 aws.String("cache"),
+							Key:                       key,
+							UpdateExpression:          aws.String("ADD #count :one"),
+							ExpressionAttributeNames:  names,
+							ExpressionAttributeValues: values,
+						}},
+						{ConditionCheck: &dynamodb.ConditionCheck{
+							TableName:           aws.String("rules"),
+							Key:                 key,
+							ConditionExpression: aws.String("attribute_exists(pk)"),
+						}},
+					},
+				})
+			},
+			wantRequest: map[string]interface{}{
+				"ClientRequestToken": "request-1",
+				"TransactItems": []interface{}{
+					map[string]interface{}{"Update": map[string]interface{}{
+						"TableName":                 "cache",
+						"Key":                       keyJSON,
+						"UpdateExpression":          "ADD #count :one",
+						"ExpressionAttributeNames":  namesJSON,
+						"ExpressionAttributeValues": valuesJSON,
+					}},
+					map[string]interface{}{"ConditionCheck": map[string]interface{}{
+						"TableName":           "rules",
+						"Key":                 keyJSON,
+						"ConditionExpression": "attribute_exists(pk)",
+					}},
+				},
+			},
+			wantOutput: &dynamodb.TransactWriteItemsOutput{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDynamoDB{status: http.StatusOK, response: tt.response}
+
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			output, err := tt.call(newDynamoV2Client(t, server))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.name, fake.operation)
+			assert.Equal(t, tt.wantRequest, fake.request)
+			assert.Equal(t, tt.wantOutput, output)
+		})
+	}
+}
+
+// TestDynamoV2Client_UnsupportedParameters test that the calls with parameters that are not translated are
+// not made
+func TestDynamoV2Client_UnsupportedParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    func(client DynamoAPI) error
+		wantErr string
+	}{
+		{
+			name: "legacy key conditions",
+			call: func(client DynamoAPI) error {
+				_, err := client.QueryWithContext(context.Background(), &dynamodb.QueryInput{
+					TableName: aws.String("cache"),
+					KeyConditions: map[string]*dynamodb.Condition{
+						"pk": {ComparisonOperator: aws.String(dynamodb.ComparisonOperatorEq)},
+					},
+				})
+
+				return err
+			},
+			wantErr: "Query KeyConditions: parameter not supported over aws-sdk-go-v2",
+		},
+		{
+			name: "consumed capacity",
+			call: func(client DynamoAPI) error {
+				_, err := client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+					TableName:              aws.String("cache"),
+					ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+				})
+
+				return err
+			},
+			wantErr: "PutItem ReturnConsumedCapacity: parameter not supported over aws-sdk-go-v2",
+		},
+		{
+			name: "projection of a batch by attribute names",
+			call: func(client DynamoAPI) error {
+				_, err := client.BatchGetItemWithContext(context.Background(), &dynamodb.BatchGetItemInput{
+					RequestItems: map[string]*dynamodb.KeysAndAttributes{
+						"rules": {AttributesToGet: aws.StringSlice([]string{"pk"})},
+					},
+				})
+
+				return err
+			},
+			wantErr: "BatchGetItem AttributesToGet: parameter not supported over aws-sdk-go-v2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDynamoDB{status: http.StatusOK, response: `{}`}
+
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			err := tt.call(newDynamoV2Client(t, server))
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrUnsupportedParameter)
+			assert.Empty(t, fake.operation)
+		})
+	}
+}
+
+// TestDynamoV2Client_Errors test that the errors keep the codes of aws-sdk-go
+func TestDynamoV2Client_Errors(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{"type": {S: aws.String("News")}}
+
+	tests := []struct {
+		name        string
+		response    string
+		call        func(client DynamoAPI) error
+		wantCode    string
+		wantReasons []string
+	}{
+		{
+			name:     "conditional check failed",
+			response: `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"failed"}`,
+			call: func(client DynamoAPI) error {
+				_, err := client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+					TableName: aws.String("rules"),
+					Item:      item,
+				})
+
+				return err
+			},
+			wantCode: dynamodb.ErrCodeConditionalCheckFailedException,
+		},
+		{
+			name: "transaction canceled",
+			response: `{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException","Message":"canceled",` +
+				`"CancellationReasons":[{"Code":"None"},{"Code":"ConditionalCheckFailed","Message":"failed"}]}`,
+			call: func(client DynamoAPI) error {
+				_, err := client.TransactWriteItemsWithContext(context.Background(), &dynamodb.TransactWriteItemsInput{
+					TransactItems: []*dynamodb.TransactWriteItem{
+						{Put: &dynamodb.Put{TableName: aws.String("rules"), Item: item}},
+						{Delete: &dynamodb.Delete{TableName: aws.String("history"), Key: item}},
+					},
+				})
+
+				return err
+			},
+			wantCode:    dynamodb.ErrCodeTransactionCanceledException,
+			wantReasons: []string{"None", "ConditionalCheckFailed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&fakeDynamoDB{status: http.StatusBadRequest, response: tt.response})
+			defer server.Close()
+
+			err := tt.call(newDynamoV2Client(t, server))
+
+			var awsErr awserr.Error
+			require.True(t, errors.As(err, &awsErr))
+			assert.Equal(t, tt.wantCode, awsErr.Code())
+
+			if tt.wantReasons == nil {
+				return
+			}
+
+			var canceled *dynamodb.TransactionCanceledException
+			require.True(t, errors.As(err, &canceled))
+
+			var reasons []string
+			for _, reason := range canceled.CancellationReasons {
+				reasons = append(reasons, aws.StringValue(reason.Code))
+			}
+
+			assert.Equal(t, tt.wantReasons, reasons)
+		})
+	}
+}
+
+// TestDynamoV2Client_Canceled test that the calls are canceled with their context
+func TestDynamoV2Client_Canceled(t *testing.T) {
+	server := httptest.NewServer(&fakeDynamoDB{status: http.StatusOK, response: `{}`})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newDynamoV2Client(t, server).GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("rules"),
+		Key:       map[string]*dynamodb.AttributeValue{"type": {S: aws.String("News")}},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
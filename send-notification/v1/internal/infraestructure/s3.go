@@ -0,0 +1,61 @@
+package infraestructure
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3API interface for the methods of the S3 API, every call is canceled with its context.
+type S3API interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+}
+
+// S3Provider interface for S3 client.
+type S3Provider interface {
+	S3Client() (S3API, error)
+}
+
+// S3Config struct with config for S3.
+type S3Config struct {
+	// Endpoint override of the S3 endpoint, empty to use the endpoint of the session
+	Endpoint string
+}
+
+// S3 attributes required for S3Provider.
+type S3 struct {
+	client  s3iface.S3API
+	session SessionProvider
+	config  *S3Config
+}
+
+// S3Client create a new client for S3, the buckets are addressed by path when the endpoint is overridden.
+func (s *S3) S3Client() (S3API, error) {
+	if s.client == nil {
+		s3Session, err := s.session.Session()
+		if err != nil {
+			return nil, err
+		}
+		s.client = s3.New(s3Session, s.config.awsConfig())
+	}
+
+	return s.client, nil
+}
+
+// awsConfig config of the client, the endpoint of the session unless it is overridden.
+func (c *S3Config) awsConfig() *aws.Config {
+	if c == nil || c.Endpoint == "" {
+		return &aws.Config{}
+	}
+
+	return &aws.Config{Endpoint: aws.String(c.Endpoint), S3ForcePathStyle: aws.Bool(true)}
+}
+
+// NewS3Provider instantiate new S3Provider.
+func NewS3Provider(session SessionProvider, config *S3Config) S3Provider {
+	return &S3{
+		session: session,
+		config:  config,
+	}
+}
@@ -0,0 +1,116 @@
+package infraestructure
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	s3v1 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3V2API methods of the aws-sdk-go-v2 S3 client used by S3V2Client.
+type s3V2API interface {
+	GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3V2Client S3API over the aws-sdk-go-v2 client, the inputs, outputs and errors are translated
+// from and into the aws-sdk-go shapes by the adapters of each call so the services do not change.
+type S3V2Client struct {
+	client s3V2API
+}
+
+// GetObjectWithContext get an object, the body must be closed by the caller
+func (c *S3V2Client) GetObjectWithContext(
+	ctx aws.Context, input *s3v1.GetObjectInput, _ ...request.Option,
+) (*s3v1.GetObjectOutput, error) {
+	return invokeV2(ctx, input, getObjectToV2, c.client.GetObject, getObjectFromV2)
+}
+
+// getObjectToV2 input of GetObject, the object of a version or a range of it with its conditions
+func getObjectToV2(input *s3v1.GetObjectInput) (*s3.GetObjectInput, error) {
+	err := unsupported("GetObject", map[string]bool{
+		"ChecksumMode":               input.ChecksumMode != nil,
+		"IfModifiedSince":            input.IfModifiedSince != nil,
+		"IfUnmodifiedSince":          input.IfUnmodifiedSince != nil,
+		"PartNumber":                 input.PartNumber != nil,
+		"RequestPayer":               input.RequestPayer != nil,
+		"ResponseCacheControl":       input.ResponseCacheControl != nil,
+		"ResponseContentDisposition": input.ResponseContentDisposition != nil,
+		"ResponseContentEncoding":    input.ResponseContentEncoding != nil,
+		"ResponseContentLanguage":    input.ResponseContentLanguage != nil,
+		"ResponseContentType":        input.ResponseContentType != nil,
+		"ResponseExpires":            input.ResponseExpires != nil,
+		"SSECustomerAlgorithm":       input.SSECustomerAlgorithm != nil,
+		"SSECustomerKey":             input.SSECustomerKey != nil,
+		"SSECustomerKeyMD5":          input.SSECustomerKeyMD5 != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.GetObjectInput{
+		Bucket:              input.Bucket,
+		Key:                 input.Key,
+		VersionId:           input.VersionId,
+		Range:               input.Range,
+		IfMatch:             input.IfMatch,
+		IfNoneMatch:         input.IfNoneMatch,
+		ExpectedBucketOwner: input.ExpectedBucketOwner,
+	}, nil
+}
+
+// getObjectFromV2 output of GetObject, the body with its content headers, the encryption, replication and
+// retention headers are not read by the services
+func getObjectFromV2(output *s3.GetObjectOutput) (*s3v1.GetObjectOutput, error) {
+	return &s3v1.GetObjectOutput{
+		Body:               output.Body,
+		ContentLength:      output.ContentLength,
+		ContentType:        output.ContentType,
+		ContentEncoding:    output.ContentEncoding,
+		ContentDisposition: output.ContentDisposition,
+		ContentLanguage:    output.ContentLanguage,
+		CacheControl:       output.CacheControl,
+		ETag:               output.ETag,
+		LastModified:       output.LastModified,
+		Metadata:           aws.StringMap(output.Metadata),
+		VersionId:          output.VersionId,
+	}, nil
+}
+
+// S3V2 attributes required for S3Provider over aws-sdk-go-v2.
+type S3V2 struct {
+	client *S3V2Client
+	config ConfigProvider
+	params *S3Config
+}
+
+// S3Client create a new aws-sdk-go-v2 client for S3, the buckets are addressed by path when the endpoint
+// is overridden.
+func (s *S3V2) S3Client() (S3API, error) {
+	if s.client == nil {
+		awsConfig, err := s.config.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		s.client = &S3V2Client{
+			client: s3.NewFromConfig(awsConfig, func(options *s3.Options) {
+				if s.params != nil && s.params.Endpoint != "" {
+					options.BaseEndpoint = aws.String(s.params.Endpoint)
+					options.UsePathStyle = true
+				}
+			}),
+		}
+	}
+
+	return s.client, nil
+}
+
+// NewS3V2Provider instantiate new S3Provider over aws-sdk-go-v2.
+func NewS3V2Provider(config ConfigProvider, params *S3Config) S3Provider {
+	return &S3V2{
+		config: config,
+		params: params,
+	}
+}
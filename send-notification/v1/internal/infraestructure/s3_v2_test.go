@@ -0,0 +1,113 @@
+package infraestructure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestS3V2Client_GetObjectWithContext test that the objects are read by path from the endpoint of the config
+func TestS3V2Client_GetObjectWithContext(t *testing.T) {
+	tests := []struct {
+		name            string
+		key             string
+		wantBody        string
+		wantContentType string
+		wantCode        string
+	}{
+		{
+			name:            "object",
+			key:             "invitations/meeting.ics",
+			wantBody:        "BEGIN:VCALENDAR",
+			wantContentType: "text/calendar",
+		},
+		{
+			name:     "missing object",
+			key:      "missing.pdf",
+			wantCode: s3.ErrCodeNoSuchKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+
+				if tt.wantCode != "" {
+					w.Header().Set("Content-Type", "application/xml")
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`))
+
+					return
+				}
+
+				w.Header().Set("Content-Type", tt.wantContentType)
+				_, _ = w.Write([]byte(tt.wantBody))
+			}))
+			defer server.Close()
+
+			fakeAWSEnv(t)
+
+			provider := NewS3V2Provider(NewConfigProvider(&SessionConfig{}), &S3Config{Endpoint: server.URL})
+
+			client, err := provider.S3Client()
+			require.NoError(t, err)
+
+			output, err := client.GetObjectWithContext(context.Background(), &s3.GetObjectInput{
+				Bucket: aws.String("reports"),
+				Key:    aws.String(tt.key),
+			})
+			assert.Equal(t, "/reports/"+tt.key, path)
+
+			if tt.wantCode != "" {
+				var awsErr awserr.Error
+				require.ErrorAs(t, err, &awsErr)
+				assert.Equal(t, tt.wantCode, awsErr.Code())
+
+				return
+			}
+
+			require.NoError(t, err)
+			defer output.Body.Close()
+
+			body, err := io.ReadAll(output.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(body))
+			assert.Equal(t, tt.wantContentType, aws.StringValue(output.ContentType))
+			assert.Equal(t, int64(len(tt.wantBody)), aws.Int64Value(output.ContentLength))
+		})
+	}
+}
+
+// TestS3V2Client_UnsupportedParameters test that the objects are not read with parameters that are not translated
+func TestS3V2Client_UnsupportedParameters(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	fakeAWSEnv(t)
+
+	client, err := NewS3V2Provider(NewConfigProvider(&SessionConfig{}), &S3Config{Endpoint: server.URL}).S3Client()
+	require.NoError(t, err)
+
+	_, err = client.GetObjectWithContext(context.Background(), &s3.GetObjectInput{
+		Bucket:         aws.String("reports"),
+		Key:            aws.String("report.pdf"),
+		SSECustomerKey: aws.String("key"),
+	})
+	assert.ErrorIs(t, err, ErrUnsupportedParameter)
+	assert.Equal(t, 0, requests)
+}
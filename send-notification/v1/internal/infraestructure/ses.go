@@ -1,13 +1,17 @@
 package infraestructure
 
 import (
-	"github.com/aws/aws-sdk-go/service/ses"
-	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/aws/aws-sdk-go/service/sesv2/sesv2iface"
 )
 
-// SESAPI interface for SES methods.
+// SESAPI interface for the methods of the SESv2 API, every call is canceled with its context.
 type SESAPI interface {
-	SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error)
+	SendEmailWithContext(
+		ctx aws.Context, input *sesv2.SendEmailInput, opts ...request.Option,
+	) (*sesv2.SendEmailOutput, error)
 }
 
 // SESProvider interface for SES client.
@@ -16,11 +20,14 @@ type SESProvider interface {
 }
 
 // SESConfig struct with config for SES.
-type SESConfig struct{}
+type SESConfig struct {
+	// Endpoint override of the SES endpoint, empty to use the endpoint of the session
+	Endpoint string
+}
 
 // SES attributes required for SESProvider.
 type SES struct {
-	client  sesiface.SESAPI
+	client  sesv2iface.SESV2API
 	session SessionProvider
 	config  *SESConfig
 }
@@ -32,12 +39,21 @@ func (s *SES) SESClient() (SESAPI, error) {
 		if err != nil {
 			return nil, err
 		}
-		s.client = ses.New(sesSession)
+		s.client = sesv2.New(sesSession, s.config.awsConfig())
 	}
 
 	return s.client, nil
 }
 
+// awsConfig config of the client, the endpoint of the session unless it is overridden.
+func (c *SESConfig) awsConfig() *aws.Config {
+	if c == nil || c.Endpoint == "" {
+		return &aws.Config{}
+	}
+
+	return &aws.Config{Endpoint: aws.String(c.Endpoint)}
+}
+
 // NewSESProvider instantiate new SESProvider.
 func NewSESProvider(session SessionProvider, config *SESConfig) SESProvider {
 	return &SES{
@@ -0,0 +1,120 @@
+package infraestructure
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	sesv2v1 "github.com/aws/aws-sdk-go/service/sesv2"
+)
+
+// sesV2API methods of the aws-sdk-go-v2 SESv2 client used by SESV2Client.
+type sesV2API interface {
+	SendEmail(
+		ctx context.Context, input *sesv2.SendEmailInput, optFns ...func(*sesv2.Options),
+	) (*sesv2.SendEmailOutput, error)
+}
+
+// SESV2Client SESAPI over the aws-sdk-go-v2 client, the inputs, outputs and errors are translated
+// from and into the aws-sdk-go shapes by the adapters of each call so the services do not change.
+type SESV2Client struct {
+	client sesV2API
+}
+
+// SendEmailWithContext send an email
+func (c *SESV2Client) SendEmailWithContext(
+	ctx aws.Context, input *sesv2v1.SendEmailInput, _ ...request.Option,
+) (*sesv2v1.SendEmailOutput, error) {
+	return invokeV2(ctx, input, sendEmailToV2, c.client.SendEmail, sendEmailFromV2)
+}
+
+// sendEmailToV2 input of SendEmail, only the raw messages built by the email service are sent
+func sendEmailToV2(input *sesv2v1.SendEmailInput) (*sesv2.SendEmailInput, error) {
+	converted := &sesv2.SendEmailInput{
+		ConfigurationSetName:                      input.ConfigurationSetName,
+		FromEmailAddress:                          input.FromEmailAddress,
+		FromEmailAddressIdentityArn:               input.FromEmailAddressIdentityArn,
+		FeedbackForwardingEmailAddress:            input.FeedbackForwardingEmailAddress,
+		FeedbackForwardingEmailAddressIdentityArn: input.FeedbackForwardingEmailAddressIdentityArn,
+		ReplyToAddresses:                          stringsToV2(input.ReplyToAddresses),
+	}
+
+	if input.Content != nil {
+		err := unsupported("SendEmail", map[string]bool{
+			"Content.Simple":   input.Content.Simple != nil,
+			"Content.Template": input.Content.Template != nil,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		converted.Content = &types.EmailContent{}
+
+		if input.Content.Raw != nil {
+			converted.Content.Raw = &types.RawMessage{Data: input.Content.Raw.Data}
+		}
+	}
+
+	if input.Destination != nil {
+		converted.Destination = &types.Destination{
+			ToAddresses:  stringsToV2(input.Destination.ToAddresses),
+			CcAddresses:  stringsToV2(input.Destination.CcAddresses),
+			BccAddresses: stringsToV2(input.Destination.BccAddresses),
+		}
+	}
+
+	for _, tag := range input.EmailTags {
+		converted.EmailTags = append(converted.EmailTags, types.MessageTag{Name: tag.Name, Value: tag.Value})
+	}
+
+	if input.ListManagementOptions != nil {
+		converted.ListManagementOptions = &types.ListManagementOptions{
+			ContactListName: input.ListManagementOptions.ContactListName,
+			TopicName:       input.ListManagementOptions.TopicName,
+		}
+	}
+
+	return converted, nil
+}
+
+// sendEmailFromV2 output of SendEmail
+func sendEmailFromV2(output *sesv2.SendEmailOutput) (*sesv2v1.SendEmailOutput, error) {
+	return &sesv2v1.SendEmailOutput{MessageId: output.MessageId}, nil
+}
+
+// SESV2 attributes required for SESProvider over aws-sdk-go-v2.
+type SESV2 struct {
+	client *SESV2Client
+	config ConfigProvider
+	params *SESConfig
+}
+
+// SESClient create a new aws-sdk-go-v2 client for SES.
+func (s *SESV2) SESClient() (SESAPI, error) {
+	if s.client == nil {
+		awsConfig, err := s.config.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		s.client = &SESV2Client{
+			client: sesv2.NewFromConfig(awsConfig, func(options *sesv2.Options) {
+				if s.params != nil && s.params.Endpoint != "" {
+					options.BaseEndpoint = aws.String(s.params.Endpoint)
+				}
+			}),
+		}
+	}
+
+	return s.client, nil
+}
+
+// NewSESV2Provider instantiate new SESProvider over aws-sdk-go-v2.
+func NewSESV2Provider(config ConfigProvider, params *SESConfig) SESProvider {
+	return &SESV2{
+		config: config,
+		params: params,
+	}
+}
@@ -0,0 +1,83 @@
+package infraestructure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSESV2Client_SendEmailWithContext test that the raw email is sent to the endpoint of the config
+func TestSESV2Client_SendEmailWithContext(t *testing.T) {
+	var path string
+
+	var request map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"MessageId":"0100018b-message"}`))
+	}))
+	defer server.Close()
+
+	fakeAWSEnv(t)
+
+	client, err := NewSESV2Provider(NewConfigProvider(&SessionConfig{Endpoint: server.URL}), &SESConfig{}).SESClient()
+	require.NoError(t, err)
+
+	output, err := client.SendEmailWithContext(context.Background(), &sesv2.SendEmailInput{
+		FromEmailAddress:     aws.String("sender@example.com"),
+		ConfigurationSetName: aws.String("notifications"),
+		Destination:          &sesv2.Destination{ToAddresses: aws.StringSlice([]string{"test@example.com"})},
+		Content:              &sesv2.EmailContent{Raw: &sesv2.RawMessage{Data: []byte("Subject: News\r\n\r\nHello")}},
+		EmailTags: []*sesv2.MessageTag{
+			{Name: aws.String("type"), Value: aws.String("News")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "0100018b-message", aws.StringValue(output.MessageId))
+	assert.Equal(t, "/v2/email/outbound-emails", path)
+	assert.Equal(t, map[string]interface{}{
+		"FromEmailAddress":     "sender@example.com",
+		"ConfigurationSetName": "notifications",
+		"Destination":          map[string]interface{}{"ToAddresses": []interface{}{"test@example.com"}},
+		"Content": map[string]interface{}{
+			"Raw": map[string]interface{}{"Data": "U3ViamVjdDogTmV3cw0KDQpIZWxsbw=="},
+		},
+		"EmailTags": []interface{}{map[string]interface{}{"Name": "type", "Value": "News"}},
+	}, request)
+}
+
+// TestSESV2Client_UnsupportedParameters test that only the raw messages are sent
+func TestSESV2Client_UnsupportedParameters(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	fakeAWSEnv(t)
+
+	client, err := NewSESV2Provider(NewConfigProvider(&SessionConfig{Endpoint: server.URL}), &SESConfig{}).SESClient()
+	require.NoError(t, err)
+
+	_, err = client.SendEmailWithContext(context.Background(), &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String("sender@example.com"),
+		Destination:      &sesv2.Destination{ToAddresses: aws.StringSlice([]string{"test@example.com"})},
+		Content: &sesv2.EmailContent{Simple: &sesv2.Message{
+			Subject: &sesv2.Content{Data: aws.String("News")},
+		}},
+	})
+	assert.EqualError(t, err, "SendEmail Content.Simple: parameter not supported over aws-sdk-go-v2")
+	assert.Equal(t, 0, requests)
+}
@@ -3,6 +3,7 @@ package infraestructure
 import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
@@ -14,8 +15,12 @@ type SessionProvider interface {
 // SessionConfig struct for Session configuration.
 type SessionConfig struct {
 	CredentialsFile string
-	Endpoint        string
-	Region          string `env:"AWS_REGION" envDefault:"us-east-1"`
+	// Endpoint override of the endpoint of every service, empty to resolve it from the region
+	Endpoint string
+	Region   string
+	// AccessKeyID and SecretAccessKey static credentials, empty to use the default credential chain
+	AccessKeyID     string
+	SecretAccessKey string
 }
 
 // Session attributes required for SessionProvider.
@@ -27,11 +32,17 @@ type Session struct {
 // Session method for create session client
 func (s *Session) Session() (client.ConfigProvider, error) {
 	if s.session == nil {
-		var err error
-		s.session, err = session.NewSession(&aws.Config{
+		awsConfig := &aws.Config{
 			Endpoint: &s.config.Endpoint,
 			Region:   &s.config.Region,
-		})
+		}
+
+		if s.config.AccessKeyID != "" {
+			awsConfig.Credentials = credentials.NewStaticCredentials(s.config.AccessKeyID, s.config.SecretAccessKey, "")
+		}
+
+		var err error
+		s.session, err = session.NewSession(awsConfig)
 
 		if err != nil {
 			return nil, err
@@ -0,0 +1,946 @@
+package infraestructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	dynamodbv2 "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+// ErrUnsupportedParameter the input of aws-sdk-go has a parameter that the adapter of the call does not translate
+// into aws-sdk-go-v2, the call is not made instead of ignoring it
+var ErrUnsupportedParameter = errors.New("parameter not supported over aws-sdk-go-v2")
+
+// invokeV2 call a method of a client of aws-sdk-go-v2 with the input of aws-sdk-go, the adapters of the call
+// translate the input and the output, and the errors are translated into the errors of aws-sdk-go
+func invokeV2[Input, InputV2, OutputV2, Output, Options any](
+	ctx context.Context,
+	input *Input,
+	toV2 func(*Input) (*InputV2, error),
+	call func(context.Context, *InputV2, ...func(*Options)) (*OutputV2, error),
+	fromV2 func(*OutputV2) (*Output, error),
+) (*Output, error) {
+	inputV2, err := toV2(input)
+	if err != nil {
+		return nil, err
+	}
+
+	outputV2, err := call(ctx, inputV2)
+	if err != nil {
+		return nil, errorV2(err)
+	}
+
+	return fromV2(outputV2)
+}
+
+// unsupported error of the first parameter in alphabetical order that is set
+func unsupported(operation string, parameters map[string]bool) error {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if parameters[name] {
+			return fmt.Errorf("%s %s: %w", operation, name, ErrUnsupportedParameter)
+		}
+	}
+
+	return nil
+}
+
+// getItemToV2 input of GetItem
+func getItemToV2(input *dynamodb.GetItemInput) (*dynamodbv2.GetItemInput, error) {
+	err := unsupported("GetItem", map[string]bool{
+		"AttributesToGet":        input.AttributesToGet != nil,
+		"ReturnConsumedCapacity": input.ReturnConsumedCapacity != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := attributesToV2(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.GetItemInput{
+		TableName:                input.TableName,
+		Key:                      key,
+		ConsistentRead:           input.ConsistentRead,
+		ProjectionExpression:     input.ProjectionExpression,
+		ExpressionAttributeNames: stringMapToV2(input.ExpressionAttributeNames),
+	}, nil
+}
+
+// getItemFromV2 output of GetItem
+func getItemFromV2(output *dynamodbv2.GetItemOutput) (*dynamodb.GetItemOutput, error) {
+	item, err := attributesFromV2(output.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+// batchGetItemToV2 input of BatchGetItem
+func batchGetItemToV2(input *dynamodb.BatchGetItemInput) (*dynamodbv2.BatchGetItemInput, error) {
+	err := unsupported("BatchGetItem", map[string]bool{"ReturnConsumedCapacity": input.ReturnConsumedCapacity != nil})
+	if err != nil {
+		return nil, err
+	}
+
+	requestItems, err := keysAndAttributesToV2(input.RequestItems)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.BatchGetItemInput{RequestItems: requestItems}, nil
+}
+
+// batchGetItemFromV2 output of BatchGetItem
+func batchGetItemFromV2(output *dynamodbv2.BatchGetItemOutput) (*dynamodb.BatchGetItemOutput, error) {
+	var responses map[string][]map[string]*dynamodb.AttributeValue
+
+	if output.Responses != nil {
+		responses = make(map[string][]map[string]*dynamodb.AttributeValue, len(output.Responses))
+
+		for table, items := range output.Responses {
+			converted, err := itemsFromV2(items)
+			if err != nil {
+				return nil, err
+			}
+
+			responses[table] = converted
+		}
+	}
+
+	unprocessed, err := keysAndAttributesFromV2(output.UnprocessedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.BatchGetItemOutput{Responses: responses, UnprocessedKeys: unprocessed}, nil
+}
+
+// putItemToV2 input of PutItem
+func putItemToV2(input *dynamodb.PutItemInput) (*dynamodbv2.PutItemInput, error) {
+	err := unsupported("PutItem", map[string]bool{
+		"ConditionalOperator":         input.ConditionalOperator != nil,
+		"Expected":                    input.Expected != nil,
+		"ReturnConsumedCapacity":      input.ReturnConsumedCapacity != nil,
+		"ReturnItemCollectionMetrics": input.ReturnItemCollectionMetrics != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := attributesToV2(input.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := attributesToV2(input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.PutItemInput{
+		TableName:                 input.TableName,
+		Item:                      item,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  stringMapToV2(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: values,
+		ReturnValues:              dynamodbtypes.ReturnValue(aws.StringValue(input.ReturnValues)),
+		ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+			aws.StringValue(input.ReturnValuesOnConditionCheckFailure),
+		),
+	}, nil
+}
+
+// putItemFromV2 output of PutItem
+func putItemFromV2(output *dynamodbv2.PutItemOutput) (*dynamodb.PutItemOutput, error) {
+	attributes, err := attributesFromV2(output.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.PutItemOutput{Attributes: attributes}, nil
+}
+
+// queryToV2 input of Query
+func queryToV2(input *dynamodb.QueryInput) (*dynamodbv2.QueryInput, error) {
+	err := unsupported("Query", map[string]bool{
+		"AttributesToGet":        input.AttributesToGet != nil,
+		"ConditionalOperator":    input.ConditionalOperator != nil,
+		"KeyConditions":          input.KeyConditions != nil,
+		"QueryFilter":            input.QueryFilter != nil,
+		"ReturnConsumedCapacity": input.ReturnConsumedCapacity != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := attributesToV2(input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey, err := attributesToV2(input.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.QueryInput{
+		TableName:                 input.TableName,
+		IndexName:                 input.IndexName,
+		KeyConditionExpression:    input.KeyConditionExpression,
+		FilterExpression:          input.FilterExpression,
+		ProjectionExpression:      input.ProjectionExpression,
+		ExpressionAttributeNames:  stringMapToV2(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: values,
+		ExclusiveStartKey:         startKey,
+		ConsistentRead:            input.ConsistentRead,
+		ScanIndexForward:          input.ScanIndexForward,
+		Limit:                     int32ToV2(input.Limit),
+		Select:                    dynamodbtypes.Select(aws.StringValue(input.Select)),
+	}, nil
+}
+
+// queryFromV2 output of Query
+func queryFromV2(output *dynamodbv2.QueryOutput) (*dynamodb.QueryOutput, error) {
+	items, err := itemsFromV2(output.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	lastKey, err := attributesFromV2(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.QueryOutput{
+		Items:            items,
+		Count:            aws.Int64(int64(output.Count)),
+		ScannedCount:     aws.Int64(int64(output.ScannedCount)),
+		LastEvaluatedKey: lastKey,
+	}, nil
+}
+
+// updateItemToV2 input of UpdateItem
+func updateItemToV2(input *dynamodb.UpdateItemInput) (*dynamodbv2.UpdateItemInput, error) {
+	err := unsupported("UpdateItem", map[string]bool{
+		"AttributeUpdates":            input.AttributeUpdates != nil,
+		"ConditionalOperator":         input.ConditionalOperator != nil,
+		"Expected":                    input.Expected != nil,
+		"ReturnConsumedCapacity":      input.ReturnConsumedCapacity != nil,
+		"ReturnItemCollectionMetrics": input.ReturnItemCollectionMetrics != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := attributesToV2(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := attributesToV2(input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.UpdateItemInput{
+		TableName:                 input.TableName,
+		Key:                       key,
+		UpdateExpression:          input.UpdateExpression,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  stringMapToV2(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: values,
+		ReturnValues:              dynamodbtypes.ReturnValue(aws.StringValue(input.ReturnValues)),
+		ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+			aws.StringValue(input.ReturnValuesOnConditionCheckFailure),
+		),
+	}, nil
+}
+
+// updateItemFromV2 output of UpdateItem
+func updateItemFromV2(output *dynamodbv2.UpdateItemOutput) (*dynamodb.UpdateItemOutput, error) {
+	attributes, err := attributesFromV2(output.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.UpdateItemOutput{Attributes: attributes}, nil
+}
+
+// deleteItemToV2 input of DeleteItem
+func deleteItemToV2(input *dynamodb.DeleteItemInput) (*dynamodbv2.DeleteItemInput, error) {
+	err := unsupported("DeleteItem", map[string]bool{
+		"ConditionalOperator":         input.ConditionalOperator != nil,
+		"Expected":                    input.Expected != nil,
+		"ReturnConsumedCapacity":      input.ReturnConsumedCapacity != nil,
+		"ReturnItemCollectionMetrics": input.ReturnItemCollectionMetrics != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := attributesToV2(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := attributesToV2(input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.DeleteItemInput{
+		TableName:                 input.TableName,
+		Key:                       key,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  stringMapToV2(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: values,
+		ReturnValues:              dynamodbtypes.ReturnValue(aws.StringValue(input.ReturnValues)),
+		ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+			aws.StringValue(input.ReturnValuesOnConditionCheckFailure),
+		),
+	}, nil
+}
+
+// deleteItemFromV2 output of DeleteItem
+func deleteItemFromV2(output *dynamodbv2.DeleteItemOutput) (*dynamodb.DeleteItemOutput, error) {
+	attributes, err := attributesFromV2(output.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DeleteItemOutput{Attributes: attributes}, nil
+}
+
+// scanToV2 input of Scan
+func scanToV2(input *dynamodb.ScanInput) (*dynamodbv2.ScanInput, error) {
+	err := unsupported("Scan", map[string]bool{
+		"AttributesToGet":        input.AttributesToGet != nil,
+		"ConditionalOperator":    input.ConditionalOperator != nil,
+		"ReturnConsumedCapacity": input.ReturnConsumedCapacity != nil,
+		"ScanFilter":             input.ScanFilter != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := attributesToV2(input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey, err := attributesToV2(input.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodbv2.ScanInput{
+		TableName:                 input.TableName,
+		IndexName:                 input.IndexName,
+		FilterExpression:          input.FilterExpression,
+		ProjectionExpression:      input.ProjectionExpression,
+		ExpressionAttributeNames:  stringMapToV2(input.ExpressionAttributeNames),
+		ExpressionAttributeValues: values,
+		ExclusiveStartKey:         startKey,
+		ConsistentRead:            input.ConsistentRead,
+		Limit:                     int32ToV2(input.Limit),
+		Segment:                   int32ToV2(input.Segment),
+		TotalSegments:             int32ToV2(input.TotalSegments),
+		Select:                    dynamodbtypes.Select(aws.StringValue(input.Select)),
+	}, nil
+}
+
+// scanFromV2 output of Scan
+func scanFromV2(output *dynamodbv2.ScanOutput) (*dynamodb.ScanOutput, error) {
+	items, err := itemsFromV2(output.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	lastKey, err := attributesFromV2(output.LastEvaluatedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ScanOutput{
+		Items:            items,
+		Count:            aws.Int64(int64(output.Count)),
+		ScannedCount:     aws.Int64(int64(output.ScannedCount)),
+		LastEvaluatedKey: lastKey,
+	}, nil
+}
+
+// transactWriteItemsToV2 input of TransactWriteItems
+func transactWriteItemsToV2(input *dynamodb.TransactWriteItemsInput) (*dynamodbv2.TransactWriteItemsInput, error) {
+	err := unsupported("TransactWriteItems", map[string]bool{
+		"ReturnConsumedCapacity":      input.ReturnConsumedCapacity != nil,
+		"ReturnItemCollectionMetrics": input.ReturnItemCollectionMetrics != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dynamodbtypes.TransactWriteItem, 0, len(input.TransactItems))
+
+	for _, item := range input.TransactItems {
+		converted, err := transactWriteItemToV2(item)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, converted)
+	}
+
+	return &dynamodbv2.TransactWriteItemsInput{
+		ClientRequestToken: input.ClientRequestToken,
+		TransactItems:      items,
+	}, nil
+}
+
+// transactWriteItemsFromV2 output of TransactWriteItems, it has nothing without consumed capacity nor metrics
+func transactWriteItemsFromV2(*dynamodbv2.TransactWriteItemsOutput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// transactWriteItemToV2 write of a transaction, with the same expressions as the writes of one item
+func transactWriteItemToV2(item *dynamodb.TransactWriteItem) (dynamodbtypes.TransactWriteItem, error) {
+	var converted dynamodbtypes.TransactWriteItem
+
+	switch {
+	case item.Put != nil:
+		attributes, err := attributesToV2(item.Put.Item)
+		if err != nil {
+			return converted, err
+		}
+
+		values, err := attributesToV2(item.Put.ExpressionAttributeValues)
+		if err != nil {
+			return converted, err
+		}
+
+		converted.Put = &dynamodbtypes.Put{
+			TableName:                 item.Put.TableName,
+			Item:                      attributes,
+			ConditionExpression:       item.Put.ConditionExpression,
+			ExpressionAttributeNames:  stringMapToV2(item.Put.ExpressionAttributeNames),
+			ExpressionAttributeValues: values,
+			ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+				aws.StringValue(item.Put.ReturnValuesOnConditionCheckFailure),
+			),
+		}
+	case item.Delete != nil:
+		key, err := attributesToV2(item.Delete.Key)
+		if err != nil {
+			return converted, err
+		}
+
+		values, err := attributesToV2(item.Delete.ExpressionAttributeValues)
+		if err != nil {
+			return converted, err
+		}
+
+		converted.Delete = &dynamodbtypes.Delete{
+			TableName:                 item.Delete.TableName,
+			Key:                       key,
+			ConditionExpression:       item.Delete.ConditionExpression,
+			ExpressionAttributeNames:  stringMapToV2(item.Delete.ExpressionAttributeNames),
+			ExpressionAttributeValues: values,
+			ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+				aws.StringValue(item.Delete.ReturnValuesOnConditionCheckFailure),
+			),
+		}
+	case item.Update != nil:
+		key, err := attributesToV2(item.Update.Key)
+		if err != nil {
+			return converted, err
+		}
+
+		values, err := attributesToV2(item.Update.ExpressionAttributeValues)
+		if err != nil {
+			return converted, err
+		}
+
+		converted.Update = &dynamodbtypes.Update{
+			TableName:                 item.Update.TableName,
+			Key:                       key,
+			UpdateExpression:          item.Update.UpdateExpression,
+			ConditionExpression:       item.Update.ConditionExpression,
+			ExpressionAttributeNames:  stringMapToV2(item.Update.ExpressionAttributeNames),
+			ExpressionAttributeValues: values,
+			ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+				aws.StringValue(item.Update.ReturnValuesOnConditionCheckFailure),
+			),
+		}
+	case item.ConditionCheck != nil:
+		key, err := attributesToV2(item.ConditionCheck.Key)
+		if err != nil {
+			return converted, err
+		}
+
+		values, err := attributesToV2(item.ConditionCheck.ExpressionAttributeValues)
+		if err != nil {
+			return converted, err
+		}
+
+		converted.ConditionCheck = &dynamodbtypes.ConditionCheck{
+			TableName:                 item.ConditionCheck.TableName,
+			Key:                       key,
+			ConditionExpression:       item.ConditionCheck.ConditionExpression,
+			ExpressionAttributeNames:  stringMapToV2(item.ConditionCheck.ExpressionAttributeNames),
+			ExpressionAttributeValues: values,
+			ReturnValuesOnConditionCheckFailure: dynamodbtypes.ReturnValuesOnConditionCheckFailure(
+				aws.StringValue(item.ConditionCheck.ReturnValuesOnConditionCheckFailure),
+			),
+		}
+	}
+
+	return converted, nil
+}
+
+// describeTableToV2 input of DescribeTable
+func describeTableToV2(input *dynamodb.DescribeTableInput) (*dynamodbv2.DescribeTableInput, error) {
+	return &dynamodbv2.DescribeTableInput{TableName: input.TableName}, nil
+}
+
+// describeTableFromV2 output of DescribeTable
+func describeTableFromV2(output *dynamodbv2.DescribeTableOutput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: tableDescriptionFromV2(output.Table)}, nil
+}
+
+// createTableToV2 input of CreateTable, only the tables of the local profile with their keys and billing mode
+func createTableToV2(input *dynamodb.CreateTableInput) (*dynamodbv2.CreateTableInput, error) {
+	err := unsupported("CreateTable", map[string]bool{
+		"DeletionProtectionEnabled": input.DeletionProtectionEnabled != nil,
+		"GlobalSecondaryIndexes":    input.GlobalSecondaryIndexes != nil,
+		"LocalSecondaryIndexes":     input.LocalSecondaryIndexes != nil,
+		"SSESpecification":          input.SSESpecification != nil,
+		"StreamSpecification":       input.StreamSpecification != nil,
+		"TableClass":                input.TableClass != nil,
+		"Tags":                      input.Tags != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	converted := &dynamodbv2.CreateTableInput{
+		TableName:            input.TableName,
+		AttributeDefinitions: make([]dynamodbtypes.AttributeDefinition, 0, len(input.AttributeDefinitions)),
+		KeySchema:            keySchemaToV2(input.KeySchema),
+		BillingMode:          dynamodbtypes.BillingMode(aws.StringValue(input.BillingMode)),
+	}
+
+	for _, definition := range input.AttributeDefinitions {
+		converted.AttributeDefinitions = append(converted.AttributeDefinitions, dynamodbtypes.AttributeDefinition{
+			AttributeName: definition.AttributeName,
+			AttributeType: dynamodbtypes.ScalarAttributeType(aws.StringValue(definition.AttributeType)),
+		})
+	}
+
+	if input.ProvisionedThroughput != nil {
+		converted.ProvisionedThroughput = &dynamodbtypes.ProvisionedThroughput{
+			ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	return converted, nil
+}
+
+// createTableFromV2 output of CreateTable
+func createTableFromV2(output *dynamodbv2.CreateTableOutput) (*dynamodb.CreateTableOutput, error) {
+	return &dynamodb.CreateTableOutput{TableDescription: tableDescriptionFromV2(output.TableDescription)}, nil
+}
+
+// updateTimeToLiveToV2 input of UpdateTimeToLive
+func updateTimeToLiveToV2(input *dynamodb.UpdateTimeToLiveInput) (*dynamodbv2.UpdateTimeToLiveInput, error) {
+	converted := &dynamodbv2.UpdateTimeToLiveInput{TableName: input.TableName}
+
+	if input.TimeToLiveSpecification != nil {
+		converted.TimeToLiveSpecification = &dynamodbtypes.TimeToLiveSpecification{
+			AttributeName: input.TimeToLiveSpecification.AttributeName,
+			Enabled:       input.TimeToLiveSpecification.Enabled,
+		}
+	}
+
+	return converted, nil
+}
+
+// updateTimeToLiveFromV2 output of UpdateTimeToLive
+func updateTimeToLiveFromV2(output *dynamodbv2.UpdateTimeToLiveOutput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	converted := &dynamodb.UpdateTimeToLiveOutput{}
+
+	if output.TimeToLiveSpecification != nil {
+		converted.TimeToLiveSpecification = &dynamodb.TimeToLiveSpecification{
+			AttributeName: output.TimeToLiveSpecification.AttributeName,
+			Enabled:       output.TimeToLiveSpecification.Enabled,
+		}
+	}
+
+	return converted, nil
+}
+
+// tableDescriptionFromV2 name, status, keys and size of a table, the indexes, streams and the other settings
+// are not read by the local profile
+func tableDescriptionFromV2(table *dynamodbtypes.TableDescription) *dynamodb.TableDescription {
+	if table == nil {
+		return nil
+	}
+
+	converted := &dynamodb.TableDescription{
+		TableName:        table.TableName,
+		TableArn:         table.TableArn,
+		TableId:          table.TableId,
+		TableStatus:      optionalString(string(table.TableStatus)),
+		CreationDateTime: table.CreationDateTime,
+		ItemCount:        table.ItemCount,
+		TableSizeBytes:   table.TableSizeBytes,
+	}
+
+	for _, definition := range table.AttributeDefinitions {
+		converted.AttributeDefinitions = append(converted.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: definition.AttributeName,
+			AttributeType: optionalString(string(definition.AttributeType)),
+		})
+	}
+
+	for _, element := range table.KeySchema {
+		converted.KeySchema = append(converted.KeySchema, &dynamodb.KeySchemaElement{
+			AttributeName: element.AttributeName,
+			KeyType:       optionalString(string(element.KeyType)),
+		})
+	}
+
+	if table.BillingModeSummary != nil {
+		converted.BillingModeSummary = &dynamodb.BillingModeSummary{
+			BillingMode:                       optionalString(string(table.BillingModeSummary.BillingMode)),
+			LastUpdateToPayPerRequestDateTime: table.BillingModeSummary.LastUpdateToPayPerRequestDateTime,
+		}
+	}
+
+	return converted
+}
+
+// keySchemaToV2 key schema of a table
+func keySchemaToV2(schema []*dynamodb.KeySchemaElement) []dynamodbtypes.KeySchemaElement {
+	converted := make([]dynamodbtypes.KeySchemaElement, 0, len(schema))
+
+	for _, element := range schema {
+		converted = append(converted, dynamodbtypes.KeySchemaElement{
+			AttributeName: element.AttributeName,
+			KeyType:       dynamodbtypes.KeyType(aws.StringValue(element.KeyType)),
+		})
+	}
+
+	return converted
+}
+
+// keysAndAttributesToV2 keys to read of each table of a BatchGetItem
+func keysAndAttributesToV2(
+	requestItems map[string]*dynamodb.KeysAndAttributes,
+) (map[string]dynamodbtypes.KeysAndAttributes, error) {
+	if requestItems == nil {
+		return nil, nil
+	}
+
+	converted := make(map[string]dynamodbtypes.KeysAndAttributes, len(requestItems))
+
+	for table, request := range requestItems {
+		err := unsupported("BatchGetItem", map[string]bool{"AttributesToGet": request.AttributesToGet != nil})
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]map[string]dynamodbtypes.AttributeValue, 0, len(request.Keys))
+
+		for _, key := range request.Keys {
+			convertedKey, err := attributesToV2(key)
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, convertedKey)
+		}
+
+		converted[table] = dynamodbtypes.KeysAndAttributes{
+			Keys:                     keys,
+			ConsistentRead:           request.ConsistentRead,
+			ProjectionExpression:     request.ProjectionExpression,
+			ExpressionAttributeNames: stringMapToV2(request.ExpressionAttributeNames),
+		}
+	}
+
+	return converted, nil
+}
+
+// keysAndAttributesFromV2 unprocessed keys of each table of a BatchGetItem
+func keysAndAttributesFromV2(
+	unprocessed map[string]dynamodbtypes.KeysAndAttributes,
+) (map[string]*dynamodb.KeysAndAttributes, error) {
+	if len(unprocessed) == 0 {
+		return nil, nil
+	}
+
+	converted := make(map[string]*dynamodb.KeysAndAttributes, len(unprocessed))
+
+	for table, request := range unprocessed {
+		keys, err := itemsFromV2(request.Keys)
+		if err != nil {
+			return nil, err
+		}
+
+		converted[table] = &dynamodb.KeysAndAttributes{
+			Keys:                 keys,
+			ConsistentRead:       request.ConsistentRead,
+			ProjectionExpression: request.ProjectionExpression,
+		}
+
+		if request.ExpressionAttributeNames != nil {
+			converted[table].ExpressionAttributeNames = aws.StringMap(request.ExpressionAttributeNames)
+		}
+	}
+
+	return converted, nil
+}
+
+// itemsFromV2 items or keys of aws-sdk-go
+func itemsFromV2(items []map[string]dynamodbtypes.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	if items == nil {
+		return nil, nil
+	}
+
+	converted := make([]map[string]*dynamodb.AttributeValue, 0, len(items))
+
+	for _, item := range items {
+		attributes, err := attributesFromV2(item)
+		if err != nil {
+			return nil, err
+		}
+
+		converted = append(converted, attributes)
+	}
+
+	return converted, nil
+}
+
+// attributesToV2 attributes of an item, a key or the values of the expressions, nil when there are none
+func attributesToV2(attributes map[string]*dynamodb.AttributeValue) (map[string]dynamodbtypes.AttributeValue, error) {
+	if attributes == nil {
+		return nil, nil
+	}
+
+	converted := make(map[string]dynamodbtypes.AttributeValue, len(attributes))
+
+	for name, value := range attributes {
+		if value == nil {
+			continue
+		}
+
+		convertedValue, err := attributeValueToV2(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if convertedValue != nil {
+			converted[name] = convertedValue
+		}
+	}
+
+	return converted, nil
+}
+
+// attributesFromV2 attributes of aws-sdk-go, nil when there are none
+func attributesFromV2(attributes map[string]dynamodbtypes.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if attributes == nil {
+		return nil, nil
+	}
+
+	converted := make(map[string]*dynamodb.AttributeValue, len(attributes))
+
+	for name, value := range attributes {
+		convertedValue, err := attributeValueFromV2(value)
+		if err != nil {
+			return nil, err
+		}
+
+		converted[name] = convertedValue
+	}
+
+	return converted, nil
+}
+
+// attributeValueToV2 attribute value of aws-sdk-go-v2, nil for an empty attribute value
+func attributeValueToV2(value *dynamodb.AttributeValue) (dynamodbtypes.AttributeValue, error) {
+	switch {
+	case value.S != nil:
+		return &dynamodbtypes.AttributeValueMemberS{Value: *value.S}, nil
+	case value.N != nil:
+		return &dynamodbtypes.AttributeValueMemberN{Value: *value.N}, nil
+	case value.B != nil:
+		return &dynamodbtypes.AttributeValueMemberB{Value: value.B}, nil
+	case value.BOOL != nil:
+		return &dynamodbtypes.AttributeValueMemberBOOL{Value: *value.BOOL}, nil
+	case value.NULL != nil:
+		return &dynamodbtypes.AttributeValueMemberNULL{Value: *value.NULL}, nil
+	case value.SS != nil:
+		return &dynamodbtypes.AttributeValueMemberSS{Value: aws.StringValueSlice(value.SS)}, nil
+	case value.NS != nil:
+		return &dynamodbtypes.AttributeValueMemberNS{Value: aws.StringValueSlice(value.NS)}, nil
+	case value.BS != nil:
+		return &dynamodbtypes.AttributeValueMemberBS{Value: value.BS}, nil
+	case value.L != nil:
+		list := make([]dynamodbtypes.AttributeValue, 0, len(value.L))
+
+		for _, item := range value.L {
+			converted, err := attributeValueToV2(item)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, converted)
+		}
+
+		return &dynamodbtypes.AttributeValueMemberL{Value: list}, nil
+	case value.M != nil:
+		attributes, err := attributesToV2(value.M)
+		if err != nil {
+			return nil, err
+		}
+
+		return &dynamodbtypes.AttributeValueMemberM{Value: attributes}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// attributeValueFromV2 attribute value of aws-sdk-go
+func attributeValueFromV2(value dynamodbtypes.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch v := value.(type) {
+	case *dynamodbtypes.AttributeValueMemberS:
+		return &dynamodb.AttributeValue{S: aws.String(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberN:
+		return &dynamodb.AttributeValue{N: aws.String(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberB:
+		return &dynamodb.AttributeValue{B: v.Value}, nil
+	case *dynamodbtypes.AttributeValueMemberBOOL:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberNULL:
+		return &dynamodb.AttributeValue{NULL: aws.Bool(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberSS:
+		return &dynamodb.AttributeValue{SS: aws.StringSlice(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberNS:
+		return &dynamodb.AttributeValue{NS: aws.StringSlice(v.Value)}, nil
+	case *dynamodbtypes.AttributeValueMemberBS:
+		return &dynamodb.AttributeValue{BS: v.Value}, nil
+	case *dynamodbtypes.AttributeValueMemberL:
+		list := make([]*dynamodb.AttributeValue, 0, len(v.Value))
+
+		for _, item := range v.Value {
+			converted, err := attributeValueFromV2(item)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, converted)
+		}
+
+		return &dynamodb.AttributeValue{L: list}, nil
+	case *dynamodbtypes.AttributeValueMemberM:
+		attributes, err := attributesFromV2(v.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if attributes == nil {
+			attributes = map[string]*dynamodb.AttributeValue{}
+		}
+
+		return &dynamodb.AttributeValue{M: attributes}, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute value %T", value)
+	}
+}
+
+// stringsToV2 strings of aws-sdk-go-v2, nil when there are none so they are not sent
+func stringsToV2(values []*string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return aws.StringValueSlice(values)
+}
+
+// stringMapToV2 names of the expressions of aws-sdk-go-v2, nil when there are none so they are not sent
+func stringMapToV2(values map[string]*string) map[string]string {
+	if values == nil {
+		return nil
+	}
+
+	return aws.StringValueMap(values)
+}
+
+// int32ToV2 number of aws-sdk-go-v2, the numbers of the inputs are int32 in aws-sdk-go-v2
+func int32ToV2(value *int64) *int32 {
+	if value == nil {
+		return nil
+	}
+
+	converted := int32(*value)
+
+	return &converted
+}
+
+// optionalString string of aws-sdk-go, nil for the empty enums of aws-sdk-go-v2
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return aws.String(value)
+}
+
+// errorV2 error of aws-sdk-go for an error of aws-sdk-go-v2 so the callers keep checking the error codes,
+// the canceled transactions keep their cancellation reasons
+func errorV2(err error) error {
+	var canceled *dynamodbtypes.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		converted := &dynamodb.TransactionCanceledException{Message_: canceled.Message}
+
+		for _, reason := range canceled.CancellationReasons {
+			item, convertErr := attributesFromV2(reason.Item)
+			if convertErr != nil {
+				return err
+			}
+
+			converted.CancellationReasons = append(converted.CancellationReasons, &dynamodb.CancellationReason{
+				Code:    reason.Code,
+				Message: reason.Message,
+				Item:    item,
+			})
+		}
+
+		return converted
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return awserr.New(apiErr.ErrorCode(), apiErr.ErrorMessage(), err)
+	}
+
+	return err
+}
@@ -0,0 +1,72 @@
+package infraestructure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+)
+
+// WriterSES SES client for offline use, the raw messages are written instead of sent.
+type WriterSES struct {
+	mu     sync.Mutex
+	writer io.Writer
+	sent   int
+}
+
+// SendEmailWithContext write the raw message with its destinations and tags, the message ID is local to this client.
+func (s *WriterSES) SendEmailWithContext(
+	ctx aws.Context,
+	input *sesv2.SendEmailInput,
+	_ ...request.Option,
+) (*sesv2.SendEmailOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent++
+	messageID := fmt.Sprintf("local-%d", s.sent)
+
+	tags := make([]string, 0, len(input.EmailTags))
+	for _, tag := range input.EmailTags {
+		tags = append(tags, aws.StringValue(tag.Name)+"="+aws.StringValue(tag.Value))
+	}
+
+	_, err := fmt.Fprintf(
+		s.writer,
+		"----- %s to %s [%s] -----\n%s\n",
+		messageID,
+		strings.Join(destinations(input.Destination), ", "),
+		strings.Join(tags, " "),
+		input.Content.Raw.Data,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sesv2.SendEmailOutput{MessageId: aws.String(messageID)}, nil
+}
+
+// destinations every address of the email, the bcc addresses are not in the raw message
+func destinations(destination *sesv2.Destination) []string {
+	if destination == nil {
+		return nil
+	}
+
+	addresses := aws.StringValueSlice(destination.ToAddresses)
+	addresses = append(addresses, aws.StringValueSlice(destination.CcAddresses)...)
+
+	return append(addresses, aws.StringValueSlice(destination.BccAddresses)...)
+}
+
+// NewWriterSES instantiate new WriterSES.
+func NewWriterSES(writer io.Writer) SESAPI {
+	return &WriterSES{writer: writer}
+}
@@ -0,0 +1,195 @@
+// Package local prepares the local profile: the tables of DynamoDB Local, the seed rules and API key and the HTTP
+// server that stands in for API Gateway
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/config"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"sigs.k8s.io/yaml"
+)
+
+// RulesCreatorInterface creates the seed rules, ManageRulesUC outside of the tests
+type RulesCreatorInterface interface {
+	Create(ctx context.Context, rule internal.RateLimitRule) (*internal.RateLimitRule, error)
+}
+
+// APIKeyStoreInterface keeps the seed API key, APIKeyRepository outside of the tests
+type APIKeyStoreInterface interface {
+	GetByHash(ctx context.Context, hash string) (*internal.APIKey, error)
+	Save(ctx context.Context, apiKey internal.APIKey) error
+}
+
+// table schema of one table, the attributes of its key and the attribute that expires its items
+type table struct {
+	name         string
+	partitionKey string
+	sortKey      string
+	sortKeyType  string
+	ttlAttribute string
+}
+
+// tables schemas of the tables of the repositories
+func tables(names config.Tables) []table {
+	return []table{
+		{name: names.RateLimitRules, partitionKey: "pk"},
+		{
+			name:         names.RateLimitRulesHistory,
+			partitionKey: "pk",
+			sortKey:      "version",
+			sortKeyType:  dynamodb.ScalarAttributeTypeN,
+		},
+		{
+			name:         names.RateLimitCache,
+			partitionKey: "pk",
+			sortKey:      "sk",
+			sortKeyType:  dynamodb.ScalarAttributeTypeS,
+			ttlAttribute: "ttl",
+		},
+		{name: names.RecipientProfiles, partitionKey: "pk"},
+		{name: names.RecipientPreferences, partitionKey: "pk"},
+		{name: names.SuppressionList, partitionKey: "pk"},
+		{name: names.APIKeys, partitionKey: "pk"},
+	}
+}
+
+// CreateTables create the tables that do not exist and enable the expiration of their items, the names of the
+// created tables are returned
+func CreateTables(
+	ctx context.Context,
+	client infraestructure.DynamoTablesAPI,
+	names config.Tables,
+) ([]string, error) {
+	var created []string
+
+	for _, table := range tables(names) {
+		_, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table.name)})
+		if err == nil {
+			continue
+		}
+
+		var awsErr awserr.Error
+		if !errors.As(err, &awsErr) || awsErr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+			return created, fmt.Errorf("describe table %s: %w", table.name, err)
+		}
+
+		if _, err := client.CreateTableWithContext(ctx, table.createInput()); err != nil {
+			return created, fmt.Errorf("create table %s: %w", table.name, err)
+		}
+
+		if table.ttlAttribute != "" {
+			_, err := client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+				TableName: aws.String(table.name),
+				TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+					AttributeName: aws.String(table.ttlAttribute),
+					Enabled:       aws.Bool(true),
+				},
+			})
+			if err != nil {
+				return created, fmt.Errorf("enable the time to live of table %s: %w", table.name, err)
+			}
+		}
+
+		created = append(created, table.name)
+	}
+
+	return created, nil
+}
+
+// createInput input that creates the table with on-demand capacity
+func (t table) createInput() *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(t.name),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(t.partitionKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(t.partitionKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}
+
+	if t.sortKey != "" {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(t.sortKey),
+			AttributeType: aws.String(t.sortKeyType),
+		})
+		input.KeySchema = append(input.KeySchema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(t.sortKey),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+
+	return input
+}
+
+// SeedRules create the rules of a YAML file with the format of ratelimitctl rules export, the types that already
+// have a rule keep it. The types of the created rules are returned
+func SeedRules(ctx context.Context, rules RulesCreatorInterface, path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file internal.RulesResponseBody
+
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	var created []string
+
+	for _, rule := range file.Rules {
+		_, err := rules.Create(ctx, rule)
+
+		var generalError *internal.GeneralError
+		if errors.As(err, &generalError) && generalError.ID == internal.IDRuleAlreadyExists {
+			continue
+		}
+
+		if err != nil {
+			return created, fmt.Errorf("seed rule of type '%s': %w", rule.Type, err)
+		}
+
+		created = append(created, rule.Type)
+	}
+
+	return created, nil
+}
+
+// SeedAPIKey save the given key as the API key of a local client that can send every type, nothing changes when
+// the key already exists. It returns whether the key was created
+func SeedAPIKey(ctx context.Context, apiKeys APIKeyStoreInterface, key string) (bool, error) {
+	hash := internal.HashAPIKey(key)
+
+	existing, err := apiKeys.GetByHash(ctx, hash)
+	if err != nil {
+		return false, fmt.Errorf("seed API key: %w", err)
+	}
+
+	if existing != nil {
+		return false, nil
+	}
+
+	err = apiKeys.Save(ctx, internal.APIKey{
+		Hash:         hash,
+		ID:           "local",
+		AllowedTypes: []string{internal.AnyType},
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("seed API key: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,272 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/config"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+// testTables names of the tables of the tests
+var testTables = config.Tables{
+	RateLimitRules:        "rules",
+	RateLimitRulesHistory: "rules-history",
+	RateLimitCache:        "cache",
+	RecipientProfiles:     "profiles",
+	RecipientPreferences:  "preferences",
+	SuppressionList:       "suppressions",
+	APIKeys:               "api-keys",
+}
+
+// mockDynamoTablesAPI mock for the DynamoDB methods that manage the tables
+type mockDynamoTablesAPI struct {
+	describeTableFunc    func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+	createTableFunc      func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)
+	updateTimeToLiveFunc func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// DescribeTableWithContext mock for this method
+func (m *mockDynamoTablesAPI) DescribeTableWithContext(
+	_ aws.Context,
+	input *dynamodb.DescribeTableInput,
+	_ ...request.Option,
+) (*dynamodb.DescribeTableOutput, error) {
+	return m.describeTableFunc(input)
+}
+
+// CreateTableWithContext mock for this method
+func (m *mockDynamoTablesAPI) CreateTableWithContext(
+	_ aws.Context,
+	input *dynamodb.CreateTableInput,
+	_ ...request.Option,
+) (*dynamodb.CreateTableOutput, error) {
+	return m.createTableFunc(input)
+}
+
+// UpdateTimeToLiveWithContext mock for this method
+func (m *mockDynamoTablesAPI) UpdateTimeToLiveWithContext(
+	_ aws.Context,
+	input *dynamodb.UpdateTimeToLiveInput,
+	_ ...request.Option,
+) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return m.updateTimeToLiveFunc(input)
+}
+
+// notFound error of DynamoDB for a table that does not exist
+var notFound = awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Cannot do operations on a non-existent table", nil)
+
+// TestCreateTables test for this function
+func TestCreateTables(t *testing.T) {
+	created := map[string]*dynamodb.CreateTableInput{}
+	var timeToLive []*dynamodb.UpdateTimeToLiveInput
+
+	client := &mockDynamoTablesAPI{
+		describeTableFunc: func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+			if *input.TableName == "rules" {
+				return &dynamodb.DescribeTableOutput{}, nil
+			}
+
+			return nil, notFound
+		},
+		createTableFunc: func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+			created[*input.TableName] = input
+
+			return &dynamodb.CreateTableOutput{}, nil
+		},
+		updateTimeToLiveFunc: func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+			timeToLive = append(timeToLive, input)
+
+			return &dynamodb.UpdateTimeToLiveOutput{}, nil
+		},
+	}
+
+	names, err := CreateTables(context.Background(), client, testTables)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rules-history", "cache", "profiles", "preferences", "suppressions", "api-keys"}, names)
+	assert.NotContains(t, created, "rules")
+
+	assert.Equal(t, []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String("pk"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		{AttributeName: aws.String("sk"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+	}, created["cache"].KeySchema)
+	assert.Equal(t, []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("pk"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		{AttributeName: aws.String("version"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+	}, created["rules-history"].AttributeDefinitions)
+	assert.Len(t, created["suppressions"].KeySchema, 1)
+	assert.Equal(t, dynamodb.BillingModePayPerRequest, *created["profiles"].BillingMode)
+
+	assert.Equal(t, []*dynamodb.UpdateTimeToLiveInput{{
+		TableName: aws.String("cache"),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String("ttl"),
+			Enabled:       aws.Bool(true),
+		},
+	}}, timeToLive)
+}
+
+// TestCreateTables_Errors test that the errors stop the creation of the tables
+func TestCreateTables_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		describeErr error
+		createErr   error
+		wantErr     string
+	}{
+		{
+			name:        "DynamoDB is not available",
+			describeErr: assert.AnError,
+			wantErr:     "describe table rules",
+		},
+		{
+			name:        "table not created",
+			describeErr: notFound,
+			createErr:   assert.AnError,
+			wantErr:     "create table rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockDynamoTablesAPI{
+				describeTableFunc: func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+					return nil, tt.describeErr
+				},
+				createTableFunc: func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+					return nil, tt.createErr
+				},
+			}
+
+			created, err := CreateTables(context.Background(), client, testTables)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, created)
+		})
+	}
+}
+
+// mockRulesCreator mock for the creation of the rules
+type mockRulesCreator struct {
+	createFunc func(rule internal.RateLimitRule) (*internal.RateLimitRule, error)
+}
+
+// Create mock for this method
+func (m *mockRulesCreator) Create(_ context.Context, rule internal.RateLimitRule) (*internal.RateLimitRule, error) {
+	return m.createFunc(rule)
+}
+
+// TestSeedRules test for this function
+func TestSeedRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		createErr   map[string]error
+		wantCreated []string
+		wantErr     bool
+	}{
+		{
+			name:        "every rule created",
+			file:        "rules:\n- type: Status\n  notifications_limit: 2\n  interval: 1m\n- type: News\n  notifications_limit: 1\n",
+			wantCreated: []string{"Status", "News"},
+		},
+		{
+			name: "types that already have a rule keep it",
+			file: "rules:\n- type: Status\n  notifications_limit: 2\n  interval: 1m\n- type: News\n  notifications_limit: 1\n",
+			createErr: map[string]error{
+				"Status": &internal.GeneralError{ID: internal.IDRuleAlreadyExists, StatusCode: http.StatusConflict},
+			},
+			wantCreated: []string{"News"},
+		},
+		{
+			name: "invalid rule",
+			file: "rules:\n- type: Status\n  notifications_limit: 0\n",
+			createErr: map[string]error{
+				"Status": &internal.GeneralError{ID: internal.IDRuleInvalid, StatusCode: http.StatusBadRequest},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown attribute",
+			file:    "rules:\n- type: Status\n  limit: 2\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.file), 0o600))
+
+			created, err := SeedRules(context.Background(), &mockRulesCreator{
+				createFunc: func(rule internal.RateLimitRule) (*internal.RateLimitRule, error) {
+					return &rule, tt.createErr[rule.Type]
+				},
+			}, path)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCreated, created)
+		})
+	}
+}
+
+// mockAPIKeyStore mock for the repository of the API keys
+type mockAPIKeyStore struct {
+	apiKeys map[string]internal.APIKey
+	saveErr error
+}
+
+// GetByHash mock for this method
+func (m *mockAPIKeyStore) GetByHash(_ context.Context, hash string) (*internal.APIKey, error) {
+	if apiKey, ok := m.apiKeys[hash]; ok {
+		return &apiKey, nil
+	}
+
+	return nil, nil
+}
+
+// Save mock for this method
+func (m *mockAPIKeyStore) Save(_ context.Context, apiKey internal.APIKey) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+
+	m.apiKeys[apiKey.Hash] = apiKey
+
+	return nil
+}
+
+// TestSeedAPIKey test for this function
+func TestSeedAPIKey(t *testing.T) {
+	store := &mockAPIKeyStore{apiKeys: map[string]internal.APIKey{}}
+
+	created, err := SeedAPIKey(context.Background(), store, "local-api-key")
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	apiKey := store.apiKeys[internal.HashAPIKey("local-api-key")]
+	assert.Equal(t, "local", apiKey.ID)
+	assert.True(t, apiKey.Client().Allows("News"))
+
+	created, err = SeedAPIKey(context.Background(), store, "local-api-key")
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	store.saveErr = errors.New("database error")
+	_, err = SeedAPIKey(context.Background(), store, "other-api-key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Package local prepares the local profile: the tables of DynamoDB Local, the seed rules and the HTTP server
+// that stands in for API Gateway
+package local
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// requestTimeout time of a request of the local server, the integration timeout of API Gateway
+const requestTimeout = 29 * time.Second
+
+// Server HTTP server that turns every request into the API Gateway event of the route, like API Gateway
+// does with the lambda function
+type Server struct {
+	route internal.Route
+}
+
+// ServeHTTP send the request to the route and write its response
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	response, err := s.route(ctx, event(r, body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+
+		return
+	}
+
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+
+	for name, values := range response.MultiValueHeaders {
+		w.Header()[http.CanonicalHeaderKey(name)] = values
+	}
+
+	responseBody := []byte(response.Body)
+	if response.IsBase64Encoded {
+		if responseBody, err = base64.StdEncoding.DecodeString(response.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+
+			return
+		}
+	}
+
+	w.WriteHeader(response.StatusCode)
+	_, _ = w.Write(responseBody)
+}
+
+// event API Gateway event of a request, the resource is left empty so the router matches the path
+func event(r *http.Request, body []byte) events.APIGatewayProxyRequest {
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      r.Method,
+		Path:                            r.URL.EscapedPath(),
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               r.Header,
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: r.URL.Query(),
+		Body:                            string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: uuid.New().String(),
+			Identity:  events.APIGatewayRequestIdentity{SourceIP: r.RemoteAddr},
+		},
+	}
+
+	for name, values := range r.Header {
+		event.Headers[name] = values[len(values)-1]
+	}
+
+	for name, values := range r.URL.Query() {
+		event.QueryStringParameters[name] = values[len(values)-1]
+	}
+
+	return event
+}
+
+// NewServer instance of the local server of a route, usually Router.Handle
+func NewServer(route internal.Route) *Server {
+	return &Server{
+		route: route,
+	}
+}
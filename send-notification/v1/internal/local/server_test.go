@@ -0,0 +1,104 @@
+package local
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestServer_ServeHTTP test for this method
+func TestServer_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   events.APIGatewayProxyResponse
+		routeErr   error
+		wantStatus int
+		wantBody   string
+		wantHeader http.Header
+	}{
+		{
+			name: "response of the route",
+			response: events.APIGatewayProxyResponse{
+				StatusCode:        http.StatusAccepted,
+				Headers:           map[string]string{"Content-Type": "application/json"},
+				MultiValueHeaders: map[string][]string{"X-Rejected": {"a", "b"}},
+				Body:              `{"status":"ok"}`,
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"status":"ok"}`,
+			wantHeader: http.Header{"Content-Type": {"application/json"}, "X-Rejected": {"a", "b"}},
+		},
+		{
+			name: "base64 body",
+			response: events.APIGatewayProxyResponse{
+				StatusCode:      http.StatusOK,
+				Body:            base64.StdEncoding.EncodeToString([]byte("type,limit\n")),
+				IsBase64Encoded: true,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "type,limit\n",
+			wantHeader: http.Header{},
+		},
+		{
+			name:       "route error",
+			routeErr:   assert.AnError,
+			wantStatus: http.StatusBadGateway,
+			wantBody:   assert.AnError.Error() + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got events.APIGatewayProxyRequest
+
+			server := httptest.NewServer(NewServer(
+				func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+					got = event
+					_, hasDeadline := ctx.Deadline()
+					assert.True(t, hasDeadline)
+
+					return tt.response, tt.routeErr
+				},
+			))
+			defer server.Close()
+
+			request, err := http.NewRequest(
+				http.MethodPost,
+				server.URL+"/v1/admin/rules/Daily%20news?dry_run=true",
+				strings.NewReader(`{"notifications_limit":2}`),
+			)
+			assert.NoError(t, err)
+			request.Header.Set("X-Api-Key", "key")
+
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err)
+			defer response.Body.Close()
+
+			body, err := io.ReadAll(response.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+			assert.Equal(t, tt.wantBody, string(body))
+			for name, values := range tt.wantHeader {
+				assert.Equal(t, values, response.Header.Values(name))
+			}
+
+			assert.Equal(t, http.MethodPost, got.HTTPMethod)
+			assert.Equal(t, "/v1/admin/rules/Daily%20news", got.Path)
+			assert.Empty(t, got.Resource)
+			assert.Equal(t, "key", got.Headers["X-Api-Key"])
+			assert.Equal(t, "true", got.QueryStringParameters["dry_run"])
+			assert.Equal(t, `{"notifications_limit":2}`, got.Body)
+			assert.NotEmpty(t, got.RequestContext.RequestID)
+			assert.NotEmpty(t, got.RequestContext.Identity.SourceIP)
+		})
+	}
+}
@@ -1,6 +1,49 @@
 // Package internal contains all the main logic
 package internal
 
+import "time"
+
+// List of reasons to reject a notification
+const (
+	// RejectionReasonRateLimited the recipient reached the limit of notifications of this type
+	RejectionReasonRateLimited string = "rate_limited"
+	// RejectionReasonQuietHours the notification arrived inside the recipient quiet hours
+	RejectionReasonQuietHours string = "quiet_hours"
+	// RejectionReasonOptedOut the recipient opted out of this type or of the email channel
+	RejectionReasonOptedOut string = "opted_out"
+	// RejectionReasonUnsubscribed the recipient unsubscribed from every notification
+	RejectionReasonUnsubscribed string = "unsubscribed"
+	// RejectionReasonSuppressed the recipient address hard bounced or complained
+	RejectionReasonSuppressed string = "suppressed"
+	// RejectionReasonSenderNotAllowed the sender of the type or tenant is not an allowed identity
+	RejectionReasonSenderNotAllowed string = "sender_not_allowed"
+	// RejectionReasonNotProcessed the request deadline passed before the notification was sent
+	RejectionReasonNotProcessed string = "not_processed"
+	// RejectionReasonSendFailed the notification could not be sent because of an error of the email provider or of
+	// the service, it can be sent again
+	RejectionReasonSendFailed string = "send_failed"
+	// RejectionReasonRecipientsRejected every recipient of the notification was rejected, each one for its reason
+	RejectionReasonRecipientsRejected string = "recipients_rejected"
+	// RejectionReasonInvalidAttachment an attachment could not be loaded from the object store or the attachments
+	// are larger than the limit
+	RejectionReasonInvalidAttachment string = "invalid_attachment"
+	// RejectionReasonDuplicate the same message of the type was sent to the recipient inside the dedup window
+	RejectionReasonDuplicate string = "duplicate"
+	// RejectionReasonForbidden the client of the request can not send notifications of this type
+	RejectionReasonForbidden string = "forbidden"
+)
+
+// List of reasons to suppress a recipient address
+const (
+	// SuppressionReasonBounce the address had a permanent bounce
+	SuppressionReasonBounce string = "bounce"
+	// SuppressionReasonComplaint the recipient marked an email as spam
+	SuppressionReasonComplaint string = "complaint"
+)
+
+// ChannelEmail channel used to deliver the notifications
+const ChannelEmail string = "email"
+
 // RequestBody struct for request body
 type RequestBody struct {
 	Notifications []Notification `json:"notifications"`
@@ -8,20 +51,279 @@ type RequestBody struct {
 
 // ResponseBody struct for response body
 type ResponseBody struct {
-	Sent   []Notification `json:"sent"`
-	Failed []Notification `json:"failed"`
+	Sent   []SentNotification   `json:"sent"`
+	Failed []FailedNotification `json:"failed"`
+}
+
+// SentNotification notification that was sent along with the message ID given by the email provider,
+// the delivery events of the provider carry the same ID
+type SentNotification struct {
+	Notification
+	MessageID string `json:"message_id,omitempty"`
+	// Recipients outcome of each recipient of a notification sent to the lists to, cc and bcc
+	Recipients []RecipientResult `json:"recipients,omitempty"`
+}
+
+// FailedNotification notification that was not sent along with the reason
+type FailedNotification struct {
+	Notification
+	Reason        string     `json:"reason"`
+	NextAllowedAt *time.Time `json:"next_allowed_at,omitempty"`
+	// Recipients outcome of each recipient of a notification sent to the lists to, cc and bcc
+	Recipients []RecipientResult `json:"recipients,omitempty"`
 }
 
-// Notification model for notification sent
+// Notification model for notification sent, to one recipient or to the lists to, cc and bcc
 type Notification struct {
-	Type      string `json:"type"`
-	Recipient string `json:"recipient"`
-	Message   string `json:"message"`
+	Type      string   `json:"type"`
+	Recipient string   `json:"recipient,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Cc        []string `json:"cc,omitempty"`
+	Bcc       []string `json:"bcc,omitempty"`
+	Message   string   `json:"message"`
+	// Attachments files sent with the message, at most MaxAttachmentsPerNotification
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Priority critical, high, normal or low, the default of the type when it is empty
+	Priority string `json:"priority,omitempty"`
 }
 
 // RateLimitRule model for rate limit rules stored in database
 type RateLimitRule struct {
-	PK                 string `dynamodbav:"pk"`
-	NotificationsLimit int    `dynamodbav:"notifications_limit"`
-	IntervalInMinutes  int    `dynamodbav:"interval_in_minutes"`
+	PK string `dynamodbav:"pk" json:"-"`
+	// Type notification type of the rule, it is not stored because it is part of the PK
+	Type               string `dynamodbav:"-" json:"type"`
+	NotificationsLimit int    `dynamodbav:"notifications_limit" json:"notifications_limit"`
+	IntervalInMinutes  int    `dynamodbav:"interval_in_minutes" json:"interval_in_minutes,omitempty"`
+	// Interval Go duration ("10s") or ISO-8601 duration ("PT10S"), when present it takes precedence over IntervalInMinutes
+	Interval string `dynamodbav:"interval,omitempty" json:"interval,omitempty"`
+	// WindowAlignment one of rolling (default), calendar_day or calendar_week
+	WindowAlignment string `dynamodbav:"window_alignment,omitempty" json:"window_alignment,omitempty"`
+	// Timezone IANA name used to align calendar windows, UTC by default
+	Timezone string `dynamodbav:"timezone,omitempty" json:"timezone,omitempty"`
+	// QuietHours overrides the default quiet hours for this type
+	QuietHours *QuietHours `dynamodbav:"quiet_hours,omitempty" json:"quiet_hours,omitempty"`
+	// QuietHoursExempt notifications of this type are sent even inside quiet hours
+	QuietHoursExempt bool `dynamodbav:"quiet_hours_exempt,omitempty" json:"quiet_hours_exempt,omitempty"`
+	// Sender identity that sends the emails of this type, the attributes not set are taken from the tenant
+	// or default sender
+	Sender *Sender `dynamodbav:"sender,omitempty" json:"sender,omitempty"`
+	// Priority how the priorities of the notifications of this type are applied, every notification is normal
+	// when it is not set
+	Priority *PriorityPolicy `dynamodbav:"priority,omitempty" json:"priority,omitempty"`
+	// DedupWindow Go or ISO-8601 duration where the same message of this type is not sent twice to a recipient,
+	// the messages are not deduplicated when it is not set
+	DedupWindow string `dynamodbav:"dedup_window,omitempty" json:"dedup_window,omitempty"`
+	// Algorithm used to count the notifications, sliding_log by default
+	Algorithm string `dynamodbav:"algorithm,omitempty" json:"algorithm,omitempty"`
+	// Version incremented on every write, rules created by hand without version have version 0
+	Version   int    `dynamodbav:"version" json:"version"`
+	UpdatedAt string `dynamodbav:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// RateLimitRuleHistory previous version of a rule, replaced by an update or a delete, stored in database
+type RateLimitRuleHistory struct {
+	RateLimitRule
+	// ReplacedAt moment where this version stopped being the current one
+	ReplacedAt string `dynamodbav:"replaced_at" json:"replaced_at"`
+	// Operation that replaced this version, update or delete
+	Operation string `dynamodbav:"operation" json:"operation"`
+}
+
+// RulesResponseBody struct for the response body of the rules list
+type RulesResponseBody struct {
+	Rules []RateLimitRule `json:"rules"`
+}
+
+// RuleHistoryResponseBody struct for the response body of the previous versions of a rule
+type RuleHistoryResponseBody struct {
+	History []RateLimitRuleHistory `json:"history"`
+}
+
+// RecipientProfile model for the recipients profile stored in database
+type RecipientProfile struct {
+	PK       string `dynamodbav:"pk"`
+	Email    string `dynamodbav:"email"`
+	Timezone string `dynamodbav:"timezone"`
+}
+
+// Unsubscription recipient and type of a signed unsubscribe link, an empty type unsubscribes the recipient from
+// every notification of the tenant
+type Unsubscription struct {
+	Tenant string
+	Email  string
+	Type   string
+}
+
+// RecipientPreferences model for the choices of the recipients stored in database
+type RecipientPreferences struct {
+	PK            string   `dynamodbav:"pk"`
+	Email         string   `dynamodbav:"email"`
+	OptedOutTypes []string `dynamodbav:"opted_out_types,stringset,omitempty"`
+	// Channels where the recipient accepts notifications, every channel when it is empty
+	Channels     []string `dynamodbav:"channels,stringset,omitempty"`
+	Unsubscribed bool     `dynamodbav:"unsubscribed"`
+}
+
+// RejectionReason get the reason to reject a notification of the given type, empty when the recipient accepts it
+func (p RecipientPreferences) RejectionReason(notificationType string) string {
+	if p.Unsubscribed {
+		return RejectionReasonUnsubscribed
+	}
+
+	if len(p.Channels) > 0 && !contains(p.Channels, ChannelEmail) {
+		return RejectionReasonOptedOut
+	}
+
+	if contains(p.OptedOutTypes, notificationType) {
+		return RejectionReasonOptedOut
+	}
+
+	return ""
+}
+
+// Email message delivered by the email service
+type Email struct {
+	// To, Cc and Bcc addresses that receive the email, at most MaxEmailDestinations
+	To             []string
+	Cc             []string
+	Bcc            []string
+	Subject        string
+	Body           string
+	UnsubscribeURL string
+	Attachments    []EmailAttachment
+	Sender         Sender
+	// Type, Tenant and RequestID tag the email so its delivery events can be joined back to the request
+	Type      string
+	Tenant    string
+	RequestID string
+}
+
+// Suppression model for the addresses that must not receive emails stored in database
+type Suppression struct {
+	PK        string `dynamodbav:"pk" json:"-"`
+	Email     string `dynamodbav:"email" json:"email"`
+	Reason    string `dynamodbav:"reason" json:"reason"`
+	Detail    string `dynamodbav:"detail" json:"detail"`
+	CreatedAt string `dynamodbav:"created_at" json:"created_at"`
+}
+
+// SuppressionsResponseBody struct for the response body of the suppressions list
+type SuppressionsResponseBody struct {
+	Suppressions []Suppression `json:"suppressions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// SESFeedback notification published by SES to SNS about bounces and complaints, the identity notifications have
+// notificationType and the events of the configuration set have eventType, both with the same bounce and complaint
+type SESFeedback struct {
+	NotificationType string          `json:"notificationType"`
+	EventType        string          `json:"eventType"`
+	Bounce           *SESBounce      `json:"bounce,omitempty"`
+	Complaint        *SESComplaint   `json:"complaint,omitempty"`
+	Mail             SESFeedbackMail `json:"mail"`
+}
+
+// Type type of the feedback, Bounce, Complaint or Delivery among others
+func (f SESFeedback) Type() string {
+	if f.NotificationType != "" {
+		return f.NotificationType
+	}
+
+	return f.EventType
+}
+
+// SESFeedbackMail original email of the feedback
+type SESFeedbackMail struct {
+	MessageID string `json:"messageId"`
+	Source    string `json:"source"`
+}
+
+// SESBounce bounce details of the feedback
+type SESBounce struct {
+	BounceType        string                 `json:"bounceType"`
+	BounceSubType     string                 `json:"bounceSubType"`
+	BouncedRecipients []SESFeedbackRecipient `json:"bouncedRecipients"`
+	Timestamp         string                 `json:"timestamp"`
+}
+
+// SESComplaint complaint details of the feedback
+type SESComplaint struct {
+	ComplainedRecipients  []SESFeedbackRecipient `json:"complainedRecipients"`
+	ComplaintFeedbackType string                 `json:"complaintFeedbackType"`
+	Timestamp             string                 `json:"timestamp"`
+}
+
+// SESFeedbackRecipient recipient affected by the feedback
+type SESFeedbackRecipient struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+// QuotaUsage notifications of one type sent to a recipient inside the current window
+type QuotaUsage struct {
+	Type        string    `json:"type"`
+	Limit       int       `json:"limit"`
+	Used        int       `json:"used"`
+	Remaining   int       `json:"remaining"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+// NotificationCountQuery count of the notifications of one type sent to a recipient since the start of a window,
+// the count can stop at the limit
+type NotificationCountQuery struct {
+	Type        string
+	Email       string
+	WindowStart time.Time
+	Limit       int
+}
+
+// PartitionKey key of the notifications of the query in the cache, "type#email"
+func (q NotificationCountQuery) PartitionKey() string {
+	return q.Type + "#" + q.Email
+}
+
+// SendResult outcome of sending a notification
+type SendResult struct {
+	Sent   bool
+	Reason string
+	// MessageID identifier given by the email provider to the sent email, the first one when it was sent in batches
+	MessageID string
+	// Recipients outcome of each recipient of the notification
+	Recipients []RecipientResult
+}
+
+// ValidationResult decision about a notification taken by the rate limiter
+type ValidationResult struct {
+	Allowed       bool
+	Reason        string
+	NextAllowedAt *time.Time
+	// Reservation quota and message recorded for the notification allowed, nil when it was not allowed
+	Reservation *Reservation
+}
+
+// Reservation quota and message recorded for a notification when it was allowed, it is released when the
+// notification is not sent so the recipient does not lose the quota and the message can be sent again
+type Reservation struct {
+	// Records notifications saved in the partitions of the type and of the recipient cap
+	Records []SentRecord
+	// ContentKey key of the message recorded, empty when the type is not deduplicated
+	ContentKey string
+}
+
+// SentRecord notification saved in a partition of the cache
+type SentRecord struct {
+	Type      string
+	Email     string
+	Timestamp string
+	UUID      string
+}
+
+// contains check if the value is in the list
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,145 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// List of priorities of a notification, from the highest to the lowest
+const (
+	// PriorityCritical the notification skips the quiet hours and every limit, it is still recorded
+	PriorityCritical string = "critical"
+	// PriorityHigh the notification can borrow the notifications over the limit of its type that its rule allows
+	PriorityHigh string = "high"
+	// PriorityNormal the notification uses the limit of its type and the cap of the recipient, the default
+	PriorityNormal string = "normal"
+	// PriorityLow the notification is rejected first when the cap of the recipient is close to full
+	PriorityLow string = "low"
+)
+
+// RecipientCapType type of the partition of the cache where every notification to a recipient is counted, the
+// types of the rules can not have '*'
+const RecipientCapType = "*"
+
+// DefaultLowPriorityPercent part of the cap of the recipients that the low priority notifications can use
+const DefaultLowPriorityPercent = 80
+
+// priorityRanks order of the priorities, the highest priority has the highest rank
+var priorityRanks = map[string]int{
+	PriorityLow:      1,
+	PriorityNormal:   2,
+	PriorityHigh:     3,
+	PriorityCritical: 4,
+}
+
+// PriorityRank position of the priority, 0 when it is not a known priority
+func PriorityRank(priority string) int {
+	return priorityRanks[priority]
+}
+
+// PriorityPolicy how the priorities of the notifications of a type are applied
+type PriorityPolicy struct {
+	// Default priority of the notifications of the type that do not ask for one, normal when it is empty
+	Default string `dynamodbav:"default,omitempty" json:"default,omitempty"`
+	// Max highest priority that the notifications of the type can ask for, Default when it is empty
+	Max string `dynamodbav:"max,omitempty" json:"max,omitempty"`
+	// Borrow notifications over the limit of the type that the high priority notifications can use in a window
+	Borrow int `dynamodbav:"borrow,omitempty" json:"borrow,omitempty"`
+}
+
+// Validate check the priorities and the borrow of the policy
+func (p PriorityPolicy) Validate() error {
+	var problems []string
+
+	for _, priority := range [][2]string{{"default", p.Default}, {"max", p.Max}} {
+		if priority[1] != "" && PriorityRank(priority[1]) == 0 {
+			problems = append(problems, fmt.Sprintf("unknown %s priority '%s', expected critical, high, normal or low",
+				priority[0], priority[1]))
+		}
+	}
+
+	if p.Default != "" && p.Max != "" && PriorityRank(p.Default) > PriorityRank(p.Max) {
+		problems = append(problems, fmt.Sprintf("default priority '%s' is higher than max priority '%s'", p.Default, p.Max))
+	}
+
+	if p.Borrow < 0 {
+		problems = append(problems, fmt.Sprintf("priority borrow must not be negative, got %d", p.Borrow))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Resolve priority of a notification of the type that asked for requested, empty to use the default. The
+// priorities higher than the max of the type are lowered to the max
+func (p PriorityPolicy) Resolve(requested string) string {
+	defaultPriority := p.Default
+	if defaultPriority == "" {
+		defaultPriority = PriorityNormal
+	}
+
+	maxPriority := p.Max
+	if maxPriority == "" {
+		maxPriority = defaultPriority
+	}
+
+	if requested == "" {
+		return defaultPriority
+	}
+
+	if PriorityRank(requested) > PriorityRank(maxPriority) {
+		return maxPriority
+	}
+
+	return requested
+}
+
+// RecipientCap maximum number of notifications of every type that a recipient receives in a rolling window
+type RecipientCap struct {
+	Limit  int
+	Window time.Duration
+	// LowPriorityPercent part of the limit that the low priority notifications can use, so they are rejected
+	// before the others when the cap is close to full
+	LowPriorityPercent int
+}
+
+// Enabled check if the recipients have a cap
+func (c RecipientCap) Enabled() bool {
+	return c.Limit > 0
+}
+
+// LimitOf notifications of the priority that the recipient can receive in the window, the critical notifications
+// have no limit
+func (c RecipientCap) LimitOf(priority string) int {
+	if priority == PriorityLow {
+		return c.Limit * c.LowPriorityPercent / 100
+	}
+
+	return c.Limit
+}
+
+// ParseRecipientCap parse a cap with the format "limit/window" like 20/24h, empty to not cap the recipients.
+// lowPriorityPercent is the part of the limit that the low priority notifications can use
+func ParseRecipientCap(value string, lowPriorityPercent int) (RecipientCap, error) {
+	if value == "" {
+		return RecipientCap{}, nil
+	}
+
+	number, duration, ok := parseLimitWindow(value)
+	if !ok {
+		return RecipientCap{}, fmt.Errorf("invalid recipient cap '%s', expected limit/window like 20/24h", value)
+	}
+
+	if lowPriorityPercent <= 0 || lowPriorityPercent > 100 {
+		return RecipientCap{}, fmt.Errorf("invalid low priority percent %d, expected a number from 1 to 100",
+			lowPriorityPercent)
+	}
+
+	return RecipientCap{Limit: number, Window: duration, LowPriorityPercent: lowPriorityPercent}, nil
+}
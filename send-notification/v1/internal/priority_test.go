@@ -0,0 +1,93 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPriorityPolicy_Resolve test for this method
+func TestPriorityPolicy_Resolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    PriorityPolicy
+		requested string
+		want      string
+	}{
+		{name: "normal without policy", requested: "", want: PriorityNormal},
+		{name: "lower than the default", requested: PriorityLow, want: PriorityLow},
+		{name: "higher than the default without max", requested: PriorityCritical, want: PriorityNormal},
+		{
+			name:      "default of the type",
+			policy:    PriorityPolicy{Default: PriorityCritical},
+			requested: "",
+			want:      PriorityCritical,
+		},
+		{
+			name:      "up to the max of the type",
+			policy:    PriorityPolicy{Max: PriorityHigh},
+			requested: PriorityHigh,
+			want:      PriorityHigh,
+		},
+		{
+			name:      "lowered to the max of the type",
+			policy:    PriorityPolicy{Max: PriorityHigh},
+			requested: PriorityCritical,
+			want:      PriorityHigh,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Resolve(tt.requested))
+		})
+	}
+}
+
+// TestParseRecipientCap test for this function
+func TestParseRecipientCap(t *testing.T) {
+	tests := []struct {
+		name               string
+		value              string
+		lowPriorityPercent int
+		want               RecipientCap
+		wantErr            bool
+	}{
+		{name: "empty", value: "", lowPriorityPercent: 80, want: RecipientCap{}},
+		{
+			name:               "limit and window",
+			value:              "20/24h",
+			lowPriorityPercent: 80,
+			want:               RecipientCap{Limit: 20, Window: 24 * time.Hour, LowPriorityPercent: 80},
+		},
+		{name: "missing window", value: "20", lowPriorityPercent: 80, wantErr: true},
+		{name: "zero limit", value: "0/24h", lowPriorityPercent: 80, wantErr: true},
+		{name: "invalid window", value: "20/day", lowPriorityPercent: 80, wantErr: true},
+		{name: "percent over 100", value: "20/24h", lowPriorityPercent: 120, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecipientCap(tt.value, tt.lowPriorityPercent)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestRecipientCap_LimitOf test for this method
+func TestRecipientCap_LimitOf(t *testing.T) {
+	recipientCap := RecipientCap{Limit: 10, Window: time.Hour, LowPriorityPercent: 80}
+
+	assert.Equal(t, 8, recipientCap.LimitOf(PriorityLow))
+	assert.Equal(t, 10, recipientCap.LimitOf(PriorityNormal))
+	assert.Equal(t, 10, recipientCap.LimitOf(PriorityHigh))
+}
@@ -0,0 +1,91 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// quietHoursLayout layout of the start and end of the quiet hours
+const quietHoursLayout = "15:04"
+
+// QuietHours window of the day, in the recipient local time, where notifications must not be sent.
+// When Start is after End the window wraps midnight, e.g. 21:00 to 08:00
+type QuietHours struct {
+	Start string `dynamodbav:"start" json:"start"`
+	End   string `dynamodbav:"end" json:"end"`
+}
+
+// ParseQuietHours parse quiet hours with the format "HH:MM-HH:MM", an empty value means no quiet hours
+func ParseQuietHours(value string) (*QuietHours, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	start, end, found := strings.Cut(value, "-")
+	if !found {
+		return nil, fmt.Errorf("invalid quiet hours '%s', expected HH:MM-HH:MM", value)
+	}
+
+	quietHours := &QuietHours{
+		Start: strings.TrimSpace(start),
+		End:   strings.TrimSpace(end),
+	}
+
+	if _, _, err := quietHours.bounds(); err != nil {
+		return nil, err
+	}
+
+	return quietHours, nil
+}
+
+// bounds get the start and end of the quiet hours as minutes of the day
+func (q QuietHours) bounds() (int, int, error) {
+	start, err := time.Parse(quietHoursLayout, q.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours start '%s': %w", q.Start, err)
+	}
+
+	end, err := time.Parse(quietHoursLayout, q.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours end '%s': %w", q.End, err)
+	}
+
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// NextAllowedAt check if the given moment is inside the quiet hours in the given location,
+// when it is returns the moment where the quiet hours end, otherwise returns nil
+func (q QuietHours) NextAllowedAt(now time.Time, location *time.Location) (*time.Time, error) {
+	start, end, err := q.bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	// Same start and end means there are no quiet hours
+	if start == end {
+		return nil, nil
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+
+	var quiet bool
+	if start < end {
+		quiet = minute >= start && minute < end
+	} else {
+		quiet = minute >= start || minute < end
+	}
+
+	if !quiet {
+		return nil, nil
+	}
+
+	nextAllowedAt := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, location)
+	if !nextAllowedAt.After(local) {
+		nextAllowedAt = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, location)
+	}
+
+	return &nextAllowedAt, nil
+}
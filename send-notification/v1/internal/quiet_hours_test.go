@@ -0,0 +1,140 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseQuietHours test for this function
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *QuietHours
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: nil},
+		{name: "wraps midnight", value: "21:00-08:00", want: &QuietHours{Start: "21:00", End: "08:00"}},
+		{name: "with spaces", value: "13:30 - 14:00", want: &QuietHours{Start: "13:30", End: "14:00"}},
+		{name: "missing separator", value: "21:00", wantErr: true},
+		{name: "invalid hour", value: "25:00-08:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuietHours(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestQuietHours_NextAllowedAt test for this method
+func TestQuietHours_NextAllowedAt(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	night := QuietHours{Start: "21:00", End: "08:00"}
+
+	tests := []struct {
+		name       string
+		quietHours QuietHours
+		now        time.Time
+		location   *time.Location
+		want       *time.Time
+		wantErr    bool
+	}{
+		{
+			name:       "before quiet hours",
+			quietHours: night,
+			now:        time.Date(2023, 10, 14, 20, 59, 0, 0, time.UTC),
+			location:   time.UTC,
+		},
+		{
+			name:       "quiet hours before midnight end the next day",
+			quietHours: night,
+			now:        time.Date(2023, 10, 14, 22, 0, 0, 0, time.UTC),
+			location:   time.UTC,
+			want:       timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:       "quiet hours after midnight end the same day",
+			quietHours: night,
+			now:        time.Date(2023, 10, 15, 7, 59, 0, 0, time.UTC),
+			location:   time.UTC,
+			want:       timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:       "end of quiet hours is allowed",
+			quietHours: night,
+			now:        time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC),
+			location:   time.UTC,
+		},
+		{
+			name:       "quiet hours in the recipient timezone",
+			quietHours: night,
+			// 02:00 UTC is 21:00 of the previous day in Bogota
+			now:      time.Date(2023, 10, 15, 2, 0, 0, 0, time.UTC),
+			location: bogota,
+			want:     timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, bogota)),
+		},
+		{
+			name:       "same day quiet hours",
+			quietHours: QuietHours{Start: "12:00", End: "14:00"},
+			now:        time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC),
+			location:   time.UTC,
+			want:       timePointer(time.Date(2023, 10, 15, 14, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:       "same start and end disables quiet hours",
+			quietHours: QuietHours{Start: "08:00", End: "08:00"},
+			now:        time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC),
+			location:   time.UTC,
+		},
+		{
+			name:       "invalid quiet hours",
+			quietHours: QuietHours{Start: "nine", End: "08:00"},
+			now:        time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC),
+			location:   time.UTC,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.quietHours.NextAllowedAt(tt.now, tt.location)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			if tt.want == nil {
+				assert.Nil(t, got)
+
+				return
+			}
+
+			if assert.NotNil(t, got) {
+				assert.True(t, tt.want.Equal(*got), "next allowed at %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
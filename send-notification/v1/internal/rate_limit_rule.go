@@ -0,0 +1,111 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// List of algorithms supported to count the notifications of a rule
+const (
+	// RateLimitAlgorithmSlidingLog every notification sent is stored and counted inside the window
+	RateLimitAlgorithmSlidingLog string = "sliding_log"
+)
+
+// List of operations that replace a version of a rule
+const (
+	// RuleOperationUpdate the version was replaced by a new one
+	RuleOperationUpdate string = "update"
+	// RuleOperationDelete the rule was deleted
+	RuleOperationDelete string = "delete"
+)
+
+// RuleKeyPrefix prefix of the partition key of the rules
+const RuleKeyPrefix = "TYPE#"
+
+// notificationTypeRegexp names allowed for the notification types, '#' is reserved for the keys
+var notificationTypeRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ErrRuleVersionConflict the rule was written by someone else since the expected version was read
+var ErrRuleVersionConflict = errors.New("rule version conflict")
+
+// Validate check that the rule can be applied, every problem found is returned in one error
+func (r RateLimitRule) Validate() error {
+	var problems []string
+
+	// The rules of a tenant have the type of TenantType, the tenant follows the same pattern as the type
+	if tenant, notificationType := SplitTenantType(r.Type); !notificationTypeRegexp.MatchString(notificationType) ||
+		strings.Contains(r.Type, tenantTypeSeparator) && !notificationTypeRegexp.MatchString(tenant) {
+		problems = append(problems, fmt.Sprintf(
+			"type '%s' must have between 1 and 64 letters, digits, '_', '.' or '-', after the tenant and ':' "+
+				"for the rules of a tenant", r.Type,
+		))
+	}
+
+	if r.NotificationsLimit < 0 {
+		problems = append(problems, fmt.Sprintf("notifications_limit must not be negative, got %d", r.NotificationsLimit))
+	}
+
+	// calendar windows have a fixed size, the interval only matters for rolling windows
+	if r.WindowAlignment == "" || r.WindowAlignment == WindowAlignmentRolling || r.Interval != "" ||
+		r.IntervalInMinutes != 0 {
+		if _, err := r.IntervalDuration(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	switch r.WindowAlignment {
+	case "", WindowAlignmentRolling, WindowAlignmentCalendarDay, WindowAlignmentCalendarWeek:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown window_alignment '%s'", r.WindowAlignment))
+	}
+
+	if _, err := r.Location(); err != nil {
+		problems = append(problems, fmt.Sprintf("unknown timezone '%s'", r.Timezone))
+	}
+
+	if r.QuietHours != nil {
+		if _, _, err := r.QuietHours.bounds(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if r.Sender != nil {
+		if err := r.Sender.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if r.Priority != nil {
+		if err := r.Priority.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if _, err := r.DedupWindowDuration(); err != nil {
+		problems = append(problems, "dedup_window: "+err.Error())
+	}
+
+	switch r.Algorithm {
+	case "", RateLimitAlgorithmSlidingLog:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown algorithm '%s'", r.Algorithm))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// PriorityOf priority of a notification of the type that asked for requested, empty to use the default of the type
+func (r RateLimitRule) PriorityOf(requested string) string {
+	if r.Priority == nil {
+		return PriorityPolicy{}.Resolve(requested)
+	}
+
+	return r.Priority.Resolve(requested)
+}
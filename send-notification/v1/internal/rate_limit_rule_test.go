@@ -0,0 +1,135 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimitRule_Validate test for this method
+func TestRateLimitRule_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        RateLimitRule
+		wantErrPart []string
+	}{
+		{
+			name: "interval in minutes",
+			rule: RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 1440},
+		},
+		{
+			name: "duration interval with every option",
+			rule: RateLimitRule{
+				Type:               "Marketing",
+				NotificationsLimit: 3,
+				Interval:           "PT1H",
+				Timezone:           "America/Bogota",
+				QuietHours:         &QuietHours{Start: "21:00", End: "08:00"},
+				Priority:           &PriorityPolicy{Default: PriorityLow, Max: PriorityHigh, Borrow: 2},
+				DedupWindow:        "PT10M",
+				Algorithm:          RateLimitAlgorithmSlidingLog,
+			},
+		},
+		{
+			name: "calendar window without interval",
+			rule: RateLimitRule{Type: "News", NotificationsLimit: 1, WindowAlignment: WindowAlignmentCalendarDay},
+		},
+		{
+			name: "zero limit blocks the type",
+			rule: RateLimitRule{Type: "Blocked", IntervalInMinutes: 1},
+		},
+		{
+			name:        "negative limit",
+			rule:        RateLimitRule{Type: "News", NotificationsLimit: -1, IntervalInMinutes: 1},
+			wantErrPart: []string{"notifications_limit"},
+		},
+		{
+			name:        "missing interval",
+			rule:        RateLimitRule{Type: "News", NotificationsLimit: 1},
+			wantErrPart: []string{"interval in minutes must be positive"},
+		},
+		{
+			name:        "invalid interval",
+			rule:        RateLimitRule{Type: "News", NotificationsLimit: 1, Interval: "P1M"},
+			wantErrPart: []string{"invalid interval"},
+		},
+		{
+			name: "rule of a tenant",
+			rule: RateLimitRule{Type: "acme:News", NotificationsLimit: 1, IntervalInMinutes: 1},
+		},
+		{
+			name:        "rule of a tenant without type",
+			rule:        RateLimitRule{Type: "acme:", NotificationsLimit: 1, IntervalInMinutes: 1},
+			wantErrPart: []string{"type 'acme:'"},
+		},
+		{
+			name:        "invalid type",
+			rule:        RateLimitRule{Type: "TYPE#News", NotificationsLimit: 1, IntervalInMinutes: 1},
+			wantErrPart: []string{"type 'TYPE#News'"},
+		},
+		{
+			name: "invalid sender",
+			rule: RateLimitRule{
+				Type:               "News",
+				NotificationsLimit: 1,
+				IntervalInMinutes:  1,
+				Sender:             &Sender{FromAddress: "News <news@example.com>", ReplyTo: "help"},
+			},
+			wantErrPart: []string{
+				"sender from_address 'News <news@example.com>' is not an email address",
+				"sender reply_to 'help' is not an email address",
+			},
+		},
+		{
+			name: "default priority higher than the max",
+			rule: RateLimitRule{
+				Type:               "News",
+				NotificationsLimit: 1,
+				IntervalInMinutes:  1,
+				Priority:           &PriorityPolicy{Default: PriorityCritical, Max: PriorityNormal},
+			},
+			wantErrPart: []string{"default priority 'critical' is higher than max priority 'normal'"},
+		},
+		{
+			name: "every problem is reported",
+			rule: RateLimitRule{
+				NotificationsLimit: 1,
+				IntervalInMinutes:  1,
+				WindowAlignment:    "calendar_month",
+				Timezone:           "Mars/Olympus",
+				QuietHours:         &QuietHours{Start: "25:00", End: "08:00"},
+				Priority:           &PriorityPolicy{Default: "urgent", Borrow: -1},
+				DedupWindow:        "forever",
+				Algorithm:          "token_bucket",
+			},
+			wantErrPart: []string{
+				"type ''",
+				"unknown window_alignment 'calendar_month'",
+				"unknown timezone 'Mars/Olympus'",
+				"invalid quiet hours start",
+				"unknown default priority 'urgent'",
+				"priority borrow must not be negative",
+				"dedup_window: invalid interval 'forever'",
+				"unknown algorithm 'token_bucket'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if len(tt.wantErrPart) == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			if assert.Error(t, err) {
+				for _, part := range tt.wantErrPart {
+					assert.Contains(t, err.Error(), part)
+				}
+			}
+		})
+	}
+}
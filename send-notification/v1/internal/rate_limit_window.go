@@ -0,0 +1,155 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// List of alignments supported by the rate limit windows
+const (
+	// WindowAlignmentRolling the window ends now and starts one interval before
+	WindowAlignmentRolling string = "rolling"
+	// WindowAlignmentCalendarDay the window is the current calendar day in the rule timezone
+	WindowAlignmentCalendarDay string = "calendar_day"
+	// WindowAlignmentCalendarWeek the window is the current calendar week (Monday to Sunday) in the rule timezone
+	WindowAlignmentCalendarWeek string = "calendar_week"
+)
+
+// windowTypeSeparator separator of the type and the alignment in the cache partitions of the calendar windows
+const windowTypeSeparator = "#"
+
+// isoDurationRegexp ISO-8601 durations with fixed length units (weeks, days, hours, minutes and seconds)
+var isoDurationRegexp = regexp.MustCompile(
+	`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`,
+)
+
+// RateLimitWindow time range where the notifications sent are counted against the limit
+type RateLimitWindow struct {
+	// Start notifications sent from this moment are counted
+	Start time.Time
+	// ExpiresAt moment from which a notification sent now is no longer counted, used as cache TTL
+	ExpiresAt time.Time
+}
+
+// ParseInterval parse a Go duration ("10s", "1h30m") or an ISO-8601 duration ("PT10S", "P1D")
+func ParseInterval(value string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return 0, fmt.Errorf("interval '%s' must be positive", value)
+		}
+
+		return duration, nil
+	}
+
+	matches := isoDurationRegexp.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value[len(value)-1] == 'T' {
+		return 0, fmt.Errorf("invalid interval '%s'", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	var duration time.Duration
+
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval '%s': %w", value, err)
+		}
+
+		duration += time.Duration(amount * float64(unit))
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("interval '%s' must be positive", value)
+	}
+
+	return duration, nil
+}
+
+// IntervalDuration get the interval of the rule, Interval has precedence over IntervalInMinutes
+func (r RateLimitRule) IntervalDuration() (time.Duration, error) {
+	if r.Interval != "" {
+		return ParseInterval(r.Interval)
+	}
+
+	if r.IntervalInMinutes <= 0 {
+		return 0, fmt.Errorf("interval in minutes must be positive, got %d", r.IntervalInMinutes)
+	}
+
+	return time.Duration(r.IntervalInMinutes) * time.Minute, nil
+}
+
+// Location get the timezone of the rule, UTC when it is not configured
+func (r RateLimitRule) Location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(r.Timezone)
+}
+
+// WindowType type of the cache partitions where the notifications of the type are counted with this rule. The
+// calendar windows have their own partitions like "News#calendar_day", so a rule whose alignment changes never counts
+// the notifications recorded for the windows of the other alignment, whose ttl was the end of those windows. The
+// rolling windows keep the partitions of the type
+func (r RateLimitRule) WindowType(notificationType string) string {
+	if r.WindowAlignment == WindowAlignmentCalendarDay || r.WindowAlignment == WindowAlignmentCalendarWeek {
+		return notificationType + windowTypeSeparator + r.WindowAlignment
+	}
+
+	return notificationType
+}
+
+// WindowTypes types of the cache partitions of the type for every alignment
+func WindowTypes(notificationType string) []string {
+	return []string{
+		notificationType,
+		notificationType + windowTypeSeparator + WindowAlignmentCalendarDay,
+		notificationType + windowTypeSeparator + WindowAlignmentCalendarWeek,
+	}
+}
+
+// Window get the window of the rule that contains the given moment
+func (r RateLimitRule) Window(now time.Time) (RateLimitWindow, error) {
+	switch r.WindowAlignment {
+	case "", WindowAlignmentRolling:
+		interval, err := r.IntervalDuration()
+		if err != nil {
+			return RateLimitWindow{}, err
+		}
+
+		return RateLimitWindow{
+			Start:     now.Add(-interval),
+			ExpiresAt: now.Add(interval),
+		}, nil
+	case WindowAlignmentCalendarDay, WindowAlignmentCalendarWeek:
+		location, err := r.Location()
+		if err != nil {
+			return RateLimitWindow{}, err
+		}
+
+		local := now.In(location)
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+		days := 1
+
+		if r.WindowAlignment == WindowAlignmentCalendarWeek {
+			// time.Weekday starts on Sunday, weeks start on Monday
+			start = start.AddDate(0, 0, -((int(local.Weekday()) + 6) % 7))
+			days = 7
+		}
+
+		return RateLimitWindow{
+			Start:     start,
+			ExpiresAt: start.AddDate(0, 0, days),
+		}, nil
+	default:
+		return RateLimitWindow{}, fmt.Errorf("unknown window alignment '%s'", r.WindowAlignment)
+	}
+}
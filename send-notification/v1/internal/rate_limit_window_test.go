@@ -0,0 +1,154 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseInterval test for this function
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "go duration seconds", value: "10s", want: 10 * time.Second},
+		{name: "go duration composed", value: "1h30m", want: 90 * time.Minute},
+		{name: "iso seconds", value: "PT10S", want: 10 * time.Second},
+		{name: "iso fractional seconds", value: "PT0.5S", want: 500 * time.Millisecond},
+		{name: "iso day", value: "P1D", want: 24 * time.Hour},
+		{name: "iso week", value: "P2W", want: 14 * 24 * time.Hour},
+		{name: "iso composed", value: "P1DT2H3M4S", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{name: "iso months not supported", value: "P1M", wantErr: true},
+		{name: "iso empty", value: "P", wantErr: true},
+		{name: "iso empty time", value: "P1DT", wantErr: true},
+		{name: "zero", value: "0s", wantErr: true},
+		{name: "negative", value: "-5m", wantErr: true},
+		{name: "iso zero", value: "PT0S", wantErr: true},
+		{name: "garbage", value: "ten seconds", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseInterval(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestRateLimitRule_Window test for this method
+func TestRateLimitRule_Window(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	// Sunday 2023-10-15 at 03:00 UTC is Saturday 2023-10-14 at 22:00 in Bogota
+	now := time.Date(2023, 10, 15, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		rule    RateLimitRule
+		want    RateLimitWindow
+		wantErr bool
+	}{
+		{
+			name: "rolling in minutes",
+			rule: RateLimitRule{IntervalInMinutes: 60},
+			want: RateLimitWindow{
+				Start:     now.Add(-time.Hour),
+				ExpiresAt: now.Add(time.Hour),
+			},
+		},
+		{
+			name: "rolling sub-minute interval has precedence",
+			rule: RateLimitRule{IntervalInMinutes: 60, Interval: "10s", WindowAlignment: WindowAlignmentRolling},
+			want: RateLimitWindow{
+				Start:     now.Add(-10 * time.Second),
+				ExpiresAt: now.Add(10 * time.Second),
+			},
+		},
+		{
+			name: "calendar day in UTC",
+			rule: RateLimitRule{WindowAlignment: WindowAlignmentCalendarDay},
+			want: RateLimitWindow{
+				Start:     time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: time.Date(2023, 10, 16, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "calendar day in the rule timezone",
+			rule: RateLimitRule{WindowAlignment: WindowAlignmentCalendarDay, Timezone: "America/Bogota"},
+			want: RateLimitWindow{
+				Start:     time.Date(2023, 10, 14, 0, 0, 0, 0, bogota),
+				ExpiresAt: time.Date(2023, 10, 15, 0, 0, 0, 0, bogota),
+			},
+		},
+		{
+			name: "calendar week starts on monday",
+			rule: RateLimitRule{WindowAlignment: WindowAlignmentCalendarWeek},
+			want: RateLimitWindow{
+				Start:     time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: time.Date(2023, 10, 16, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "calendar week in the rule timezone",
+			rule: RateLimitRule{WindowAlignment: WindowAlignmentCalendarWeek, Timezone: "America/Bogota"},
+			want: RateLimitWindow{
+				Start:     time.Date(2023, 10, 9, 0, 0, 0, 0, bogota),
+				ExpiresAt: time.Date(2023, 10, 16, 0, 0, 0, 0, bogota),
+			},
+		},
+		{
+			name:    "rolling without interval",
+			rule:    RateLimitRule{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown timezone",
+			rule:    RateLimitRule{WindowAlignment: WindowAlignmentCalendarDay, Timezone: "Mars/Olympus"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown alignment",
+			rule:    RateLimitRule{IntervalInMinutes: 1, WindowAlignment: "calendar_month"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Window(now)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Start.Equal(got.Start), "start %v, want %v", got.Start, tt.want.Start)
+			assert.True(t, tt.want.ExpiresAt.Equal(got.ExpiresAt), "expires at %v, want %v", got.ExpiresAt, tt.want.ExpiresAt)
+		})
+	}
+}
+
+// TestRateLimitRule_WindowType test for this method
+func TestRateLimitRule_WindowType(t *testing.T) {
+	assert.Equal(t, "acme:News", RateLimitRule{IntervalInMinutes: 1}.WindowType("acme:News"))
+	assert.Equal(t, "News", RateLimitRule{WindowAlignment: WindowAlignmentRolling}.WindowType("News"))
+	assert.Equal(t, "News#calendar_day", RateLimitRule{WindowAlignment: WindowAlignmentCalendarDay}.WindowType("News"))
+	assert.Equal(t, "News#calendar_week", RateLimitRule{WindowAlignment: WindowAlignmentCalendarWeek}.WindowType("News"))
+	assert.Equal(t, []string{"News", "News#calendar_day", "News#calendar_week"}, WindowTypes("News"))
+}
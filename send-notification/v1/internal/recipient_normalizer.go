@@ -0,0 +1,113 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// AnyProvider key of the normalization of the domains that do not have their own
+const AnyProvider = "*"
+
+// DefaultRecipientNormalization normalization of the providers that ignore the case, the sub addresses after '+'
+// or the dots of the local part
+const DefaultRecipientNormalization = `{
+	"*": {"lowercase_local": true},
+	"gmail.com": {"lowercase_local": true, "strip_plus": true, "strip_dots": true},
+	"googlemail.com": {"lowercase_local": true, "strip_plus": true, "strip_dots": true},
+	"outlook.com": {"lowercase_local": true, "strip_plus": true},
+	"hotmail.com": {"lowercase_local": true, "strip_plus": true},
+	"live.com": {"lowercase_local": true, "strip_plus": true}
+}`
+
+// AddressNormalization rules of a mail provider to find the mailbox of its addresses, the domain is always lowercase
+type AddressNormalization struct {
+	// LowercaseLocal the provider ignores the case of the local part, e.g. User@example.com
+	LowercaseLocal bool `json:"lowercase_local,omitempty"`
+	// StripPlus the provider ignores the sub address after '+', e.g. user+news@gmail.com
+	StripPlus bool `json:"strip_plus,omitempty"`
+	// StripDots the provider ignores the dots of the local part, e.g. u.ser@gmail.com
+	StripDots bool `json:"strip_dots,omitempty"`
+}
+
+// RecipientNormalizer normalization of the recipients of each provider, the addresses of one mailbox get one key
+// so they share the quota of the rate limit. The emails are still delivered to the original address
+type RecipientNormalizer struct {
+	// Providers normalization of each domain in lowercase ASCII, AnyProvider for the other domains
+	Providers map[string]AddressNormalization
+}
+
+// ParseRecipientNormalizer parse the normalization of each provider, a JSON object like
+// {"gmail.com": {"strip_plus": true}} where the domains can be Unicode or punycode
+func ParseRecipientNormalizer(providers string) (RecipientNormalizer, error) {
+	var parsed map[string]AddressNormalization
+
+	if err := json.Unmarshal([]byte(providers), &parsed); err != nil {
+		return RecipientNormalizer{}, fmt.Errorf("invalid recipient normalization: %w", err)
+	}
+
+	normalizer := RecipientNormalizer{Providers: make(map[string]AddressNormalization, len(parsed))}
+
+	for domain, normalization := range parsed {
+		if domain != AnyProvider {
+			asciiDomain, err := toASCII(domain)
+			if err != nil {
+				return RecipientNormalizer{}, fmt.Errorf("invalid recipient normalization domain '%s': %w", domain, err)
+			}
+
+			domain = asciiDomain
+		}
+
+		normalizer.Providers[domain] = normalization
+	}
+
+	return normalizer, nil
+}
+
+// Normalize key of the mailbox of an address, e.g. User.Name+news@GMAIL.com is username@gmail.com and
+// user@bücher.example is user@xn--bcher-kva.example. The addresses without domain are returned as they are
+func (n RecipientNormalizer) Normalize(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return address
+	}
+
+	local, domain := address[:at], address[at+1:]
+
+	asciiDomain, err := toASCII(domain)
+	if err != nil {
+		// The domain is only lowercased when it is not a valid IDN, the address was validated before
+		asciiDomain = strings.ToLower(domain)
+	}
+
+	normalization, ok := n.Providers[asciiDomain]
+	if !ok {
+		normalization = n.Providers[AnyProvider]
+	}
+
+	if normalization.StripPlus {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+
+	if normalization.StripDots {
+		if stripped := strings.ReplaceAll(local, ".", ""); stripped != "" {
+			local = stripped
+		}
+	}
+
+	if normalization.LowercaseLocal {
+		local = strings.ToLower(local)
+	}
+
+	return local + "@" + asciiDomain
+}
+
+// toASCII lowercase ASCII form of a domain, the Unicode labels are encoded with punycode
+func toASCII(domain string) (string, error) {
+	return idna.Lookup.ToASCII(strings.ToLower(domain))
+}
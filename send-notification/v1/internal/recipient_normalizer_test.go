@@ -0,0 +1,127 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseRecipientNormalizer test for this function
+func TestParseRecipientNormalizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers string
+		want      RecipientNormalizer
+		wantErr   bool
+	}{
+		{
+			name:      "domains in punycode and lowercase",
+			providers: `{"*": {"lowercase_local": true}, "Bücher.example": {"strip_plus": true}}`,
+			want: RecipientNormalizer{Providers: map[string]AddressNormalization{
+				AnyProvider:             {LowercaseLocal: true},
+				"xn--bcher-kva.example": {StripPlus: true},
+			}},
+		},
+		{
+			name:      "not a JSON object",
+			providers: "gmail.com",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid domain",
+			providers: `{"exa mple.com": {"strip_plus": true}}`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecipientNormalizer(tt.providers)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestRecipientNormalizer_Normalize test for this method
+func TestRecipientNormalizer_Normalize(t *testing.T) {
+	normalizer, err := ParseRecipientNormalizer(DefaultRecipientNormalization)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		normalizer RecipientNormalizer
+		address    string
+		want       string
+	}{
+		{
+			name:       "domain in lowercase",
+			normalizer: RecipientNormalizer{},
+			address:    "User@Example.COM",
+			want:       "User@example.com",
+		},
+		{
+			name:       "local part in lowercase",
+			normalizer: normalizer,
+			address:    "User@Example.COM",
+			want:       "user@example.com",
+		},
+		{
+			name:       "sub address kept by the other providers",
+			normalizer: normalizer,
+			address:    "user+news@example.com",
+			want:       "user+news@example.com",
+		},
+		{
+			name:       "sub address and dots of gmail",
+			normalizer: normalizer,
+			address:    "User.Name+news@Gmail.com",
+			want:       "username@gmail.com",
+		},
+		{
+			name:       "sub address of outlook",
+			normalizer: normalizer,
+			address:    "first.last+news@outlook.com",
+			want:       "first.last@outlook.com",
+		},
+		{
+			name:       "local part that starts with '+'",
+			normalizer: normalizer,
+			address:    "+news@gmail.com",
+			want:       "+news@gmail.com",
+		},
+		{
+			name:       "unicode domain",
+			normalizer: normalizer,
+			address:    "user@BÜCHER.example",
+			want:       "user@xn--bcher-kva.example",
+		},
+		{
+			name: "provider of a unicode domain",
+			normalizer: RecipientNormalizer{Providers: map[string]AddressNormalization{
+				"xn--bcher-kva.example": {StripPlus: true},
+			}},
+			address: "user+news@bücher.example",
+			want:    "user@xn--bcher-kva.example",
+		},
+		{
+			name:       "address without domain",
+			normalizer: normalizer,
+			address:    "User",
+			want:       "User",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.normalizer.Normalize(tt.address))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Package internal contains all the main logic
+package internal
+
+import "time"
+
+// MaxEmailDestinations maximum number of To, Cc and Bcc addresses of one email, the limit of SES
+const MaxEmailDestinations = 50
+
+// MaxRecipientsPerNotification maximum number of addresses in the lists to, cc and bcc of one notification
+const MaxRecipientsPerNotification = 500
+
+// List of kinds of recipients of a notification
+const (
+	// RecipientKindTo the recipient is in the To header
+	RecipientKindTo string = "to"
+	// RecipientKindCc the recipient is in the Cc header
+	RecipientKindCc string = "cc"
+	// RecipientKindBcc the recipient receives the email without being in the headers
+	RecipientKindBcc string = "bcc"
+)
+
+// Recipient address that receives a notification and how it receives it
+type Recipient struct {
+	Address string `json:"address"`
+	Kind    string `json:"kind"`
+}
+
+// RecipientResult outcome of one recipient of a notification sent to several recipients
+type RecipientResult struct {
+	Recipient
+	Sent          bool       `json:"sent"`
+	Reason        string     `json:"reason,omitempty"`
+	NextAllowedAt *time.Time `json:"next_allowed_at,omitempty"`
+	// MessageID identifier of the email that was sent to the recipient
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// HasRecipientList check if the notification is sent to the lists to, cc and bcc instead of one recipient
+func (n Notification) HasRecipientList() bool {
+	return len(n.To) > 0 || len(n.Cc) > 0 || len(n.Bcc) > 0
+}
+
+// Recipients every recipient of the notification, in the order of to, cc and bcc
+func (n Notification) Recipients() []Recipient {
+	if !n.HasRecipientList() {
+		return []Recipient{{Address: n.Recipient, Kind: RecipientKindTo}}
+	}
+
+	recipients := make([]Recipient, 0, len(n.To)+len(n.Cc)+len(n.Bcc))
+
+	for _, list := range []struct {
+		kind      string
+		addresses []string
+	}{
+		{RecipientKindTo, n.To},
+		{RecipientKindCc, n.Cc},
+		{RecipientKindBcc, n.Bcc},
+	} {
+		for _, address := range list.addresses {
+			recipients = append(recipients, Recipient{Address: address, Kind: list.kind})
+		}
+	}
+
+	return recipients
+}
+
+// recipientCount number of recipients of all the notifications of the request
+func (b RequestBody) recipientCount() int {
+	count := 0
+
+	for _, notification := range b.Notifications {
+		if notification.HasRecipientList() {
+			count += len(notification.To) + len(notification.Cc) + len(notification.Bcc)
+		} else {
+			count++
+		}
+	}
+
+	return count
+}
+
+// WithRecipients copy of the notification sent only to the given recipients, a notification with one recipient
+// is returned as it is
+func (n Notification) WithRecipients(recipients []Recipient) Notification {
+	if !n.HasRecipientList() {
+		return n
+	}
+
+	n.To, n.Cc, n.Bcc = addRecipients(nil, nil, nil, recipients)
+
+	return n
+}
+
+// AddRecipients add the recipients to the destinations of the email
+func (e *Email) AddRecipients(recipients []Recipient) {
+	e.To, e.Cc, e.Bcc = addRecipients(e.To, e.Cc, e.Bcc, recipients)
+}
+
+// DestinationCount number of addresses that receive the email
+func (e Email) DestinationCount() int {
+	return len(e.To) + len(e.Cc) + len(e.Bcc)
+}
+
+// addRecipients add the address of each recipient to the list of its kind
+func addRecipients(to, cc, bcc []string, recipients []Recipient) ([]string, []string, []string) {
+	for _, recipient := range recipients {
+		switch recipient.Kind {
+		case RecipientKindCc:
+			cc = append(cc, recipient.Address)
+		case RecipientKindBcc:
+			bcc = append(bcc, recipient.Address)
+		default:
+			to = append(to, recipient.Address)
+		}
+	}
+
+	return to, cc, bcc
+}
+
+// RecipientBatches split the recipients in groups that fit in one email, in their order
+func RecipientBatches(recipients []Recipient) [][]Recipient {
+	batches := make([][]Recipient, 0, (len(recipients)+MaxEmailDestinations-1)/MaxEmailDestinations)
+
+	for start := 0; start < len(recipients); start += MaxEmailDestinations {
+		end := start + MaxEmailDestinations
+		if end > len(recipients) {
+			end = len(recipients)
+		}
+
+		batches = append(batches, recipients[start:end])
+	}
+
+	return batches
+}
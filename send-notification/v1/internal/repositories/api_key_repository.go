@@ -0,0 +1,105 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// apiKeyKeyPrefix prefix of the partition key of the API keys, followed by the hash of the key
+const apiKeyKeyPrefix = "KEY#"
+
+// APIKeyRepository struct for this repository
+type APIKeyRepository struct {
+	client    infraestructure.DynamoAPI
+	tableName string
+}
+
+// GetByHash get the API key with the hash, nil when no key has it
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*internal.APIKey, error) {
+	result, err := r.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String(apiKeyKeyPrefix + hash)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var apiKey internal.APIKey
+
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// Save create or replace an API key
+func (r *APIKeyRepository) Save(ctx context.Context, apiKey internal.APIKey) error {
+	apiKey.PK = apiKeyKeyPrefix + apiKey.Hash
+
+	item, err := dynamodbattribute.MarshalMap(apiKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+// List get every API key
+func (r *APIKeyRepository) List(ctx context.Context) ([]internal.APIKey, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	}
+
+	apiKeys := []internal.APIKey{}
+
+	for {
+		result, err := r.client.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []internal.APIKey
+
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, err
+		}
+
+		apiKeys = append(apiKeys, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return apiKeys, nil
+		}
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// NewAPIKeyRepository instance of a new repository
+func NewAPIKeyRepository(
+	client infraestructure.DynamoAPI,
+	tableName string,
+) *APIKeyRepository {
+	return &APIKeyRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
@@ -0,0 +1,158 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// TestAPIKeyRepository_GetByHash test for this method
+func TestAPIKeyRepository_GetByHash(t *testing.T) {
+	apiKey := internal.APIKey{
+		PK:           "KEY#hash-1",
+		Hash:         "hash-1",
+		ID:           "0123456789abcdef",
+		Tenant:       "acme",
+		AllowedTypes: []string{"News"},
+	}
+
+	item, _ := dynamodbattribute.MarshalMap(apiKey)
+
+	tests := []struct {
+		name    string
+		mock    *mockDynamoAPI
+		want    *internal.APIKey
+		wantErr bool
+	}{
+		{
+			name: "key found",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					if aws.StringValue(input.Key["pk"].S) != "KEY#hash-1" {
+						return nil, errors.New("unexpected key")
+					}
+
+					return &dynamodb.GetItemOutput{Item: item}, nil
+				},
+			},
+			want: &apiKey,
+		},
+		{
+			name: "key not found",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{}, nil
+				},
+			},
+		},
+		{
+			name: "error fetching data",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return nil, errors.New("error fetching data")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewAPIKeyRepository(tt.mock, "api-keys")
+			got, err := r.GetByHash(context.Background(), "hash-1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetByHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetByHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAPIKeyRepository_Save test for this method
+func TestAPIKeyRepository_Save(t *testing.T) {
+	var got map[string]*dynamodb.AttributeValue
+
+	r := NewAPIKeyRepository(&mockDynamoAPI{
+		PutItemFunc: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			got = input.Item
+
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}, "api-keys")
+
+	err := r.Save(context.Background(), internal.APIKey{Hash: "hash-1", ID: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if aws.StringValue(got["pk"].S) != "KEY#hash-1" || aws.StringValue(got["hash"].S) != "hash-1" {
+		t.Errorf("Save() item = %v", got)
+	}
+}
+
+// TestAPIKeyRepository_List test for this method
+func TestAPIKeyRepository_List(t *testing.T) {
+	first, _ := dynamodbattribute.MarshalMap(internal.APIKey{PK: "KEY#hash-1", Hash: "hash-1", ID: "1"})
+	second, _ := dynamodbattribute.MarshalMap(internal.APIKey{PK: "KEY#hash-2", Hash: "hash-2", ID: "2"})
+
+	tests := []struct {
+		name    string
+		mock    *mockDynamoAPI
+		want    []internal.APIKey
+		wantErr bool
+	}{
+		{
+			name: "every page",
+			mock: &mockDynamoAPI{
+				ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+					if input.ExclusiveStartKey == nil {
+						return &dynamodb.ScanOutput{
+							Items:            []map[string]*dynamodb.AttributeValue{first},
+							LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("KEY#hash-1")}},
+						}, nil
+					}
+
+					return &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{second}}, nil
+				},
+			},
+			want: []internal.APIKey{
+				{PK: "KEY#hash-1", Hash: "hash-1", ID: "1"},
+				{PK: "KEY#hash-2", Hash: "hash-2", ID: "2"},
+			},
+		},
+		{
+			name: "error on scan",
+			mock: &mockDynamoAPI{
+				ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+					return nil, errors.New("error on scan")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewAPIKeyRepository(tt.mock, "api-keys")
+			got, err := r.List(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("List() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// rateLimitRulesRepository methods of the rules repositories decorated by CachedRateLimitRulesRepository
+type rateLimitRulesRepository interface {
+	GetByType(ctx context.Context, notificationType string) (*internal.RateLimitRule, error)
+	GetByTypes(ctx context.Context, notificationTypes []string) (map[string]internal.RateLimitRule, error)
+	List(ctx context.Context) ([]internal.RateLimitRule, error)
+	Create(ctx context.Context, rule internal.RateLimitRule) error
+	Update(ctx context.Context, rule internal.RateLimitRule, previous internal.RateLimitRuleHistory) error
+	Delete(ctx context.Context, previous internal.RateLimitRuleHistory) error
+	History(ctx context.Context, notificationType string) ([]internal.RateLimitRuleHistory, error)
+}
+
+// CachedRateLimitRulesRepository keep in memory the rules read by type so every notification of a request
+// does not read the rules table again. Types without rule are cached too, with their own TTL, and concurrent
+// misses of the same type share a single read. Only the reads by type are cached, the other methods go to the repository
+type CachedRateLimitRulesRepository struct {
+	repository  rateLimitRulesRepository
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]cachedRule
+	generation uint64
+	group      singleflight.Group
+}
+
+// cachedRule rule of a type read from the repository, nil when the type has no rule
+type cachedRule struct {
+	rule      *internal.RateLimitRule
+	expiresAt time.Time
+}
+
+// GetByType get the rule of a type from the cache, it is read from the repository when it is missing or expired
+func (r *CachedRateLimitRulesRepository) GetByType(
+	ctx context.Context,
+	notificationType string,
+) (*internal.RateLimitRule, error) {
+	if r.ttl <= 0 && r.negativeTTL <= 0 {
+		return r.repository.GetByType(ctx, notificationType)
+	}
+
+	r.mu.Lock()
+	entry, ok := r.entries[notificationType]
+	generation := r.generation
+	r.mu.Unlock()
+
+	if ok && r.now().Before(entry.expiresAt) {
+		return copyRule(entry.rule), nil
+	}
+
+	// The shared read is not cancelled with the caller that started it, the other callers may still wait for it
+	readCtx := context.WithoutCancel(ctx)
+
+	results := r.group.DoChan(notificationType, func() (interface{}, error) {
+		rule, err := r.repository.GetByType(readCtx, notificationType)
+		if err != nil {
+			return nil, err
+		}
+
+		r.store(notificationType, rule, generation)
+
+		return rule, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		return copyRule(result.Val.(*internal.RateLimitRule)), nil
+	}
+}
+
+// GetByTypes get the rules of several types, the types that are not cached are read in one batch
+func (r *CachedRateLimitRulesRepository) GetByTypes(
+	ctx context.Context,
+	notificationTypes []string,
+) (map[string]internal.RateLimitRule, error) {
+	if r.ttl <= 0 && r.negativeTTL <= 0 {
+		return r.repository.GetByTypes(ctx, notificationTypes)
+	}
+
+	rules := map[string]internal.RateLimitRule{}
+
+	var misses []string
+
+	now := r.now()
+
+	r.mu.Lock()
+	generation := r.generation
+
+	for _, notificationType := range notificationTypes {
+		entry, ok := r.entries[notificationType]
+		if !ok || !now.Before(entry.expiresAt) {
+			if !containsString(misses, notificationType) {
+				misses = append(misses, notificationType)
+			}
+
+			continue
+		}
+
+		if entry.rule != nil {
+			rules[notificationType] = *copyRule(entry.rule)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(misses) == 0 {
+		return rules, nil
+	}
+
+	read, err := r.repository.GetByTypes(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, notificationType := range misses {
+		rule, ok := read[notificationType]
+		if !ok {
+			r.store(notificationType, nil, generation)
+
+			continue
+		}
+
+		r.store(notificationType, copyRule(&rule), generation)
+		rules[notificationType] = rule
+	}
+
+	return rules, nil
+}
+
+// List get every rule from the repository
+func (r *CachedRateLimitRulesRepository) List(ctx context.Context) ([]internal.RateLimitRule, error) {
+	return r.repository.List(ctx)
+}
+
+// Create save a new rule in the repository
+func (r *CachedRateLimitRulesRepository) Create(ctx context.Context, rule internal.RateLimitRule) error {
+	return r.repository.Create(ctx, rule)
+}
+
+// Update replace the previous version of a rule in the repository
+func (r *CachedRateLimitRulesRepository) Update(
+	ctx context.Context,
+	rule internal.RateLimitRule,
+	previous internal.RateLimitRuleHistory,
+) error {
+	return r.repository.Update(ctx, rule, previous)
+}
+
+// Delete remove the previous version of a rule from the repository
+func (r *CachedRateLimitRulesRepository) Delete(ctx context.Context, previous internal.RateLimitRuleHistory) error {
+	return r.repository.Delete(ctx, previous)
+}
+
+// History get the previous versions of the rule of a type from the repository
+func (r *CachedRateLimitRulesRepository) History(
+	ctx context.Context,
+	notificationType string,
+) ([]internal.RateLimitRuleHistory, error) {
+	return r.repository.History(ctx, notificationType)
+}
+
+// Invalidate remove the cached rule of a type, the next read goes to the repository.
+// The reads that were in flight when the type was invalidated do not fill the cache
+func (r *CachedRateLimitRulesRepository) Invalidate(notificationType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries, notificationType)
+	r.generation++
+	r.group.Forget(notificationType)
+}
+
+// store cache the rule read from the repository unless the cache was invalidated since the read started
+func (r *CachedRateLimitRulesRepository) store(
+	notificationType string,
+	rule *internal.RateLimitRule,
+	generation uint64,
+) {
+	ttl := r.ttl
+	if rule == nil {
+		ttl = r.negativeTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ttl <= 0 || generation != r.generation {
+		return
+	}
+
+	r.entries[notificationType] = cachedRule{
+		rule:      rule,
+		expiresAt: r.now().Add(ttl),
+	}
+}
+
+// copyRule copy of a cached rule so the callers can change it
+func copyRule(rule *internal.RateLimitRule) *internal.RateLimitRule {
+	if rule == nil {
+		return nil
+	}
+
+	copied := *rule
+	if rule.QuietHours != nil {
+		quietHours := *rule.QuietHours
+		copied.QuietHours = &quietHours
+	}
+
+	if rule.Sender != nil {
+		sender := *rule.Sender
+		copied.Sender = &sender
+	}
+
+	if rule.Priority != nil {
+		priority := *rule.Priority
+		copied.Priority = &priority
+	}
+
+	return &copied
+}
+
+// NewCachedRateLimitRulesRepository instance of a new repository that caches the rules of the given one,
+// ttl is how long a rule is kept and negativeTTL how long a type without rule is kept, zero disables each cache
+func NewCachedRateLimitRulesRepository(
+	repository rateLimitRulesRepository,
+	ttl, negativeTTL time.Duration,
+) *CachedRateLimitRulesRepository {
+	return &CachedRateLimitRulesRepository{
+		repository:  repository,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     map[string]cachedRule{},
+	}
+}
@@ -0,0 +1,351 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRulesRepository memory rules repository that counts the reads by type and can block them
+type countingRulesRepository struct {
+	*MemoryRateLimitRulesRepository
+	reads      int32
+	batchReads [][]string
+	release    chan struct{}
+	err        error
+}
+
+// GetByTypes record the types of the batch read
+func (r *countingRulesRepository) GetByTypes(
+	ctx context.Context,
+	notificationTypes []string,
+) (map[string]internal.RateLimitRule, error) {
+	r.batchReads = append(r.batchReads, notificationTypes)
+
+	return r.MemoryRateLimitRulesRepository.GetByTypes(ctx, notificationTypes)
+}
+
+// GetByType count the read and wait for the release when there is one
+func (r *countingRulesRepository) GetByType(
+	ctx context.Context,
+	notificationType string,
+) (*internal.RateLimitRule, error) {
+	atomic.AddInt32(&r.reads, 1)
+
+	if r.release != nil {
+		<-r.release
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return r.MemoryRateLimitRulesRepository.GetByType(ctx, notificationType)
+}
+
+// newCountingRulesRepository repository with a News rule
+func newCountingRulesRepository(t *testing.T) *countingRulesRepository {
+	store, err := NewMemoryStore("")
+	require.NoError(t, err)
+
+	repository := &countingRulesRepository{MemoryRateLimitRulesRepository: NewMemoryRateLimitRulesRepository(store)}
+	require.NoError(t, repository.Create(
+		context.Background(),
+		internal.RateLimitRule{Type: "News", NotificationsLimit: 1, Version: 1},
+	))
+
+	return repository
+}
+
+// TestCachedRateLimitRulesRepository_GetByType test for this method
+func TestCachedRateLimitRulesRepository_GetByType(t *testing.T) {
+	tests := []struct {
+		name             string
+		notificationType string
+		ttl              time.Duration
+		negativeTTL      time.Duration
+		elapsed          time.Duration
+		wantReads        int32
+	}{
+		{
+			name:             "rule is cached",
+			notificationType: "News",
+			ttl:              time.Minute,
+			elapsed:          30 * time.Second,
+			wantReads:        1,
+		},
+		{
+			name:             "expired rule is read again",
+			notificationType: "News",
+			ttl:              time.Minute,
+			elapsed:          time.Minute,
+			wantReads:        2,
+		},
+		{
+			name:             "type without rule is cached",
+			notificationType: "Unknown",
+			negativeTTL:      time.Minute,
+			elapsed:          30 * time.Second,
+			wantReads:        1,
+		},
+		{
+			name:             "type without rule uses the negative TTL",
+			notificationType: "Unknown",
+			ttl:              time.Hour,
+			negativeTTL:      time.Minute,
+			elapsed:          2 * time.Minute,
+			wantReads:        2,
+		},
+		{
+			name:             "zero TTL disables the cache",
+			notificationType: "News",
+			wantReads:        2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newCountingRulesRepository(t)
+			now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+			cached := NewCachedRateLimitRulesRepository(repository, tt.ttl, tt.negativeTTL)
+			cached.now = func() time.Time { return now }
+
+			first, err := cached.GetByType(context.Background(), tt.notificationType)
+			require.NoError(t, err)
+
+			now = now.Add(tt.elapsed)
+
+			second, err := cached.GetByType(context.Background(), tt.notificationType)
+			require.NoError(t, err)
+
+			assert.Equal(t, first, second)
+			assert.Equal(t, tt.wantReads, atomic.LoadInt32(&repository.reads))
+		})
+	}
+}
+
+// TestCachedRateLimitRulesRepository_GetByTypes test that only the types that are not cached are read in one batch
+func TestCachedRateLimitRulesRepository_GetByTypes(t *testing.T) {
+	repository := newCountingRulesRepository(t)
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+
+	_, err := cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+
+	rules, err := cached.GetByTypes(context.Background(), []string{"News", "Unknown", "Unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"News"}, keys(rules))
+
+	rules, err = cached.GetByTypes(context.Background(), []string{"News", "Unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"News"}, keys(rules))
+
+	assert.Equal(t, [][]string{{"Unknown"}}, repository.batchReads)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&repository.reads))
+}
+
+// keys types of the rules
+func keys(rules map[string]internal.RateLimitRule) []string {
+	types := []string{}
+	for notificationType := range rules {
+		types = append(types, notificationType)
+	}
+
+	return types
+}
+
+// TestCachedRateLimitRulesRepository_Staleness test that the cached rule is served until it expires or
+// the type is invalidated
+func TestCachedRateLimitRulesRepository_Staleness(t *testing.T) {
+	repository := newCountingRulesRepository(t)
+	now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+	cached.now = func() time.Time { return now }
+
+	rule, err := cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rule.NotificationsLimit)
+
+	// Changes made by other writers are not seen before the TTL
+	updated := internal.RateLimitRule{Type: "News", NotificationsLimit: 5, Version: 2}
+	require.NoError(t, repository.Update(
+		context.Background(),
+		updated,
+		internal.RateLimitRuleHistory{RateLimitRule: *rule},
+	))
+
+	rule, err = cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rule.NotificationsLimit)
+
+	now = now.Add(time.Minute)
+
+	rule, err = cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.Equal(t, 5, rule.NotificationsLimit)
+
+	// Invalidating the type discards the cached rule before the TTL
+	require.NoError(t, repository.Delete(context.Background(), internal.RateLimitRuleHistory{RateLimitRule: updated}))
+	cached.Invalidate("News")
+
+	rule, err = cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.Nil(t, rule)
+
+	// The callers get copies, changing them does not change the cache
+	require.NoError(t, repository.Create(context.Background(), internal.RateLimitRule{
+		Type:       "Status",
+		QuietHours: &internal.QuietHours{Start: "22:00", End: "07:00"},
+		Sender:     &internal.Sender{FromAddress: "status@example.com"},
+		Priority:   &internal.PriorityPolicy{Default: internal.PriorityLow, Borrow: 2},
+	}))
+
+	rule, err = cached.GetByType(context.Background(), "Status")
+	require.NoError(t, err)
+	rule.QuietHours.Start = "00:00"
+	rule.Sender.FromAddress = "changed@example.com"
+	rule.Priority.Default = internal.PriorityHigh
+	rule.Priority.Borrow = 0
+
+	rule, err = cached.GetByType(context.Background(), "Status")
+	require.NoError(t, err)
+	assert.Equal(t, "22:00", rule.QuietHours.Start)
+	assert.Equal(t, "status@example.com", rule.Sender.FromAddress)
+	assert.Equal(t, internal.PriorityPolicy{Default: internal.PriorityLow, Borrow: 2}, *rule.Priority)
+}
+
+// TestCachedRateLimitRulesRepository_ConcurrentMisses test that concurrent misses of a type share a single read
+func TestCachedRateLimitRulesRepository_ConcurrentMisses(t *testing.T) {
+	const callers = 20
+
+	repository := newCountingRulesRepository(t)
+	repository.release = make(chan struct{})
+
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+
+	var started, done sync.WaitGroup
+
+	started.Add(callers)
+	done.Add(callers)
+
+	rules := make([]*internal.RateLimitRule, callers)
+
+	for i := 0; i < callers; i++ {
+		i := i
+		go func() {
+			defer done.Done()
+
+			started.Done()
+
+			rule, err := cached.GetByType(context.Background(), "News")
+			assert.NoError(t, err)
+
+			rules[i] = rule
+		}()
+	}
+
+	started.Wait()
+
+	// Wait for the first read before releasing it so the other callers join it
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&repository.reads) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(repository.release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&repository.reads))
+
+	for _, rule := range rules {
+		if assert.NotNil(t, rule) {
+			assert.Equal(t, 1, rule.NotificationsLimit)
+		}
+	}
+}
+
+// TestCachedRateLimitRulesRepository_Errors test that the errors are not cached
+func TestCachedRateLimitRulesRepository_Errors(t *testing.T) {
+	repository := newCountingRulesRepository(t)
+	repository.err = errors.New("database error")
+
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+
+	_, err := cached.GetByType(context.Background(), "News")
+	assert.Error(t, err)
+
+	repository.err = nil
+
+	rule, err := cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.NotNil(t, rule)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&repository.reads))
+}
+
+// TestCachedRateLimitRulesRepository_InvalidateDuringRead test that a read in flight when the type is invalidated
+// does not fill the cache with the old rule
+func TestCachedRateLimitRulesRepository_InvalidateDuringRead(t *testing.T) {
+	repository := newCountingRulesRepository(t)
+	repository.release = make(chan struct{})
+
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, err := cached.GetByType(context.Background(), "News")
+		assert.NoError(t, err)
+	}()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&repository.reads) == 1 }, time.Second, time.Millisecond)
+	cached.Invalidate("News")
+	close(repository.release)
+	<-done
+
+	_, err := cached.GetByType(context.Background(), "News")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&repository.reads))
+}
+
+// TestCachedRateLimitRulesRepository_Cancelled test that a caller stops waiting for a read when its context is
+// cancelled, the read is not cancelled and still fills the cache
+func TestCachedRateLimitRulesRepository_Cancelled(t *testing.T) {
+	repository := newCountingRulesRepository(t)
+	repository.release = make(chan struct{})
+
+	cached := NewCachedRateLimitRulesRepository(repository, time.Minute, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, err := cached.GetByType(ctx, "News")
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&repository.reads) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	close(repository.release)
+
+	assert.Eventually(t, func() bool {
+		rule, err := cached.GetByType(context.Background(), "News")
+
+		return err == nil && rule != nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&repository.reads))
+}
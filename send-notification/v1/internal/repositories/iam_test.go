@@ -0,0 +1,174 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+// serverlessPath serverless.yml of the service, relative to this package
+const serverlessPath = "../../../serverless.yml"
+
+// iamStatement statement of the role of the functions in serverless.yml
+type iamStatement struct {
+	Effect   string        `json:"Effect"`
+	Action   []string      `json:"Action"`
+	Resource []interface{} `json:"Resource"`
+}
+
+// TestRepositories_IAMActions test that the role of the functions allows every DynamoDB call of the repositories
+// on their tables, a call without its action fails with AccessDenied once deployed
+func TestRepositories_IAMActions(t *testing.T) {
+	// Tables of each repository, a new repository with DynamoDB calls has to be added here
+	tables := map[string][]string{
+		"api_key_repository.go":               {"NotificationAPIKeys"},
+		"rate_limit_cache_repository.go":      {"NotificationRateLimitCache"},
+		"rate_limit_rules_repository.go":      {"NotificationRateLimitRules", "NotificationRateLimitRulesHistory"},
+		"recipient_preferences_repository.go": {"NotificationRecipientPreferences"},
+		"recipient_profile_repository.go":     {"NotificationRecipientProfiles"},
+		"suppression_repository.go":           {"NotificationSuppressionList"},
+	}
+
+	// ratelimitctl creates and lists the API keys with the credentials of the operator, not with the role
+	operatorOnly := map[string]bool{
+		"NotificationAPIKeys dynamodb:PutItem": true,
+		"NotificationAPIKeys dynamodb:Scan":    true,
+	}
+
+	allowed := serverlessIAMActions(t)
+
+	files, err := filepath.Glob("*_repository.go")
+	assert.NoError(t, err)
+
+	for _, file := range files {
+		actions := dynamoActions(t, file)
+		if len(actions) == 0 {
+			continue
+		}
+
+		fileTables, ok := tables[file]
+		if !assert.True(t, ok, "tables of %s", file) {
+			continue
+		}
+
+		for _, action := range actions {
+			granted := false
+
+			for _, table := range fileTables {
+				granted = granted || allowed[table][action] || operatorOnly[table+" "+action]
+			}
+
+			assert.True(t, granted, "%s calls %s, not allowed on %v in serverless.yml", file, action, fileTables)
+		}
+	}
+}
+
+// serverlessIAMActions actions allowed on each table by the role of the functions
+func serverlessIAMActions(t *testing.T) map[string]map[string]bool {
+	content, err := os.ReadFile(serverlessPath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var serverless struct {
+		Provider struct {
+			IAMRoleStatements []iamStatement `json:"iamRoleStatements"`
+		} `json:"provider"`
+	}
+
+	if !assert.NoError(t, yaml.Unmarshal(content, &serverless)) {
+		t.FailNow()
+	}
+
+	allowed := map[string]map[string]bool{}
+
+	for _, statement := range serverless.Provider.IAMRoleStatements {
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		for _, resource := range statement.Resource {
+			arn, _ := resource.(string)
+
+			_, table, found := strings.Cut(arn, ":table/")
+			if !found {
+				continue
+			}
+
+			if allowed[table] == nil {
+				allowed[table] = map[string]bool{}
+			}
+
+			for _, action := range statement.Action {
+				allowed[table][action] = true
+			}
+		}
+	}
+
+	return allowed
+}
+
+// dynamoActions IAM actions of the DynamoDB calls of a repository file, the writes of a transaction need the
+// actions of each of its items
+func dynamoActions(t *testing.T, file string) []string {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	transactItems := map[string]string{
+		"Put":            "PutItem",
+		"Delete":         "DeleteItem",
+		"Update":         "UpdateItem",
+		"ConditionCheck": "ConditionCheckItem",
+	}
+
+	found := map[string]bool{}
+
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpr:
+			// r.client.<Operation>WithContext(...)
+			call, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok || !strings.HasSuffix(call.Sel.Name, "WithContext") {
+				return true
+			}
+
+			client, ok := call.X.(*ast.SelectorExpr)
+			if !ok || client.Sel.Name != "client" {
+				return true
+			}
+
+			if operation := strings.TrimSuffix(call.Sel.Name, "WithContext"); operation != "TransactWriteItems" {
+				found["dynamodb:"+operation] = true
+			}
+		case *ast.CompositeLit:
+			// dynamodb.Put{...} and the other items of a transaction
+			item, ok := node.Type.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			if pkg, ok := item.X.(*ast.Ident); ok && pkg.Name == "dynamodb" && transactItems[item.Sel.Name] != "" {
+				found["dynamodb:"+transactItems[item.Sel.Name]] = true
+			}
+		}
+
+		return true
+	})
+
+	actions := make([]string, 0, len(found))
+	for action := range found {
+		actions = append(actions, action)
+	}
+
+	return actions
+}
@@ -0,0 +1,575 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// MemoryRateLimitRulesRepository rules repository backed by a MemoryStore
+type MemoryRateLimitRulesRepository struct {
+	store *MemoryStore
+}
+
+// GetByType get the rule of a type, nil when the type has no rule
+func (r *MemoryRateLimitRulesRepository) GetByType(
+	ctx context.Context,
+	notificationType string,
+) (*internal.RateLimitRule, error) {
+	var rule *internal.RateLimitRule
+
+	r.store.read(func(data *memoryStoreData) {
+		if stored, ok := data.Rules[notificationType]; ok {
+			stored.PK = internal.RuleKeyPrefix + notificationType
+			stored.Type = notificationType
+			rule = &stored
+		}
+	})
+
+	return rule, nil
+}
+
+// GetByTypes get the rules of several types, the types without rule are not in the result
+func (r *MemoryRateLimitRulesRepository) GetByTypes(
+	ctx context.Context,
+	notificationTypes []string,
+) (map[string]internal.RateLimitRule, error) {
+	rules := map[string]internal.RateLimitRule{}
+
+	r.store.read(func(data *memoryStoreData) {
+		for _, notificationType := range notificationTypes {
+			if stored, ok := data.Rules[notificationType]; ok {
+				stored.PK = internal.RuleKeyPrefix + notificationType
+				stored.Type = notificationType
+				rules[notificationType] = stored
+			}
+		}
+	})
+
+	return rules, nil
+}
+
+// List get every rule sorted by type
+func (r *MemoryRateLimitRulesRepository) List(ctx context.Context) ([]internal.RateLimitRule, error) {
+	rules := []internal.RateLimitRule{}
+
+	r.store.read(func(data *memoryStoreData) {
+		for notificationType, rule := range data.Rules {
+			rule.PK = internal.RuleKeyPrefix + notificationType
+			rule.Type = notificationType
+			rules = append(rules, rule)
+		}
+	})
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Type < rules[j].Type })
+
+	return rules, nil
+}
+
+// Create save a new rule, internal.ErrRuleVersionConflict is returned when the type already has a rule
+func (r *MemoryRateLimitRulesRepository) Create(ctx context.Context, rule internal.RateLimitRule) error {
+	return r.store.write(func(data *memoryStoreData) error {
+		if _, ok := data.Rules[rule.Type]; ok {
+			return internal.ErrRuleVersionConflict
+		}
+
+		data.Rules[rule.Type] = rule
+
+		return nil
+	})
+}
+
+// Update replace the previous version of a rule and keep it in the history,
+// internal.ErrRuleVersionConflict is returned when the stored rule is not the previous version anymore
+func (r *MemoryRateLimitRulesRepository) Update(
+	ctx context.Context,
+	rule internal.RateLimitRule,
+	previous internal.RateLimitRuleHistory,
+) error {
+	return r.store.write(func(data *memoryStoreData) error {
+		current, ok := data.Rules[rule.Type]
+		if !ok || current.Version != previous.Version {
+			return internal.ErrRuleVersionConflict
+		}
+
+		data.Rules[rule.Type] = rule
+		data.RulesHistory[rule.Type] = append(data.RulesHistory[rule.Type], previous)
+
+		return nil
+	})
+}
+
+// Delete remove the previous version of a rule and keep it in the history,
+// internal.ErrRuleVersionConflict is returned when the stored rule is not the previous version anymore
+func (r *MemoryRateLimitRulesRepository) Delete(ctx context.Context, previous internal.RateLimitRuleHistory) error {
+	return r.store.write(func(data *memoryStoreData) error {
+		current, ok := data.Rules[previous.Type]
+		if !ok || current.Version != previous.Version {
+			return internal.ErrRuleVersionConflict
+		}
+
+		delete(data.Rules, previous.Type)
+		data.RulesHistory[previous.Type] = append(data.RulesHistory[previous.Type], previous)
+
+		return nil
+	})
+}
+
+// History get the previous versions of the rule of a type, the newest first
+func (r *MemoryRateLimitRulesRepository) History(
+	ctx context.Context,
+	notificationType string,
+) ([]internal.RateLimitRuleHistory, error) {
+	history := []internal.RateLimitRuleHistory{}
+
+	r.store.read(func(data *memoryStoreData) {
+		versions := data.RulesHistory[notificationType]
+		for i := len(versions) - 1; i >= 0; i-- {
+			history = append(history, versions[i])
+		}
+	})
+
+	return history, nil
+}
+
+// NewMemoryRateLimitRulesRepository instance of a new repository
+func NewMemoryRateLimitRulesRepository(store *MemoryStore) *MemoryRateLimitRulesRepository {
+	return &MemoryRateLimitRulesRepository{store: store}
+}
+
+// MemoryRateLimitCacheRepository cache repository backed by a MemoryStore
+type MemoryRateLimitCacheRepository struct {
+	store *MemoryStore
+	now   func() time.Time
+}
+
+// SetNotificationSentTimestamp save that this user was notified in that timestamp, expired notifications are purged
+func (r *MemoryRateLimitCacheRepository) SetNotificationSentTimestamp(
+	ctx context.Context,
+	notificationType, email, timestamp, uuid string,
+	ttl int64,
+) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp '%s': %w", timestamp, err)
+	}
+
+	partitionKey := fmt.Sprintf("%s#%s", notificationType, email)
+
+	return r.store.write(func(data *memoryStoreData) error {
+		now := r.now().Unix()
+		notifications := data.Notifications[partitionKey][:0]
+
+		for _, notification := range data.Notifications[partitionKey] {
+			if notification.TTL > now {
+				notifications = append(notifications, notification)
+			}
+		}
+
+		data.Notifications[partitionKey] = append(notifications, memoryNotification{
+			Timestamp: sentAt,
+			UUID:      uuid,
+			TTL:       ttl,
+		})
+
+		return nil
+	})
+}
+
+// CountNotificationsWithinInterval check the number of notifications that one user had since the start of the window,
+// it stops as soon as the count reaches the given limit, a limit lower or equal than zero counts every notification
+func (r *MemoryRateLimitCacheRepository) CountNotificationsWithinInterval(
+	ctx context.Context,
+	notificationType, email string,
+	windowStart time.Time,
+	limit int,
+) (int, error) {
+	count := 0
+
+	r.store.read(func(data *memoryStoreData) {
+		for _, notification := range data.Notifications[fmt.Sprintf("%s#%s", notificationType, email)] {
+			if notification.Timestamp < windowStart.Unix() {
+				continue
+			}
+
+			count++
+
+			if limit > 0 && count >= limit {
+				return
+			}
+		}
+	})
+
+	return count, nil
+}
+
+// CountNotificationsGrouped count the notifications of several partitions, keyed by the partition key of the queries
+func (r *MemoryRateLimitCacheRepository) CountNotificationsGrouped(
+	ctx context.Context,
+	queries []internal.NotificationCountQuery,
+) (map[string]int, error) {
+	counts := make(map[string]int, len(queries))
+
+	for _, query := range queries {
+		if _, ok := counts[query.PartitionKey()]; ok {
+			continue
+		}
+
+		count, err := r.CountNotificationsWithinInterval(ctx, query.Type, query.Email, query.WindowStart, query.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[query.PartitionKey()] = count
+	}
+
+	return counts, nil
+}
+
+// DeleteNotifications remove every notification of one type sent to one user, it returns how many were removed
+func (r *MemoryRateLimitCacheRepository) DeleteNotifications(
+	ctx context.Context, notificationType,
+	email string,
+) (int, error) {
+	partitionKey := fmt.Sprintf("%s#%s", notificationType, email)
+	deleted := 0
+
+	err := r.store.write(func(data *memoryStoreData) error {
+		deleted = len(data.Notifications[partitionKey])
+		delete(data.Notifications, partitionKey)
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// DeleteNotification remove one notification sent to one user, the one saved with the timestamp and the uuid
+func (r *MemoryRateLimitCacheRepository) DeleteNotification(
+	ctx context.Context,
+	notificationType, email, timestamp, uuid string,
+) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp '%s': %w", timestamp, err)
+	}
+
+	partitionKey := fmt.Sprintf("%s#%s", notificationType, email)
+
+	return r.store.write(func(data *memoryStoreData) error {
+		notifications := data.Notifications[partitionKey][:0]
+
+		for _, notification := range data.Notifications[partitionKey] {
+			if notification.Timestamp != sentAt || notification.UUID != uuid {
+				notifications = append(notifications, notification)
+			}
+		}
+
+		data.Notifications[partitionKey] = notifications
+
+		return nil
+	})
+}
+
+// GetRecordedContents check which messages were already sent, the result has the content keys recorded whose
+// ttl is after now
+func (r *MemoryRateLimitCacheRepository) GetRecordedContents(
+	ctx context.Context,
+	contentKeys []string,
+	now int64,
+) (map[string]bool, error) {
+	recorded := map[string]bool{}
+
+	r.store.read(func(data *memoryStoreData) {
+		for _, contentKey := range contentKeys {
+			if data.Contents[contentKey] > now {
+				recorded[contentKey] = true
+			}
+		}
+	})
+
+	return recorded, nil
+}
+
+// RecordContent save that a message was sent until ttl, it returns false without saving it when the message was
+// already recorded and its ttl is after now. Expired messages are purged
+func (r *MemoryRateLimitCacheRepository) RecordContent(
+	ctx context.Context,
+	contentKey string,
+	now, ttl int64,
+) (bool, error) {
+	recorded := false
+
+	err := r.store.write(func(data *memoryStoreData) error {
+		if data.Contents[contentKey] > now {
+			return nil
+		}
+
+		for key, expiresAt := range data.Contents {
+			if expiresAt <= now {
+				delete(data.Contents, key)
+			}
+		}
+
+		data.Contents[contentKey] = ttl
+		recorded = true
+
+		return nil
+	})
+
+	return recorded, err
+}
+
+// DeleteContent remove a message recorded, so the same message can be sent again inside its dedup window
+func (r *MemoryRateLimitCacheRepository) DeleteContent(ctx context.Context, contentKey string) error {
+	return r.store.write(func(data *memoryStoreData) error {
+		delete(data.Contents, contentKey)
+
+		return nil
+	})
+}
+
+// NewMemoryRateLimitCacheRepository instance of a new repository
+func NewMemoryRateLimitCacheRepository(store *MemoryStore) *MemoryRateLimitCacheRepository {
+	return &MemoryRateLimitCacheRepository{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// MemoryRecipientProfileRepository recipient profile repository backed by a MemoryStore
+type MemoryRecipientProfileRepository struct {
+	store *MemoryStore
+}
+
+// GetByEmail get the profile of a recipient, nil when the recipient has no profile
+func (r *MemoryRecipientProfileRepository) GetByEmail(
+	ctx context.Context,
+	email string,
+) (*internal.RecipientProfile, error) {
+	var profile *internal.RecipientProfile
+
+	r.store.read(func(data *memoryStoreData) {
+		if stored, ok := data.Profiles[email]; ok {
+			profile = &stored
+		}
+	})
+
+	return profile, nil
+}
+
+// Save create or replace the profile of a recipient
+func (r *MemoryRecipientProfileRepository) Save(ctx context.Context, profile internal.RecipientProfile) error {
+	profile.PK = recipientKeyPrefix + profile.Email
+
+	return r.store.write(func(data *memoryStoreData) error {
+		data.Profiles[profile.Email] = profile
+
+		return nil
+	})
+}
+
+// NewMemoryRecipientProfileRepository instance of a new repository
+func NewMemoryRecipientProfileRepository(store *MemoryStore) *MemoryRecipientProfileRepository {
+	return &MemoryRecipientProfileRepository{store: store}
+}
+
+// MemoryRecipientPreferencesRepository recipient preferences repository backed by a MemoryStore
+type MemoryRecipientPreferencesRepository struct {
+	store *MemoryStore
+}
+
+// GetByEmail get the preferences of a recipient in a tenant, nil when the recipient has no preferences
+func (r *MemoryRecipientPreferencesRepository) GetByEmail(
+	ctx context.Context,
+	tenant string,
+	email string,
+) (*internal.RecipientPreferences, error) {
+	var preferences *internal.RecipientPreferences
+
+	r.store.read(func(data *memoryStoreData) {
+		if stored, ok := data.Preferences[internal.TenantType(tenant, email)]; ok {
+			stored.OptedOutTypes = append([]string(nil), stored.OptedOutTypes...)
+			stored.Channels = append([]string(nil), stored.Channels...)
+			preferences = &stored
+		}
+	})
+
+	return preferences, nil
+}
+
+// OptOut record that the recipient does not want to receive the given type of the tenant anymore,
+// an empty type unsubscribes the recipient from every notification of the tenant
+func (r *MemoryRecipientPreferencesRepository) OptOut(
+	ctx context.Context,
+	tenant string,
+	email string,
+	notificationType string,
+) error {
+	key := internal.TenantType(tenant, email)
+
+	return r.store.write(func(data *memoryStoreData) error {
+		preferences := data.Preferences[key]
+		preferences.PK = recipientKeyPrefix + key
+		preferences.Email = email
+
+		if notificationType == "" {
+			preferences.Unsubscribed = true
+		} else if !containsString(preferences.OptedOutTypes, notificationType) {
+			preferences.OptedOutTypes = append(preferences.OptedOutTypes, notificationType)
+		}
+
+		data.Preferences[key] = preferences
+
+		return nil
+	})
+}
+
+// NewMemoryRecipientPreferencesRepository instance of a new repository
+func NewMemoryRecipientPreferencesRepository(store *MemoryStore) *MemoryRecipientPreferencesRepository {
+	return &MemoryRecipientPreferencesRepository{store: store}
+}
+
+// MemorySuppressionRepository suppression repository backed by a MemoryStore, the addresses are normalized like in
+// the rate limit
+type MemorySuppressionRepository struct {
+	store      *MemoryStore
+	normalizer internal.RecipientNormalizer
+}
+
+// GetByEmail get the suppression of an address, nil when the address is not suppressed
+func (r *MemorySuppressionRepository) GetByEmail(ctx context.Context, email string) (*internal.Suppression, error) {
+	var suppression *internal.Suppression
+
+	r.store.read(func(data *memoryStoreData) {
+		if stored, ok := data.Suppressions[r.normalizer.Normalize(email)]; ok {
+			suppression = &stored
+		}
+	})
+
+	return suppression, nil
+}
+
+// Save add an address to the suppression list, an existing suppression is replaced
+func (r *MemorySuppressionRepository) Save(ctx context.Context, suppression internal.Suppression) error {
+	suppression.Email = r.normalizer.Normalize(suppression.Email)
+	suppression.PK = suppressionKeyPrefix + suppression.Email
+
+	return r.store.write(func(data *memoryStoreData) error {
+		data.Suppressions[suppression.Email] = suppression
+
+		return nil
+	})
+}
+
+// List get one page of suppressed addresses sorted by email starting after the cursor,
+// the returned cursor is empty in the last page
+func (r *MemorySuppressionRepository) List(
+	ctx context.Context,
+	limit int,
+	cursor string,
+) ([]internal.Suppression, string, error) {
+	suppressions := []internal.Suppression{}
+
+	r.store.read(func(data *memoryStoreData) {
+		for email, suppression := range data.Suppressions {
+			if email > cursor {
+				suppressions = append(suppressions, suppression)
+			}
+		}
+	})
+
+	sort.Slice(suppressions, func(i, j int) bool { return suppressions[i].Email < suppressions[j].Email })
+
+	if limit > 0 && len(suppressions) > limit {
+		suppressions = suppressions[:limit]
+
+		return suppressions, suppressions[limit-1].Email, nil
+	}
+
+	return suppressions, "", nil
+}
+
+// Delete remove an address from the suppression list
+func (r *MemorySuppressionRepository) Delete(ctx context.Context, email string) error {
+	return r.store.write(func(data *memoryStoreData) error {
+		delete(data.Suppressions, r.normalizer.Normalize(email))
+
+		return nil
+	})
+}
+
+// NewMemorySuppressionRepository instance of a new repository
+func NewMemorySuppressionRepository(
+	store *MemoryStore,
+	normalizer internal.RecipientNormalizer,
+) *MemorySuppressionRepository {
+	return &MemorySuppressionRepository{store: store, normalizer: normalizer}
+}
+
+// MemoryAPIKeyRepository API key repository backed by a MemoryStore
+type MemoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+// GetByHash get the API key with the hash, nil when no key has it
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*internal.APIKey, error) {
+	var apiKey *internal.APIKey
+
+	r.store.read(func(data *memoryStoreData) {
+		if stored, ok := data.APIKeys[hash]; ok {
+			stored.Hash = hash
+			apiKey = &stored
+		}
+	})
+
+	return apiKey, nil
+}
+
+// Save create or replace an API key
+func (r *MemoryAPIKeyRepository) Save(ctx context.Context, apiKey internal.APIKey) error {
+	apiKey.PK = apiKeyKeyPrefix + apiKey.Hash
+
+	return r.store.write(func(data *memoryStoreData) error {
+		data.APIKeys[apiKey.Hash] = apiKey
+
+		return nil
+	})
+}
+
+// List get every API key sorted by creation
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]internal.APIKey, error) {
+	apiKeys := []internal.APIKey{}
+
+	r.store.read(func(data *memoryStoreData) {
+		for hash, apiKey := range data.APIKeys {
+			apiKey.Hash = hash
+			apiKeys = append(apiKeys, apiKey)
+		}
+	})
+
+	sort.Slice(apiKeys, func(i, j int) bool { return apiKeys[i].CreatedAt.Before(apiKeys[j].CreatedAt) })
+
+	return apiKeys, nil
+}
+
+// NewMemoryAPIKeyRepository instance of a new repository
+func NewMemoryAPIKeyRepository(store *MemoryStore) *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{store: store}
+}
+
+// containsString check if the value is in the list
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,336 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemoryStore_File test that the state of the file store survives between instances
+func TestMemoryStore_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	store, err := NewMemoryStore(path)
+	assert.NoError(t, err)
+
+	rule := internal.RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 1440, Version: 1}
+	assert.NoError(t, NewMemoryRateLimitRulesRepository(store).Create(context.Background(), rule))
+	assert.NoError(t, NewMemoryRateLimitCacheRepository(store).SetNotificationSentTimestamp(
+		context.Background(),
+		"News", "test@example.com", "1700000000", "uuid", time.Now().Add(time.Hour).Unix(),
+	))
+
+	reopened, err := NewMemoryStore(path)
+	assert.NoError(t, err)
+
+	got, err := NewMemoryRateLimitRulesRepository(reopened).GetByType(context.Background(), "News")
+	assert.NoError(t, err)
+
+	rule.PK = "TYPE#News"
+	assert.Equal(t, &rule, got)
+
+	count, err := NewMemoryRateLimitCacheRepository(reopened).CountNotificationsWithinInterval(
+		context.Background(),
+		"News", "test@example.com", time.Unix(1700000000, 0), 0,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestMemoryRateLimitRulesRepository_Versions test for the optimistic concurrency of the writes
+func TestMemoryRateLimitRulesRepository_Versions(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	r := NewMemoryRateLimitRulesRepository(store)
+
+	v1 := internal.RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 60, Version: 1}
+	v2 := internal.RateLimitRule{Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60, Version: 2}
+
+	assert.NoError(t, r.Create(context.Background(), v1))
+	assert.True(t, errors.Is(r.Create(context.Background(), v1), internal.ErrRuleVersionConflict))
+
+	assert.NoError(t, r.Update(
+		context.Background(),
+		v2,
+		internal.RateLimitRuleHistory{RateLimitRule: v1, Operation: internal.RuleOperationUpdate},
+	))
+	assert.True(t, errors.Is(
+		r.Update(
+			context.Background(),
+			v2,
+			internal.RateLimitRuleHistory{RateLimitRule: v1, Operation: internal.RuleOperationUpdate},
+		),
+		internal.ErrRuleVersionConflict,
+	))
+
+	assert.True(t, errors.Is(
+		r.Delete(
+			context.Background(),
+			internal.RateLimitRuleHistory{RateLimitRule: v1, Operation: internal.RuleOperationDelete},
+		),
+		internal.ErrRuleVersionConflict,
+	))
+	assert.NoError(t, r.Delete(
+		context.Background(),
+		internal.RateLimitRuleHistory{RateLimitRule: v2, Operation: internal.RuleOperationDelete},
+	))
+
+	assert.NoError(t, r.Create(
+		context.Background(),
+		internal.RateLimitRule{Type: "Status", NotificationsLimit: 3, Version: 1},
+	))
+
+	byType, err := r.GetByTypes(context.Background(), []string{"News", "Status", "Status"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]internal.RateLimitRule{
+		"Status": {PK: "TYPE#Status", Type: "Status", NotificationsLimit: 3, Version: 1},
+	}, byType)
+
+	assert.NoError(t, r.Delete(context.Background(), internal.RateLimitRuleHistory{RateLimitRule: byType["Status"]}))
+
+	rules, _ := r.List(context.Background())
+	assert.Empty(t, rules)
+
+	history, _ := r.History(context.Background(), "News")
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, 2, history[0].Version)
+		assert.Equal(t, internal.RuleOperationDelete, history[0].Operation)
+		assert.Equal(t, 1, history[1].Version)
+	}
+}
+
+// TestMemoryRateLimitCacheRepository test for the counting and the reset of the notifications
+func TestMemoryRateLimitCacheRepository(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	r := NewMemoryRateLimitCacheRepository(store)
+	r.now = func() time.Time { return time.Unix(1700000100, 0) }
+
+	// The first notification already expired, it is purged on the next write
+	assert.NoError(t, r.SetNotificationSentTimestamp(
+		context.Background(),
+		"News",
+		"test@example.com",
+		"1700000000",
+		"a",
+		1700000050,
+	))
+
+	for i, timestamp := range []string{"1700000060", "1700000070", "1700000080"} {
+		assert.NoError(t, r.SetNotificationSentTimestamp(
+			context.Background(),
+			"News",
+			"test@example.com",
+			timestamp,
+			string(rune('b'+i)),
+			1700001000,
+		))
+	}
+
+	count, _ := r.CountNotificationsWithinInterval(
+		context.Background(),
+		"News",
+		"test@example.com",
+		time.Unix(1700000065, 0),
+		0,
+	)
+	assert.Equal(t, 2, count)
+
+	count, _ = r.CountNotificationsWithinInterval(
+		context.Background(),
+		"News",
+		"test@example.com",
+		time.Unix(1700000000, 0),
+		2,
+	)
+	assert.Equal(t, 2, count)
+
+	count, _ = r.CountNotificationsWithinInterval(
+		context.Background(),
+		"Status",
+		"test@example.com",
+		time.Unix(1700000000, 0),
+		0,
+	)
+	assert.Equal(t, 0, count)
+
+	counts, err := r.CountNotificationsGrouped(context.Background(), []internal.NotificationCountQuery{
+		{Type: "News", Email: "test@example.com", WindowStart: time.Unix(1700000065, 0)},
+		{Type: "Status", Email: "test@example.com", WindowStart: time.Unix(1700000000, 0)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"News#test@example.com": 2, "Status#test@example.com": 0}, counts)
+
+	// Only the notification with the timestamp and the uuid is removed
+	assert.NoError(t, r.DeleteNotification(context.Background(), "News", "test@example.com", "1700000060", "b"))
+	assert.NoError(t, r.DeleteNotification(context.Background(), "News", "test@example.com", "1700000060", "c"))
+
+	count, _ = r.CountNotificationsWithinInterval(
+		context.Background(),
+		"News",
+		"test@example.com",
+		time.Unix(1700000000, 0),
+		0,
+	)
+	assert.Equal(t, 2, count)
+
+	deleted, err := r.DeleteNotifications(context.Background(), "News", "test@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	count, _ = r.CountNotificationsWithinInterval(
+		context.Background(),
+		"News",
+		"test@example.com",
+		time.Unix(1700000000, 0),
+		0,
+	)
+	assert.Equal(t, 0, count)
+}
+
+// TestMemoryRateLimitCacheRepository_Contents test for the messages sent kept for the deduplication
+func TestMemoryRateLimitCacheRepository_Contents(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	r := NewMemoryRateLimitCacheRepository(store)
+	ctx := context.Background()
+
+	recorded, err := r.RecordContent(ctx, "DEDUP#a", 1700000000, 1700000600)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	// The content can not be recorded again until it expires
+	recorded, err = r.RecordContent(ctx, "DEDUP#a", 1700000100, 1700000700)
+	assert.NoError(t, err)
+	assert.False(t, recorded)
+
+	contents, err := r.GetRecordedContents(ctx, []string{"DEDUP#a", "DEDUP#b"}, 1700000100)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"DEDUP#a": true}, contents)
+
+	contents, err = r.GetRecordedContents(ctx, []string{"DEDUP#a"}, 1700000600)
+	assert.NoError(t, err)
+	assert.Empty(t, contents)
+
+	recorded, err = r.RecordContent(ctx, "DEDUP#a", 1700000600, 1700001200)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	// A content deleted can be recorded again before it expires
+	assert.NoError(t, r.DeleteContent(ctx, "DEDUP#a"))
+
+	recorded, err = r.RecordContent(ctx, "DEDUP#a", 1700000700, 1700001300)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+}
+
+// TestMemoryRecipientRepositories test for the profiles and preferences of the recipients
+func TestMemoryRecipientRepositories(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	profiles := NewMemoryRecipientProfileRepository(store)
+	preferences := NewMemoryRecipientPreferencesRepository(store)
+
+	profile, _ := profiles.GetByEmail(context.Background(), "test@example.com")
+	assert.Nil(t, profile)
+
+	assert.NoError(t, profiles.Save(
+		context.Background(),
+		internal.RecipientProfile{Email: "test@example.com", Timezone: "America/Bogota"},
+	))
+
+	profile, _ = profiles.GetByEmail(context.Background(), "test@example.com")
+	assert.Equal(t, &internal.RecipientProfile{
+		PK:       "RECIPIENT#test@example.com",
+		Email:    "test@example.com",
+		Timezone: "America/Bogota",
+	}, profile)
+
+	assert.NoError(t, preferences.OptOut(context.Background(), "", "test@example.com", "News"))
+	assert.NoError(t, preferences.OptOut(context.Background(), "", "test@example.com", "News"))
+
+	got, _ := preferences.GetByEmail(context.Background(), "", "test@example.com")
+	assert.Equal(t, []string{"News"}, got.OptedOutTypes)
+	assert.False(t, got.Unsubscribed)
+
+	assert.NoError(t, preferences.OptOut(context.Background(), "", "test@example.com", ""))
+
+	got, _ = preferences.GetByEmail(context.Background(), "", "test@example.com")
+	assert.True(t, got.Unsubscribed)
+
+	// The choices of the recipient in a tenant do not apply to the other tenants
+	assert.NoError(t, preferences.OptOut(context.Background(), "acme", "test@example.com", ""))
+
+	got, _ = preferences.GetByEmail(context.Background(), "acme", "test@example.com")
+	assert.Equal(t, "RECIPIENT#acme:test@example.com", got.PK)
+	assert.True(t, got.Unsubscribed)
+
+	got, _ = preferences.GetByEmail(context.Background(), "globex", "test@example.com")
+	assert.Nil(t, got)
+}
+
+// TestMemorySuppressionRepository_List test for the pagination of the suppressed addresses
+func TestMemorySuppressionRepository_List(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	r := NewMemorySuppressionRepository(store, defaultNormalizer(t))
+
+	for _, email := range []string{"c@example.com", "A@Example.com", "b@example.com"} {
+		assert.NoError(t, r.Save(
+			context.Background(),
+			internal.Suppression{Email: email, Reason: internal.SuppressionReasonBounce},
+		))
+	}
+
+	page, cursor, _ := r.List(context.Background(), 2, "")
+	assert.Equal(t, "b@example.com", cursor)
+
+	if assert.Len(t, page, 2) {
+		assert.Equal(t, "a@example.com", page[0].Email)
+		assert.Equal(t, "EMAIL#a@example.com", page[0].PK)
+	}
+
+	page, cursor, _ = r.List(context.Background(), 2, cursor)
+	assert.Equal(t, "", cursor)
+	assert.Len(t, page, 1)
+
+	// The variants of a suppressed address are suppressed too
+	suppression, _ := r.GetByEmail(context.Background(), "a@EXAMPLE.com")
+	if assert.NotNil(t, suppression) {
+		assert.Equal(t, "a@example.com", suppression.Email)
+	}
+
+	assert.NoError(t, r.Delete(context.Background(), "A@example.com"))
+
+	suppression, _ = r.GetByEmail(context.Background(), "a@example.com")
+	assert.Nil(t, suppression)
+}
+
+// TestMemoryAPIKeyRepository test for the API keys kept in memory
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	r := NewMemoryAPIKeyRepository(store)
+
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	later := internal.APIKey{Hash: "hash-2", ID: "2", CreatedAt: now.Add(time.Hour)}
+	assert.NoError(t, r.Save(context.Background(), later))
+	assert.NoError(t, r.Save(context.Background(), internal.APIKey{Hash: "hash-1", ID: "1", CreatedAt: now}))
+
+	got, _ := r.GetByHash(context.Background(), "hash-1")
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "1", got.ID)
+		assert.Equal(t, "hash-1", got.Hash)
+	}
+
+	got, _ = r.GetByHash(context.Background(), "hash-3")
+	assert.Nil(t, got)
+
+	apiKeys, _ := r.List(context.Background())
+	if assert.Len(t, apiKeys, 2) {
+		assert.Equal(t, "1", apiKeys[0].ID)
+		assert.Equal(t, "hash-2", apiKeys[1].Hash)
+	}
+}
@@ -0,0 +1,147 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"modak/send-notification/v1/internal"
+)
+
+// MemoryStore storage backend kept in memory for offline use, optionally persisted in a JSON file
+// so the state survives between executions. It is shared by the Memory* repositories
+type MemoryStore struct {
+	mu   sync.Mutex
+	path string
+	data memoryStoreData
+}
+
+// memoryStoreData every table of the store, the keys of the maps are the keys of the DynamoDB tables without prefix
+type memoryStoreData struct {
+	Rules         map[string]internal.RateLimitRule          `json:"rules"`
+	RulesHistory  map[string][]internal.RateLimitRuleHistory `json:"rules_history"`
+	Notifications map[string][]memoryNotification            `json:"notifications"`
+	Profiles      map[string]internal.RecipientProfile       `json:"profiles"`
+	Preferences   map[string]internal.RecipientPreferences   `json:"preferences"`
+	Suppressions  map[string]internal.Suppression            `json:"suppressions"`
+	// Contents ttl of the messages sent by content key
+	Contents map[string]int64 `json:"contents"`
+	// APIKeys API keys by hash
+	APIKeys map[string]internal.APIKey `json:"api_keys"`
+}
+
+// memoryNotification notification sent, the equivalent of one item of the cache table
+type memoryNotification struct {
+	Timestamp int64  `json:"timestamp"`
+	UUID      string `json:"uuid"`
+	TTL       int64  `json:"ttl"`
+}
+
+// read run the function with the data of the store, the data must not be changed
+func (s *MemoryStore) read(fn func(data *memoryStoreData)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.data)
+}
+
+// write run the function that changes the data of the store and persist the result when the store has a file,
+// nothing is persisted when the function fails
+func (s *MemoryStore) write(fn func(data *memoryStoreData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := fn(&s.data); err != nil {
+		return err
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted write does not corrupt the store
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// init create the missing tables
+func (d *memoryStoreData) init() {
+	if d.Rules == nil {
+		d.Rules = map[string]internal.RateLimitRule{}
+	}
+
+	if d.RulesHistory == nil {
+		d.RulesHistory = map[string][]internal.RateLimitRuleHistory{}
+	}
+
+	if d.Notifications == nil {
+		d.Notifications = map[string][]memoryNotification{}
+	}
+
+	if d.Profiles == nil {
+		d.Profiles = map[string]internal.RecipientProfile{}
+	}
+
+	if d.Preferences == nil {
+		d.Preferences = map[string]internal.RecipientPreferences{}
+	}
+
+	if d.Suppressions == nil {
+		d.Suppressions = map[string]internal.Suppression{}
+	}
+
+	if d.Contents == nil {
+		d.Contents = map[string]int64{}
+	}
+
+	if d.APIKeys == nil {
+		d.APIKeys = map[string]internal.APIKey{}
+	}
+}
+
+// NewMemoryStore instance of a new store, with an empty path the data only lives in memory,
+// otherwise the file is loaded when it exists and it is rewritten on every change
+func NewMemoryStore(path string) (*MemoryStore, error) {
+	store := &MemoryStore{path: path}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if len(content) > 0 {
+			if err := json.Unmarshal(content, &store.data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	store.data.init()
+
+	return store, nil
+}
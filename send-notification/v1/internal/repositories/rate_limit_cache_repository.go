@@ -2,15 +2,31 @@
 package repositories
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"modak/send-notification/v1/internal"
 	"modak/send-notification/v1/internal/infraestructure"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"golang.org/x/sync/errgroup"
 )
 
+// groupedCountConcurrency maximum number of partitions counted at the same time by CountNotificationsGrouped
+const groupedCountConcurrency = 10
+
+// contentSortKey sort key of the items of the messages sent, each message has one item in its partition
+const contentSortKey = "CONTENT"
+
+// clientRequestsKeyPrefix prefix of the partitions of the requests of each client, one item per window
+const clientRequestsKeyPrefix = "CLIENT#"
+
 // RateLimitCacheRepository struct for this repository
 type RateLimitCacheRepository struct {
 	client    infraestructure.DynamoAPI
@@ -19,6 +35,7 @@ type RateLimitCacheRepository struct {
 
 // SetNotificationSentTimestamp Save in database a record to identify that this user was notified in that timestamp
 func (r *RateLimitCacheRepository) SetNotificationSentTimestamp(
+	ctx context.Context,
 	notificationType, email, timestamp, uuid string,
 	ttl int64,
 ) error {
@@ -40,17 +57,21 @@ func (r *RateLimitCacheRepository) SetNotificationSentTimestamp(
 		},
 	}
 
-	_, err := r.client.PutItem(input)
+	_, err := r.client.PutItemWithContext(ctx, input)
 
 	return err
 }
 
-// CountNotificationsWithinInterval check the number of notifications that one user had in the given interval of time
+// CountNotificationsWithinInterval check the number of notifications that one user had since the start of the window.
+// The query is paginated following LastEvaluatedKey and stops as soon as the count reaches the given limit,
+// a limit lower or equal than zero counts every notification in the interval
 func (r *RateLimitCacheRepository) CountNotificationsWithinInterval(
+	ctx context.Context,
 	notificationType, email string,
-	intervalInMinutes int,
+	windowStart time.Time,
+	limit int,
 ) (int, error) {
-	startTimestamp := time.Now().Add(-time.Duration(intervalInMinutes) * time.Minute).Unix()
+	startTimestamp := windowStart.Unix()
 
 	partitionKey := fmt.Sprintf("%s#%s", notificationType, email)
 
@@ -65,14 +86,312 @@ func (r *RateLimitCacheRepository) CountNotificationsWithinInterval(
 				S: aws.String(fmt.Sprintf("%d#-", startTimestamp)),
 			},
 		},
+		Select: aws.String(dynamodb.SelectCount),
+	}
+
+	count := 0
+
+	for {
+		// Evaluate only the items needed to reach the limit
+		if limit > 0 {
+			input.Limit = aws.Int64(int64(limit - count))
+		}
+
+		result, err := r.client.QueryWithContext(ctx, input)
+		if err != nil {
+			return 0, err
+		}
+
+		count += int(aws.Int64Value(result.Count))
+
+		// The exact count beyond the limit is not needed to reject the notification
+		if limit > 0 && count >= limit {
+			return count, nil
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// CountNotificationsGrouped count the notifications of several partitions, each partition is counted once
+// even when several queries have it. The result is keyed by the partition key of the queries
+func (r *RateLimitCacheRepository) CountNotificationsGrouped(
+	ctx context.Context,
+	queries []internal.NotificationCountQuery,
+) (map[string]int, error) {
+	var mu sync.Mutex
+
+	counts := make(map[string]int, len(queries))
+	counted := map[string]bool{}
+
+	// The first error cancels the queries still running
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	group.SetLimit(groupedCountConcurrency)
+
+	for _, query := range queries {
+		query := query
+		if counted[query.PartitionKey()] {
+			continue
+		}
+
+		counted[query.PartitionKey()] = true
+
+		group.Go(func() error {
+			count, err := r.CountNotificationsWithinInterval(
+				groupCtx, query.Type, query.Email, query.WindowStart, query.Limit,
+			)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			counts[query.PartitionKey()] = count
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// DeleteNotifications remove every notification of one type sent to one user, it returns how many were removed
+func (r *RateLimitCacheRepository) DeleteNotifications(
+	ctx context.Context, notificationType,
+	email string,
+) (int, error) {
+	partitionKey := fmt.Sprintf("%s#%s", notificationType, email)
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk": {
+				S: aws.String(partitionKey),
+			},
+		},
+		ProjectionExpression: aws.String("pk, sk"),
+	}
+
+	deleted := 0
+
+	for {
+		result, err := r.client.QueryWithContext(ctx, input)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, item := range result.Items {
+			_, err := r.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(r.tableName),
+				Key:       item,
+			})
+			if err != nil {
+				return deleted, err
+			}
+
+			deleted++
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return deleted, nil
+		}
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// DeleteNotification remove one notification sent to one user, the one saved with the timestamp and the uuid
+func (r *RateLimitCacheRepository) DeleteNotification(
+	ctx context.Context,
+	notificationType, email, timestamp, uuid string,
+) error {
+	_, err := r.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String(fmt.Sprintf("%s#%s", notificationType, email))},
+			"sk": {S: aws.String(fmt.Sprintf("%s#%s", timestamp, uuid))},
+		},
+	})
+
+	return err
+}
+
+// GetRecordedContents check which messages were already sent, the result has the content keys recorded whose
+// ttl is after now. DynamoDB deletes the expired items some time after their ttl, so they are ignored
+func (r *RateLimitCacheRepository) GetRecordedContents(
+	ctx context.Context,
+	contentKeys []string,
+	now int64,
+) (map[string]bool, error) {
+	recorded := map[string]bool{}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(contentKeys))
+	seen := map[string]bool{}
+
+	for _, contentKey := range contentKeys {
+		if !seen[contentKey] {
+			seen[contentKey] = true
+			keys = append(keys, r.contentKey(contentKey))
+		}
+	}
+
+	for start := 0; start < len(keys); start += batchGetItemMaxKeys {
+		end := start + batchGetItemMaxKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := r.batchGetContents(ctx, keys[start:end], now, recorded); err != nil {
+			return nil, err
+		}
+	}
+
+	return recorded, nil
+}
+
+// batchGetContents read one chunk of content keys, retrying the keys that DynamoDB did not process
+func (r *RateLimitCacheRepository) batchGetContents(
+	ctx context.Context,
+	keys []map[string]*dynamodb.AttributeValue,
+	now int64,
+	recorded map[string]bool,
+) error {
+	requestItems := map[string]*dynamodb.KeysAndAttributes{
+		r.tableName: {
+			Keys:                     keys,
+			ProjectionExpression:     aws.String("pk, #ttl"),
+			ExpressionAttributeNames: map[string]*string{"#ttl": aws.String("ttl")},
+		},
 	}
 
-	result, err := r.client.Query(input)
+	for attempt := 0; attempt < batchGetItemMaxAttempts; attempt++ {
+		result, err := r.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: requestItems})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range result.Responses[r.tableName] {
+			if item["ttl"] == nil {
+				continue
+			}
+
+			ttl, err := strconv.ParseInt(aws.StringValue(item["ttl"].N), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid ttl of content %s: %w", aws.StringValue(item["pk"].S), err)
+			}
+
+			if ttl > now {
+				recorded[aws.StringValue(item["pk"].S)] = true
+			}
+		}
+
+		if len(result.UnprocessedKeys) == 0 {
+			return nil
+		}
+
+		requestItems = result.UnprocessedKeys
+	}
+
+	return fmt.Errorf("contents not read after %d BatchGetItem attempts", batchGetItemMaxAttempts)
+}
+
+// RecordContent save that a message was sent until ttl, it returns false without saving it when the message was
+// already recorded and its ttl is after now, so two requests with the same message can not both send it
+func (r *RateLimitCacheRepository) RecordContent(
+	ctx context.Context,
+	contentKey string,
+	now, ttl int64,
+) (bool, error) {
+	item := r.contentKey(contentKey)
+	item["ttl"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ttl, 10))}
+
+	_, err := r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(r.tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(pk) OR #ttl <= :now"),
+		ExpressionAttributeNames: map[string]*string{"#ttl": aws.String("ttl")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now, 10))},
+		},
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteContent remove a message recorded, so the same message can be sent again inside its dedup window
+func (r *RateLimitCacheRepository) DeleteContent(ctx context.Context, contentKey string) error {
+	_, err := r.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.contentKey(contentKey),
+	})
+
+	return err
+}
+
+// IncrementRequests count one request of a client in the window that starts at windowStart, it returns the
+// requests of the client in the window including this one. The counter expires at ttl
+func (r *RateLimitCacheRepository) IncrementRequests(
+	ctx context.Context,
+	clientID string,
+	windowStart time.Time,
+	ttl int64,
+) (int, error) {
+	result, err := r.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String(clientRequestsKeyPrefix + clientID)},
+			"sk": {S: aws.String(strconv.FormatInt(windowStart.Unix(), 10))},
+		},
+		UpdateExpression:         aws.String("ADD #requests :one SET #ttl = :ttl"),
+		ExpressionAttributeNames: map[string]*string{"#requests": aws.String("requests"), "#ttl": aws.String("ttl")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one": {N: aws.String("1")},
+			":ttl": {N: aws.String(strconv.FormatInt(ttl, 10))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return int(*result.Count), nil
+	attribute := result.Attributes["requests"]
+	if attribute == nil {
+		return 0, fmt.Errorf("the requests of client %s were not returned", clientID)
+	}
+
+	requests, err := strconv.Atoi(aws.StringValue(attribute.N))
+	if err != nil {
+		return 0, fmt.Errorf("invalid requests of client %s: %w", clientID, err)
+	}
+
+	return requests, nil
+}
+
+// contentKey key of the item of a message sent
+func (r *RateLimitCacheRepository) contentKey(contentKey string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {S: aws.String(contentKey)},
+		"sk": {S: aws.String(contentSortKey)},
+	}
 }
 
 // NewRateLimitCacheRepository new instance of this repository
@@ -2,35 +2,113 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
 )
 
 // mockDynamoAPI mock for dynamoAPI
 type mockDynamoAPI struct {
-	PutItemFunc func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
-	QueryFunc   func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
-	GetItemFunc func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	PutItemFunc            func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	QueryFunc              func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	QueryContextFunc       func(aws.Context, *dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	GetItemFunc            func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	BatchGetItemFunc       func(*dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error)
+	UpdateItemFunc         func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	DeleteItemFunc         func(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
+	ScanFunc               func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error)
+	TransactWriteItemsFunc func(*dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
-// PutItem insert a new item into dynamoDB
-func (m *mockDynamoAPI) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+// PutItemWithContext insert a new item into dynamoDB
+func (m *mockDynamoAPI) PutItemWithContext(
+	_ aws.Context,
+	input *dynamodb.PutItemInput,
+	_ ...request.Option,
+) (*dynamodb.PutItemOutput, error) {
 	return m.PutItemFunc(input)
 }
 
-// Query get elements from dynamo given a query
-func (m *mockDynamoAPI) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+// QueryWithContext get elements from dynamo given a query
+func (m *mockDynamoAPI) QueryWithContext(
+	ctx aws.Context,
+	input *dynamodb.QueryInput,
+	_ ...request.Option,
+) (*dynamodb.QueryOutput, error) {
+	if m.QueryContextFunc != nil {
+		return m.QueryContextFunc(ctx, input)
+	}
+
 	return m.QueryFunc(input)
 }
 
-// GetItem get only one element from dynamoDB
-func (m *mockDynamoAPI) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+// GetItemWithContext get only one element from dynamoDB
+func (m *mockDynamoAPI) GetItemWithContext(
+	_ aws.Context,
+	input *dynamodb.GetItemInput,
+	_ ...request.Option,
+) (*dynamodb.GetItemOutput, error) {
 	return m.GetItemFunc(input)
 }
 
+// BatchGetItemWithContext get several elements from dynamoDB
+func (m *mockDynamoAPI) BatchGetItemWithContext(
+	_ aws.Context,
+	input *dynamodb.BatchGetItemInput,
+	_ ...request.Option,
+) (*dynamodb.BatchGetItemOutput, error) {
+	return m.BatchGetItemFunc(input)
+}
+
+// UpdateItemWithContext update the attributes of one element in dynamoDB
+func (m *mockDynamoAPI) UpdateItemWithContext(
+	_ aws.Context,
+	input *dynamodb.UpdateItemInput,
+	_ ...request.Option,
+) (*dynamodb.UpdateItemOutput, error) {
+	return m.UpdateItemFunc(input)
+}
+
+// DeleteItemWithContext delete one element from dynamoDB
+func (m *mockDynamoAPI) DeleteItemWithContext(
+	_ aws.Context,
+	input *dynamodb.DeleteItemInput,
+	_ ...request.Option,
+) (*dynamodb.DeleteItemOutput, error) {
+	return m.DeleteItemFunc(input)
+}
+
+// ScanWithContext read every element of a table from dynamoDB
+func (m *mockDynamoAPI) ScanWithContext(
+	_ aws.Context,
+	input *dynamodb.ScanInput,
+	_ ...request.Option,
+) (*dynamodb.ScanOutput, error) {
+	return m.ScanFunc(input)
+}
+
+// TransactWriteItemsWithContext write several elements of dynamoDB in one transaction
+func (m *mockDynamoAPI) TransactWriteItemsWithContext(
+	_ aws.Context,
+	input *dynamodb.TransactWriteItemsInput,
+	_ ...request.Option,
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	return m.TransactWriteItemsFunc(input)
+}
+
 // TestRateLimitCacheRepository_SetNotificationSentTimestamp test for this method
 func TestRateLimitCacheRepository_SetNotificationSentTimestamp(t *testing.T) {
 	tests := []struct {
@@ -61,7 +139,14 @@ func TestRateLimitCacheRepository_SetNotificationSentTimestamp(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := NewRateLimitCacheRepository(tt.mock, "test-table")
-			err := r.SetNotificationSentTimestamp("testType", "test@email.com", "1234567890", "testUUID", 1234567890)
+			err := r.SetNotificationSentTimestamp(
+				context.Background(),
+				"testType",
+				"test@email.com",
+				"1234567890",
+				"testUUID",
+				1234567890,
+			)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SetNotificationSentTimestamp() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -69,21 +154,66 @@ func TestRateLimitCacheRepository_SetNotificationSentTimestamp(t *testing.T) {
 	}
 }
 
+// pagedQueryFake fake for a Query split in pages, each page is resolved by the ExclusiveStartKey received
+type pagedQueryFake struct {
+	pageSizes []int64
+	calls     int
+	limits    []int64
+}
+
+// Query returns the page pointed by ExclusiveStartKey honoring the Limit of the input
+func (f *pagedQueryFake) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	page := 0
+	if input.ExclusiveStartKey != nil {
+		page, _ = strconv.Atoi(aws.StringValue(input.ExclusiveStartKey["sk"].S))
+	}
+
+	f.calls++
+	f.limits = append(f.limits, aws.Int64Value(input.Limit))
+
+	count := f.pageSizes[page]
+	if input.Limit != nil && *input.Limit < count {
+		count = *input.Limit
+	}
+
+	output := &dynamodb.QueryOutput{Count: aws.Int64(count)}
+	if page+1 < len(f.pageSizes) {
+		output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String("testType#test@email.com")},
+			"sk": {S: aws.String(strconv.Itoa(page + 1))},
+		}
+	}
+
+	return output, nil
+}
+
 // TestRateLimitCacheRepository_CountNotificationsWithinInterval test for this method
 func TestRateLimitCacheRepository_CountNotificationsWithinInterval(t *testing.T) {
+	windowStart := time.Unix(1700000000, 0)
+
 	tests := []struct {
 		name    string
 		mock    *mockDynamoAPI
+		limit   int
 		want    int
 		wantErr bool
 	}{
 		{
 			name: "success",
 			mock: &mockDynamoAPI{
-				QueryFunc: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+				QueryFunc: func(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+					if aws.StringValue(input.Select) != dynamodb.SelectCount {
+						return nil, errors.New("expected a COUNT query")
+					}
+
+					if aws.StringValue(input.ExpressionAttributeValues[":startRange"].S) != "1700000000#-" {
+						return nil, errors.New("expected the range to start at the window start")
+					}
+
 					return &dynamodb.QueryOutput{Count: aws.Int64(5)}, nil
 				},
 			},
+			limit:   10,
 			want:    5,
 			wantErr: false,
 		},
@@ -94,6 +224,7 @@ func TestRateLimitCacheRepository_CountNotificationsWithinInterval(t *testing.T)
 					return nil, errors.New("error on query")
 				},
 			},
+			limit:   10,
 			want:    0,
 			wantErr: true,
 		},
@@ -102,7 +233,13 @@ func TestRateLimitCacheRepository_CountNotificationsWithinInterval(t *testing.T)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := NewRateLimitCacheRepository(tt.mock, "test-table")
-			got, err := r.CountNotificationsWithinInterval("testType", "test@email.com", 10)
+			got, err := r.CountNotificationsWithinInterval(
+				context.Background(),
+				"testType",
+				"test@email.com",
+				windowStart,
+				tt.limit,
+			)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CountNotificationsWithinInterval() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -114,6 +251,384 @@ func TestRateLimitCacheRepository_CountNotificationsWithinInterval(t *testing.T)
 	}
 }
 
+// TestRateLimitCacheRepository_CountNotificationsWithinInterval_Pagination test the count across several pages
+func TestRateLimitCacheRepository_CountNotificationsWithinInterval_Pagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageSizes  []int64
+		limit      int
+		want       int
+		wantCalls  int
+		wantLimits []int64
+	}{
+		{
+			name:       "sums every page when the limit is not reached",
+			pageSizes:  []int64{2, 3, 1},
+			limit:      10,
+			want:       6,
+			wantCalls:  3,
+			wantLimits: []int64{10, 8, 5},
+		},
+		{
+			name:       "stops as soon as the limit is reached",
+			pageSizes:  []int64{2, 3, 4, 4},
+			limit:      4,
+			want:       4,
+			wantCalls:  2,
+			wantLimits: []int64{4, 2},
+		},
+		{
+			name:       "limit reached in the first page",
+			pageSizes:  []int64{5, 5},
+			limit:      3,
+			want:       3,
+			wantCalls:  1,
+			wantLimits: []int64{3},
+		},
+		{
+			name:       "without limit counts every page",
+			pageSizes:  []int64{4, 4, 4},
+			limit:      0,
+			want:       12,
+			wantCalls:  3,
+			wantLimits: []int64{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &pagedQueryFake{pageSizes: tt.pageSizes}
+			r := NewRateLimitCacheRepository(&mockDynamoAPI{QueryFunc: fake.Query}, "test-table")
+
+			got, err := r.CountNotificationsWithinInterval(
+				context.Background(),
+				"testType",
+				"test@email.com",
+				time.Now().Add(-10*time.Minute),
+				tt.limit,
+			)
+			if err != nil {
+				t.Fatalf("CountNotificationsWithinInterval() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountNotificationsWithinInterval() got = %v, want %v", got, tt.want)
+			}
+			if fake.calls != tt.wantCalls {
+				t.Errorf("CountNotificationsWithinInterval() queries = %v, want %v", fake.calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(fake.limits, tt.wantLimits) {
+				t.Errorf("CountNotificationsWithinInterval() limits = %v, want %v", fake.limits, tt.wantLimits)
+			}
+		})
+	}
+}
+
+// TestRateLimitCacheRepository_CountNotificationsGrouped test for this method
+func TestRateLimitCacheRepository_CountNotificationsGrouped(t *testing.T) {
+	var mu sync.Mutex
+
+	queried := map[string]int{}
+	client := &mockDynamoAPI{
+		QueryFunc: func(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			partitionKey := aws.StringValue(input.ExpressionAttributeValues[":pk"].S)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			queried[partitionKey]++
+
+			if partitionKey == "Status#b@example.com" {
+				return nil, errors.New("database error")
+			}
+
+			return &dynamodb.QueryOutput{Count: aws.Int64(int64(len(partitionKey)))}, nil
+		},
+	}
+
+	windowStart := time.Now().Add(-time.Hour)
+	r := NewRateLimitCacheRepository(client, "test-table")
+
+	got, err := r.CountNotificationsGrouped(context.Background(), []internal.NotificationCountQuery{
+		{Type: "News", Email: "a@example.com", WindowStart: windowStart},
+		{Type: "News", Email: "a@example.com", WindowStart: windowStart},
+		{Type: "Status", Email: "a@example.com", WindowStart: windowStart},
+	})
+	if err != nil {
+		t.Fatalf("CountNotificationsGrouped() unexpected error = %v", err)
+	}
+
+	assert.Equal(t, map[string]int{"News#a@example.com": 18, "Status#a@example.com": 20}, got)
+	assert.Equal(t, map[string]int{"News#a@example.com": 1, "Status#a@example.com": 1}, queried)
+
+	_, err = r.CountNotificationsGrouped(context.Background(), []internal.NotificationCountQuery{
+		{Type: "News", Email: "b@example.com", WindowStart: windowStart},
+		{Type: "Status", Email: "b@example.com", WindowStart: windowStart},
+	})
+	assert.Error(t, err)
+}
+
+// TestRateLimitCacheRepository_CountNotificationsGrouped_Cancel test that the first error cancels the other queries
+func TestRateLimitCacheRepository_CountNotificationsGrouped_Cancel(t *testing.T) {
+	var cancelled atomic.Bool
+
+	client := &mockDynamoAPI{
+		QueryContextFunc: func(ctx aws.Context, input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			if aws.StringValue(input.ExpressionAttributeValues[":pk"].S) == "Status#a@example.com" {
+				return nil, errors.New("database error")
+			}
+
+			select {
+			case <-ctx.Done():
+				cancelled.Store(true)
+
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return &dynamodb.QueryOutput{Count: aws.Int64(1)}, nil
+			}
+		},
+	}
+
+	windowStart := time.Now().Add(-time.Hour)
+
+	_, err := NewRateLimitCacheRepository(client, "test-table").CountNotificationsGrouped(
+		context.Background(),
+		[]internal.NotificationCountQuery{
+			{Type: "News", Email: "a@example.com", WindowStart: windowStart},
+			{Type: "Status", Email: "a@example.com", WindowStart: windowStart},
+		},
+	)
+	assert.EqualError(t, err, "database error")
+	assert.True(t, cancelled.Load())
+}
+
+// TestRateLimitCacheRepository_DeleteNotifications test for this method
+func TestRateLimitCacheRepository_DeleteNotifications(t *testing.T) {
+	item := func(sk string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"pk": {S: aws.String("testType#test@email.com")},
+			"sk": {S: aws.String(sk)},
+		}
+	}
+
+	var deletedKeys []string
+
+	client := &mockDynamoAPI{
+		QueryFunc: func(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			if aws.StringValue(input.ExpressionAttributeValues[":pk"].S) != "testType#test@email.com" {
+				return nil, errors.New("unexpected partition")
+			}
+
+			if input.ExclusiveStartKey == nil {
+				return &dynamodb.QueryOutput{
+					Items:            []map[string]*dynamodb.AttributeValue{item("1#a"), item("2#b")},
+					LastEvaluatedKey: item("2#b"),
+				}, nil
+			}
+
+			return &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item("3#c")}}, nil
+		},
+		DeleteItemFunc: func(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+			deletedKeys = append(deletedKeys, aws.StringValue(input.Key["sk"].S))
+
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	deleted, err := NewRateLimitCacheRepository(client, "test-table").DeleteNotifications(
+		context.Background(),
+		"testType",
+		"test@email.com",
+	)
+	if err != nil {
+		t.Fatalf("DeleteNotifications() unexpected error = %v", err)
+	}
+
+	if deleted != 3 || !reflect.DeepEqual(deletedKeys, []string{"1#a", "2#b", "3#c"}) {
+		t.Errorf("DeleteNotifications() deleted = %v %v, want 3 [1#a 2#b 3#c]", deleted, deletedKeys)
+	}
+}
+
+// TestRateLimitCacheRepository_DeleteNotification test for this method
+func TestRateLimitCacheRepository_DeleteNotification(t *testing.T) {
+	var deletedKeys []string
+
+	client := &mockDynamoAPI{
+		DeleteItemFunc: func(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+			deletedKeys = append(deletedKeys, aws.StringValue(input.Key["pk"].S)+"/"+aws.StringValue(input.Key["sk"].S))
+
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	err := NewRateLimitCacheRepository(client, "test-table").DeleteNotification(
+		context.Background(),
+		"testType",
+		"test@email.com",
+		"1700000000",
+		"uuid-1",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testType#test@email.com/1700000000#uuid-1"}, deletedKeys)
+}
+
+// TestRateLimitCacheRepository_GetRecordedContents test for this method
+func TestRateLimitCacheRepository_GetRecordedContents(t *testing.T) {
+	item := func(pk, ttl string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"pk": {S: aws.String(pk)}, "ttl": {N: aws.String(ttl)}}
+	}
+
+	var requested [][]string
+
+	client := &mockDynamoAPI{
+		BatchGetItemFunc: func(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+			var keys []string
+			for _, key := range input.RequestItems["test-table"].Keys {
+				keys = append(keys, aws.StringValue(key["pk"].S)+"/"+aws.StringValue(key["sk"].S))
+			}
+
+			requested = append(requested, keys)
+
+			// The second key is not processed by the first request
+			if len(requested) == 1 {
+				return &dynamodb.BatchGetItemOutput{
+					Responses: map[string][]map[string]*dynamodb.AttributeValue{
+						"test-table": {item("DEDUP#a", "1700000100"), item("DEDUP#c", "1700000000")},
+					},
+					UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{
+						"test-table": {Keys: input.RequestItems["test-table"].Keys[1:2]},
+					},
+				}, nil
+			}
+
+			return &dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]*dynamodb.AttributeValue{
+					"test-table": {item("DEDUP#b", "1700000200")},
+				},
+			}, nil
+		},
+	}
+
+	got, err := NewRateLimitCacheRepository(client, "test-table").GetRecordedContents(
+		context.Background(),
+		[]string{"DEDUP#a", "DEDUP#b", "DEDUP#a", "DEDUP#c"},
+		1700000000,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"DEDUP#a": true, "DEDUP#b": true}, got)
+	assert.Equal(t, [][]string{
+		{"DEDUP#a/CONTENT", "DEDUP#b/CONTENT", "DEDUP#c/CONTENT"},
+		{"DEDUP#b/CONTENT"},
+	}, requested)
+}
+
+// TestRateLimitCacheRepository_RecordContent test for this method
+func TestRateLimitCacheRepository_RecordContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		putErr  error
+		want    bool
+		wantErr bool
+	}{
+		{name: "new content", want: true},
+		{
+			name:   "content already recorded",
+			putErr: awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil),
+			want:   false,
+		},
+		{name: "database error", putErr: errors.New("database error"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockDynamoAPI{
+				PutItemFunc: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					assert.Equal(t, "DEDUP#a", aws.StringValue(input.Item["pk"].S))
+					assert.Equal(t, "1700000600", aws.StringValue(input.Item["ttl"].N))
+					assert.Equal(t, "attribute_not_exists(pk) OR #ttl <= :now",
+						aws.StringValue(input.ConditionExpression))
+					assert.Equal(t, "1700000000", aws.StringValue(input.ExpressionAttributeValues[":now"].N))
+
+					return &dynamodb.PutItemOutput{}, tt.putErr
+				},
+			}
+
+			got, err := NewRateLimitCacheRepository(client, "test-table").RecordContent(
+				context.Background(), "DEDUP#a", 1700000000, 1700000600,
+			)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestRateLimitCacheRepository_DeleteContent test for this method
+func TestRateLimitCacheRepository_DeleteContent(t *testing.T) {
+	var deletedKeys []string
+
+	client := &mockDynamoAPI{
+		DeleteItemFunc: func(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+			deletedKeys = append(deletedKeys, aws.StringValue(input.Key["pk"].S)+"/"+aws.StringValue(input.Key["sk"].S))
+
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	err := NewRateLimitCacheRepository(client, "test-table").DeleteContent(context.Background(), "DEDUP#a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DEDUP#a/CONTENT"}, deletedKeys)
+}
+
+// TestRateLimitCacheRepository_IncrementRequests test for this method
+func TestRateLimitCacheRepository_IncrementRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]*dynamodb.AttributeValue
+		updateErr  error
+		want       int
+		wantErr    bool
+	}{
+		{
+			name:       "requests in the window",
+			attributes: map[string]*dynamodb.AttributeValue{"requests": {N: aws.String("3")}},
+			want:       3,
+		},
+		{name: "requests not returned", wantErr: true},
+		{name: "database error", updateErr: errors.New("database error"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockDynamoAPI{
+				UpdateItemFunc: func(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, "CLIENT#client-1", aws.StringValue(input.Key["pk"].S))
+					assert.Equal(t, "1700000000", aws.StringValue(input.Key["sk"].S))
+					assert.Equal(t, "1700000120", aws.StringValue(input.ExpressionAttributeValues[":ttl"].N))
+
+					return &dynamodb.UpdateItemOutput{Attributes: tt.attributes}, tt.updateErr
+				},
+			}
+
+			got, err := NewRateLimitCacheRepository(client, "test-table").IncrementRequests(
+				context.Background(), "client-1", time.Unix(1700000000, 0), 1700000120,
+			)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 // TestNewRateLimitCacheRepository test for this repository
 func TestNewRateLimitCacheRepository(t *testing.T) {
 	client := &mockDynamoAPI{}
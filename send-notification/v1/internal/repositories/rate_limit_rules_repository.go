@@ -2,32 +2,48 @@
 package repositories
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"modak/send-notification/v1/internal"
 	"modak/send-notification/v1/internal/infraestructure"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
+// batchGetItemMaxKeys maximum number of keys of one BatchGetItem request
+const batchGetItemMaxKeys = 100
+
+// batchGetItemMaxAttempts maximum number of BatchGetItem requests to read the unprocessed keys of a chunk
+const batchGetItemMaxAttempts = 5
+
+// transactionConditionalCheckFailed cancellation reason of a transaction item whose condition failed
+const transactionConditionalCheckFailed = "ConditionalCheckFailed"
+
 // RateLimitRulesRepository struct for this repository
 type RateLimitRulesRepository struct {
-	client    infraestructure.DynamoAPI
-	tableName string
+	client           infraestructure.DynamoAPI
+	tableName        string
+	historyTableName string
 }
 
 // GetByType get the records in database given a valid type
-func (r *RateLimitRulesRepository) GetByType(notificationType string) (*internal.RateLimitRule, error) {
+func (r *RateLimitRulesRepository) GetByType(
+	ctx context.Context,
+	notificationType string,
+) (*internal.RateLimitRule, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"pk": {
-				S: aws.String("TYPE#" + notificationType),
-			},
-		},
+		Key:       r.key(notificationType),
 	}
 
-	result, err := r.client.GetItem(input)
+	result, err := r.client.GetItemWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -43,16 +59,315 @@ func (r *RateLimitRulesRepository) GetByType(notificationType string) (*internal
 		return nil, err
 	}
 
+	rule.Type = strings.TrimPrefix(rule.PK, internal.RuleKeyPrefix)
+
 	return &rule, nil
 }
 
+// GetByTypes get the rules of several types with BatchGetItem, the types without rule are not in the result
+func (r *RateLimitRulesRepository) GetByTypes(
+	ctx context.Context,
+	notificationTypes []string,
+) (map[string]internal.RateLimitRule, error) {
+	rules := map[string]internal.RateLimitRule{}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(notificationTypes))
+	seen := map[string]bool{}
+
+	for _, notificationType := range notificationTypes {
+		if !seen[notificationType] {
+			seen[notificationType] = true
+			keys = append(keys, r.key(notificationType))
+		}
+	}
+
+	for start := 0; start < len(keys); start += batchGetItemMaxKeys {
+		end := start + batchGetItemMaxKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := r.batchGet(ctx, keys[start:end], rules); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// batchGet read one chunk of keys into the rules, retrying the keys that DynamoDB did not process
+func (r *RateLimitRulesRepository) batchGet(
+	ctx context.Context,
+	keys []map[string]*dynamodb.AttributeValue,
+	rules map[string]internal.RateLimitRule,
+) error {
+	requestItems := map[string]*dynamodb.KeysAndAttributes{
+		r.tableName: {Keys: keys},
+	}
+
+	for attempt := 0; attempt < batchGetItemMaxAttempts; attempt++ {
+		result, err := r.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: requestItems})
+		if err != nil {
+			return err
+		}
+
+		var page []internal.RateLimitRule
+
+		err = dynamodbattribute.UnmarshalListOfMaps(result.Responses[r.tableName], &page)
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range page {
+			rule.Type = strings.TrimPrefix(rule.PK, internal.RuleKeyPrefix)
+			rules[rule.Type] = rule
+		}
+
+		if len(result.UnprocessedKeys) == 0 {
+			return nil
+		}
+
+		requestItems = result.UnprocessedKeys
+	}
+
+	return fmt.Errorf("rules not read after %d BatchGetItem attempts", batchGetItemMaxAttempts)
+}
+
+// List get every rule in database
+func (r *RateLimitRulesRepository) List(ctx context.Context) ([]internal.RateLimitRule, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	}
+
+	rules := []internal.RateLimitRule{}
+
+	for {
+		result, err := r.client.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []internal.RateLimitRule
+
+		err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rule := range page {
+			rule.Type = strings.TrimPrefix(rule.PK, internal.RuleKeyPrefix)
+			rules = append(rules, rule)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return rules, nil
+		}
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// Create save a new rule, internal.ErrRuleVersionConflict is returned when the type already has a rule
+func (r *RateLimitRulesRepository) Create(ctx context.Context, rule internal.RateLimitRule) error {
+	item, err := r.item(rule)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+
+	return conflictError(err)
+}
+
+// Update replace the previous version of a rule and keep it in the history in one transaction,
+// internal.ErrRuleVersionConflict is returned when the stored rule is not the previous version anymore
+func (r *RateLimitRulesRepository) Update(
+	ctx context.Context,
+	rule internal.RateLimitRule,
+	previous internal.RateLimitRuleHistory,
+) error {
+	item, err := r.item(rule)
+	if err != nil {
+		return err
+	}
+
+	historyPut, err := r.historyPut(previous)
+	if err != nil {
+		return err
+	}
+
+	condition, values := r.versionCondition(previous.Version)
+
+	_, err = r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName:                 aws.String(r.tableName),
+					Item:                      item,
+					ConditionExpression:       condition,
+					ExpressionAttributeValues: values,
+				},
+			},
+			{Put: historyPut},
+		},
+	})
+
+	return conflictError(err)
+}
+
+// Delete remove the previous version of a rule and keep it in the history in one transaction,
+// internal.ErrRuleVersionConflict is returned when the stored rule is not the previous version anymore
+func (r *RateLimitRulesRepository) Delete(ctx context.Context, previous internal.RateLimitRuleHistory) error {
+	historyPut, err := r.historyPut(previous)
+	if err != nil {
+		return err
+	}
+
+	condition, values := r.versionCondition(previous.Version)
+
+	_, err = r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Delete: &dynamodb.Delete{
+					TableName:                 aws.String(r.tableName),
+					Key:                       r.key(previous.Type),
+					ConditionExpression:       condition,
+					ExpressionAttributeValues: values,
+				},
+			},
+			{Put: historyPut},
+		},
+	})
+
+	return conflictError(err)
+}
+
+// History get the previous versions of the rule of a type, the newest first
+func (r *RateLimitRulesRepository) History(
+	ctx context.Context,
+	notificationType string,
+) ([]internal.RateLimitRuleHistory, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.historyTableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk": {S: aws.String(internal.RuleKeyPrefix + notificationType)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	history := []internal.RateLimitRuleHistory{}
+
+	for {
+		result, err := r.client.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []internal.RateLimitRuleHistory
+
+		err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range page {
+			version.Type = notificationType
+			history = append(history, version)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return history, nil
+		}
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// key primary key of the rule of a type
+func (r *RateLimitRulesRepository) key(notificationType string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {
+			S: aws.String(internal.RuleKeyPrefix + notificationType),
+		},
+	}
+}
+
+// item attributes of a rule, the PK is built from the type
+func (r *RateLimitRulesRepository) item(rule internal.RateLimitRule) (map[string]*dynamodb.AttributeValue, error) {
+	rule.PK = internal.RuleKeyPrefix + rule.Type
+
+	return dynamodbattribute.MarshalMap(rule)
+}
+
+// historyPut write of a previous version in the history table, keyed by pk and version
+func (r *RateLimitRulesRepository) historyPut(previous internal.RateLimitRuleHistory) (*dynamodb.Put, error) {
+	previous.PK = internal.RuleKeyPrefix + previous.Type
+
+	item, err := dynamodbattribute.MarshalMap(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.Put{
+		TableName: aws.String(r.historyTableName),
+		Item:      item,
+	}, nil
+}
+
+// versionCondition condition that the stored rule exists with the given version,
+// rules created by hand have no version attribute and match the version 0
+func (r *RateLimitRulesRepository) versionCondition(
+	version int,
+) (*string, map[string]*dynamodb.AttributeValue) {
+	condition := "attribute_exists(pk) AND version = :version"
+	if version == 0 {
+		condition = "attribute_exists(pk) AND (attribute_not_exists(version) OR version = :version)"
+	}
+
+	return aws.String(condition), map[string]*dynamodb.AttributeValue{
+		":version": {N: aws.String(strconv.Itoa(version))},
+	}
+}
+
+// conflictError translate the failed conditions of DynamoDB into internal.ErrRuleVersionConflict
+func conflictError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var canceled *dynamodb.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.StringValue(reason.Code) == transactionConditionalCheckFailed {
+				return internal.ErrRuleVersionConflict
+			}
+		}
+
+		return err
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return internal.ErrRuleVersionConflict
+	}
+
+	return err
+}
+
 // NewRateLimitRulesRepository instance of a new repository
 func NewRateLimitRulesRepository(
 	client infraestructure.DynamoAPI,
 	tableName string,
+	historyTableName string,
 ) *RateLimitRulesRepository {
 	return &RateLimitRulesRepository{
-		client:    client,
-		tableName: tableName,
+		client:           client,
+		tableName:        tableName,
+		historyTableName: historyTableName,
 	}
 }
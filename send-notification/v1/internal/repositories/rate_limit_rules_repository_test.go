@@ -2,16 +2,20 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"modak/send-notification/v1/internal"
 	"modak/send-notification/v1/internal/infraestructure"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
 )
 
 // TestRateLimitRulesRepository_GetByType test for this method
@@ -27,6 +31,7 @@ func TestRateLimitRulesRepository_GetByType(t *testing.T) {
 
 	rule := internal.RateLimitRule{
 		PK:                 "TYPE#testType",
+		Type:               "testType",
 		NotificationsLimit: 5,
 		IntervalInMinutes:  10,
 	}
@@ -97,7 +102,7 @@ func TestRateLimitRulesRepository_GetByType(t *testing.T) {
 				client:    tt.fields.client,
 				tableName: tt.fields.tableName,
 			}
-			got, err := r.GetByType(tt.args.notificationType)
+			got, err := r.GetByType(context.Background(), tt.args.notificationType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetByType() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -109,13 +114,319 @@ func TestRateLimitRulesRepository_GetByType(t *testing.T) {
 	}
 }
 
+// TestRateLimitRulesRepository_List test for this method
+func TestRateLimitRulesRepository_List(t *testing.T) {
+	pages := []*dynamodb.ScanOutput{
+		{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"pk": {S: aws.String("TYPE#News")}, "notifications_limit": {N: aws.String("1")}},
+			},
+			LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("TYPE#News")}},
+		},
+		{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"pk": {S: aws.String("TYPE#Status")}, "notifications_limit": {N: aws.String("2")}},
+			},
+		},
+	}
+
+	calls := 0
+	client := &mockDynamoAPI{
+		ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+			if calls > 0 && aws.StringValue(input.ExclusiveStartKey["pk"].S) != "TYPE#News" {
+				return nil, errors.New("unexpected start key")
+			}
+
+			calls++
+
+			return pages[calls-1], nil
+		},
+	}
+
+	got, err := NewRateLimitRulesRepository(client, "rules", "history").List(context.Background())
+	if err != nil {
+		t.Fatalf("List() unexpected error = %v", err)
+	}
+
+	want := []internal.RateLimitRule{
+		{PK: "TYPE#News", Type: "News", NotificationsLimit: 1},
+		{PK: "TYPE#Status", Type: "Status", NotificationsLimit: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+}
+
+// TestRateLimitRulesRepository_GetByTypes test for this method
+func TestRateLimitRulesRepository_GetByTypes(t *testing.T) {
+	types := []string{"News", "News"}
+	for i := 0; i < 150; i++ {
+		types = append(types, "Type"+strconv.Itoa(i))
+	}
+
+	var requests []int
+
+	unprocessedReturned := false
+	client := &mockDynamoAPI{
+		BatchGetItemFunc: func(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+			keys := input.RequestItems["rules"].Keys
+			requests = append(requests, len(keys))
+
+			output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+
+			for i, key := range keys {
+				// The last key of the first request is left unprocessed once
+				if !unprocessedReturned && i == len(keys)-1 {
+					unprocessedReturned = true
+					output.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{
+						"rules": {Keys: []map[string]*dynamodb.AttributeValue{key}},
+					}
+
+					continue
+				}
+
+				// Only News and the even types have a rule
+				pk := aws.StringValue(key["pk"].S)
+				if number, err := strconv.Atoi(pk[len("TYPE#Type"):]); pk != "TYPE#News" && (err != nil || number%2 != 0) {
+					continue
+				}
+
+				output.Responses["rules"] = append(output.Responses["rules"], map[string]*dynamodb.AttributeValue{
+					"pk":                  {S: aws.String(pk)},
+					"notifications_limit": {N: aws.String("1")},
+				})
+			}
+
+			return output, nil
+		},
+	}
+
+	got, err := NewRateLimitRulesRepository(client, "rules", "history").GetByTypes(context.Background(), types)
+	if err != nil {
+		t.Fatalf("GetByTypes() unexpected error = %v", err)
+	}
+
+	assert.Equal(t, []int{100, 1, 51}, requests)
+	assert.Len(t, got, 76)
+	assert.Equal(t, internal.RateLimitRule{PK: "TYPE#News", Type: "News", NotificationsLimit: 1}, got["News"])
+	assert.Contains(t, got, "Type98")
+	assert.NotContains(t, got, "Type99")
+
+	client.BatchGetItemFunc = func(*dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+		return nil, errors.New("database error")
+	}
+
+	_, err = NewRateLimitRulesRepository(client, "rules", "history").GetByTypes(context.Background(), types)
+	assert.Error(t, err)
+}
+
+// TestRateLimitRulesRepository_Create test for this method
+func TestRateLimitRulesRepository_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		putErr  error
+		wantErr error
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:    "type already has a rule",
+			putErr:  awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conditional request failed", nil),
+			wantErr: internal.ErrRuleVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockDynamoAPI{
+				PutItemFunc: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					if aws.StringValue(input.ConditionExpression) != "attribute_not_exists(pk)" {
+						return nil, errors.New("create must not overwrite rules")
+					}
+
+					if aws.StringValue(input.Item["pk"].S) != "TYPE#News" || aws.StringValue(input.Item["version"].N) != "1" {
+						return nil, errors.New("unexpected item")
+					}
+
+					return &dynamodb.PutItemOutput{}, tt.putErr
+				},
+			}
+
+			err := NewRateLimitRulesRepository(client, "rules", "history").Create(context.Background(), internal.RateLimitRule{
+				Type:               "News",
+				NotificationsLimit: 1,
+				IntervalInMinutes:  1440,
+				Version:            1,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRateLimitRulesRepository_Update test for this method
+func TestRateLimitRulesRepository_Update(t *testing.T) {
+	tests := []struct {
+		name            string
+		previousVersion int
+		transactErr     error
+		wantCondition   string
+		wantErr         error
+	}{
+		{
+			name:            "success",
+			previousVersion: 2,
+			wantCondition:   "attribute_exists(pk) AND version = :version",
+		},
+		{
+			name:            "rule created by hand without version",
+			previousVersion: 0,
+			wantCondition:   "attribute_exists(pk) AND (attribute_not_exists(version) OR version = :version)",
+		},
+		{
+			name:            "version conflict",
+			previousVersion: 2,
+			transactErr: &dynamodb.TransactionCanceledException{
+				CancellationReasons: []*dynamodb.CancellationReason{
+					{Code: aws.String(transactionConditionalCheckFailed)},
+					{Code: aws.String("None")},
+				},
+			},
+			wantCondition: "attribute_exists(pk) AND version = :version",
+			wantErr:       internal.ErrRuleVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockDynamoAPI{
+				TransactWriteItemsFunc: func(
+					input *dynamodb.TransactWriteItemsInput,
+				) (*dynamodb.TransactWriteItemsOutput, error) {
+					rulePut := input.TransactItems[0].Put
+					assert.Equal(t, "rules", aws.StringValue(rulePut.TableName))
+					assert.Equal(t, tt.wantCondition, aws.StringValue(rulePut.ConditionExpression))
+					assert.Equal(t, strconv.Itoa(tt.previousVersion), aws.StringValue(rulePut.ExpressionAttributeValues[":version"].N))
+					assert.Equal(t, strconv.Itoa(tt.previousVersion+1), aws.StringValue(rulePut.Item["version"].N))
+
+					var history internal.RateLimitRuleHistory
+
+					historyPut := input.TransactItems[1].Put
+					assert.Equal(t, "history", aws.StringValue(historyPut.TableName))
+					assert.NoError(t, dynamodbattribute.UnmarshalMap(historyPut.Item, &history))
+					assert.Equal(t, internal.RateLimitRuleHistory{
+						RateLimitRule: internal.RateLimitRule{
+							PK:                 "TYPE#News",
+							NotificationsLimit: 1,
+							IntervalInMinutes:  60,
+							Version:            tt.previousVersion,
+						},
+						ReplacedAt: "2023-10-15T13:00:00Z",
+						Operation:  internal.RuleOperationUpdate,
+					}, history)
+
+					return &dynamodb.TransactWriteItemsOutput{}, tt.transactErr
+				},
+			}
+
+			err := NewRateLimitRulesRepository(client, "rules", "history").Update(
+				context.Background(),
+				internal.RateLimitRule{
+					Type:               "News",
+					NotificationsLimit: 2,
+					IntervalInMinutes:  60,
+					Version:            tt.previousVersion + 1,
+				},
+				internal.RateLimitRuleHistory{
+					RateLimitRule: internal.RateLimitRule{
+						Type:               "News",
+						NotificationsLimit: 1,
+						IntervalInMinutes:  60,
+						Version:            tt.previousVersion,
+					},
+					ReplacedAt: "2023-10-15T13:00:00Z",
+					Operation:  internal.RuleOperationUpdate,
+				},
+			)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRateLimitRulesRepository_Delete test for this method
+func TestRateLimitRulesRepository_Delete(t *testing.T) {
+	client := &mockDynamoAPI{
+		TransactWriteItemsFunc: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+			ruleDelete := input.TransactItems[0].Delete
+			assert.Equal(t, "TYPE#News", aws.StringValue(ruleDelete.Key["pk"].S))
+			assert.Equal(t, "3", aws.StringValue(ruleDelete.ExpressionAttributeValues[":version"].N))
+			assert.Equal(t, internal.RuleOperationDelete, aws.StringValue(input.TransactItems[1].Put.Item["operation"].S))
+
+			return nil, errors.New("transaction error")
+		},
+	}
+
+	err := NewRateLimitRulesRepository(client, "rules", "history").Delete(context.Background(), internal.RateLimitRuleHistory{
+		RateLimitRule: internal.RateLimitRule{Type: "News", Version: 3},
+		Operation:     internal.RuleOperationDelete,
+	})
+	if err == nil || errors.Is(err, internal.ErrRuleVersionConflict) {
+		t.Errorf("Delete() error = %v, want the transaction error", err)
+	}
+}
+
+// TestRateLimitRulesRepository_History test for this method
+func TestRateLimitRulesRepository_History(t *testing.T) {
+	client := &mockDynamoAPI{
+		QueryFunc: func(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "history", aws.StringValue(input.TableName))
+			assert.Equal(t, "TYPE#News", aws.StringValue(input.ExpressionAttributeValues[":pk"].S))
+			assert.False(t, aws.BoolValue(input.ScanIndexForward))
+
+			return &dynamodb.QueryOutput{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{
+						"pk":        {S: aws.String("TYPE#News")},
+						"version":   {N: aws.String("2")},
+						"operation": {S: aws.String(internal.RuleOperationDelete)},
+					},
+					{
+						"pk":        {S: aws.String("TYPE#News")},
+						"version":   {N: aws.String("1")},
+						"operation": {S: aws.String(internal.RuleOperationUpdate)},
+					},
+				},
+			}, nil
+		},
+	}
+
+	got, err := NewRateLimitRulesRepository(client, "rules", "history").History(context.Background(), "News")
+	assert.NoError(t, err)
+
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "News", got[0].Type)
+		assert.Equal(t, 2, got[0].Version)
+		assert.Equal(t, internal.RuleOperationDelete, got[0].Operation)
+		assert.Equal(t, 1, got[1].Version)
+	}
+}
+
 // TestNewRateLimitRulesRepository tests for this repository
 func TestNewRateLimitRulesRepository(t *testing.T) {
-	rateLimitRulesRepository := NewRateLimitRulesRepository(&mockDynamoAPI{}, "rate-limit-rules")
+	rateLimitRulesRepository := NewRateLimitRulesRepository(
+		&mockDynamoAPI{},
+		"rate-limit-rules",
+		"rate-limit-rules-history",
+	)
 
 	type args struct {
-		client    infraestructure.DynamoAPI
-		tableName string
+		client           infraestructure.DynamoAPI
+		tableName        string
+		historyTableName string
 	}
 
 	tests := []struct {
@@ -126,8 +437,9 @@ func TestNewRateLimitRulesRepository(t *testing.T) {
 		{
 			name: "success",
 			args: args{
-				client:    &mockDynamoAPI{},
-				tableName: "rate-limit-rules",
+				client:           &mockDynamoAPI{},
+				tableName:        "rate-limit-rules",
+				historyTableName: "rate-limit-rules-history",
 			},
 			want: rateLimitRulesRepository,
 		},
@@ -135,7 +447,8 @@ func TestNewRateLimitRulesRepository(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewRateLimitRulesRepository(tt.args.client, tt.args.tableName); !reflect.DeepEqual(got, tt.want) {
+			got := NewRateLimitRulesRepository(tt.args.client, tt.args.tableName, tt.args.historyTableName)
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewRateLimitRulesRepository() = %v, want %v", got, tt.want)
 			}
 		})
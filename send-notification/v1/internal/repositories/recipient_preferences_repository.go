@@ -0,0 +1,103 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// RecipientPreferencesRepository struct for this repository
+type RecipientPreferencesRepository struct {
+	client    infraestructure.DynamoAPI
+	tableName string
+}
+
+// GetByEmail get the preferences of a recipient in a tenant given its email, nil when the recipient has no
+// preferences
+func (r *RecipientPreferencesRepository) GetByEmail(
+	ctx context.Context,
+	tenant string,
+	email string,
+) (*internal.RecipientPreferences, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(tenant, email),
+	}
+
+	result, err := r.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var preferences internal.RecipientPreferences
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preferences, nil
+}
+
+// OptOut record that the recipient does not want to receive the given type of the tenant anymore,
+// an empty type unsubscribes the recipient from every notification of the tenant
+func (r *RecipientPreferencesRepository) OptOut(ctx context.Context, tenant, email, notificationType string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:        aws.String(r.tableName),
+		Key:              r.key(tenant, email),
+		UpdateExpression: aws.String("SET email = :email, unsubscribed = :unsubscribed"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":email": {
+				S: aws.String(email),
+			},
+			":unsubscribed": {
+				BOOL: aws.Bool(true),
+			},
+		},
+	}
+
+	if notificationType != "" {
+		input.UpdateExpression = aws.String("SET email = :email ADD opted_out_types :types")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":email": {
+				S: aws.String(email),
+			},
+			":types": {
+				SS: aws.StringSlice([]string{notificationType}),
+			},
+		}
+	}
+
+	_, err := r.client.UpdateItemWithContext(ctx, input)
+
+	return err
+}
+
+// key primary key of the preferences of a recipient in a tenant, the global recipients keep the key without tenant
+func (r *RecipientPreferencesRepository) key(tenant, email string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {
+			S: aws.String(recipientKeyPrefix + internal.TenantType(tenant, email)),
+		},
+	}
+}
+
+// NewRecipientPreferencesRepository instance of a new repository
+func NewRecipientPreferencesRepository(
+	client infraestructure.DynamoAPI,
+	tableName string,
+) *RecipientPreferencesRepository {
+	return &RecipientPreferencesRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
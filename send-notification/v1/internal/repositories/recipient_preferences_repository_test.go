@@ -0,0 +1,161 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// TestRecipientPreferencesRepository_GetByEmail test for this method
+func TestRecipientPreferencesRepository_GetByEmail(t *testing.T) {
+	preferences := internal.RecipientPreferences{
+		PK:            "RECIPIENT#test@example.com",
+		Email:         "test@example.com",
+		OptedOutTypes: []string{"Marketing"},
+		Channels:      []string{"email"},
+	}
+
+	item, _ := dynamodbattribute.MarshalMap(preferences)
+
+	tests := []struct {
+		name    string
+		tenant  string
+		mock    *mockDynamoAPI
+		want    *internal.RecipientPreferences
+		wantErr bool
+	}{
+		{
+			name: "success",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					if aws.StringValue(input.Key["pk"].S) != "RECIPIENT#test@example.com" {
+						return nil, errors.New("unexpected key")
+					}
+
+					return &dynamodb.GetItemOutput{Item: item}, nil
+				},
+			},
+			want: &preferences,
+		},
+		{
+			name:   "preferences in a tenant",
+			tenant: "acme",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					if aws.StringValue(input.Key["pk"].S) != "RECIPIENT#acme:test@example.com" {
+						return nil, errors.New("unexpected key")
+					}
+
+					return &dynamodb.GetItemOutput{Item: item}, nil
+				},
+			},
+			want: &preferences,
+		},
+		{
+			name: "preferences not found",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{}, nil
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "error fetching data",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return nil, errors.New("error fetching data")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecipientPreferencesRepository(tt.mock, "recipient-preferences")
+			got, err := r.GetByEmail(context.Background(), tt.tenant, "test@example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetByEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetByEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRecipientPreferencesRepository_OptOut test for this method
+func TestRecipientPreferencesRepository_OptOut(t *testing.T) {
+	tests := []struct {
+		name             string
+		tenant           string
+		notificationType string
+		updateErr        error
+		wantExpression   string
+		wantKey          string
+		wantErr          bool
+	}{
+		{
+			name:             "opt out of one type",
+			notificationType: "Marketing",
+			wantExpression:   "SET email = :email ADD opted_out_types :types",
+			wantKey:          "RECIPIENT#test@example.com",
+		},
+		{
+			name:           "unsubscribe from every type",
+			wantExpression: "SET email = :email, unsubscribed = :unsubscribed",
+			wantKey:        "RECIPIENT#test@example.com",
+		},
+		{
+			name:             "opt out of one type of a tenant",
+			tenant:           "acme",
+			notificationType: "Marketing",
+			wantExpression:   "SET email = :email ADD opted_out_types :types",
+			wantKey:          "RECIPIENT#acme:test@example.com",
+		},
+		{
+			name:             "error on update",
+			notificationType: "Marketing",
+			updateErr:        errors.New("error on update"),
+			wantExpression:   "SET email = :email ADD opted_out_types :types",
+			wantKey:          "RECIPIENT#test@example.com",
+			wantErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *dynamodb.UpdateItemInput
+
+			mock := &mockDynamoAPI{
+				UpdateItemFunc: func(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+					got = input
+
+					return &dynamodb.UpdateItemOutput{}, tt.updateErr
+				},
+			}
+
+			r := NewRecipientPreferencesRepository(mock, "recipient-preferences")
+			err := r.OptOut(context.Background(), tt.tenant, "test@example.com", tt.notificationType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OptOut() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if aws.StringValue(got.UpdateExpression) != tt.wantExpression {
+				t.Errorf("OptOut() expression = %v, want %v", aws.StringValue(got.UpdateExpression), tt.wantExpression)
+			}
+			if aws.StringValue(got.Key["pk"].S) != tt.wantKey {
+				t.Errorf("OptOut() key = %v", got.Key)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// recipientKeyPrefix prefix of the partition key of the recipients profile and preferences
+const recipientKeyPrefix = "RECIPIENT#"
+
+// RecipientProfileRepository struct for this repository
+type RecipientProfileRepository struct {
+	client    infraestructure.DynamoAPI
+	tableName string
+}
+
+// GetByEmail get the profile of a recipient given its email, nil when the recipient has no profile
+func (r *RecipientProfileRepository) GetByEmail(ctx context.Context, email string) (*internal.RecipientProfile, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(recipientKeyPrefix + email),
+			},
+		},
+	}
+
+	result, err := r.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var profile internal.RecipientProfile
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// NewRecipientProfileRepository instance of a new repository
+func NewRecipientProfileRepository(
+	client infraestructure.DynamoAPI,
+	tableName string,
+) *RecipientProfileRepository {
+	return &RecipientProfileRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
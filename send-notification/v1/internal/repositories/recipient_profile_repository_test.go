@@ -0,0 +1,79 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// TestRecipientProfileRepository_GetByEmail test for this method
+func TestRecipientProfileRepository_GetByEmail(t *testing.T) {
+	profile := internal.RecipientProfile{
+		PK:       "RECIPIENT#test@example.com",
+		Email:    "test@example.com",
+		Timezone: "America/Bogota",
+	}
+
+	item, _ := dynamodbattribute.MarshalMap(profile)
+
+	tests := []struct {
+		name    string
+		mock    *mockDynamoAPI
+		want    *internal.RecipientProfile
+		wantErr bool
+	}{
+		{
+			name: "success",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					if aws.StringValue(input.Key["pk"].S) != "RECIPIENT#test@example.com" {
+						return nil, errors.New("unexpected key")
+					}
+
+					return &dynamodb.GetItemOutput{Item: item}, nil
+				},
+			},
+			want: &profile,
+		},
+		{
+			name: "profile not found",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{}, nil
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "error fetching data",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return nil, errors.New("error fetching data")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecipientProfileRepository(tt.mock, "recipient-profiles")
+			got, err := r.GetByEmail(context.Background(), "test@example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetByEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetByEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
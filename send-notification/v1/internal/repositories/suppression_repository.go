@@ -0,0 +1,137 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// suppressionKeyPrefix prefix of the partition key of the suppressed addresses
+const suppressionKeyPrefix = "EMAIL#"
+
+// SuppressionRepository struct for this repository, the addresses are normalized like in the rate limit so the
+// variants of a suppressed mailbox are suppressed too
+type SuppressionRepository struct {
+	client     infraestructure.DynamoAPI
+	tableName  string
+	normalizer internal.RecipientNormalizer
+}
+
+// GetByEmail get the suppression of an address, nil when the address is not suppressed
+func (r *SuppressionRepository) GetByEmail(ctx context.Context, email string) (*internal.Suppression, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(email),
+	}
+
+	result, err := r.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var suppression internal.Suppression
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &suppression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &suppression, nil
+}
+
+// Save add an address to the suppression list, an existing suppression is replaced
+func (r *SuppressionRepository) Save(ctx context.Context, suppression internal.Suppression) error {
+	suppression.Email = r.normalizer.Normalize(suppression.Email)
+	suppression.PK = suppressionKeyPrefix + suppression.Email
+
+	item, err := dynamodbattribute.MarshalMap(suppression)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+// List get one page of suppressed addresses starting after the cursor, the returned cursor is empty in the last page
+func (r *SuppressionRepository) List(
+	ctx context.Context,
+	limit int,
+	cursor string,
+) ([]internal.Suppression, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	}
+
+	if limit > 0 {
+		input.Limit = aws.Int64(int64(limit))
+	}
+
+	if cursor != "" {
+		input.ExclusiveStartKey = r.key(cursor)
+	}
+
+	result, err := r.client.ScanWithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	suppressions := make([]internal.Suppression, 0, len(result.Items))
+
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &suppressions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if pk, ok := result.LastEvaluatedKey["pk"]; ok {
+		nextCursor = aws.StringValue(pk.S)[len(suppressionKeyPrefix):]
+	}
+
+	return suppressions, nextCursor, nil
+}
+
+// Delete remove an address from the suppression list
+func (r *SuppressionRepository) Delete(ctx context.Context, email string) error {
+	_, err := r.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(email),
+	})
+
+	return err
+}
+
+// key primary key of a suppressed address
+func (r *SuppressionRepository) key(email string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {
+			S: aws.String(suppressionKeyPrefix + r.normalizer.Normalize(email)),
+		},
+	}
+}
+
+// NewSuppressionRepository instance of a new repository
+func NewSuppressionRepository(
+	client infraestructure.DynamoAPI,
+	tableName string,
+	normalizer internal.RecipientNormalizer,
+) *SuppressionRepository {
+	return &SuppressionRepository{
+		client:     client,
+		tableName:  tableName,
+		normalizer: normalizer,
+	}
+}
@@ -0,0 +1,212 @@
+// Package repositories contains all logic related to repositories
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// defaultNormalizer normalization of the recipients when RECIPIENT_NORMALIZATION is not set
+func defaultNormalizer(t *testing.T) internal.RecipientNormalizer {
+	normalizer, err := internal.ParseRecipientNormalizer(internal.DefaultRecipientNormalization)
+	if err != nil {
+		t.Fatalf("ParseRecipientNormalizer() error = %v", err)
+	}
+
+	return normalizer
+}
+
+// TestSuppressionRepository_GetByEmail test for this method, the address is looked up normalized
+func TestSuppressionRepository_GetByEmail(t *testing.T) {
+	suppression := internal.Suppression{
+		PK:        "EMAIL#bounce@example.com",
+		Email:     "bounce@example.com",
+		Reason:    internal.SuppressionReasonBounce,
+		Detail:    "General",
+		CreatedAt: "2023-10-15T13:00:00Z",
+	}
+
+	item, _ := dynamodbattribute.MarshalMap(suppression)
+
+	tests := []struct {
+		name    string
+		mock    *mockDynamoAPI
+		want    *internal.Suppression
+		wantErr bool
+	}{
+		{
+			name: "suppressed",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					if aws.StringValue(input.Key["pk"].S) != "EMAIL#bounce@example.com" {
+						return nil, errors.New("unexpected key")
+					}
+
+					return &dynamodb.GetItemOutput{Item: item}, nil
+				},
+			},
+			want: &suppression,
+		},
+		{
+			name: "not suppressed",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{}, nil
+				},
+			},
+		},
+		{
+			name: "error fetching data",
+			mock: &mockDynamoAPI{
+				GetItemFunc: func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return nil, errors.New("error fetching data")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSuppressionRepository(tt.mock, "suppressions", defaultNormalizer(t))
+			got, err := r.GetByEmail(context.Background(), "Bounce@Example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetByEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetByEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSuppressionRepository_Save test for this method, the address is saved normalized
+func TestSuppressionRepository_Save(t *testing.T) {
+	var got map[string]*dynamodb.AttributeValue
+
+	r := NewSuppressionRepository(&mockDynamoAPI{
+		PutItemFunc: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			got = input.Item
+
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}, "suppressions", defaultNormalizer(t))
+
+	err := r.Save(
+		context.Background(),
+		internal.Suppression{Email: "Bounce@Example.com", Reason: internal.SuppressionReasonBounce},
+	)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if aws.StringValue(got["pk"].S) != "EMAIL#bounce@example.com" || aws.StringValue(got["reason"].S) != "bounce" ||
+		aws.StringValue(got["email"].S) != "bounce@example.com" {
+		t.Errorf("Save() item = %v", got)
+	}
+}
+
+// TestSuppressionRepository_List test for this method
+func TestSuppressionRepository_List(t *testing.T) {
+	item, _ := dynamodbattribute.MarshalMap(internal.Suppression{
+		PK:     "EMAIL#a@example.com",
+		Email:  "a@example.com",
+		Reason: internal.SuppressionReasonComplaint,
+	})
+
+	tests := []struct {
+		name           string
+		cursor         string
+		mock           *mockDynamoAPI
+		want           []internal.Suppression
+		wantNextCursor string
+		wantErr        bool
+	}{
+		{
+			name:   "page with next cursor",
+			cursor: "0@example.com",
+			mock: &mockDynamoAPI{
+				ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+					if aws.StringValue(input.ExclusiveStartKey["pk"].S) != "EMAIL#0@example.com" ||
+						aws.Int64Value(input.Limit) != 1 {
+						return nil, errors.New("unexpected input")
+					}
+
+					return &dynamodb.ScanOutput{
+						Items:            []map[string]*dynamodb.AttributeValue{item},
+						LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("EMAIL#a@example.com")}},
+					}, nil
+				},
+			},
+			want: []internal.Suppression{
+				{PK: "EMAIL#a@example.com", Email: "a@example.com", Reason: internal.SuppressionReasonComplaint},
+			},
+			wantNextCursor: "a@example.com",
+		},
+		{
+			name: "last page",
+			mock: &mockDynamoAPI{
+				ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+					return &dynamodb.ScanOutput{}, nil
+				},
+			},
+			want: []internal.Suppression{},
+		},
+		{
+			name: "error on scan",
+			mock: &mockDynamoAPI{
+				ScanFunc: func(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+					return nil, errors.New("error on scan")
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSuppressionRepository(tt.mock, "suppressions", defaultNormalizer(t))
+			got, nextCursor, err := r.List(context.Background(), 1, tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("List() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+			if nextCursor != tt.wantNextCursor {
+				t.Errorf("List() next cursor = %v, want %v", nextCursor, tt.wantNextCursor)
+			}
+		})
+	}
+}
+
+// TestSuppressionRepository_Delete test for this method, the address is deleted normalized
+func TestSuppressionRepository_Delete(t *testing.T) {
+	var got string
+
+	r := NewSuppressionRepository(&mockDynamoAPI{
+		DeleteItemFunc: func(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+			got = aws.StringValue(input.Key["pk"].S)
+
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}, "suppressions", defaultNormalizer(t))
+
+	if err := r.Delete(context.Background(), "BOUNCE@example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if got != "EMAIL#bounce@example.com" {
+		t.Errorf("Delete() key = %v", got)
+	}
+}
@@ -0,0 +1,26 @@
+// Package internal contains all the main logic
+package internal
+
+import "context"
+
+// requestMetadataKey key of the RequestMetadata in the context of a request
+type requestMetadataKey struct{}
+
+// RequestMetadata attributes of the request that sends the notifications, the emails are tagged with them
+// so the delivery, bounce and open events can be joined back to the request
+type RequestMetadata struct {
+	RequestID string
+	Tenant    string
+}
+
+// WithRequestMetadata context of a request with its metadata
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, metadata)
+}
+
+// RequestMetadataFromContext metadata of the request of the context, empty when it has none
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	metadata, _ := ctx.Value(requestMetadataKey{}).(RequestMetadata)
+
+	return metadata
+}
@@ -0,0 +1,242 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxMessageSize maximum size in bytes of the messages of the types without their own limit
+const DefaultMaxMessageSize = 64 * 1024
+
+// maxRecipientLength maximum length of an email address, the 256 octets of a RFC 5321 path without the brackets
+const maxRecipientLength = 254
+
+// FieldError problem of one attribute of the request, Pointer is the JSON pointer (RFC 6901) of the attribute,
+// empty when the problem is the whole body
+type FieldError struct {
+	Pointer string
+	Detail  string
+}
+
+// ValidationError problems found validating the body of a request, 400 when the body can not be decoded and
+// 422 when its values are not valid
+type ValidationError struct {
+	StatusCode int
+	Fields     []FieldError
+}
+
+// Error get the problems of the attributes in one message
+func (e *ValidationError) Error() string {
+	problems := make([]string, 0, len(e.Fields))
+
+	for _, field := range e.Fields {
+		if field.Pointer == "" {
+			problems = append(problems, field.Detail)
+
+			continue
+		}
+
+		problems = append(problems, fmt.Sprintf("%s: %s", field.Pointer, field.Detail))
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+// ParseMessageSizes parse the maximum size in bytes of the messages of each type, a JSON object like {"Status": 1024}
+func ParseMessageSizes(value string) (map[string]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var sizes map[string]int
+
+	if err := json.Unmarshal([]byte(value), &sizes); err != nil {
+		return nil, fmt.Errorf("invalid message sizes: %w", err)
+	}
+
+	for notificationType, size := range sizes {
+		if !notificationTypeRegexp.MatchString(notificationType) || size <= 0 {
+			return nil, fmt.Errorf("invalid message size %d of type '%s', expected a positive number", size, notificationType)
+		}
+	}
+
+	return sizes, nil
+}
+
+// decodeRequestBody decode the body of a request that sends notifications, the malformed bodies and the values of
+// the wrong type are returned as a ValidationError
+func decodeRequestBody(body string) (RequestBody, error) {
+	var requestBody RequestBody
+
+	err := json.Unmarshal([]byte(body), &requestBody)
+	if err == nil {
+		return requestBody, nil
+	}
+
+	field := FieldError{Detail: "The body is not a valid JSON document: " + err.Error()}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		field = FieldError{
+			Pointer: "/" + strings.ReplaceAll(typeError.Field, ".", "/"),
+			Detail:  fmt.Sprintf("Invalid value, expected a %s but got a %s", typeError.Type, typeError.Value),
+		}
+	}
+
+	return requestBody, &ValidationError{StatusCode: http.StatusBadRequest, Fields: []FieldError{field}}
+}
+
+// Validate check the notifications of the request, every problem found is returned in one ValidationError
+func (b RequestBody) Validate(config HandlerConfig) error {
+	var fields []FieldError
+
+	if len(b.Notifications) == 0 {
+		fields = append(fields, FieldError{
+			Pointer: "/notifications",
+			Detail:  "The request must have at least one notification",
+		})
+	}
+
+	for i, notification := range b.Notifications {
+		fields = append(fields, notification.validate(fmt.Sprintf("/notifications/%d", i), config)...)
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{StatusCode: http.StatusUnprocessableEntity, Fields: fields}
+	}
+
+	return nil
+}
+
+// validate check the attributes of the notification, pointer is the JSON pointer of the notification
+func (n Notification) validate(pointer string, config HandlerConfig) []FieldError {
+	var fields []FieldError
+
+	if !notificationTypeRegexp.MatchString(n.Type) {
+		fields = append(fields, FieldError{
+			Pointer: pointer + "/type",
+			Detail:  "The type must have between 1 and 64 letters, digits, '_', '.' or '-'",
+		})
+	}
+
+	fields = append(fields, n.validateRecipients(pointer)...)
+
+	if detail := messageProblem(n.Message, config.messageSize(n.Type)); detail != "" {
+		fields = append(fields, FieldError{Pointer: pointer + "/message", Detail: detail})
+	}
+
+	fields = append(fields, n.validateAttachments(pointer, config.MaxAttachmentsSize)...)
+
+	if n.Priority != "" && PriorityRank(n.Priority) == 0 {
+		fields = append(fields, FieldError{
+			Pointer: pointer + "/priority",
+			Detail:  "The priority must be critical, high, normal or low",
+		})
+	}
+
+	return fields
+}
+
+// validateRecipients check the recipient or the lists to, cc and bcc of the notification, only one of them can
+// be used and every address can be only once in the lists
+func (n Notification) validateRecipients(pointer string) []FieldError {
+	if !n.HasRecipientList() {
+		if detail := recipientProblem(n.Recipient); detail != "" {
+			return []FieldError{{Pointer: pointer + "/recipient", Detail: detail}}
+		}
+
+		return nil
+	}
+
+	if n.Recipient != "" {
+		return []FieldError{{
+			Pointer: pointer + "/recipient",
+			Detail:  "The recipient can not be used with the lists to, cc and bcc",
+		}}
+	}
+
+	if count := len(n.To) + len(n.Cc) + len(n.Bcc); count > MaxRecipientsPerNotification {
+		return []FieldError{{
+			Pointer: pointer + "/to",
+			Detail: fmt.Sprintf("The notification has %d recipients, the maximum is %d",
+				count, MaxRecipientsPerNotification),
+		}}
+	}
+
+	var fields []FieldError
+
+	seen := make(map[string]bool, len(n.To)+len(n.Cc)+len(n.Bcc))
+
+	for _, list := range []struct {
+		kind      string
+		addresses []string
+	}{
+		{RecipientKindTo, n.To},
+		{RecipientKindCc, n.Cc},
+		{RecipientKindBcc, n.Bcc},
+	} {
+		for i, address := range list.addresses {
+			field := FieldError{Pointer: fmt.Sprintf("%s/%s/%d", pointer, list.kind, i)}
+
+			switch detail := recipientProblem(address); {
+			case detail != "":
+				field.Detail = detail
+			case seen[strings.ToLower(address)]:
+				field.Detail = "The recipient is already in the lists of the notification"
+			default:
+				seen[strings.ToLower(address)] = true
+
+				continue
+			}
+
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// recipientProblem problem of the recipient, empty when it is a plain RFC 5322 address like user@example.com
+func recipientProblem(recipient string) string {
+	if recipient == "" {
+		return "The recipient is required"
+	}
+
+	if len(recipient) > maxRecipientLength {
+		return fmt.Sprintf("The recipient must have at most %d characters", maxRecipientLength)
+	}
+
+	// The display name and the comments are rejected, the recipient is used as is in the keys of the rate limit
+	address, err := mail.ParseAddress(recipient)
+	if err != nil || address.Name != "" || address.Address != recipient || !strings.Contains(address.Address, "@") {
+		return "The recipient must be an email address like user@example.com"
+	}
+
+	return ""
+}
+
+// messageProblem problem of the message, empty when it is not empty, it fits the size and it has no control
+// characters other than tabs and line breaks
+func messageProblem(message string, maxSize int) string {
+	if strings.TrimSpace(message) == "" {
+		return "The message is required"
+	}
+
+	if len(message) > maxSize {
+		return fmt.Sprintf("The message has %d bytes, the maximum of its type is %d", len(message), maxSize)
+	}
+
+	for _, character := range message {
+		if unicode.IsControl(character) && character != '\t' && character != '\n' && character != '\r' {
+			return fmt.Sprintf("The message has the control character %U", character)
+		}
+	}
+
+	return ""
+}
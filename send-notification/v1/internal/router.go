@@ -0,0 +1,130 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Route handler of one method and resource of the API
+type Route func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Router dispatches every API Gateway event to the handler of its method and resource
+type Router struct {
+	routes       map[string]Route
+	defaultRoute Route
+}
+
+// Add register the handler of a method and resource, e.g. "POST" and "/v1"
+func (r *Router) Add(method, resource string, route Route) *Router {
+	r.routes[method+" "+resource] = route
+
+	return r
+}
+
+// Handle main method to execute this lambda function
+func (r *Router) Handle(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	resource := event.Resource
+	if resource == "" {
+		resource = event.Path
+	}
+
+	// Direct invocations without method nor path keep sending notifications
+	if event.HTTPMethod == "" && resource == "" {
+		return r.defaultRoute(ctx, event)
+	}
+
+	route, ok := r.routes[event.HTTPMethod+" "+resource]
+	if !ok && event.Resource == "" {
+		route, ok = r.match(&event)
+	}
+
+	if !ok {
+		return responseError(&GeneralError{
+			Code:       CodeRouteError,
+			ID:         IDRouteNotFound,
+			Message:    fmt.Sprintf("Route '%s %s' not found", event.HTTPMethod, resource),
+			StatusCode: http.StatusNotFound,
+		})
+	}
+
+	return route(ctx, event)
+}
+
+// match find the route of a path without resource, like the ones of the local server, by matching it with the
+// resources of the routes; the segments of the path that match a parameter like {type} are the path parameters
+func (r *Router) match(event *events.APIGatewayProxyRequest) (Route, bool) {
+	segments := strings.Split(event.Path, "/")
+
+	for key, route := range r.routes {
+		method, resource, _ := strings.Cut(key, " ")
+		if method != event.HTTPMethod {
+			continue
+		}
+
+		parameters, ok := matchResource(strings.Split(resource, "/"), segments)
+		if !ok {
+			continue
+		}
+
+		event.Resource = resource
+		event.PathParameters = parameters
+
+		return route, true
+	}
+
+	return nil, false
+}
+
+// matchResource path parameters of the segments of a path, false when the path is not of the resource
+func matchResource(resource, segments []string) (map[string]string, bool) {
+	if len(resource) != len(segments) {
+		return nil, false
+	}
+
+	parameters := map[string]string{}
+
+	for i, segment := range resource {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segments[i] != "" {
+			parameters[strings.Trim(segment, "{}")] = segments[i]
+
+			continue
+		}
+
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return parameters, true
+}
+
+// NewRouter Initialize Router with the routes of every handler
+func NewRouter(
+	handler *Handler,
+	unsubscribeHandler *UnsubscribeHandler,
+	suppressionsHandler *SuppressionsHandler,
+	rulesHandler *RulesHandler,
+) *Router {
+	router := &Router{
+		routes:       map[string]Route{},
+		defaultRoute: handler.Handle,
+	}
+
+	return router.
+		Add(http.MethodPost, "/v1", handler.Handle).
+		Add(http.MethodGet, "/v1/unsubscribe", unsubscribeHandler.Handle).
+		Add(http.MethodPost, "/v1/unsubscribe", unsubscribeHandler.Handle).
+		Add(http.MethodGet, "/v1/admin/suppressions", suppressionsHandler.List).
+		Add(http.MethodDelete, "/v1/admin/suppressions/{email}", suppressionsHandler.Remove).
+		Add(http.MethodGet, "/v1/admin/rules", rulesHandler.List).
+		Add(http.MethodPost, "/v1/admin/rules", rulesHandler.Create).
+		Add(http.MethodGet, "/v1/admin/rules/{type}", rulesHandler.Get).
+		Add(http.MethodPut, "/v1/admin/rules/{type}", rulesHandler.Update).
+		Add(http.MethodDelete, "/v1/admin/rules/{type}", rulesHandler.Delete).
+		Add(http.MethodGet, "/v1/admin/rules/{type}/history", rulesHandler.History)
+}
@@ -0,0 +1,98 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Handle(t *testing.T) {
+	notificationsBody := `{"notifications":[{"type":"News","recipient":"user@example.com","message":"Hello"}]}`
+	handler := NewHandler(
+		&mockAuthenticateClientUC{},
+		&mockValidateRateLimitUC{
+			handleFunc: func(notification Notification) (ValidationResult, error) {
+				return ValidationResult{Reason: RejectionReasonRateLimited}, nil
+			},
+		},
+		&mockSendNotificationUC{},
+		HandlerConfig{},
+		&mockLogger{},
+	)
+	unsubscribeHandler := NewUnsubscribeHandler(&mockUnsubscribeUC{
+		handleFunc: func(token string) error {
+			return nil
+		},
+	}, &mockLogger{})
+
+	tests := []struct {
+		name           string
+		event          events.APIGatewayProxyRequest
+		wantStatusCode int
+		wantBody       string
+		wantContains   string
+	}{
+		{
+			name:           "send notifications",
+			event:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Resource: "/v1", Body: notificationsBody},
+			wantStatusCode: http.StatusOK,
+			wantContains:   `"reason":"rate_limited"`,
+		},
+		{
+			name:           "direct invocation sends notifications",
+			event:          events.APIGatewayProxyRequest{Body: notificationsBody},
+			wantStatusCode: http.StatusOK,
+			wantContains:   `"reason":"rate_limited"`,
+		},
+		{
+			name:           "unsubscribe by path",
+			event:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/v1/unsubscribe"},
+			wantStatusCode: http.StatusOK,
+			wantBody:       unsubscribedPage,
+		},
+		{
+			name:           "path parameter of a path without resource",
+			event:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/v1/admin/rules/Daily%20news"},
+			wantStatusCode: http.StatusOK,
+			wantContains:   `"type":"Daily news"`,
+		},
+		{
+			name:           "path without resource with an empty parameter",
+			event:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/v1/admin/rules//history"},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "route not found",
+			event:          events.APIGatewayProxyRequest{HTTPMethod: http.MethodDelete, Resource: "/v1"},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(
+				handler,
+				unsubscribeHandler,
+				NewSuppressionsHandler(&mockManageSuppressionsUC{}, &mockLogger{}),
+				NewRulesHandler(&mockManageRulesUC{
+					getFunc: func(notificationType string) (*RateLimitRule, error) {
+						return &RateLimitRule{Type: notificationType, NotificationsLimit: 1, Interval: "1h"}, nil
+					},
+				}, &mockLogger{}),
+			)
+			resp, err := router.Handle(context.Background(), tt.event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, resp.Body)
+			}
+
+			assert.Contains(t, resp.Body, tt.wantContains)
+		})
+	}
+}
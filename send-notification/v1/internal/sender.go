@@ -0,0 +1,157 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Sender identity that sends the emails, the empty attributes are taken from the sender it overrides
+type Sender struct {
+	FromAddress string `dynamodbav:"from_address,omitempty" json:"from_address,omitempty"`
+	DisplayName string `dynamodbav:"display_name,omitempty" json:"display_name,omitempty"`
+	ReplyTo     string `dynamodbav:"reply_to,omitempty" json:"reply_to,omitempty"`
+}
+
+// Validate check that the addresses of the sender are plain addresses, every problem found is returned in one error
+func (s Sender) Validate() error {
+	var problems []string
+
+	for _, address := range [][2]string{{"from_address", s.FromAddress}, {"reply_to", s.ReplyTo}} {
+		if address[1] == "" {
+			continue
+		}
+
+		parsed, err := mail.ParseAddress(address[1])
+		if err != nil || parsed.Name != "" || parsed.Address != address[1] {
+			problems = append(problems, fmt.Sprintf("sender %s '%s' is not an email address", address[0], address[1]))
+		}
+	}
+
+	if strings.ContainsAny(s.DisplayName, "\r\n") {
+		problems = append(problems, "sender display_name must be one line")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Override sender with the attributes of the given sender that are set
+func (s Sender) Override(sender *Sender) Sender {
+	if sender == nil {
+		return s
+	}
+
+	if sender.FromAddress != "" {
+		s.FromAddress = sender.FromAddress
+	}
+
+	if sender.DisplayName != "" {
+		s.DisplayName = sender.DisplayName
+	}
+
+	if sender.ReplyTo != "" {
+		s.ReplyTo = sender.ReplyTo
+	}
+
+	return s
+}
+
+// Address from address with the display name, e.g. "Modak <notifications@example.com>"
+func (s Sender) Address() string {
+	if s.DisplayName == "" {
+		return s.FromAddress
+	}
+
+	return (&mail.Address{Name: s.DisplayName, Address: s.FromAddress}).String()
+}
+
+// SenderConfig senders of the emails, the default sender is overridden by the sender of the tenant
+// and then by the sender of the rule of the type
+type SenderConfig struct {
+	Default Sender
+	Tenants map[string]Sender
+	// AllowedIdentities addresses or domains that may send, the default from address is always allowed
+	AllowedIdentities []string
+}
+
+// ParseSenderConfig parse the sender config, tenants is a JSON object with the sender of each tenant and
+// allowedIdentities a comma separated list of addresses or domains
+func ParseSenderConfig(defaultSender Sender, tenants, allowedIdentities string) (SenderConfig, error) {
+	config := SenderConfig{Default: defaultSender}
+
+	if defaultSender.FromAddress == "" {
+		return SenderConfig{}, errors.New("the default sender needs a from address")
+	}
+
+	if err := defaultSender.Validate(); err != nil {
+		return SenderConfig{}, err
+	}
+
+	if tenants != "" {
+		if err := json.Unmarshal([]byte(tenants), &config.Tenants); err != nil {
+			return SenderConfig{}, fmt.Errorf("invalid tenant senders: %w", err)
+		}
+	}
+
+	for tenant, sender := range config.Tenants {
+		if err := sender.Validate(); err != nil {
+			return SenderConfig{}, fmt.Errorf("tenant '%s': %w", tenant, err)
+		}
+	}
+
+	for _, identity := range strings.Split(allowedIdentities, ",") {
+		if identity = strings.TrimSpace(identity); identity != "" {
+			config.AllowedIdentities = append(config.AllowedIdentities, identity)
+		}
+	}
+
+	// The senders of the config are checked when it loads, only the senders of the rules are checked per request
+	for tenant := range config.Tenants {
+		if address := config.Resolve(tenant, nil).FromAddress; !config.Allows(address) {
+			return SenderConfig{}, fmt.Errorf(
+				"tenant '%s': sender from_address '%s' is not an allowed identity", tenant, address,
+			)
+		}
+	}
+
+	return config, nil
+}
+
+// Resolve sender of the emails of a tenant, typeSender is the sender of the rule of the type, nil when it has none
+func (c SenderConfig) Resolve(tenant string, typeSender *Sender) Sender {
+	sender := c.Default
+
+	if tenantSender, ok := c.Tenants[tenant]; ok && tenant != "" {
+		sender = sender.Override(&tenantSender)
+	}
+
+	return sender.Override(typeSender)
+}
+
+// Allows check if the address is the default from address, an allowed address or an address of an allowed domain
+func (c SenderConfig) Allows(address string) bool {
+	if strings.EqualFold(address, c.Default.FromAddress) {
+		return true
+	}
+
+	_, domain, _ := strings.Cut(address, "@")
+
+	for _, identity := range c.AllowedIdentities {
+		if strings.EqualFold(identity, address) {
+			return true
+		}
+
+		if !strings.Contains(identity, "@") && strings.EqualFold(identity, domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSenderConfig test for this function
+func TestParseSenderConfig(t *testing.T) {
+	tests := []struct {
+		name              string
+		defaultSender     Sender
+		tenants           string
+		allowedIdentities string
+		want              SenderConfig
+		wantErr           bool
+	}{
+		{
+			name:          "only the default sender",
+			defaultSender: Sender{FromAddress: "notifications@example.com"},
+			want:          SenderConfig{Default: Sender{FromAddress: "notifications@example.com"}},
+		},
+		{
+			name:              "tenants and allowed identities",
+			defaultSender:     Sender{FromAddress: "notifications@example.com", DisplayName: "Modak"},
+			tenants:           `{"acme":{"from_address":"alerts@acme.com","display_name":"Acme"}}`,
+			allowedIdentities: "acme.com, news@example.org,",
+			want: SenderConfig{
+				Default:           Sender{FromAddress: "notifications@example.com", DisplayName: "Modak"},
+				Tenants:           map[string]Sender{"acme": {FromAddress: "alerts@acme.com", DisplayName: "Acme"}},
+				AllowedIdentities: []string{"acme.com", "news@example.org"},
+			},
+		},
+		{
+			name:    "missing default from address",
+			wantErr: true,
+		},
+		{
+			name:          "default from address with display name",
+			defaultSender: Sender{FromAddress: "Modak <notifications@example.com>"},
+			wantErr:       true,
+		},
+		{
+			name:          "invalid tenants",
+			defaultSender: Sender{FromAddress: "notifications@example.com"},
+			tenants:       `{"acme":`,
+			wantErr:       true,
+		},
+		{
+			name:              "sender of a tenant not allowed",
+			defaultSender:     Sender{FromAddress: "notifications@example.com"},
+			tenants:           `{"acme":{"from_address":"alerts@acme.com"}}`,
+			allowedIdentities: "news@example.org",
+			wantErr:           true,
+		},
+		{
+			name:          "invalid reply-to of a tenant",
+			defaultSender: Sender{FromAddress: "notifications@example.com"},
+			tenants:       `{"acme":{"reply_to":"help"}}`,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSenderConfig(tt.defaultSender, tt.tenants, tt.allowedIdentities)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestSenderConfig_Resolve test for this method
+func TestSenderConfig_Resolve(t *testing.T) {
+	config := SenderConfig{
+		Default: Sender{FromAddress: "notifications@example.com", DisplayName: "Modak", ReplyTo: "help@example.com"},
+		Tenants: map[string]Sender{"acme": {FromAddress: "alerts@acme.com", DisplayName: "Acme"}},
+	}
+
+	tests := []struct {
+		name       string
+		tenant     string
+		typeSender *Sender
+		want       Sender
+	}{
+		{
+			name: "default sender",
+			want: Sender{FromAddress: "notifications@example.com", DisplayName: "Modak", ReplyTo: "help@example.com"},
+		},
+		{
+			name:   "tenant sender keeps the default reply-to",
+			tenant: "acme",
+			want:   Sender{FromAddress: "alerts@acme.com", DisplayName: "Acme", ReplyTo: "help@example.com"},
+		},
+		{
+			name:       "type sender overrides the tenant sender",
+			tenant:     "acme",
+			typeSender: &Sender{DisplayName: "Acme News"},
+			want:       Sender{FromAddress: "alerts@acme.com", DisplayName: "Acme News", ReplyTo: "help@example.com"},
+		},
+		{
+			name:       "tenant without sender",
+			tenant:     "globex",
+			typeSender: &Sender{FromAddress: "news@example.com"},
+			want:       Sender{FromAddress: "news@example.com", DisplayName: "Modak", ReplyTo: "help@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, config.Resolve(tt.tenant, tt.typeSender))
+		})
+	}
+}
+
+// TestSenderConfig_Allows test for this method
+func TestSenderConfig_Allows(t *testing.T) {
+	config := SenderConfig{
+		Default:           Sender{FromAddress: "notifications@example.com"},
+		AllowedIdentities: []string{"acme.com", "news@example.org"},
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    bool
+	}{
+		{name: "default from address", address: "Notifications@Example.com", want: true},
+		{name: "address of an allowed domain", address: "alerts@acme.com", want: true},
+		{name: "allowed address", address: "news@example.org", want: true},
+		{name: "other address of the domain of an allowed address", address: "sales@example.org", want: false},
+		{name: "subdomain of an allowed domain", address: "alerts@mail.acme.com", want: false},
+		{name: "other domain", address: "alerts@example.net", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, config.Allows(tt.address))
+		})
+	}
+}
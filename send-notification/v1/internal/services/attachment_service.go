@@ -0,0 +1,127 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"modak/send-notification/v1/internal"
+	"modak/send-notification/v1/internal/infraestructure"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// missingObjectCodes error codes of S3 for the objects that do not exist or can not be read by the function
+var missingObjectCodes = map[string]bool{
+	s3.ErrCodeNoSuchKey:    true,
+	s3.ErrCodeNoSuchBucket: true,
+	"NotFound":             true,
+	"AccessDenied":         true,
+}
+
+// AttachmentService struct for this service
+type AttachmentService struct {
+	client  infraestructure.S3API
+	maxSize int
+}
+
+// Load get the content of the attachments, decoding it from base64 or reading it from the object store. The
+// attachments that can not be loaded and the attachments larger than the limit return internal.ErrInvalidAttachment
+func (s *AttachmentService) Load(
+	ctx context.Context,
+	attachments []internal.Attachment,
+) ([]internal.EmailAttachment, error) {
+	loaded := make([]internal.EmailAttachment, 0, len(attachments))
+	size := 0
+
+	for _, attachment := range attachments {
+		data, err := s.content(ctx, attachment, s.maxSize-size)
+		if err != nil {
+			return nil, err
+		}
+
+		size += len(data)
+
+		loaded = append(loaded, internal.EmailAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Data:        data,
+		})
+	}
+
+	return loaded, nil
+}
+
+// content decoded content of the attachment, at most maxSize bytes
+func (s *AttachmentService) content(ctx context.Context, attachment internal.Attachment, maxSize int) ([]byte, error) {
+	if attachment.ObjectPath == "" {
+		data, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is not encoded in base64",
+				internal.ErrInvalidAttachment, attachment.Filename)
+		}
+
+		if len(data) > maxSize {
+			return nil, s.tooLarge(attachment)
+		}
+
+		return data, nil
+	}
+
+	bucket, key, err := internal.ParseObjectPath(attachment.ObjectPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", internal.ErrInvalidAttachment, err)
+	}
+
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && missingObjectCodes[awsErr.Code()] {
+			return nil, fmt.Errorf("%w: %s can not be read: %s",
+				internal.ErrInvalidAttachment, attachment.ObjectPath, err)
+		}
+
+		return nil, err
+	}
+
+	defer output.Body.Close()
+
+	if aws.Int64Value(output.ContentLength) > int64(maxSize) {
+		return nil, s.tooLarge(attachment)
+	}
+
+	// The length is checked again reading the body, one byte over the limit is enough to reject it
+	data, err := io.ReadAll(io.LimitReader(output.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxSize {
+		return nil, s.tooLarge(attachment)
+	}
+
+	return data, nil
+}
+
+// tooLarge error of an attachment that does not fit in the limit of the attachments
+func (s *AttachmentService) tooLarge(attachment internal.Attachment) error {
+	return fmt.Errorf("%w: the attachments with %s have more than %d bytes",
+		internal.ErrInvalidAttachment, attachment.Filename, s.maxSize)
+}
+
+// NewAttachmentService creates a new instance of the attachment service, maxSize is the maximum size in bytes of
+// the attachments of one email
+func NewAttachmentService(client infraestructure.S3API, maxSize int) *AttachmentService {
+	return &AttachmentService{
+		client:  client,
+		maxSize: maxSize,
+	}
+}
@@ -0,0 +1,144 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockS3API mock for S3 API
+type mockS3API struct {
+	GetObjectFunc func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+// GetObjectWithContext mock for this method to get an object
+func (m *mockS3API) GetObjectWithContext(
+	_ aws.Context,
+	input *s3.GetObjectInput,
+	_ ...request.Option,
+) (*s3.GetObjectOutput, error) {
+	return m.GetObjectFunc(input)
+}
+
+// objects mock of S3 with the given objects by bucket/key, the length is only known for the objects of lengths
+func objects(contents map[string]string, lengths map[string]int64) *mockS3API {
+	return &mockS3API{
+		GetObjectFunc: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+			path := aws.StringValue(input.Bucket) + "/" + aws.StringValue(input.Key)
+
+			content, ok := contents[path]
+			if !ok {
+				return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+			}
+
+			output := &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}
+			if length, ok := lengths[path]; ok {
+				output.ContentLength = aws.Int64(length)
+			}
+
+			return output, nil
+		},
+	}
+}
+
+// TestAttachmentService_Load test for this method
+func TestAttachmentService_Load(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *mockS3API
+		maxSize     int
+		attachments []internal.Attachment
+		want        []internal.EmailAttachment
+		wantInvalid bool
+		wantErr     bool
+	}{
+		{
+			name:    "base64 content and object",
+			client:  objects(map[string]string{"reports/2023/october.pdf": "%PDF-1.7"}, nil),
+			maxSize: 23,
+			attachments: []internal.Attachment{
+				{Filename: "invite.ics", ContentType: "text/calendar", Content: "QkVHSU46VkNBTEVOREFS"},
+				{Filename: "october.pdf", ContentType: "application/pdf", ObjectPath: "s3://reports/2023/october.pdf"},
+			},
+			want: []internal.EmailAttachment{
+				{Filename: "invite.ics", ContentType: "text/calendar", Data: []byte("BEGIN:VCALENDAR")},
+				{Filename: "october.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.7")},
+			},
+		},
+		{
+			name:        "without attachments",
+			client:      objects(nil, nil),
+			maxSize:     20,
+			attachments: nil,
+			want:        []internal.EmailAttachment{},
+		},
+		{
+			name:        "missing object",
+			client:      objects(nil, nil),
+			maxSize:     20,
+			attachments: []internal.Attachment{{Filename: "october.pdf", ObjectPath: "s3://reports/october.pdf"}},
+			wantInvalid: true,
+		},
+		{
+			name: "objects larger than the limit by their length",
+			client: objects(
+				map[string]string{"reports/october.pdf": "%PDF"},
+				map[string]int64{"reports/october.pdf": 30},
+			),
+			maxSize: 20,
+			attachments: []internal.Attachment{
+				{Filename: "october.pdf", ObjectPath: "s3://reports/october.pdf"},
+			},
+			wantInvalid: true,
+		},
+		{
+			name:    "attachments larger than the limit together",
+			client:  objects(map[string]string{"reports/october.pdf": "%PDF-1.7"}, nil),
+			maxSize: 20,
+			attachments: []internal.Attachment{
+				{Filename: "invite.ics", ContentType: "text/calendar", Content: "QkVHSU46VkNBTEVOREFS"},
+				{Filename: "october.pdf", ObjectPath: "s3://reports/october.pdf"},
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "object store error",
+			client: &mockS3API{
+				GetObjectFunc: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+					return nil, awserr.New("SlowDown", "Please reduce your request rate.", nil)
+				},
+			},
+			maxSize:     20,
+			attachments: []internal.Attachment{{Filename: "october.pdf", ObjectPath: "s3://reports/october.pdf"}},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAttachmentService(tt.client, tt.maxSize).Load(context.Background(), tt.attachments)
+
+			switch {
+			case tt.wantInvalid:
+				assert.ErrorIs(t, err, internal.ErrInvalidAttachment)
+			case tt.wantErr:
+				assert.Error(t, err)
+				assert.False(t, errors.Is(err, internal.ErrInvalidAttachment))
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
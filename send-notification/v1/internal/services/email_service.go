@@ -2,51 +2,300 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"modak/send-notification/v1/internal"
 	"modak/send-notification/v1/internal/infraestructure"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+)
+
+// Names of the message tags of the emails, SES adds them to the events of the configuration set
+const (
+	tagType      = "type"
+	tagTenant    = "tenant"
+	tagRequestID = "request_id"
 )
 
-// EmailSource address information about sender
-const EmailSource = "kahs_kevin@hotmail.com"
+// maxTagValueLength maximum length of the value of a message tag
+const maxTagValueLength = 256
+
+// maxRawMessageSize maximum size in bytes of a raw email accepted by SES, after the encoding of the attachments
+const maxRawMessageSize = 40 * 1024 * 1024
+
+// base64LineLength maximum length of the lines of the attachments encoded in base64
+const base64LineLength = 76
 
 // EmailService struct for this service
 type EmailService struct {
-	client infraestructure.SESAPI
+	client           infraestructure.SESAPI
+	configurationSet string
 }
 
-// Send sends an email using Amazon SES
-func (s *EmailService) Send(recipient, subject, message string) error {
-	input := &ses.SendEmailInput{
-		Destination: &ses.Destination{
-			ToAddresses: []*string{
-				aws.String(recipient),
-			},
+// errMissingSender the email has no from address
+var errMissingSender = errors.New("the email has no sender")
+
+// errDestinations the email has no destination or more than SES accepts in one email
+var errDestinations = fmt.Errorf("the email must have between 1 and %d destinations", internal.MaxEmailDestinations)
+
+// Send sends an email using Amazon SES and returns the ID that SES gave to the message
+func (s *EmailService) Send(ctx context.Context, email internal.Email) (string, error) {
+	if email.Sender.FromAddress == "" {
+		return "", errMissingSender
+	}
+
+	if email.DestinationCount() == 0 || email.DestinationCount() > internal.MaxEmailDestinations {
+		return "", errDestinations
+	}
+
+	message, err := s.buildMessage(email)
+	if err != nil {
+		return "", err
+	}
+
+	input := &sesv2.SendEmailInput{
+		Destination: &sesv2.Destination{
+			ToAddresses:  aws.StringSlice(email.To),
+			CcAddresses:  aws.StringSlice(email.Cc),
+			BccAddresses: aws.StringSlice(email.Bcc),
 		},
-		Message: &ses.Message{
-			Body: &ses.Body{
-				Text: &ses.Content{
-					Charset: aws.String("UTF-8"),
-					Data:    aws.String(message),
-				},
-			},
-			Subject: &ses.Content{
-				Charset: aws.String("UTF-8"),
-				Data:    aws.String(subject),
+		Content: &sesv2.EmailContent{
+			Raw: &sesv2.RawMessage{
+				Data: message,
 			},
 		},
-		Source: aws.String(EmailSource),
+		FromEmailAddress: aws.String(email.Sender.Address()),
+		EmailTags:        s.tags(email),
 	}
 
-	_, err := s.client.SendEmail(input)
+	if email.Sender.ReplyTo != "" {
+		input.ReplyToAddresses = []*string{aws.String(email.Sender.ReplyTo)}
+	}
+
+	if s.configurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.configurationSet)
+	}
+
+	output, err := s.client.SendEmailWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.MessageId), nil
+}
+
+// tags message tags of the email, the empty attributes are not tagged
+func (s *EmailService) tags(email internal.Email) []*sesv2.MessageTag {
+	var tags []*sesv2.MessageTag
+
+	for _, tag := range [][2]string{
+		{tagType, email.Type},
+		{tagTenant, email.Tenant},
+		{tagRequestID, email.RequestID},
+	} {
+		if tag[1] == "" {
+			continue
+		}
+
+		tags = append(tags, &sesv2.MessageTag{
+			Name:  aws.String(tag[0]),
+			Value: aws.String(tagValue(tag[1])),
+		})
+	}
+
+	return tags
+}
+
+// tagValue value accepted by SES for a message tag, the characters other than ASCII letters, numbers,
+// underscores and dashes are replaced by underscores
+func tagValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+
+	if len(value) > maxTagValueLength {
+		value = value[:maxTagValueLength]
+	}
+
+	return value
+}
+
+// buildMessage build the MIME message, when the email has an unsubscribe link it is added to the
+// body and to the List-Unsubscribe headers so the clients can offer one-click unsubscribe (RFC 8058). The emails
+// with attachments are multipart/mixed messages
+func (s *EmailService) buildMessage(email internal.Email) ([]byte, error) {
+	var message bytes.Buffer
+
+	body := email.Body
+
+	fmt.Fprintf(&message, "From: %s\r\n", email.Sender.Address())
+
+	if email.Sender.ReplyTo != "" {
+		fmt.Fprintf(&message, "Reply-To: %s\r\n", (&mail.Address{Address: email.Sender.ReplyTo}).String())
+	}
+
+	// The Bcc addresses are only destinations of the email
+	if len(email.To) > 0 {
+		fmt.Fprintf(&message, "To: %s\r\n", addressList(email.To))
+	}
+
+	if len(email.Cc) > 0 {
+		fmt.Fprintf(&message, "Cc: %s\r\n", addressList(email.Cc))
+	}
+
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+
+	if email.UnsubscribeURL != "" {
+		fmt.Fprintf(&message, "List-Unsubscribe: <%s>\r\n", email.UnsubscribeURL)
+		fmt.Fprintf(&message, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+
+		body += "\n\n--\nTo stop receiving these emails visit " + email.UnsubscribeURL
+	}
+
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+
+	if len(email.Attachments) == 0 {
+		fmt.Fprintf(&message, "Content-Type: text/plain; charset=UTF-8\r\n")
+		fmt.Fprintf(&message, "Content-Transfer-Encoding: quoted-printable\r\n")
+		fmt.Fprintf(&message, "\r\n")
+
+		if err := writeQuotedPrintable(&message, body); err != nil {
+			return nil, err
+		}
+
+		return message.Bytes(), nil
+	}
+
+	if err := writeMixed(&message, body, email.Attachments); err != nil {
+		return nil, err
+	}
+
+	if message.Len() > maxRawMessageSize {
+		return nil, fmt.Errorf("%w: the email has %d bytes, SES accepts %d",
+			internal.ErrInvalidAttachment, message.Len(), maxRawMessageSize)
+	}
+
+	return message.Bytes(), nil
+}
+
+// writeMixed write the body of a multipart/mixed message, the text of the email followed by the attachments
+// encoded in base64
+func writeMixed(message *bytes.Buffer, body string, attachments []internal.EmailAttachment) error {
+	writer := multipart.NewWriter(message)
+
+	fmt.Fprintf(message, "Content-Type: %s\r\n", mime.FormatMediaType("multipart/mixed", map[string]string{
+		"boundary": writer.Boundary(),
+	}))
+	fmt.Fprintf(message, "\r\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeQuotedPrintable(part, body); err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachmentContentType(attachment)},
+			"Content-Disposition":       {disposition},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// attachmentContentType media type of the attachment with its name, application/octet-stream when it is not valid
+func attachmentContentType(attachment internal.EmailAttachment) string {
+	mediaType, params, err := mime.ParseMediaType(attachment.ContentType)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	params["name"] = attachment.Filename
+
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// writeQuotedPrintable write the text encoded as quoted-printable
+func writeQuotedPrintable(w io.Writer, text string) error {
+	writer := quotedprintable.NewWriter(w)
+
+	if _, err := writer.Write([]byte(text)); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// writeBase64 write the data encoded in base64 in lines of base64LineLength characters (RFC 2045)
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	for len(encoded) > 0 {
+		line := encoded
+		if len(line) > base64LineLength {
+			line = line[:base64LineLength]
+		}
+
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+
+		encoded = encoded[len(line):]
+	}
+
+	return nil
+}
+
+// addressList value of an address header with several addresses
+func addressList(addresses []string) string {
+	formatted := make([]string, 0, len(addresses))
+
+	for _, address := range addresses {
+		formatted = append(formatted, (&mail.Address{Address: address}).String())
+	}
 
-	return err
+	return strings.Join(formatted, ", ")
 }
 
-// NewEmailService creates a new instance of the email service
-func NewEmailService(client infraestructure.SESAPI) *EmailService {
+// NewEmailService creates a new instance of the email service, the events of the emails are published
+// to the given configuration set, empty to use the default configuration set of the identity
+func NewEmailService(client infraestructure.SESAPI, configurationSet string) *EmailService {
 	return &EmailService{
-		client: client,
+		client:           client,
+		configurationSet: configurationSet,
 	}
 }
@@ -2,23 +2,43 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
 	"testing"
 
+	"modak/send-notification/v1/internal"
 	"modak/send-notification/v1/internal/infraestructure"
 
-	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
 	"github.com/stretchr/testify/assert"
 )
 
+// testSender sender of the emails of the tests
+var testSender = internal.Sender{FromAddress: "notifications@example.com"}
+
 // mockSESAPI mock for SES API
 type mockSESAPI struct {
-	SendEmailFunc func(input *ses.SendEmailInput) (*ses.SendEmailOutput, error)
+	SendEmailFunc func(ctx aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error)
 }
 
-// SendEmail mock for this method to send email
-func (m *mockSESAPI) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
-	return m.SendEmailFunc(input)
+// SendEmailWithContext mock for this method to send email
+func (m *mockSESAPI) SendEmailWithContext(
+	ctx aws.Context,
+	input *sesv2.SendEmailInput,
+	_ ...request.Option,
+) (*sesv2.SendEmailOutput, error) {
+	return m.SendEmailFunc(ctx, input)
 }
 
 // TestEmailService_Send test for this method
@@ -43,8 +63,8 @@ func TestEmailService_Send(t *testing.T) {
 			name: "success",
 			fields: fields{
 				client: &mockSESAPI{
-					SendEmailFunc: func(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
-						return &ses.SendEmailOutput{}, nil
+					SendEmailFunc: func(_ aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+						return &sesv2.SendEmailOutput{}, nil
 					},
 				},
 			},
@@ -59,7 +79,7 @@ func TestEmailService_Send(t *testing.T) {
 			name: "error sending email",
 			fields: fields{
 				client: &mockSESAPI{
-					SendEmailFunc: func(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+					SendEmailFunc: func(_ aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
 						return nil, assert.AnError
 					},
 				},
@@ -78,7 +98,12 @@ func TestEmailService_Send(t *testing.T) {
 			s := &EmailService{
 				client: tt.fields.client,
 			}
-			err := s.Send(tt.args.recipient, tt.args.subject, tt.args.message)
+			_, err := s.Send(context.Background(), internal.Email{
+				Sender:  testSender,
+				To:      []string{tt.args.recipient},
+				Subject: tt.args.subject,
+				Body:    tt.args.message,
+			})
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -88,6 +113,424 @@ func TestEmailService_Send(t *testing.T) {
 	}
 }
 
+// TestEmailService_Send_Context test that the context of the request reaches SES
+func TestEmailService_Send_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service := NewEmailService(&mockSESAPI{
+		SendEmailFunc: func(ctx aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+			return nil, ctx.Err()
+		},
+	}, "")
+
+	_, err := service.Send(ctx, internal.Email{
+		Sender:  testSender,
+		To:      []string{"test@example.com"},
+		Subject: "subject",
+		Body:    "message",
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestEmailService_Send_RawMessage test the MIME message parsing it back
+func TestEmailService_Send_RawMessage(t *testing.T) {
+	tests := []struct {
+		name                string
+		email               internal.Email
+		wantSubject         string
+		wantListUnsubscribe string
+		wantBody            string
+	}{
+		{
+			name: "with unsubscribe link",
+			email: internal.Email{
+				Sender:         testSender,
+				To:             []string{"test@example.com"},
+				Subject:        "Marketing",
+				Body:           "Hello",
+				UnsubscribeURL: "https://example.com/unsubscribe?token=abc",
+			},
+			wantSubject:         "Marketing",
+			wantListUnsubscribe: "<https://example.com/unsubscribe?token=abc>",
+			wantBody:            "Hello\r\n\r\n--\r\nTo stop receiving these emails visit https://example.com/unsubscribe?token=abc",
+		},
+		{
+			name: "without unsubscribe link and non ascii content",
+			email: internal.Email{
+				Sender:  testSender,
+				To:      []string{"test@example.com"},
+				Subject: "Notificación",
+				Body:    "¡Hola!",
+			},
+			wantSubject: "Notificación",
+			wantBody:    "¡Hola!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sesv2.SendEmailInput
+
+			service := NewEmailService(&mockSESAPI{
+				SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+					input = i
+
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}, "")
+
+			_, err := service.Send(context.Background(), tt.email)
+			assert.NoError(t, err)
+			assert.Equal(t, "notifications@example.com", *input.FromEmailAddress)
+			assert.Equal(t, tt.email.To, aws.StringValueSlice(input.Destination.ToAddresses))
+
+			message, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+			assert.NoError(t, err)
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, subject)
+			assert.Equal(t, "<test@example.com>", message.Header.Get("To"))
+			assert.Equal(t, tt.wantListUnsubscribe, message.Header.Get("List-Unsubscribe"))
+
+			if tt.wantListUnsubscribe != "" {
+				assert.Equal(t, "List-Unsubscribe=One-Click", message.Header.Get("List-Unsubscribe-Post"))
+			}
+
+			body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+// TestEmailService_Send_Sender test the sender of the email in the input and in the MIME message
+func TestEmailService_Send_Sender(t *testing.T) {
+	tests := []struct {
+		name             string
+		sender           internal.Sender
+		wantFromAddress  string
+		wantFrom         *mail.Address
+		wantReplyTo      []*string
+		wantReplyToEmail string
+		wantErr          error
+	}{
+		{
+			name: "display name and reply-to",
+			sender: internal.Sender{
+				FromAddress: "news@example.com",
+				DisplayName: "Modak Noticias",
+				ReplyTo:     "help@example.com",
+			},
+			wantFromAddress:  `"Modak Noticias" <news@example.com>`,
+			wantFrom:         &mail.Address{Name: "Modak Noticias", Address: "news@example.com"},
+			wantReplyTo:      aws.StringSlice([]string{"help@example.com"}),
+			wantReplyToEmail: "<help@example.com>",
+		},
+		{
+			name:            "non ascii display name",
+			sender:          internal.Sender{FromAddress: "news@example.com", DisplayName: "Noticias Año"},
+			wantFromAddress: "=?utf-8?q?Noticias_A=C3=B1o?= <news@example.com>",
+			wantFrom:        &mail.Address{Name: "Noticias Año", Address: "news@example.com"},
+		},
+		{
+			name:    "without sender",
+			wantErr: errMissingSender,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sesv2.SendEmailInput
+
+			service := NewEmailService(&mockSESAPI{
+				SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+					input = i
+
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}, "")
+
+			_, err := service.Send(context.Background(), internal.Email{
+				Sender:  tt.sender,
+				To:      []string{"test@example.com"},
+				Subject: "News",
+				Body:    "Hello",
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if tt.wantErr != nil {
+				assert.Nil(t, input)
+
+				return
+			}
+
+			assert.Equal(t, tt.wantFromAddress, *input.FromEmailAddress)
+			assert.Equal(t, tt.wantReplyTo, input.ReplyToAddresses)
+
+			message, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+			assert.NoError(t, err)
+
+			from, err := message.Header.AddressList("From")
+			assert.NoError(t, err)
+			assert.Equal(t, []*mail.Address{tt.wantFrom}, from)
+			assert.Equal(t, tt.wantReplyToEmail, message.Header.Get("Reply-To"))
+		})
+	}
+}
+
+// TestEmailService_Send_Destinations test the to, cc and bcc of the email
+func TestEmailService_Send_Destinations(t *testing.T) {
+	tooMany := make([]string, internal.MaxEmailDestinations+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+
+	tests := []struct {
+		name    string
+		email   internal.Email
+		wantTo  string
+		wantCc  string
+		wantErr error
+	}{
+		{
+			name: "to, cc and bcc",
+			email: internal.Email{
+				Sender: testSender,
+				To:     []string{"first@example.com", "second@example.com"},
+				Cc:     []string{"copy@example.com"},
+				Bcc:    []string{"hidden@example.com"},
+			},
+			wantTo: "<first@example.com>, <second@example.com>",
+			wantCc: "<copy@example.com>",
+		},
+		{
+			name:    "without destinations",
+			email:   internal.Email{Sender: testSender},
+			wantErr: errDestinations,
+		},
+		{
+			name:    "more destinations than SES accepts",
+			email:   internal.Email{Sender: testSender, To: tooMany},
+			wantErr: errDestinations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sesv2.SendEmailInput
+
+			service := NewEmailService(&mockSESAPI{
+				SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+					input = i
+
+					return &sesv2.SendEmailOutput{}, nil
+				},
+			}, "")
+
+			tt.email.Subject = "News"
+			tt.email.Body = "Hello"
+
+			_, err := service.Send(context.Background(), tt.email)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			if tt.wantErr != nil {
+				assert.Nil(t, input)
+
+				return
+			}
+
+			assert.Equal(t, tt.email.To, aws.StringValueSlice(input.Destination.ToAddresses))
+			assert.Equal(t, tt.email.Cc, aws.StringValueSlice(input.Destination.CcAddresses))
+			assert.Equal(t, tt.email.Bcc, aws.StringValueSlice(input.Destination.BccAddresses))
+
+			message, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTo, message.Header.Get("To"))
+			assert.Equal(t, tt.wantCc, message.Header.Get("Cc"))
+			// The bcc recipients are not visible to the other recipients
+			assert.Empty(t, message.Header.Get("Bcc"))
+			assert.NotContains(t, string(input.Content.Raw.Data), "hidden@example.com")
+		})
+	}
+}
+
+// TestEmailService_Send_Attachments test the multipart/mixed message parsing it back
+func TestEmailService_Send_Attachments(t *testing.T) {
+	pdf := bytes.Repeat([]byte("%PDF-1.7\x00\xff"), 20)
+
+	var input *sesv2.SendEmailInput
+
+	service := NewEmailService(&mockSESAPI{
+		SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+			input = i
+
+			return &sesv2.SendEmailOutput{}, nil
+		},
+	}, "")
+
+	_, err := service.Send(context.Background(), internal.Email{
+		Sender:         testSender,
+		To:             []string{"test@example.com"},
+		Subject:        "Invitation",
+		Body:           "¡Hola!",
+		UnsubscribeURL: "https://example.com/unsubscribe?token=abc",
+		Attachments: []internal.EmailAttachment{
+			{Filename: "invite.ics", ContentType: "text/calendar; method=REQUEST", Data: []byte("BEGIN:VCALENDAR")},
+			{Filename: "informe de octubre.pdf", ContentType: "application/pdf", Data: pdf},
+			{Filename: "notes", ContentType: "not a type", Data: []byte("notes")},
+		},
+	})
+	assert.NoError(t, err)
+
+	message, err := mail.ReadMessage(bytes.NewReader(input.Content.Raw.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", message.Header.Get("MIME-Version"))
+	assert.Equal(t, "<https://example.com/unsubscribe?token=abc>", message.Header.Get("List-Unsubscribe"))
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(message.Body, params["boundary"])
+
+	text, err := reader.NextRawPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=UTF-8", text.Header.Get("Content-Type"))
+	assert.Equal(t, "quoted-printable", text.Header.Get("Content-Transfer-Encoding"))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(text))
+	assert.NoError(t, err)
+	assert.Equal(t, "¡Hola!\r\n\r\n--\r\nTo stop receiving these emails visit https://example.com/unsubscribe?token=abc",
+		string(body))
+
+	for _, want := range []struct {
+		filename    string
+		contentType string
+		data        []byte
+	}{
+		{"invite.ics", "text/calendar; method=REQUEST; name=invite.ics", []byte("BEGIN:VCALENDAR")},
+		{"informe de octubre.pdf", `application/pdf; name="informe de octubre.pdf"`, pdf},
+		{"notes", "application/octet-stream; name=notes", []byte("notes")},
+	} {
+		part, err := reader.NextRawPart()
+		assert.NoError(t, err)
+		assert.Equal(t, want.filename, part.FileName())
+		assert.Equal(t, want.contentType, part.Header.Get("Content-Type"))
+		assert.Equal(t, "base64", part.Header.Get("Content-Transfer-Encoding"))
+
+		encoded, err := io.ReadAll(part)
+		assert.NoError(t, err)
+
+		for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+			assert.LessOrEqual(t, len(line), base64LineLength)
+		}
+
+		data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(encoded)))
+		assert.NoError(t, err)
+		assert.Equal(t, want.data, data)
+	}
+
+	_, err = reader.NextRawPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// TestEmailService_Send_TooLarge test that the emails larger than SES accepts are not sent
+func TestEmailService_Send_TooLarge(t *testing.T) {
+	service := NewEmailService(&mockSESAPI{
+		SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+			return nil, errors.New("too large email must not be sent")
+		},
+	}, "")
+
+	// The base64 encoding makes the attachment larger than the raw limit
+	_, err := service.Send(context.Background(), internal.Email{
+		Sender:  testSender,
+		To:      []string{"test@example.com"},
+		Subject: "Report",
+		Body:    "Hello",
+		Attachments: []internal.EmailAttachment{
+			{Filename: "report.pdf", ContentType: "application/pdf", Data: make([]byte, maxRawMessageSize*3/4+1)},
+		},
+	})
+	assert.ErrorIs(t, err, internal.ErrInvalidAttachment)
+}
+
+// TestEmailService_Send_Tags test the configuration set, the message tags and the message ID
+func TestEmailService_Send_Tags(t *testing.T) {
+	tests := []struct {
+		name                 string
+		configurationSet     string
+		email                internal.Email
+		wantConfigurationSet *string
+		wantTags             map[string]string
+	}{
+		{
+			name:             "every tag",
+			configurationSet: "notifications",
+			email: internal.Email{
+				Sender:    testSender,
+				To:        []string{"test@example.com"},
+				Type:      "News",
+				Tenant:    "acme",
+				RequestID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+			},
+			wantConfigurationSet: aws.String("notifications"),
+			wantTags: map[string]string{
+				"type":       "News",
+				"tenant":     "acme",
+				"request_id": "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+			},
+		},
+		{
+			name: "without configuration set nor tenant",
+			email: internal.Email{
+				Sender: testSender,
+				To:     []string{"test@example.com"},
+				Type:   "News",
+			},
+			wantTags: map[string]string{"type": "News"},
+		},
+		{
+			name: "characters not accepted by SES",
+			email: internal.Email{
+				Sender: testSender,
+				To:     []string{"test@example.com"},
+				Type:   "Daily news.v2",
+				Tenant: "acme@example.com",
+			},
+			wantTags: map[string]string{"type": "Daily_news_v2", "tenant": "acme_example_com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input *sesv2.SendEmailInput
+
+			service := NewEmailService(&mockSESAPI{
+				SendEmailFunc: func(_ aws.Context, i *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+					input = i
+
+					return &sesv2.SendEmailOutput{MessageId: aws.String("0100018b-message")}, nil
+				},
+			}, tt.configurationSet)
+
+			messageID, err := service.Send(context.Background(), tt.email)
+			assert.NoError(t, err)
+			assert.Equal(t, "0100018b-message", messageID)
+			assert.Equal(t, tt.wantConfigurationSet, input.ConfigurationSetName)
+
+			tags := map[string]string{}
+			for _, tag := range input.EmailTags {
+				tags[*tag.Name] = *tag.Value
+			}
+
+			assert.Equal(t, tt.wantTags, tags)
+		})
+	}
+}
+
 // TestNewEmailService test for this service
 func TestNewEmailService(t *testing.T) {
 	type args struct {
@@ -103,8 +546,8 @@ func TestNewEmailService(t *testing.T) {
 			name: "success",
 			args: args{
 				client: &mockSESAPI{
-					SendEmailFunc: func(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
-						return &ses.SendEmailOutput{}, nil
+					SendEmailFunc: func(_ aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+						return &sesv2.SendEmailOutput{}, nil
 					},
 				},
 			},
@@ -114,7 +557,7 @@ func TestNewEmailService(t *testing.T) {
 			name: "send error",
 			args: args{
 				client: &mockSESAPI{
-					SendEmailFunc: func(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+					SendEmailFunc: func(_ aws.Context, input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
 						return nil, errors.New("send error")
 					},
 				},
@@ -125,8 +568,11 @@ func TestNewEmailService(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewEmailService(tt.args.client)
-			err := service.Send("test@example.com", "subject", "message")
+			service := NewEmailService(tt.args.client, "")
+			_, err := service.Send(
+				context.Background(),
+				internal.Email{Sender: testSender, To: []string{"test@example.com"}, Subject: "subject", Body: "message"},
+			)
 			if (err != nil) != tt.wantSendError {
 				t.Errorf("EmailService.Send() error = %v, wantSendError %v", err, tt.wantSendError)
 			}
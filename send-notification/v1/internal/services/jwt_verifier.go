@@ -0,0 +1,355 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// tokenLeeway difference accepted between the clock of the issuer of the tokens and the clock of the function
+const tokenLeeway = time.Minute
+
+// jwksRefreshInterval minimum time between two loads of the JWKS for tokens of unknown keys, so tokens with made
+// up key IDs can not make the function load the JWKS on every request
+const jwksRefreshInterval = time.Minute
+
+// jwksFetchTimeout maximum time of one load of the JWKS
+const jwksFetchTimeout = 5 * time.Second
+
+// maxJWKSSize maximum size in bytes of the JWKS document
+const maxJWKSSize = 1 << 20
+
+// signingAlgorithms supported algorithms of the tokens, RSA PKCS #1 v1.5 and ECDSA. The symmetric algorithms and
+// none are never accepted
+var signingAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// verificationKey parsed public key of the JWKS
+type verificationKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+// tokenClaims claims of a token read by this service, scope is the space separated list of RFC 8693 and scp
+// the array used by other providers. tenant is the tenant of the caller, bound to the token by the issuer
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope  string   `json:"scope"`
+	Scp    []string `json:"scp"`
+	Tenant string   `json:"tenant"`
+}
+
+// cachedKeys keys of the JWKS and the time they were loaded
+type cachedKeys struct {
+	keys     []verificationKey
+	loadedAt time.Time
+}
+
+// JWTVerifier struct for this service, the signature and the registered claims are checked by golang-jwt and this
+// service only adds the policy of the subject and the tenant
+type JWTVerifier struct {
+	// jwks URL or path of the file of the JWKS with the keys of the issuer
+	jwks     string
+	audience string
+	// issuer of the tokens, empty to accept every issuer of the keys
+	issuer       string
+	cacheTTL     time.Duration
+	fetchTimeout time.Duration
+	httpClient   *http.Client
+	now          func() time.Time
+
+	mutex sync.Mutex
+	cache cachedKeys
+	group singleflight.Group
+}
+
+// Verify check the signature, the audience and the expiration of the bearer token and get its claims. The tokens
+// that are not accepted return an error that wraps internal.ErrInvalidToken
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (internal.TokenClaims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+		jwt.WithTimeFunc(v.now),
+	}
+
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+
+	var (
+		claims tokenClaims
+		keyErr error
+	)
+
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(parsed *jwt.Token) (interface{}, error) {
+		keyID, _ := parsed.Header["kid"].(string)
+
+		var keys []verificationKey
+
+		keys, keyErr = v.keysOf(ctx, keyID)
+
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, key.key)
+		}
+
+		return set, keyErr
+	}, options...)
+
+	// The errors loading the JWKS are not errors of the token
+	if keyErr != nil {
+		return internal.TokenClaims{}, keyErr
+	}
+
+	if err != nil {
+		return internal.TokenClaims{}, v.tokenError(parsed, claims, err)
+	}
+
+	if err := v.checkClaims(claims); err != nil {
+		return internal.TokenClaims{}, err
+	}
+
+	scopes := append(strings.Fields(claims.Scope), claims.Scp...)
+
+	return internal.TokenClaims{Subject: claims.Subject, Scopes: scopes, Tenant: claims.Tenant}, nil
+}
+
+// checkClaims check the subject and the tenant of a token whose registered claims are valid
+func (v *JWTVerifier) checkClaims(claims tokenClaims) error {
+	if claims.Subject == "" {
+		return invalidToken("it must have a subject")
+	}
+
+	if claims.Tenant != "" && !internal.ValidTenant(claims.Tenant) {
+		return invalidToken(fmt.Sprintf("tenant '%s' is not valid", claims.Tenant))
+	}
+
+	return nil
+}
+
+// tokenError reason of a token rejected by the parser
+func (v *JWTVerifier) tokenError(parsed *jwt.Token, claims tokenClaims, err error) error {
+	if parsed != nil {
+		if algorithm, _ := parsed.Header["alg"].(string); !slices.Contains(signingAlgorithms, algorithm) {
+			return invalidToken(fmt.Sprintf("algorithm '%s' is not supported", algorithm))
+		}
+	}
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return invalidToken("it is malformed")
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return invalidToken("invalid signature")
+	case claims.ExpiresAt == nil:
+		return invalidToken("it must have an expiration")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return invalidToken("it expired at " + claims.ExpiresAt.UTC().Format(time.RFC3339))
+	case errors.Is(err, jwt.ErrTokenNotValidYet) && claims.NotBefore != nil:
+		return invalidToken("it is not valid before " + claims.NotBefore.UTC().Format(time.RFC3339))
+	case errors.Is(err, jwt.ErrTokenInvalidAudience), errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return invalidToken("it is not issued for the audience " + v.audience)
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return invalidToken(fmt.Sprintf("issuer '%s' is not accepted", claims.Issuer))
+	}
+
+	return fmt.Errorf("%w: %s", internal.ErrInvalidToken, err)
+}
+
+// keysOf keys that can have signed a token with the key ID, every key when the token has none. The JWKS is loaded
+// again when the cache expired, or when no key has the ID because the issuer may have rotated its keys
+func (v *JWTVerifier) keysOf(ctx context.Context, keyID string) ([]verificationKey, error) {
+	now := v.now()
+
+	v.mutex.Lock()
+	cache := v.cache
+	v.mutex.Unlock()
+
+	if cache.loadedAt.IsZero() || now.Sub(cache.loadedAt) >= v.cacheTTL {
+		var err error
+
+		if cache, err = v.load(ctx, now); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := matchingKeys(cache.keys, keyID)
+	if len(keys) == 0 && now.Sub(cache.loadedAt) >= jwksRefreshInterval {
+		var err error
+
+		if cache, err = v.load(ctx, now); err != nil {
+			return nil, err
+		}
+
+		keys = matchingKeys(cache.keys, keyID)
+	}
+
+	if len(keys) == 0 {
+		return nil, invalidToken(fmt.Sprintf("no key of the JWKS has the ID '%s'", keyID))
+	}
+
+	return keys, nil
+}
+
+// load read the JWKS and replace the cached keys. The concurrent loads share one read, made without the lock so
+// the tokens of the cached keys are not blocked by a slow issuer. When it can not be read the cached keys are kept
+// while there are any, so a failure of the issuer does not reject the tokens of the keys it already published
+func (v *JWTVerifier) load(ctx context.Context, now time.Time) (cachedKeys, error) {
+	// The shared read is not cancelled with the caller that started it, the other callers may still wait for it
+	readCtx := context.WithoutCancel(ctx)
+
+	results := v.group.DoChan("jwks", func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(readCtx, v.fetchTimeout)
+		defer cancel()
+
+		keys, err := v.readJWKS(fetchCtx)
+
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+
+		if err != nil && len(v.cache.keys) == 0 {
+			return nil, fmt.Errorf("load JWKS %s: %w", v.jwks, err)
+		}
+
+		if err == nil {
+			v.cache.keys = keys
+		}
+
+		v.cache.loadedAt = now
+
+		return v.cache, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return cachedKeys{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return cachedKeys{}, result.Err
+		}
+
+		return result.Val.(cachedKeys), nil
+	}
+}
+
+// readJWKS read and parse the JWKS of the URL or the file
+func (v *JWTVerifier) readJWKS(ctx context.Context) ([]verificationKey, error) {
+	var content []byte
+
+	if strings.HasPrefix(v.jwks, "http://") || strings.HasPrefix(v.jwks, "https://") {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwks, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := v.httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+		}
+
+		content, err = io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+
+		content, err = os.ReadFile(v.jwks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseJWKS(content)
+}
+
+// parseJWKS parse the signing keys of a JWKS with jwkset, the keys of other types or uses are ignored
+func parseJWKS(content []byte) ([]verificationKey, error) {
+	var jwks jwkset.JWKSMarshal
+
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	var keys []verificationKey
+
+	for _, marshal := range jwks.Keys {
+		if marshal.USE != "" && marshal.USE != jwkset.UseSig {
+			continue
+		}
+
+		if marshal.KTY != jwkset.KtyRSA && marshal.KTY != jwkset.KtyEC {
+			continue
+		}
+
+		jwk, err := jwkset.NewJWKFromMarshal(marshal, jwkset.JWKMarshalOptions{}, jwkset.JWKValidateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s' of the JWKS: %w", marshal.KID, err)
+		}
+
+		keys = append(keys, verificationKey{id: marshal.KID, key: jwk.Key()})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("the JWKS has no signing keys")
+	}
+
+	return keys, nil
+}
+
+// matchingKeys keys with the key ID, every key when it is empty
+func matchingKeys(keys []verificationKey, keyID string) []verificationKey {
+	if keyID == "" {
+		return keys
+	}
+
+	var matching []verificationKey
+
+	for _, key := range keys {
+		if key.id == keyID {
+			matching = append(matching, key)
+		}
+	}
+
+	return matching
+}
+
+// invalidToken error of a token that is not accepted
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", internal.ErrInvalidToken, reason)
+}
+
+// NewJWTVerifier new instance of this service, jwks is the URL or the path of the file of the JWKS, which is
+// cached for cacheTTL. The tokens must be issued for the audience and, when it is not empty, by the issuer
+func NewJWTVerifier(jwks, audience, issuer string, cacheTTL time.Duration) *JWTVerifier {
+	return &JWTVerifier{
+		jwks:         jwks,
+		audience:     audience,
+		issuer:       issuer,
+		cacheTTL:     cacheTTL,
+		fetchTimeout: jwksFetchTimeout,
+		httpClient:   &http.Client{},
+		now:          time.Now,
+	}
+}
@@ -0,0 +1,497 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testIssuer keys of the issuer of the tokens of the tests
+type testIssuer struct {
+	rsaKey   *rsa.PrivateKey
+	ecdsaKey *ecdsa.PrivateKey
+}
+
+// newTestIssuer issuer with a new RSA and a new ECDSA key
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return &testIssuer{rsaKey: rsaKey, ecdsaKey: ecdsaKey}
+}
+
+// jwks JWKS with the public keys of the issuer, rsa-1 and ec-1
+func (i *testIssuer) jwks() []byte {
+	encode := func(value *big.Int) string { return base64.RawURLEncoding.EncodeToString(value.Bytes()) }
+	coordinate := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, 32)))
+	}
+
+	content, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"n":   encode(i.rsaKey.N),
+				"e":   encode(big.NewInt(int64(i.rsaKey.E))),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   coordinate(i.ecdsaKey.X),
+				"y":   coordinate(i.ecdsaKey.Y),
+			},
+			{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+		},
+	})
+
+	return content
+}
+
+// token signed token with the claims, alg RS256 is signed with the RSA key and ES256 with the ECDSA key
+func (i *testIssuer) token(t *testing.T, header map[string]string, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(value interface{}) string {
+		content, _ := json.Marshal(value)
+
+		return base64.RawURLEncoding.EncodeToString(content)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	hashed := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+
+	switch header["alg"] {
+	case "RS256":
+		var err error
+
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, hashed[:])
+		assert.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, i.ecdsaKey, hashed[:])
+		assert.NoError(t, err)
+
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestJWTVerifier_Verify test for this method
+func TestJWTVerifier_Verify(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer(t)
+	other := newTestIssuer(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(issuer.jwks())
+	}))
+	defer server.Close()
+
+	claims := func(change func(claims map[string]interface{})) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":   "https://issuer.example.com",
+			"sub":   "billing-service",
+			"aud":   "notifications",
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "openid notifications:send:marketing",
+		}
+
+		if change != nil {
+			change(claims)
+		}
+
+		return claims
+	}
+	rs256 := map[string]string{"alg": "RS256", "kid": "rsa-1", "typ": "JWT"}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    internal.TokenClaims
+		wantErr string
+	}{
+		{
+			name:  "RS256 token",
+			token: issuer.token(t, rs256, claims(nil)),
+			want: internal.TokenClaims{
+				Subject: "billing-service",
+				Scopes:  []string{"openid", "notifications:send:marketing"},
+			},
+		},
+		{
+			name: "ES256 token with an audience array and scp",
+			token: issuer.token(
+				t,
+				map[string]string{"alg": "ES256", "kid": "ec-1"},
+				claims(func(c map[string]interface{}) {
+					c["aud"] = []string{"other", "notifications"}
+					c["scp"] = []string{"notifications:send:Status"}
+					delete(c, "scope")
+				}),
+			),
+			want: internal.TokenClaims{Subject: "billing-service", Scopes: []string{"notifications:send:Status"}},
+		},
+		{
+			name:  "token without key ID",
+			token: issuer.token(t, map[string]string{"alg": "RS256"}, claims(nil)),
+			want: internal.TokenClaims{
+				Subject: "billing-service",
+				Scopes:  []string{"openid", "notifications:send:marketing"},
+			},
+		},
+		{
+			name: "expired inside the leeway",
+			token: issuer.token(t, rs256, claims(func(c map[string]interface{}) {
+				c["exp"] = now.Add(-30 * time.Second).Unix()
+			})),
+			want: internal.TokenClaims{
+				Subject: "billing-service",
+				Scopes:  []string{"openid", "notifications:send:marketing"},
+			},
+		},
+		{
+			name: "expired",
+			token: issuer.token(t, rs256, claims(func(c map[string]interface{}) {
+				c["exp"] = now.Add(-time.Hour).Unix()
+			})),
+			wantErr: "invalid token: it expired at 2023-10-02T11:00:00Z",
+		},
+		{
+			name:    "without expiration",
+			token:   issuer.token(t, rs256, claims(func(c map[string]interface{}) { delete(c, "exp") })),
+			wantErr: "invalid token: it must have an expiration",
+		},
+		{
+			name: "not valid yet",
+			token: issuer.token(t, rs256, claims(func(c map[string]interface{}) {
+				c["nbf"] = now.Add(time.Hour).Unix()
+			})),
+			wantErr: "invalid token: it is not valid before 2023-10-02T13:00:00Z",
+		},
+		{
+			name:    "other audience",
+			token:   issuer.token(t, rs256, claims(func(c map[string]interface{}) { c["aud"] = "billing" })),
+			wantErr: "invalid token: it is not issued for the audience notifications",
+		},
+		{
+			name: "other issuer",
+			token: issuer.token(t, rs256, claims(func(c map[string]interface{}) {
+				c["iss"] = "https://attacker.example.com"
+			})),
+			wantErr: "invalid token: issuer 'https://attacker.example.com' is not accepted",
+		},
+		{
+			name:  "token of a tenant",
+			token: issuer.token(t, rs256, claims(func(c map[string]interface{}) { c["tenant"] = "acme" })),
+			want: internal.TokenClaims{
+				Subject: "billing-service",
+				Scopes:  []string{"openid", "notifications:send:marketing"},
+				Tenant:  "acme",
+			},
+		},
+		{
+			name:    "invalid tenant",
+			token:   issuer.token(t, rs256, claims(func(c map[string]interface{}) { c["tenant"] = "acme:News" })),
+			wantErr: "invalid token: tenant 'acme:News' is not valid",
+		},
+		{
+			name:    "without subject",
+			token:   issuer.token(t, rs256, claims(func(c map[string]interface{}) { delete(c, "sub") })),
+			wantErr: "invalid token: it must have a subject",
+		},
+		{
+			name:    "signed by another key",
+			token:   other.token(t, rs256, claims(nil)),
+			wantErr: "invalid token: invalid signature",
+		},
+		{
+			name:    "algorithm of another key type",
+			token:   issuer.token(t, map[string]string{"alg": "ES256", "kid": "rsa-1"}, claims(nil)),
+			wantErr: "invalid token: invalid signature",
+		},
+		{
+			name:    "unsigned token",
+			token:   issuer.token(t, map[string]string{"alg": "none"}, claims(nil)),
+			wantErr: "invalid token: algorithm 'none' is not supported",
+		},
+		{
+			name:    "symmetric algorithm",
+			token:   issuer.token(t, map[string]string{"alg": "HS256", "kid": "secret"}, claims(nil)),
+			wantErr: "invalid token: algorithm 'HS256' is not supported",
+		},
+		{
+			name:    "unknown key ID",
+			token:   issuer.token(t, map[string]string{"alg": "RS256", "kid": "rsa-2"}, claims(nil)),
+			wantErr: "invalid token: no key of the JWKS has the ID 'rsa-2'",
+		},
+		{
+			name:    "malformed token",
+			token:   "not-a-token",
+			wantErr: "invalid token: it is malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewJWTVerifier(server.URL, "notifications", "https://issuer.example.com", time.Hour)
+			verifier.now = func() time.Time { return now }
+
+			got, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, internal.ErrInvalidToken)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestJWTVerifier_Cache test that the JWKS is loaded once per cache TTL and again for the tokens of new keys
+func TestJWTVerifier_Cache(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer(t)
+	rotated := newTestIssuer(t)
+
+	var (
+		requests atomic.Int32
+		current  atomic.Pointer[testIssuer]
+	)
+
+	current.Store(issuer)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(current.Load().jwks())
+	}))
+	defer server.Close()
+
+	verifier := NewJWTVerifier(server.URL, "notifications", "", 10*time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	token := func(signer *testIssuer, kid string) string {
+		return signer.token(t, map[string]string{"alg": "RS256", "kid": kid}, map[string]interface{}{
+			"sub": "billing-service",
+			"aud": "notifications",
+			"exp": now.Add(time.Hour).Unix(),
+		})
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), token(issuer, "rsa-1"))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), requests.Load())
+
+	// A token of an unknown key loads the JWKS again at most once per refresh interval
+	_, err := verifier.Verify(context.Background(), token(issuer, "rsa-2"))
+	assert.ErrorIs(t, err, internal.ErrInvalidToken)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// The keys of the issuer are rotated and published with the same key ID
+	current.Store(rotated)
+	now = now.Add(2 * time.Minute)
+
+	_, err = verifier.Verify(context.Background(), token(rotated, "rsa-1"))
+	assert.ErrorIs(t, err, internal.ErrInvalidToken)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// The cache expires
+	now = now.Add(10 * time.Minute)
+
+	_, err = verifier.Verify(context.Background(), token(rotated, "rsa-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+// TestJWTVerifier_File test the JWKS of a file and the errors loading it
+func TestJWTVerifier_File(t *testing.T) {
+	issuer := newTestIssuer(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, issuer.jwks(), 0o600))
+
+	token := issuer.token(t, map[string]string{"alg": "ES256", "kid": "ec-1"}, map[string]interface{}{
+		"sub":   "billing-service",
+		"aud":   "notifications",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "notifications:send:*",
+	})
+
+	got, err := NewJWTVerifier(path, "notifications", "", time.Hour).Verify(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"notifications:send:*"}, got.Scopes)
+
+	_, err = NewJWTVerifier(filepath.Join(t.TempDir(), "missing.json"), "notifications", "", time.Hour).
+		Verify(context.Background(), token)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, internal.ErrInvalidToken))
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`), 0o600))
+
+	_, err = NewJWTVerifier(path, "notifications", "", time.Hour).Verify(context.Background(), token)
+	assert.ErrorContains(t, err, "the JWKS has no signing keys")
+}
+
+// TestJWTVerifier_Unavailable test that the cached keys are kept while the JWKS can not be loaded
+func TestJWTVerifier_Unavailable(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer(t)
+
+	var available atomic.Bool
+
+	available.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write(issuer.jwks())
+	}))
+	defer server.Close()
+
+	token := issuer.token(t, map[string]string{"alg": "RS256", "kid": "rsa-1"}, map[string]interface{}{
+		"sub": "billing-service",
+		"aud": "notifications",
+		"exp": now.Add(time.Hour).Unix(),
+	})
+
+	verifier := NewJWTVerifier(server.URL, "notifications", "", time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	_, err := verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+
+	available.Store(false)
+	now = now.Add(5 * time.Minute)
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+
+	unavailable := NewJWTVerifier(server.URL, "notifications", "", time.Minute)
+	unavailable.now = func() time.Time { return now }
+
+	_, err = unavailable.Verify(context.Background(), token)
+	assert.ErrorContains(t, err, "unexpected status 503")
+	assert.False(t, errors.Is(err, internal.ErrInvalidToken))
+}
+
+// TestJWTVerifier_ConcurrentLoads test that the concurrent loads of the JWKS share one request, which is made
+// without blocking the tokens of the cached keys and stops after the fetch timeout
+func TestJWTVerifier_ConcurrentLoads(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer(t)
+
+	var (
+		requests atomic.Int32
+		blocked  atomic.Bool
+	)
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if blocked.Load() {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		_, _ = w.Write(issuer.jwks())
+	}))
+	defer server.Close()
+	defer close(release)
+
+	token := func(kid string) string {
+		return issuer.token(t, map[string]string{"alg": "RS256", "kid": kid}, map[string]interface{}{
+			"sub": "billing-service",
+			"aud": "notifications",
+			"exp": now.Add(time.Hour).Unix(),
+		})
+	}
+
+	verifier := NewJWTVerifier(server.URL, "notifications", "", time.Hour)
+	verifier.now = func() time.Time { return now }
+	verifier.fetchTimeout = time.Second
+
+	_, err := verifier.Verify(context.Background(), token("rsa-1"))
+	assert.NoError(t, err)
+
+	// The tokens of an unknown key load the JWKS again, the issuer does not answer until the timeout
+	blocked.Store(true)
+	now = now.Add(2 * jwksRefreshInterval)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := verifier.Verify(context.Background(), token("rsa-2"))
+			assert.ErrorIs(t, err, internal.ErrInvalidToken)
+		}()
+	}
+
+	// The tokens of the cached keys are verified while the JWKS is loaded
+	assert.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+
+	_, err = verifier.Verify(context.Background(), token("rsa-1"))
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), verifier.fetchTimeout/2)
+
+	wg.Wait()
+	assert.Equal(t, int32(2), requests.Load())
+
+	// A caller that is cancelled stops waiting for the load
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now = now.Add(2 * jwksRefreshInterval)
+
+	_, err = verifier.Verify(ctx, token("rsa-2"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,95 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// ErrInvalidUnsubscribeToken the token was not signed by this service, it is malformed or it expired
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeLinkService struct for this service
+type UnsubscribeLinkService struct {
+	baseURL string
+	secret  []byte
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// URL build the signed one-click unsubscribe link of a recipient of a tenant for the given type, empty without
+// base URL so the emails do not have a link that can not be opened
+func (s *UnsubscribeLinkService) URL(tenant, email, notificationType string) string {
+	if s.baseURL == "" {
+		return ""
+	}
+
+	return s.baseURL + "?" + url.Values{"token": {s.sign(tenant, email, notificationType)}}.Encode()
+}
+
+// Verify check the signature and the expiry of the token and get the recipient, tenant and type it was issued for
+func (s *UnsubscribeLinkService) Verify(token string) (internal.Unsubscription, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return internal.Unsubscription{}, ErrInvalidUnsubscribeToken
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.mac(payload)) {
+		return internal.Unsubscription{}, ErrInvalidUnsubscribeToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return internal.Unsubscription{}, ErrInvalidUnsubscribeToken
+	}
+
+	// The tokens without tenant or expiry were issued before the links expired and they are not accepted anymore
+	fields := strings.Split(string(decoded), "\n")
+	if len(fields) != 4 || fields[1] == "" {
+		return internal.Unsubscription{}, ErrInvalidUnsubscribeToken
+	}
+
+	expiresAt, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil || !s.now().Before(time.Unix(expiresAt, 0)) {
+		return internal.Unsubscription{}, ErrInvalidUnsubscribeToken
+	}
+
+	return internal.Unsubscription{Tenant: fields[0], Email: fields[1], Type: fields[2]}, nil
+}
+
+// sign create the token with the tenant, recipient, type and expiry signed with HMAC-SHA256
+func (s *UnsubscribeLinkService) sign(tenant, email, notificationType string) string {
+	expiresAt := strconv.FormatInt(s.now().Add(s.ttl).Unix(), 10)
+	payload := base64.RawURLEncoding.EncodeToString(
+		[]byte(strings.Join([]string{tenant, email, notificationType, expiresAt}, "\n")),
+	)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// mac signature of the payload
+func (s *UnsubscribeLinkService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+
+	return h.Sum(nil)
+}
+
+// NewUnsubscribeLinkService creates a new instance of this service, the links expire after the ttl
+func NewUnsubscribeLinkService(baseURL, secret string, ttl time.Duration) *UnsubscribeLinkService {
+	return &UnsubscribeLinkService{
+		baseURL: baseURL,
+		secret:  []byte(secret),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
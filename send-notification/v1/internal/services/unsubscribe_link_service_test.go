@@ -0,0 +1,107 @@
+// Package services contains all logic related to services
+package services
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUnsubscribeLinkService_URL test that the link can be verified back
+func TestUnsubscribeLinkService_URL(t *testing.T) {
+	service := NewUnsubscribeLinkService("https://example.com/dev/v1/unsubscribe", "secret", time.Hour)
+
+	link := service.URL("acme", "test@example.com", "Marketing")
+	assert.True(t, strings.HasPrefix(link, "https://example.com/dev/v1/unsubscribe?token="))
+
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+
+	unsubscription, err := service.Verify(parsed.Query().Get("token"))
+	assert.NoError(t, err)
+	assert.Equal(t, internal.Unsubscription{
+		Tenant: "acme",
+		Email:  "test@example.com",
+		Type:   "Marketing",
+	}, unsubscription)
+
+	// Without base URL the emails have no link
+	assert.Empty(t, NewUnsubscribeLinkService("", "secret", time.Hour).URL("", "test@example.com", "Marketing"))
+}
+
+// TestUnsubscribeLinkService_Verify test for this method
+func TestUnsubscribeLinkService_Verify(t *testing.T) {
+	service := NewUnsubscribeLinkService("https://example.com/unsubscribe", "secret", time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	valid := service.sign("", "test@example.com", "News")
+	payload, signature, _ := strings.Cut(valid, ".")
+	otherPayload, _, _ := strings.Cut(service.sign("", "other@example.com", "News"), ".")
+
+	// The tokens issued before the tenant and the expiry were signed
+	legacy := func(fields string) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fields))
+
+		return payload + "." + base64.RawURLEncoding.EncodeToString(service.mac(payload))
+	}
+	withoutTenant := legacy("test@example.com\nNews\n" + strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+	withoutExpiry := legacy("test@example.com\nNews")
+
+	expired := NewUnsubscribeLinkService("", "secret", time.Hour)
+	expired.now = func() time.Time { return now.Add(-time.Hour) }
+
+	tests := []struct {
+		name       string
+		token      string
+		wantTenant string
+		wantType   string
+		wantErr    bool
+	}{
+		{name: "valid token", token: valid, wantType: "News"},
+		{name: "global unsubscribe", token: service.sign("", "test@example.com", ""), wantType: ""},
+		{
+			name:       "token of a tenant",
+			token:      service.sign("acme", "test@example.com", "News"),
+			wantTenant: "acme",
+			wantType:   "News",
+		},
+		{
+			name:    "signed with other secret",
+			token:   NewUnsubscribeLinkService("", "other", time.Hour).sign("", "test@example.com", "News"),
+			wantErr: true,
+		},
+		{name: "expired", token: expired.sign("", "test@example.com", "News"), wantErr: true},
+		{name: "without tenant", token: withoutTenant, wantErr: true},
+		{name: "without expiry", token: withoutExpiry, wantErr: true},
+		{name: "tampered payload", token: otherPayload + "." + signature, wantErr: true},
+		{name: "without signature", token: payload, wantErr: true},
+		{name: "malformed signature", token: payload + ".%%%", wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsubscription, err := service.Verify(tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, internal.Unsubscription{
+				Tenant: tt.wantTenant,
+				Email:  "test@example.com",
+				Type:   tt.wantType,
+			}, unsubscription)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DefaultTenantHeader header of the requests with the name of their tenant
+const DefaultTenantHeader = "X-Tenant-ID"
+
+// tenantTypeSeparator separates the tenant from the type in the types of the rules and the cache partitions of a
+// tenant, the types can not have it
+const tenantTypeSeparator = ":"
+
+// TenantConfig how the tenant of the requests that send notifications is identified, the requests without tenant
+// use the global rules and quotas. The tenant is always the one of the credentials of the request
+type TenantConfig struct {
+	// Header name of the header that asserts the tenant of the credentials, empty to ignore the header
+	Header string
+}
+
+// ValidTenant check if the tenant has between 1 and 64 letters, digits, '_', '.' or '-', like the types
+func ValidTenant(tenant string) bool {
+	return notificationTypeRegexp.MatchString(tenant)
+}
+
+// Resolve tenant of a request of the client, the tenant of its API key or its bearer token and empty when it has
+// none. The header can only repeat the tenant of the credentials, so a client can never choose another tenant
+func (c TenantConfig) Resolve(event events.APIGatewayProxyRequest, client Client) (string, error) {
+	headerTenant := ""
+	if c.Header != "" {
+		headerTenant = strings.TrimSpace(header(event, c.Header))
+	}
+
+	if headerTenant == "" || headerTenant == client.Tenant {
+		return client.Tenant, nil
+	}
+
+	if !ValidTenant(headerTenant) {
+		return "", &GeneralError{
+			Code:       CodeRequestError,
+			ID:         IDRequestInvalidParameter,
+			Message:    fmt.Sprintf("The %s header must have between 1 and 64 letters, digits, '_', '.' or '-'", c.Header),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	message := fmt.Sprintf("The credentials can not send notifications of the tenant '%s'", headerTenant)
+	if client.Tenant == "" {
+		message = fmt.Sprintf("The credentials do not have a tenant, the %s header can not be used", c.Header)
+	}
+
+	return "", &GeneralError{
+		Code:       CodeRequestError,
+		ID:         IDRequestTenantForbidden,
+		Message:    message,
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+// header value of a header of the request, the names of the headers are case insensitive
+func header(event events.APIGatewayProxyRequest, headerName string) string {
+	for name, value := range event.Headers {
+		if strings.EqualFold(name, headerName) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// TenantType type of the rules and the cache partitions of a tenant like "acme:News", the type itself when there
+// is no tenant so the requests without tenant keep the global rules and partitions
+func TenantType(tenant, notificationType string) string {
+	if tenant == "" {
+		return notificationType
+	}
+
+	return tenant + tenantTypeSeparator + notificationType
+}
+
+// SplitTenantType tenant and type of a type of TenantType, the tenant is empty for the global types
+func SplitTenantType(tenantType string) (string, string) {
+	tenant, notificationType, found := strings.Cut(tenantType, tenantTypeSeparator)
+	if !found {
+		return "", tenantType
+	}
+
+	return tenant, notificationType
+}
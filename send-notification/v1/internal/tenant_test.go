@@ -0,0 +1,108 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTenantConfig_Resolve test for this method
+func TestTenantConfig_Resolve(t *testing.T) {
+	config := TenantConfig{Header: DefaultTenantHeader}
+
+	request := func(headers map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{Headers: headers}
+	}
+
+	tests := []struct {
+		name           string
+		config         TenantConfig
+		event          events.APIGatewayProxyRequest
+		client         Client
+		want           string
+		wantStatusCode int
+	}{
+		{
+			name:   "without tenant",
+			config: config,
+			event:  request(nil),
+			want:   "",
+		},
+		{
+			name:   "tenant of the client",
+			config: config,
+			event:  request(nil),
+			client: Client{ID: "client-1", Tenant: "acme"},
+			want:   "acme",
+		},
+		{
+			name:   "header with the tenant of the client",
+			config: config,
+			event:  request(map[string]string{"x-tenant-id": " acme "}),
+			client: Client{ID: "client-1", Tenant: "acme"},
+			want:   "acme",
+		},
+		{
+			name:   "header ignored without header name",
+			config: TenantConfig{},
+			event:  request(map[string]string{"X-Tenant-ID": "globex"}),
+			client: Client{ID: "client-1", Tenant: "acme"},
+			want:   "acme",
+		},
+		{
+			name:           "header with another tenant than the client",
+			config:         config,
+			event:          request(map[string]string{"X-Tenant-ID": "acme"}),
+			client:         Client{ID: "client-1", Tenant: "globex"},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "header of a client without tenant",
+			config:         config,
+			event:          request(map[string]string{"X-Tenant-ID": "globex"}),
+			client:         Client{ID: "client-1"},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "invalid tenant",
+			config:         config,
+			event:          request(map[string]string{"X-Tenant-ID": "acme:News"}),
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Resolve(tt.event, tt.client)
+			if tt.wantStatusCode != 0 {
+				var generalError *GeneralError
+				if assert.True(t, errors.As(err, &generalError)) {
+					assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestTenantType test for this function and SplitTenantType
+func TestTenantType(t *testing.T) {
+	assert.Equal(t, "News", TenantType("", "News"))
+	assert.Equal(t, "acme:News", TenantType("acme", "News"))
+
+	tenant, notificationType := SplitTenantType("acme:News")
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, "News", notificationType)
+
+	tenant, notificationType = SplitTenantType("News")
+	assert.Empty(t, tenant)
+	assert.Equal(t, "News", notificationType)
+}
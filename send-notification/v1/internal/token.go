@@ -0,0 +1,65 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// AuthorizationHeader header with the bearer token of the requests that send notifications
+const AuthorizationHeader = "Authorization"
+
+// SendScopePrefix prefix of the scopes that allow a type, e.g. notifications:send:marketing, and
+// notifications:send:* allows every type
+const SendScopePrefix = "notifications:send:"
+
+// tokenClientPrefix prefix of the ID of the clients of a token, so they never share the counter of an API key
+const tokenClientPrefix = "token:"
+
+// ErrInvalidToken the bearer token is malformed, its signature is not valid or its claims are not accepted
+var ErrInvalidToken = errors.New("invalid token")
+
+// Credentials of a request that sends notifications, the bearer token is used when the request has both
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+}
+
+// credentials of the headers of a request, the token of an Authorization header with the Bearer scheme and the
+// API key of APIKeyHeader
+func credentials(event events.APIGatewayProxyRequest) Credentials {
+	result := Credentials{APIKey: header(event, APIKeyHeader)}
+
+	scheme, token, found := strings.Cut(strings.TrimSpace(header(event, AuthorizationHeader)), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		result.BearerToken = strings.TrimSpace(token)
+	}
+
+	return result
+}
+
+// TokenClaims claims of a verified bearer token used by the service
+type TokenClaims struct {
+	// Subject caller of the token
+	Subject string
+	// Scopes of the token, the ones with SendScopePrefix are the types the caller can send
+	Scopes []string
+	// Tenant of the notifications of the caller, empty for the callers without tenant
+	Tenant string
+}
+
+// Client of the token, it can send the types of its send scopes of its tenant
+func (c TokenClaims) Client() Client {
+	client := Client{ID: tokenClientPrefix + c.Subject, Tenant: c.Tenant}
+
+	for _, scope := range c.Scopes {
+		notificationType, found := strings.CutPrefix(scope, SendScopePrefix)
+		if found && notificationType != "" {
+			client.AllowedTypes = append(client.AllowedTypes, notificationType)
+		}
+	}
+
+	return client
+}
@@ -0,0 +1,60 @@
+// Package internal contains all the main logic
+package internal
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTokenClaims_Client test for this method
+func TestTokenClaims_Client(t *testing.T) {
+	client := TokenClaims{
+		Subject: "billing-service",
+		Scopes:  []string{"openid", "notifications:send:marketing", "notifications:send:Status", "notifications:send:"},
+		Tenant:  "acme",
+	}.Client()
+
+	assert.Equal(t, "token:billing-service", client.ID)
+	assert.Equal(t, "acme", client.Tenant)
+	assert.Equal(t, []string{"marketing", "Status"}, client.AllowedTypes)
+	assert.True(t, client.Allows("Marketing"))
+	assert.True(t, client.Allows("status"))
+	assert.False(t, client.Allows("News"))
+
+	assert.True(t, TokenClaims{Scopes: []string{"notifications:send:*"}}.Client().Allows("News"))
+	assert.False(t, TokenClaims{Scopes: []string{"notifications:read"}}.Client().Allows("News"))
+}
+
+// Test_credentials test for this function
+func Test_credentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    Credentials
+	}{
+		{name: "without credentials", want: Credentials{}},
+		{
+			name:    "API key",
+			headers: map[string]string{"x-api-key": "mk_key"},
+			want:    Credentials{APIKey: "mk_key"},
+		},
+		{
+			name:    "bearer token",
+			headers: map[string]string{"authorization": "bearer eyJ.eyJ.sig", "X-Api-Key": "mk_key"},
+			want:    Credentials{APIKey: "mk_key", BearerToken: "eyJ.eyJ.sig"},
+		},
+		{
+			name:    "other scheme",
+			headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			want:    Credentials{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, credentials(events.APIGatewayProxyRequest{Headers: tt.headers}))
+		})
+	}
+}
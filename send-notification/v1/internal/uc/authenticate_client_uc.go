@@ -0,0 +1,196 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// APIKeyRepositoryInterface struct for this repository related to the API keys of the clients
+type APIKeyRepositoryInterface interface {
+	GetByHash(ctx context.Context, hash string) (*internal.APIKey, error)
+	Save(ctx context.Context, apiKey internal.APIKey) error
+	List(ctx context.Context) ([]internal.APIKey, error)
+}
+
+// ClientRequestsRepositoryInterface struct for this repository related to the requests of the clients
+type ClientRequestsRepositoryInterface interface {
+	IncrementRequests(ctx context.Context, clientID string, windowStart time.Time, ttl int64) (int, error)
+}
+
+// TokenVerifierInterface interface for the service that verifies the bearer tokens
+type TokenVerifierInterface interface {
+	Verify(ctx context.Context, token string) (internal.TokenClaims, error)
+}
+
+// AuthenticateClientUC struct for this use case
+type AuthenticateClientUC struct {
+	apiKeyRepository         APIKeyRepositoryInterface
+	clientRequestsRepository ClientRequestsRepositoryInterface
+	// tokenVerifier verifier of the bearer tokens, nil when the tokens are not accepted
+	tokenVerifier TokenVerifierInterface
+	// defaultRate rate limit of the clients of the tokens and of the API keys without one
+	defaultRate internal.RequestRate
+	now         func() time.Time
+}
+
+// Handle get the client of the bearer token or the API key of the request and count its request, the requests
+// without valid credentials are rejected with 401 and the requests over the rate limit of the client with 429 and
+// the time until the next window
+func (uc *AuthenticateClientUC) Handle(ctx context.Context, credentials internal.Credentials) (internal.Client, error) {
+	if credentials.BearerToken != "" {
+		return uc.handleToken(ctx, credentials.BearerToken)
+	}
+
+	if credentials.APIKey == "" {
+		return internal.Client{}, unauthorized(
+			"The request needs the " + internal.APIKeyHeader + " header or a bearer token",
+		)
+	}
+
+	apiKey, err := uc.apiKeyRepository.GetByHash(ctx, internal.HashAPIKey(credentials.APIKey))
+	if err != nil {
+		return internal.Client{}, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from API key repository (GetByHash)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	// The unknown and the disabled keys get the same error, so the error does not tell which keys exist
+	if apiKey == nil || apiKey.Disabled {
+		return internal.Client{}, unauthorized("The API key is not valid")
+	}
+
+	rate := uc.defaultRate
+	if apiKey.RateLimit != "" {
+		rate, err = internal.ParseRequestRate(apiKey.RateLimit)
+		if err != nil {
+			return internal.Client{}, &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Invalid rate limit of API key " + apiKey.ID,
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+	}
+
+	if err := uc.countRequest(ctx, apiKey.ID, rate); err != nil {
+		return internal.Client{}, err
+	}
+
+	return apiKey.Client(), nil
+}
+
+// handleToken get the client of a bearer token, its requests are limited by the default rate limit
+func (uc *AuthenticateClientUC) handleToken(ctx context.Context, token string) (internal.Client, error) {
+	if uc.tokenVerifier == nil {
+		return internal.Client{}, unauthorized(
+			"The bearer tokens are not accepted, use the " + internal.APIKeyHeader + " header",
+		)
+	}
+
+	claims, err := uc.tokenVerifier.Verify(ctx, token)
+	if errors.Is(err, internal.ErrInvalidToken) {
+		return internal.Client{}, &internal.GeneralError{
+			Code:          internal.CodeRequestError,
+			ID:            internal.IDRequestUnauthorized,
+			Message:       "The bearer token is not valid (" + err.Error() + ")",
+			StatusCode:    http.StatusUnauthorized,
+			OriginalError: err,
+		}
+	}
+
+	if err != nil {
+		return internal.Client{}, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error verifying the bearer token",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	client := claims.Client()
+
+	if err := uc.countRequest(ctx, client.ID, uc.defaultRate); err != nil {
+		return internal.Client{}, err
+	}
+
+	return client, nil
+}
+
+// countRequest count a request of the client, the requests over its rate limit are rejected
+func (uc *AuthenticateClientUC) countRequest(ctx context.Context, clientID string, rate internal.RequestRate) error {
+	if !rate.Enabled() {
+		return nil
+	}
+
+	// The requests are counted in fixed windows, the counter of a window expires with the next one
+	now := uc.now()
+	windowStart := now.Truncate(rate.Window)
+	windowEnd := windowStart.Add(rate.Window)
+
+	requests, err := uc.clientRequestsRepository.IncrementRequests(
+		ctx, clientID, windowStart, windowEnd.Add(rate.Window).Unix(),
+	)
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error updating the client requests repository (IncrementRequests)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	if requests > rate.Limit {
+		return &internal.GeneralError{
+			Code: internal.CodeRequestError,
+			ID:   internal.IDRequestRateLimited,
+			Message: fmt.Sprintf(
+				"The client sent more than %d requests in %s, retry after %s",
+				rate.Limit, rate.Window, windowEnd.UTC().Format(time.RFC3339),
+			),
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: windowEnd.Sub(now),
+		}
+	}
+
+	return nil
+}
+
+// unauthorized error of a request without valid credentials
+func unauthorized(message string) error {
+	return &internal.GeneralError{
+		Code:       internal.CodeRequestError,
+		ID:         internal.IDRequestUnauthorized,
+		Message:    message,
+		StatusCode: http.StatusUnauthorized,
+	}
+}
+
+// NewAuthenticateClientUC new instance of this use case, defaultRate is the rate limit of the clients of the tokens
+// and of the API keys without one. tokenVerifier is nil when the bearer tokens are not accepted
+func NewAuthenticateClientUC(
+	apiKeyRepository APIKeyRepositoryInterface,
+	clientRequestsRepository ClientRequestsRepositoryInterface,
+	tokenVerifier TokenVerifierInterface,
+	defaultRate internal.RequestRate,
+) *AuthenticateClientUC {
+	return &AuthenticateClientUC{
+		apiKeyRepository:         apiKeyRepository,
+		clientRequestsRepository: clientRequestsRepository,
+		tokenVerifier:            tokenVerifier,
+		defaultRate:              defaultRate,
+		now:                      time.Now,
+	}
+}
@@ -0,0 +1,299 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAPIKeyRepository Mock for API key repository
+type MockAPIKeyRepository struct {
+	GetByHashFunc func(hash string) (*internal.APIKey, error)
+	SaveFunc      func(apiKey internal.APIKey) error
+	ListFunc      func() ([]internal.APIKey, error)
+}
+
+// GetByHash Mock for method to get the API key of a hash
+func (m *MockAPIKeyRepository) GetByHash(_ context.Context, hash string) (*internal.APIKey, error) {
+	return m.GetByHashFunc(hash)
+}
+
+// Save Mock for method to save an API key
+func (m *MockAPIKeyRepository) Save(_ context.Context, apiKey internal.APIKey) error {
+	return m.SaveFunc(apiKey)
+}
+
+// List Mock for method to get every API key
+func (m *MockAPIKeyRepository) List(_ context.Context) ([]internal.APIKey, error) {
+	return m.ListFunc()
+}
+
+// mockClientRequestsRepository Mock for the repository of the requests of the clients
+type mockClientRequestsRepository struct {
+	IncrementRequestsFunc func(clientID string, windowStart time.Time, ttl int64) (int, error)
+}
+
+// IncrementRequests Mock for method to count a request of a client
+func (m *mockClientRequestsRepository) IncrementRequests(
+	_ context.Context,
+	clientID string,
+	windowStart time.Time,
+	ttl int64,
+) (int, error) {
+	return m.IncrementRequestsFunc(clientID, windowStart, ttl)
+}
+
+// TestAuthenticateClientUC_Handle test for this method
+func TestAuthenticateClientUC_Handle(t *testing.T) {
+	const key = "mk_0123456789abcdef_secret"
+
+	now := time.Date(2023, 10, 2, 12, 0, 45, 0, time.UTC)
+	apiKey := internal.APIKey{ID: "0123456789abcdef", Tenant: "acme", AllowedTypes: []string{"News"}}
+
+	tests := []struct {
+		name           string
+		key            string
+		apiKey         *internal.APIKey
+		getErr         error
+		requests       int
+		incrementErr   error
+		wantRate       time.Duration
+		want           internal.Client
+		wantStatusCode int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:     "client of the key",
+			key:      key,
+			apiKey:   &apiKey,
+			requests: 10,
+			wantRate: time.Minute,
+			want:     internal.Client{ID: "0123456789abcdef", Tenant: "acme", AllowedTypes: []string{"News"}},
+		},
+		{
+			name: "rate limit of the key",
+			key:  key,
+			apiKey: &internal.APIKey{
+				ID:           "0123456789abcdef",
+				AllowedTypes: []string{internal.AnyType},
+				RateLimit:    "100/1h",
+			},
+			requests: 100,
+			wantRate: time.Hour,
+			want:     internal.Client{ID: "0123456789abcdef", AllowedTypes: []string{internal.AnyType}},
+		},
+		{
+			name:           "over the rate limit",
+			key:            key,
+			apiKey:         &apiKey,
+			requests:       11,
+			wantRate:       time.Minute,
+			wantStatusCode: http.StatusTooManyRequests,
+			wantRetryAfter: 15 * time.Second,
+		},
+		{
+			name:           "without API key",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown API key",
+			key:            key,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "disabled API key",
+			key:  key,
+			apiKey: &internal.APIKey{
+				ID:           "0123456789abcdef",
+				AllowedTypes: []string{"News"},
+				Disabled:     true,
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "repository error",
+			key:            key,
+			getErr:         errors.New("database error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "requests repository error",
+			key:            key,
+			apiKey:         &apiKey,
+			incrementErr:   errors.New("database error"),
+			wantRate:       time.Minute,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ucInstance := NewAuthenticateClientUC(
+				&MockAPIKeyRepository{
+					GetByHashFunc: func(hash string) (*internal.APIKey, error) {
+						assert.Equal(t, internal.HashAPIKey(key), hash)
+
+						return tt.apiKey, tt.getErr
+					},
+				},
+				&mockClientRequestsRepository{
+					IncrementRequestsFunc: func(clientID string, windowStart time.Time, ttl int64) (int, error) {
+						assert.Equal(t, "0123456789abcdef", clientID)
+						assert.Equal(t, now.Truncate(tt.wantRate), windowStart)
+						assert.Equal(t, windowStart.Add(2*tt.wantRate).Unix(), ttl)
+
+						return tt.requests, tt.incrementErr
+					},
+				},
+				nil,
+				internal.RequestRate{Limit: 10, Window: time.Minute},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			got, err := ucInstance.Handle(context.Background(), internal.Credentials{APIKey: tt.key})
+			if tt.wantStatusCode == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+
+				return
+			}
+
+			var generalError *internal.GeneralError
+			if assert.ErrorAs(t, err, &generalError) {
+				assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+				assert.Equal(t, tt.wantRetryAfter, generalError.RetryAfter)
+			}
+		})
+	}
+}
+
+// TestAuthenticateClientUC_Handle_WithoutRateLimit test that the requests are not counted without rate limit
+func TestAuthenticateClientUC_Handle_WithoutRateLimit(t *testing.T) {
+	ucInstance := NewAuthenticateClientUC(
+		&MockAPIKeyRepository{
+			GetByHashFunc: func(hash string) (*internal.APIKey, error) {
+				return &internal.APIKey{ID: "0123456789abcdef", AllowedTypes: []string{"News"}}, nil
+			},
+		},
+		&mockClientRequestsRepository{},
+		nil,
+		internal.RequestRate{},
+	)
+
+	got, err := ucInstance.Handle(context.Background(), internal.Credentials{APIKey: "mk_0123456789abcdef_secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, internal.Client{ID: "0123456789abcdef", AllowedTypes: []string{"News"}}, got)
+}
+
+// mockTokenVerifier Mock for the verifier of the bearer tokens
+type mockTokenVerifier struct {
+	VerifyFunc func(token string) (internal.TokenClaims, error)
+}
+
+// Verify Mock for method to verify a bearer token
+func (m *mockTokenVerifier) Verify(_ context.Context, token string) (internal.TokenClaims, error) {
+	return m.VerifyFunc(token)
+}
+
+// TestAuthenticateClientUC_Handle_Token test for the requests with a bearer token
+func TestAuthenticateClientUC_Handle_Token(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 45, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		verifier       TokenVerifierInterface
+		requests       int
+		want           internal.Client
+		wantStatusCode int
+	}{
+		{
+			name: "client of the scopes",
+			verifier: &mockTokenVerifier{
+				VerifyFunc: func(token string) (internal.TokenClaims, error) {
+					assert.Equal(t, "eyJ.eyJ.sig", token)
+
+					return internal.TokenClaims{
+						Subject: "billing-service",
+						Scopes:  []string{"openid", "notifications:send:marketing"},
+					}, nil
+				},
+			},
+			requests: 1,
+			want:     internal.Client{ID: "token:billing-service", AllowedTypes: []string{"marketing"}},
+		},
+		{
+			name: "over the rate limit",
+			verifier: &mockTokenVerifier{
+				VerifyFunc: func(token string) (internal.TokenClaims, error) {
+					return internal.TokenClaims{Subject: "billing-service"}, nil
+				},
+			},
+			requests:       11,
+			wantStatusCode: http.StatusTooManyRequests,
+		},
+		{
+			name: "invalid token",
+			verifier: &mockTokenVerifier{
+				VerifyFunc: func(token string) (internal.TokenClaims, error) {
+					return internal.TokenClaims{}, fmt.Errorf("%w: it expired", internal.ErrInvalidToken)
+				},
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "JWKS not available",
+			verifier: &mockTokenVerifier{
+				VerifyFunc: func(token string) (internal.TokenClaims, error) {
+					return internal.TokenClaims{}, errors.New("load JWKS: unexpected status 503")
+				},
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "tokens not accepted",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ucInstance := NewAuthenticateClientUC(
+				&MockAPIKeyRepository{},
+				&mockClientRequestsRepository{
+					IncrementRequestsFunc: func(clientID string, windowStart time.Time, ttl int64) (int, error) {
+						assert.Equal(t, "token:billing-service", clientID)
+
+						return tt.requests, nil
+					},
+				},
+				tt.verifier,
+				internal.RequestRate{Limit: 10, Window: time.Minute},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			got, err := ucInstance.Handle(
+				context.Background(),
+				internal.Credentials{APIKey: "mk_0123456789abcdef_secret", BearerToken: "eyJ.eyJ.sig"},
+			)
+			if tt.wantStatusCode == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+
+				return
+			}
+
+			var generalError *internal.GeneralError
+			if assert.ErrorAs(t, err, &generalError) {
+				assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// ManageAPIKeysUC struct for this use case
+type ManageAPIKeysUC struct {
+	apiKeyRepository APIKeyRepositoryInterface
+	now              func() time.Time
+}
+
+// Create save a new API key for the client, the key is returned only here because the repository keeps its hash
+func (uc *ManageAPIKeysUC) Create(ctx context.Context, apiKey internal.APIKey) (string, *internal.APIKey, error) {
+	if err := apiKey.Validate(); err != nil {
+		return "", nil, &internal.GeneralError{
+			Code:          internal.CodeAPIKeyError,
+			ID:            internal.IDAPIKeyInvalid,
+			Message:       "Invalid API key: " + err.Error(),
+			StatusCode:    http.StatusUnprocessableEntity,
+			OriginalError: err,
+		}
+	}
+
+	key, id, err := internal.NewAPIKeyValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey.Hash = internal.HashAPIKey(key)
+	apiKey.ID = id
+	apiKey.Disabled = false
+	apiKey.CreatedAt = uc.now().UTC()
+
+	if err := uc.apiKeyRepository.Save(ctx, apiKey); err != nil {
+		return "", nil, apiKeyRepositoryError("Save", err)
+	}
+
+	return key, &apiKey, nil
+}
+
+// List get every API key, without the keys
+func (uc *ManageAPIKeysUC) List(ctx context.Context) ([]internal.APIKey, error) {
+	apiKeys, err := uc.apiKeyRepository.List(ctx)
+	if err != nil {
+		return nil, apiKeyRepositoryError("List", err)
+	}
+
+	return apiKeys, nil
+}
+
+// Disable reject the requests of the API key with the given ID from now on, the key is kept for the audit
+func (uc *ManageAPIKeysUC) Disable(ctx context.Context, id string) error {
+	apiKeys, err := uc.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, apiKey := range apiKeys {
+		if apiKey.ID != id {
+			continue
+		}
+
+		apiKey.Disabled = true
+
+		if err := uc.apiKeyRepository.Save(ctx, apiKey); err != nil {
+			return apiKeyRepositoryError("Save", err)
+		}
+
+		return nil
+	}
+
+	return &internal.GeneralError{
+		Code:       internal.CodeAPIKeyError,
+		ID:         internal.IDAPIKeyNotFound,
+		Message:    "API key '" + id + "' does not exist",
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// apiKeyRepositoryError error of a method of the repository of the API keys
+func apiKeyRepositoryError(method string, err error) error {
+	return &internal.GeneralError{
+		Code:          internal.CodeGeneralError,
+		ID:            internal.IDGeneralError,
+		Message:       "Error in API key repository (" + method + ")",
+		StatusCode:    http.StatusInternalServerError,
+		OriginalError: err,
+	}
+}
+
+// NewManageAPIKeysUC new instance of this use case
+func NewManageAPIKeysUC(apiKeyRepository APIKeyRepositoryInterface) *ManageAPIKeysUC {
+	return &ManageAPIKeysUC{
+		apiKeyRepository: apiKeyRepository,
+		now:              time.Now,
+	}
+}
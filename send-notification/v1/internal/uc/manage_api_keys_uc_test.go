@@ -0,0 +1,129 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManageAPIKeysUC_Create test for this method
+func TestManageAPIKeysUC_Create(t *testing.T) {
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		apiKey         internal.APIKey
+		saveErr        error
+		wantStatusCode int
+	}{
+		{
+			name:   "success",
+			apiKey: internal.APIKey{Tenant: "acme", AllowedTypes: []string{"News"}, Disabled: true},
+		},
+		{
+			name:           "invalid key",
+			apiKey:         internal.APIKey{Tenant: "acme"},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "repository error",
+			apiKey:         internal.APIKey{AllowedTypes: []string{"News"}},
+			saveErr:        errors.New("database error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved internal.APIKey
+
+			ucInstance := NewManageAPIKeysUC(&MockAPIKeyRepository{
+				SaveFunc: func(apiKey internal.APIKey) error {
+					saved = apiKey
+
+					return tt.saveErr
+				},
+			})
+			ucInstance.now = func() time.Time { return now }
+
+			key, created, err := ucInstance.Create(context.Background(), tt.apiKey)
+			if tt.wantStatusCode != 0 {
+				var generalError *internal.GeneralError
+				if assert.ErrorAs(t, err, &generalError) {
+					assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, saved, *created)
+			assert.Equal(t, internal.HashAPIKey(key), saved.Hash)
+			assert.Contains(t, key, saved.ID)
+			assert.Equal(t, now, saved.CreatedAt)
+			assert.False(t, saved.Disabled)
+		})
+	}
+}
+
+// TestManageAPIKeysUC_Disable test for this method
+func TestManageAPIKeysUC_Disable(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		wantSaved      []internal.APIKey
+		wantStatusCode int
+	}{
+		{
+			name:      "success",
+			id:        "0123456789abcdef",
+			wantSaved: []internal.APIKey{{ID: "0123456789abcdef", Hash: "hash-1", Disabled: true}},
+		},
+		{
+			name:           "unknown key",
+			id:             "fedcba9876543210",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved []internal.APIKey
+
+			ucInstance := NewManageAPIKeysUC(&MockAPIKeyRepository{
+				ListFunc: func() ([]internal.APIKey, error) {
+					return []internal.APIKey{
+						{ID: "0123456789abcdef", Hash: "hash-1"},
+						{ID: "00112233445566ff", Hash: "hash-2"},
+					}, nil
+				},
+				SaveFunc: func(apiKey internal.APIKey) error {
+					saved = append(saved, apiKey)
+
+					return nil
+				},
+			})
+
+			err := ucInstance.Disable(context.Background(), tt.id)
+			assert.Equal(t, tt.wantSaved, saved)
+
+			if tt.wantStatusCode == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var generalError *internal.GeneralError
+			if assert.ErrorAs(t, err, &generalError) {
+				assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+			}
+		})
+	}
+}
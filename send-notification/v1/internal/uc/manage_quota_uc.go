@@ -0,0 +1,149 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// ManageQuotaUC struct for this use case
+type ManageQuotaUC struct {
+	rateLimitRulesRepository   RateLimitRulesRepositoryInterface
+	rateLimitCacheRepository   RateLimitCacheRepositoryInterface
+	recipientProfileRepository RecipientProfileRepositoryInterface
+	recipientNormalizer        internal.RecipientNormalizer
+	now                        func() time.Time
+}
+
+// Usage get the notifications sent to a recipient inside the current window of every rule
+func (uc *ManageQuotaUC) Usage(ctx context.Context, email string) ([]internal.QuotaUsage, error) {
+	rules, err := uc.rateLimitRulesRepository.List(ctx)
+	if err != nil {
+		return nil, repositoryError("List", err)
+	}
+
+	profile, err := uc.recipientProfileRepository.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from recipient profile repository (GetByEmail)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	// The notifications are counted by the mailbox of the address, like the rate limit does
+	mailbox := uc.recipientNormalizer.Normalize(email)
+	now := uc.now()
+	usages := make([]internal.QuotaUsage, 0, len(rules))
+
+	for _, rule := range rules {
+		// The recipient timezone has precedence over the timezone of the rule
+		if profile != nil && profile.Timezone != "" {
+			rule.Timezone = profile.Timezone
+		}
+
+		window, err := rule.Window(now)
+		if err != nil {
+			return nil, &internal.GeneralError{
+				Code:          internal.CodeNotificationError,
+				ID:            internal.IDNotificationRuleInvalid,
+				Message:       fmt.Sprintf("Invalid rate limit rule for notification type '%s'", rule.Type),
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+
+		// Every notification is counted, not only the ones needed to reach the limit
+		used, err := uc.rateLimitCacheRepository.CountNotificationsWithinInterval(
+			ctx, rule.WindowType(rule.Type), mailbox, window.Start, 0,
+		)
+		if err != nil {
+			return nil, &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error getting from cache repository (CountNotificationsWithinInterval)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+
+		remaining := rule.NotificationsLimit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		usages = append(usages, internal.QuotaUsage{
+			Type:        rule.Type,
+			Limit:       rule.NotificationsLimit,
+			Used:        used,
+			Remaining:   remaining,
+			WindowStart: window.Start,
+		})
+	}
+
+	return usages, nil
+}
+
+// Reset remove the notifications sent to a recipient so its window starts again,
+// only for the given type or for every type with a rule when the type is empty. The partitions of every alignment
+// of the windows are removed. It returns how many were removed
+func (uc *ManageQuotaUC) Reset(ctx context.Context, email, notificationType string) (int, error) {
+	types := internal.WindowTypes(notificationType)
+
+	if notificationType == "" {
+		rules, err := uc.rateLimitRulesRepository.List(ctx)
+		if err != nil {
+			return 0, repositoryError("List", err)
+		}
+
+		types = types[:0]
+		for _, rule := range rules {
+			types = append(types, internal.WindowTypes(rule.Type)...)
+		}
+
+		// The cap of the recipient counts the notifications of every type
+		types = append(types, internal.RecipientCapType)
+	}
+
+	mailbox := uc.recipientNormalizer.Normalize(email)
+	deleted := 0
+
+	for _, t := range types {
+		count, err := uc.rateLimitCacheRepository.DeleteNotifications(ctx, t, mailbox)
+		deleted += count
+
+		if err != nil {
+			return deleted, &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error deleting from cache repository (DeleteNotifications)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// NewManageQuotaUC new instance of this use case
+func NewManageQuotaUC(
+	rateLimitRulesRepository RateLimitRulesRepositoryInterface,
+	rateLimitCacheRepository RateLimitCacheRepositoryInterface,
+	recipientProfileRepository RecipientProfileRepositoryInterface,
+	recipientNormalizer internal.RecipientNormalizer,
+) *ManageQuotaUC {
+	return &ManageQuotaUC{
+		rateLimitRulesRepository:   rateLimitRulesRepository,
+		rateLimitCacheRepository:   rateLimitCacheRepository,
+		recipientProfileRepository: recipientProfileRepository,
+		recipientNormalizer:        recipientNormalizer,
+		now:                        time.Now,
+	}
+}
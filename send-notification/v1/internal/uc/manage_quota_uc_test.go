@@ -0,0 +1,123 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManageQuotaUC_Usage test for this method
+func TestManageQuotaUC_Usage(t *testing.T) {
+	now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+	bogota, _ := time.LoadLocation("America/Bogota")
+
+	ucInstance := &ManageQuotaUC{
+		rateLimitRulesRepository: &MockRateLimitRulesRepository{
+			ListFunc: func() ([]internal.RateLimitRule, error) {
+				return []internal.RateLimitRule{
+					{Type: "Status", NotificationsLimit: 2, IntervalInMinutes: 1},
+					{Type: "News", NotificationsLimit: 1, WindowAlignment: internal.WindowAlignmentCalendarDay},
+				}, nil
+			},
+		},
+		rateLimitCacheRepository: &MockRateLimitCacheRepository{
+			CountNotificationsWithinIntervalFunc: func(
+				notificationType, email string,
+				windowStart time.Time,
+				limit int,
+			) (int, error) {
+				assert.Equal(t, 0, limit)
+
+				if notificationType == "News#calendar_day" {
+					return 3, nil
+				}
+
+				return 1, nil
+			},
+		},
+		recipientProfileRepository: &MockRecipientProfileRepository{
+			GetByEmailFunc: func(email string) (*internal.RecipientProfile, error) {
+				return &internal.RecipientProfile{Email: email, Timezone: "America/Bogota"}, nil
+			},
+		},
+		now: func() time.Time { return now },
+	}
+
+	got, err := ucInstance.Usage(context.Background(), "test@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.QuotaUsage{
+		{Type: "Status", Limit: 2, Used: 1, Remaining: 1, WindowStart: now.Add(-time.Minute)},
+		{Type: "News", Limit: 1, Used: 3, Remaining: 0, WindowStart: time.Date(2023, 10, 15, 0, 0, 0, 0, bogota)},
+	}, got)
+}
+
+// TestManageQuotaUC_Reset test for this method
+func TestManageQuotaUC_Reset(t *testing.T) {
+	tests := []struct {
+		name             string
+		notificationType string
+		deleteErr        error
+		wantTypes        []string
+		wantDeleted      int
+		wantStatusCode   int
+	}{
+		{
+			name:             "one type",
+			notificationType: "News",
+			wantTypes:        []string{"News", "News#calendar_day", "News#calendar_week"},
+			wantDeleted:      6,
+		},
+		{
+			name: "every type",
+			wantTypes: []string{
+				"News", "News#calendar_day", "News#calendar_week",
+				"Status", "Status#calendar_day", "Status#calendar_week",
+				internal.RecipientCapType,
+			},
+			wantDeleted: 14,
+		},
+		{
+			name:             "cache error",
+			notificationType: "News",
+			deleteErr:        errors.New("database error"),
+			wantTypes:        []string{"News"},
+			wantDeleted:      2,
+			wantStatusCode:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []string
+
+			ucInstance := NewManageQuotaUC(
+				&MockRateLimitRulesRepository{
+					ListFunc: func() ([]internal.RateLimitRule, error) {
+						return []internal.RateLimitRule{{Type: "News"}, {Type: "Status"}}, nil
+					},
+				},
+				&MockRateLimitCacheRepository{
+					DeleteNotificationsFunc: func(notificationType, email string) (int, error) {
+						types = append(types, notificationType)
+
+						return 2, tt.deleteErr
+					},
+				},
+				&MockRecipientProfileRepository{},
+				internal.RecipientNormalizer{},
+			)
+
+			deleted, err := ucInstance.Reset(context.Background(), "test@example.com", tt.notificationType)
+			assert.Equal(t, tt.wantTypes, types)
+			assert.Equal(t, tt.wantDeleted, deleted)
+			assertStatusCode(t, err, tt.wantStatusCode)
+		})
+	}
+}
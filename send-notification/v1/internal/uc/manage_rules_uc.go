@@ -0,0 +1,210 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// RulesCacheInterface interface for the cache of the rules used while sending
+type RulesCacheInterface interface {
+	Invalidate(notificationType string)
+}
+
+// ManageRulesUC struct for this use case
+type ManageRulesUC struct {
+	rateLimitRulesRepository RateLimitRulesRepositoryInterface
+	rulesCache               RulesCacheInterface
+	now                      func() time.Time
+}
+
+// List get every rule
+func (uc *ManageRulesUC) List(ctx context.Context) ([]internal.RateLimitRule, error) {
+	rules, err := uc.rateLimitRulesRepository.List(ctx)
+	if err != nil {
+		return nil, repositoryError("List", err)
+	}
+
+	return rules, nil
+}
+
+// Get get the rule of a type, the cached rule is discarded so the stored one is returned
+func (uc *ManageRulesUC) Get(ctx context.Context, notificationType string) (*internal.RateLimitRule, error) {
+	uc.rulesCache.Invalidate(notificationType)
+
+	rule, err := uc.rateLimitRulesRepository.GetByType(ctx, notificationType)
+	if err != nil {
+		return nil, repositoryError("GetByType", err)
+	}
+
+	if rule == nil {
+		return nil, ruleNotFoundError(notificationType)
+	}
+
+	return rule, nil
+}
+
+// Create save the rule of a type without rule, the rule starts on version 1
+func (uc *ManageRulesUC) Create(ctx context.Context, rule internal.RateLimitRule) (*internal.RateLimitRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, ruleInvalidError(err)
+	}
+
+	rule.Version = 1
+	rule.UpdatedAt = uc.now().UTC().Format(time.RFC3339)
+
+	err := uc.rateLimitRulesRepository.Create(ctx, rule)
+	uc.rulesCache.Invalidate(rule.Type)
+	if errors.Is(err, internal.ErrRuleVersionConflict) {
+		return nil, &internal.GeneralError{
+			Code:          internal.CodeRuleError,
+			ID:            internal.IDRuleAlreadyExists,
+			Message:       "Type '" + rule.Type + "' already has a rule",
+			StatusCode:    http.StatusConflict,
+			OriginalError: err,
+		}
+	}
+
+	if err != nil {
+		return nil, repositoryError("Create", err)
+	}
+
+	return &rule, nil
+}
+
+// Update replace the rule of a type, the version of the given rule must be the version stored,
+// the replaced version is kept in the history
+func (uc *ManageRulesUC) Update(ctx context.Context, rule internal.RateLimitRule) (*internal.RateLimitRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, ruleInvalidError(err)
+	}
+
+	current, err := uc.Get(ctx, rule.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Version != rule.Version {
+		return nil, versionConflictError(rule.Type, nil)
+	}
+
+	now := uc.now().UTC().Format(time.RFC3339)
+	rule.Version = current.Version + 1
+	rule.UpdatedAt = now
+
+	err = uc.rateLimitRulesRepository.Update(ctx, rule, internal.RateLimitRuleHistory{
+		RateLimitRule: *current,
+		ReplacedAt:    now,
+		Operation:     internal.RuleOperationUpdate,
+	})
+	uc.rulesCache.Invalidate(rule.Type)
+	if errors.Is(err, internal.ErrRuleVersionConflict) {
+		return nil, versionConflictError(rule.Type, err)
+	}
+
+	if err != nil {
+		return nil, repositoryError("Update", err)
+	}
+
+	return &rule, nil
+}
+
+// Delete remove the rule of a type when the stored version is the given one, the deleted version is kept in the history
+func (uc *ManageRulesUC) Delete(ctx context.Context, notificationType string, version int) error {
+	current, err := uc.Get(ctx, notificationType)
+	if err != nil {
+		return err
+	}
+
+	if current.Version != version {
+		return versionConflictError(notificationType, nil)
+	}
+
+	err = uc.rateLimitRulesRepository.Delete(ctx, internal.RateLimitRuleHistory{
+		RateLimitRule: *current,
+		ReplacedAt:    uc.now().UTC().Format(time.RFC3339),
+		Operation:     internal.RuleOperationDelete,
+	})
+	uc.rulesCache.Invalidate(notificationType)
+	if errors.Is(err, internal.ErrRuleVersionConflict) {
+		return versionConflictError(notificationType, err)
+	}
+
+	if err != nil {
+		return repositoryError("Delete", err)
+	}
+
+	return nil
+}
+
+// History get the previous versions of the rule of a type, the newest first
+func (uc *ManageRulesUC) History(
+	ctx context.Context,
+	notificationType string,
+) ([]internal.RateLimitRuleHistory, error) {
+	history, err := uc.rateLimitRulesRepository.History(ctx, notificationType)
+	if err != nil {
+		return nil, repositoryError("History", err)
+	}
+
+	return history, nil
+}
+
+// repositoryError unexpected error of a method of the rules repository
+func repositoryError(method string, err error) error {
+	return &internal.GeneralError{
+		Code:          internal.CodeGeneralError,
+		ID:            internal.IDGeneralError,
+		Message:       "Error in rate limit rules repository (" + method + ")",
+		StatusCode:    http.StatusInternalServerError,
+		OriginalError: err,
+	}
+}
+
+// ruleNotFoundError error for the types without rule
+func ruleNotFoundError(notificationType string) error {
+	return &internal.GeneralError{
+		Code:       internal.CodeRuleError,
+		ID:         internal.IDRuleNotFound,
+		Message:    "Type '" + notificationType + "' has no rule",
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// ruleInvalidError error for the rules that do not pass the validation
+func ruleInvalidError(err error) error {
+	return &internal.GeneralError{
+		Code:          internal.CodeRuleError,
+		ID:            internal.IDRuleInvalid,
+		Message:       "Invalid rule: " + err.Error(),
+		StatusCode:    http.StatusUnprocessableEntity,
+		OriginalError: err,
+	}
+}
+
+// versionConflictError error for the writes based on a version that is not the stored one
+func versionConflictError(notificationType string, err error) error {
+	return &internal.GeneralError{
+		Code:          internal.CodeRuleError,
+		ID:            internal.IDRuleVersionConflict,
+		Message:       "The rule of type '" + notificationType + "' was changed, read it again and retry",
+		StatusCode:    http.StatusConflict,
+		OriginalError: err,
+	}
+}
+
+// NewManageRulesUC new instance of this use case
+func NewManageRulesUC(
+	rateLimitRulesRepository RateLimitRulesRepositoryInterface,
+	rulesCache RulesCacheInterface,
+) *ManageRulesUC {
+	return &ManageRulesUC{
+		rateLimitRulesRepository: rateLimitRulesRepository,
+		rulesCache:               rulesCache,
+		now:                      time.Now,
+	}
+}
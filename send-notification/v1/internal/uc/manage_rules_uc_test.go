@@ -0,0 +1,272 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertStatusCode check that the error is a general error with the given status code, or nil for status 0
+func assertStatusCode(t *testing.T, err error, statusCode int) {
+	t.Helper()
+
+	if statusCode == 0 {
+		assert.NoError(t, err)
+
+		return
+	}
+
+	var generalError *internal.GeneralError
+	if assert.True(t, errors.As(err, &generalError), "error %v is not a general error", err) {
+		assert.Equal(t, statusCode, generalError.StatusCode)
+	}
+}
+
+// MockRulesCache mock of the rules cache that records the invalidated types
+type MockRulesCache struct {
+	invalidated []string
+}
+
+// Invalidate record the invalidated type
+func (m *MockRulesCache) Invalidate(notificationType string) {
+	m.invalidated = append(m.invalidated, notificationType)
+}
+
+// TestManageRulesUC_Create test for this method
+func TestManageRulesUC_Create(t *testing.T) {
+	now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		rule           internal.RateLimitRule
+		createErr      error
+		wantCreated    bool
+		wantStatusCode int
+	}{
+		{
+			name:        "success",
+			rule:        internal.RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 1440},
+			wantCreated: true,
+		},
+		{
+			name:           "invalid rule is not saved",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: -1, IntervalInMinutes: 1440},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "type already has a rule",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 1440},
+			createErr:      internal.ErrRuleVersionConflict,
+			wantCreated:    true,
+			wantStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+
+			ucInstance := &ManageRulesUC{
+				rateLimitRulesRepository: &MockRateLimitRulesRepository{
+					CreateFunc: func(rule internal.RateLimitRule) error {
+						created = true
+
+						assert.Equal(t, 1, rule.Version)
+						assert.Equal(t, "2023-10-15T13:00:00Z", rule.UpdatedAt)
+
+						return tt.createErr
+					},
+				},
+				rulesCache: &MockRulesCache{},
+				now:        func() time.Time { return now },
+			}
+
+			got, err := ucInstance.Create(context.Background(), tt.rule)
+			assert.Equal(t, tt.wantCreated, created)
+			assertStatusCode(t, err, tt.wantStatusCode)
+
+			if tt.wantStatusCode == 0 {
+				assert.Equal(t, 1, got.Version)
+			}
+		})
+	}
+}
+
+// TestManageRulesUC_Update test for this method
+func TestManageRulesUC_Update(t *testing.T) {
+	now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+	stored := &internal.RateLimitRule{PK: "TYPE#News", Type: "News", NotificationsLimit: 1, IntervalInMinutes: 60, Version: 2}
+
+	tests := []struct {
+		name           string
+		rule           internal.RateLimitRule
+		stored         *internal.RateLimitRule
+		updateErr      error
+		wantUpdated    bool
+		wantStatusCode int
+	}{
+		{
+			name:        "success",
+			rule:        internal.RateLimitRule{Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60, Version: 2},
+			stored:      stored,
+			wantUpdated: true,
+		},
+		{
+			name:           "stale version",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60, Version: 1},
+			stored:         stored,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "concurrent write between read and update",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60, Version: 2},
+			stored:         stored,
+			updateErr:      internal.ErrRuleVersionConflict,
+			wantUpdated:    true,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "type without rule",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60, Version: 2},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "invalid rule",
+			rule:           internal.RateLimitRule{Type: "News", NotificationsLimit: 2, Interval: "often", Version: 2},
+			stored:         stored,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+
+			ucInstance := &ManageRulesUC{
+				rateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return tt.stored, nil
+					},
+					UpdateFunc: func(rule internal.RateLimitRule, previous internal.RateLimitRuleHistory) error {
+						updated = true
+
+						assert.Equal(t, 3, rule.Version)
+						assert.Equal(t, *tt.stored, previous.RateLimitRule)
+						assert.Equal(t, internal.RuleOperationUpdate, previous.Operation)
+						assert.Equal(t, "2023-10-15T13:00:00Z", previous.ReplacedAt)
+
+						return tt.updateErr
+					},
+				},
+				rulesCache: &MockRulesCache{},
+				now:        func() time.Time { return now },
+			}
+
+			got, err := ucInstance.Update(context.Background(), tt.rule)
+			assert.Equal(t, tt.wantUpdated, updated)
+			assertStatusCode(t, err, tt.wantStatusCode)
+
+			if tt.wantStatusCode == 0 {
+				assert.Equal(t, 3, got.Version)
+				assert.Equal(t, 2, got.NotificationsLimit)
+			}
+		})
+	}
+}
+
+// TestManageRulesUC_Delete test for this method
+func TestManageRulesUC_Delete(t *testing.T) {
+	stored := &internal.RateLimitRule{Type: "News", NotificationsLimit: 1, IntervalInMinutes: 60, Version: 2}
+
+	tests := []struct {
+		name           string
+		version        int
+		stored         *internal.RateLimitRule
+		deleteErr      error
+		wantDeleted    bool
+		wantStatusCode int
+	}{
+		{
+			name:        "success",
+			version:     2,
+			stored:      stored,
+			wantDeleted: true,
+		},
+		{
+			name:           "stale version",
+			version:        1,
+			stored:         stored,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "type without rule",
+			version:        2,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "repository error",
+			version:        2,
+			stored:         stored,
+			deleteErr:      errors.New("database error"),
+			wantDeleted:    true,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := false
+			rulesCache := &MockRulesCache{}
+
+			ucInstance := NewManageRulesUC(&MockRateLimitRulesRepository{
+				GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+					return tt.stored, nil
+				},
+				DeleteFunc: func(previous internal.RateLimitRuleHistory) error {
+					deleted = true
+
+					assert.Equal(t, internal.RuleOperationDelete, previous.Operation)
+
+					return tt.deleteErr
+				},
+			}, rulesCache)
+
+			err := ucInstance.Delete(context.Background(), "News", tt.version)
+			assert.Equal(t, tt.wantDeleted, deleted)
+			assert.Contains(t, rulesCache.invalidated, "News")
+			assertStatusCode(t, err, tt.wantStatusCode)
+		})
+	}
+}
+
+// TestManageRulesUC_ListGetHistory test for the read methods
+func TestManageRulesUC_ListGetHistory(t *testing.T) {
+	ucInstance := NewManageRulesUC(&MockRateLimitRulesRepository{
+		ListFunc: func() ([]internal.RateLimitRule, error) {
+			return nil, errors.New("database error")
+		},
+		GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+			return nil, nil
+		},
+		HistoryFunc: func(notificationType string) ([]internal.RateLimitRuleHistory, error) {
+			return []internal.RateLimitRuleHistory{{Operation: internal.RuleOperationUpdate}}, nil
+		},
+	}, &MockRulesCache{})
+
+	_, err := ucInstance.List(context.Background())
+	assertStatusCode(t, err, http.StatusInternalServerError)
+
+	_, err = ucInstance.Get(context.Background(), "News")
+	assertStatusCode(t, err, http.StatusNotFound)
+
+	history, err := ucInstance.History(context.Background(), "News")
+	assertStatusCode(t, err, 0)
+	assert.Len(t, history, 1)
+}
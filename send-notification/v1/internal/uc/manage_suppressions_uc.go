@@ -0,0 +1,77 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"net/http"
+
+	"modak/send-notification/v1/internal"
+)
+
+// ManageSuppressionsUC struct for this use case
+type ManageSuppressionsUC struct {
+	suppressionRepository SuppressionRepositoryInterface
+}
+
+// List get one page of the suppressed addresses
+func (uc *ManageSuppressionsUC) List(
+	ctx context.Context,
+	limit int,
+	cursor string,
+) ([]internal.Suppression, string, error) {
+	suppressions, nextCursor, err := uc.suppressionRepository.List(ctx, limit, cursor)
+	if err != nil {
+		return nil, "", &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from suppression repository (List)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	return suppressions, nextCursor, nil
+}
+
+// Remove delete an address from the suppression list so it can receive emails again
+func (uc *ManageSuppressionsUC) Remove(ctx context.Context, email string) error {
+	suppression, err := uc.suppressionRepository.GetByEmail(ctx, email)
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from suppression repository (GetByEmail)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	if suppression == nil {
+		return &internal.GeneralError{
+			Code:       internal.CodeSuppressionError,
+			ID:         internal.IDSuppressionNotFound,
+			Message:    "Address '" + email + "' is not suppressed",
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	err = uc.suppressionRepository.Delete(ctx, email)
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error deleting from suppression repository (Delete)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	return nil
+}
+
+// NewManageSuppressionsUC new instance of this use case
+func NewManageSuppressionsUC(suppressionRepository SuppressionRepositoryInterface) *ManageSuppressionsUC {
+	return &ManageSuppressionsUC{
+		suppressionRepository: suppressionRepository,
+	}
+}
@@ -0,0 +1,117 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+)
+
+// TestManageSuppressionsUC_List test for this method
+func TestManageSuppressionsUC_List(t *testing.T) {
+	tests := []struct {
+		name       string
+		listErr    error
+		wantCursor string
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			wantCursor: "bounce@example.com",
+		},
+		{
+			name:    "repository error",
+			listErr: errors.New("database error"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ucInstance := NewManageSuppressionsUC(&MockSuppressionRepository{
+				ListFunc: func(limit int, cursor string) ([]internal.Suppression, string, error) {
+					if tt.listErr != nil {
+						return nil, "", tt.listErr
+					}
+
+					return []internal.Suppression{{Email: "bounce@example.com"}}, "bounce@example.com", nil
+				},
+			})
+
+			_, cursor, err := ucInstance.List(context.Background(), 10, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ManageSuppressionsUC.List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if cursor != tt.wantCursor {
+				t.Errorf("ManageSuppressionsUC.List() cursor = %v, want %v", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+// TestManageSuppressionsUC_Remove test for this method
+func TestManageSuppressionsUC_Remove(t *testing.T) {
+	tests := []struct {
+		name           string
+		suppression    *internal.Suppression
+		deleteErr      error
+		wantDeleted    bool
+		wantStatusCode int
+	}{
+		{
+			name:        "success",
+			suppression: &internal.Suppression{Email: "bounce@example.com"},
+			wantDeleted: true,
+		},
+		{
+			name:           "not suppressed",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "delete error",
+			suppression:    &internal.Suppression{Email: "bounce@example.com"},
+			deleteErr:      errors.New("database error"),
+			wantDeleted:    true,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := false
+
+			ucInstance := NewManageSuppressionsUC(&MockSuppressionRepository{
+				GetByEmailFunc: func(email string) (*internal.Suppression, error) {
+					return tt.suppression, nil
+				},
+				DeleteFunc: func(email string) error {
+					deleted = true
+
+					return tt.deleteErr
+				},
+			})
+
+			err := ucInstance.Remove(context.Background(), "bounce@example.com")
+			if deleted != tt.wantDeleted {
+				t.Errorf("ManageSuppressionsUC.Remove() deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+
+			if tt.wantStatusCode == 0 {
+				if err != nil {
+					t.Errorf("ManageSuppressionsUC.Remove() unexpected error = %v", err)
+				}
+
+				return
+			}
+
+			var generalError *internal.GeneralError
+			if !errors.As(err, &generalError) || generalError.StatusCode != tt.wantStatusCode {
+				t.Errorf("ManageSuppressionsUC.Remove() error = %v, want status %v", err, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// sesBounceTypePermanent bounce type of the addresses that will never accept emails
+const sesBounceTypePermanent = "Permanent"
+
+// RecordFeedbackUC struct for this use case
+type RecordFeedbackUC struct {
+	suppressionRepository SuppressionRepositoryInterface
+	now                   func() time.Time
+}
+
+// Handle main method with the logic to add to the suppression list the recipients of hard bounces and complaints,
+// transient bounces and other notifications are ignored
+func (uc *RecordFeedbackUC) Handle(ctx context.Context, feedback internal.SESFeedback) error {
+	var reason, detail string
+
+	var recipients []internal.SESFeedbackRecipient
+
+	switch {
+	case feedback.Bounce != nil && feedback.Bounce.BounceType == sesBounceTypePermanent:
+		reason = internal.SuppressionReasonBounce
+		detail = feedback.Bounce.BounceSubType
+		recipients = feedback.Bounce.BouncedRecipients
+	case feedback.Complaint != nil:
+		reason = internal.SuppressionReasonComplaint
+		detail = feedback.Complaint.ComplaintFeedbackType
+		recipients = feedback.Complaint.ComplainedRecipients
+	default:
+		return nil
+	}
+
+	createdAt := uc.now().UTC().Format(time.RFC3339)
+
+	for _, recipient := range recipients {
+		err := uc.suppressionRepository.Save(ctx, internal.Suppression{
+			Email:     recipient.EmailAddress,
+			Reason:    reason,
+			Detail:    detail,
+			CreatedAt: createdAt,
+		})
+		if err != nil {
+			return &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error saving in suppression repository (Save)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewRecordFeedbackUC new instance of this use case
+func NewRecordFeedbackUC(suppressionRepository SuppressionRepositoryInterface) *RecordFeedbackUC {
+	return &RecordFeedbackUC{
+		suppressionRepository: suppressionRepository,
+		now:                   time.Now,
+	}
+}
@@ -0,0 +1,108 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"modak/send-notification/v1/internal"
+)
+
+// loadFeedbackFixture read a recorded SES notification from the testdata of the internal package
+func loadFeedbackFixture(t *testing.T, fixture string) internal.SESFeedback {
+	var feedback internal.SESFeedback
+
+	content, err := os.ReadFile("../testdata/" + fixture)
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", fixture, err)
+	}
+
+	if err := json.Unmarshal(content, &feedback); err != nil {
+		t.Fatalf("parsing fixture %s: %v", fixture, err)
+	}
+
+	return feedback
+}
+
+// TestRecordFeedbackUC_Handle test for this method
+func TestRecordFeedbackUC_Handle(t *testing.T) {
+	now := time.Date(2023, 10, 15, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		fixture string
+		saveErr error
+		want    []internal.Suppression
+		wantErr bool
+	}{
+		{
+			name:    "permanent bounce is suppressed",
+			fixture: "ses_bounce_permanent.json",
+			want: []internal.Suppression{{
+				Email:     "bounce@simulator.amazonses.com",
+				Reason:    internal.SuppressionReasonBounce,
+				Detail:    "General",
+				CreatedAt: "2023-10-15T13:00:00Z",
+			}},
+		},
+		{
+			name:    "complaint is suppressed",
+			fixture: "ses_complaint.json",
+			want: []internal.Suppression{{
+				Email:     "complaint@simulator.amazonses.com",
+				Reason:    internal.SuppressionReasonComplaint,
+				Detail:    "abuse",
+				CreatedAt: "2023-10-15T13:00:00Z",
+			}},
+		},
+		{
+			name:    "transient bounce is ignored",
+			fixture: "ses_bounce_transient.json",
+		},
+		{
+			name:    "delivery is ignored",
+			fixture: "ses_delivery.json",
+		},
+		{
+			name:    "save error",
+			fixture: "ses_complaint.json",
+			saveErr: errors.New("database error"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved []internal.Suppression
+
+			ucInstance := &RecordFeedbackUC{
+				suppressionRepository: &MockSuppressionRepository{
+					SaveFunc: func(suppression internal.Suppression) error {
+						if tt.saveErr != nil {
+							return tt.saveErr
+						}
+
+						saved = append(saved, suppression)
+
+						return nil
+					},
+				},
+				now: func() time.Time { return now },
+			}
+
+			err := ucInstance.Handle(context.Background(), loadFeedbackFixture(t, tt.fixture))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordFeedbackUC.Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(saved, tt.want) {
+				t.Errorf("RecordFeedbackUC.Handle() saved = %v, want %v", saved, tt.want)
+			}
+		})
+	}
+}
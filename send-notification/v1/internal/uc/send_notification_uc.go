@@ -2,6 +2,8 @@
 package uc
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"modak/send-notification/v1/internal"
@@ -9,38 +11,278 @@ import (
 
 // EmailServiceInterface interface for this service
 type EmailServiceInterface interface {
-	Send(recipient, subject, message string) error
+	Send(ctx context.Context, email internal.Email) (string, error)
+}
+
+// UnsubscribeLinkServiceInterface interface for the service of signed unsubscribe links
+type UnsubscribeLinkServiceInterface interface {
+	URL(tenant, email, notificationType string) string
+	Verify(token string) (internal.Unsubscription, error)
+}
+
+// AttachmentServiceInterface interface for the service that loads the content of the attachments
+type AttachmentServiceInterface interface {
+	Load(ctx context.Context, attachments []internal.Attachment) ([]internal.EmailAttachment, error)
+}
+
+// SuppressionRepositoryInterface struct for this repository related to the suppressed addresses
+type SuppressionRepositoryInterface interface {
+	GetByEmail(ctx context.Context, email string) (*internal.Suppression, error)
+	Save(ctx context.Context, suppression internal.Suppression) error
+	List(ctx context.Context, limit int, cursor string) ([]internal.Suppression, string, error)
+	Delete(ctx context.Context, email string) error
 }
 
 // SendNotificationUC struct for this use case
 type SendNotificationUC struct {
-	EmailService EmailServiceInterface
+	EmailService             EmailServiceInterface
+	UnsubscribeLinkService   UnsubscribeLinkServiceInterface
+	SuppressionRepository    SuppressionRepositoryInterface
+	RateLimitRulesRepository RateLimitRulesRepositoryInterface
+	AttachmentService        AttachmentServiceInterface
+	Senders                  internal.SenderConfig
+}
+
+// Handle main method with the logic to send notifications, the notifications to several recipients are sent
+// to the recipients that are not suppressed in emails of at most internal.MaxEmailDestinations addresses, or in one
+// email per recipient when the emails have unsubscribe links
+func (uc *SendNotificationUC) Handle(
+	ctx context.Context,
+	notification internal.Notification,
+) (internal.SendResult, error) {
+	var results []internal.RecipientResult
+
+	var recipients []internal.Recipient
+
+	// Addresses that hard bounced or complained are never sent to protect the SES reputation
+	for _, recipient := range notification.Recipients() {
+		suppression, err := uc.SuppressionRepository.GetByEmail(ctx, recipient.Address)
+		if err != nil {
+			return internal.SendResult{}, &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error getting from suppression repository (GetByEmail)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+
+		if suppression != nil {
+			results = append(results, internal.RecipientResult{
+				Recipient: recipient,
+				Reason:    internal.RejectionReasonSuppressed,
+			})
+
+			continue
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return internal.SendResult{
+			Reason:     internal.RejectionReasonSuppressed,
+			Recipients: recipientResults(notification, results),
+		}, nil
+	}
+
+	metadata := internal.RequestMetadataFromContext(ctx)
+
+	sender, err := uc.sender(ctx, metadata.Tenant, notification.Type)
+	if err != nil {
+		return internal.SendResult{}, err
+	}
+
+	// The requests check the sender before the rate limit, this check only covers a rule changed since then
+	if !uc.Senders.Allows(sender.FromAddress) {
+		return internal.SendResult{Reason: internal.RejectionReasonSenderNotAllowed}, nil
+	}
+
+	// The attachments are loaded once for every email of the notification
+	attachments, err := uc.AttachmentService.Load(ctx, notification.Attachments)
+	if err != nil {
+		return uc.attachmentError(notification, results, err)
+	}
+
+	result := internal.SendResult{}
+
+	batches := internal.RecipientBatches(recipients)
+
+	// The unsubscribe links are signed for one address, so every recipient gets its own email when there are links
+	if uc.UnsubscribeLinkService.URL(metadata.Tenant, recipients[0].Address, notification.Type) != "" {
+		batches = make([][]internal.Recipient, 0, len(recipients))
+		for i := range recipients {
+			batches = append(batches, recipients[i:i+1])
+		}
+	}
+
+	for _, batch := range batches {
+		email := internal.Email{
+			Subject:     notification.Type,
+			Body:        notification.Message,
+			Sender:      sender,
+			Attachments: attachments,
+			Type:        notification.Type,
+			Tenant:      metadata.Tenant,
+			RequestID:   metadata.RequestID,
+		}
+
+		if len(batch) == 1 {
+			email.UnsubscribeURL = uc.UnsubscribeLinkService.URL(metadata.Tenant, batch[0].Address, notification.Type)
+		}
+
+		email.AddRecipients(batch)
+
+		// send notification via email
+		messageID, err := uc.EmailService.Send(ctx, email)
+		if err != nil && !result.Sent {
+			if errors.Is(err, internal.ErrInvalidAttachment) {
+				return uc.attachmentError(notification, results, err)
+			}
+
+			return internal.SendResult{}, &internal.GeneralError{
+				Code:          internal.CodeNotificationError,
+				ID:            internal.IDNotificationEmailNotSent,
+				Message:       "Error sending email notification",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+
+		for _, recipient := range batch {
+			// The recipients of a batch that failed after another batch was sent can be sent again
+			if err != nil {
+				results = append(results, internal.RecipientResult{
+					Recipient: recipient,
+					Reason:    internal.RejectionReasonNotProcessed,
+				})
+
+				continue
+			}
+
+			results = append(results, internal.RecipientResult{Recipient: recipient, Sent: true, MessageID: messageID})
+		}
+
+		if err == nil && !result.Sent {
+			result.Sent = true
+			result.MessageID = messageID
+		}
+	}
+
+	result.Recipients = recipientResults(notification, results)
+
+	return result, nil
 }
 
-// Handle main method with the logic to send notifications
-func (uc *SendNotificationUC) Handle(notification internal.Notification) error {
-	// send notification via email
-	err := uc.EmailService.Send(
-		notification.Recipient,
-		notification.Type,
-		notification.Message,
-	)
+// SenderAllowed check if the sender of the notifications of the type is an allowed identity, the requests check it
+// before the rate limit so the notifications that can not be sent do not use any quota
+func (uc *SendNotificationUC) SenderAllowed(ctx context.Context, notificationType string) (bool, error) {
+	sender, err := uc.sender(ctx, internal.RequestMetadataFromContext(ctx).Tenant, notificationType)
 	if err != nil {
-		return &internal.GeneralError{
-			Code:          internal.CodeNotificationError,
-			ID:            internal.IDNotificationEmailNotSent,
-			Message:       "Error sending email notification",
-			StatusCode:    http.StatusInternalServerError,
-			OriginalError: err,
+		return false, err
+	}
+
+	return uc.Senders.Allows(sender.FromAddress), nil
+}
+
+// sender sender of the emails of the type for the tenant
+func (uc *SendNotificationUC) sender(ctx context.Context, tenant, notificationType string) (internal.Sender, error) {
+	typeSender, err := uc.typeSender(ctx, tenant, notificationType)
+	if err != nil {
+		return internal.Sender{}, err
+	}
+
+	return uc.Senders.Resolve(tenant, typeSender), nil
+}
+
+// attachmentError result of a notification whose attachments could not be sent, the attachments that can not be
+// loaded or are too large reject the notification and the other errors are general errors
+func (uc *SendNotificationUC) attachmentError(
+	notification internal.Notification,
+	results []internal.RecipientResult,
+	err error,
+) (internal.SendResult, error) {
+	if errors.Is(err, internal.ErrInvalidAttachment) {
+		return internal.SendResult{
+			Reason:     internal.RejectionReasonInvalidAttachment,
+			Recipients: recipientResults(notification, results),
+		}, nil
+	}
+
+	return internal.SendResult{}, &internal.GeneralError{
+		Code:          internal.CodeGeneralError,
+		ID:            internal.IDGeneralError,
+		Message:       "Error loading the attachments (Load)",
+		StatusCode:    http.StatusInternalServerError,
+		OriginalError: err,
+	}
+}
+
+// recipientResults outcome of each recipient, only the notifications to several recipients report it
+func recipientResults(
+	notification internal.Notification,
+	results []internal.RecipientResult,
+) []internal.RecipientResult {
+	if !notification.HasRecipientList() {
+		return nil
+	}
+
+	return results
+}
+
+// typeSender sender of the rule of the type for the tenant, nil when it has none. The sender of a global rule is
+// not used by the tenants with their own sender, so a tenant never sends with the identity of another product
+func (uc *SendNotificationUC) typeSender(
+	ctx context.Context,
+	tenant, notificationType string,
+) (*internal.Sender, error) {
+	// The sender of the type is kept with its rule, the rules were cached when the notification was validated
+	types := []string{internal.TenantType(tenant, notificationType)}
+	if tenant != "" {
+		types = append(types, notificationType)
+	}
+
+	for _, ruleType := range types {
+		rule, err := uc.RateLimitRulesRepository.GetByType(ctx, ruleType)
+		if err != nil {
+			return nil, &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error getting from rate limit rules repository (GetByType)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
 		}
+
+		if rule == nil {
+			continue
+		}
+
+		if _, ok := uc.Senders.Tenants[tenant]; ok && ruleType == notificationType && tenant != "" {
+			return nil, nil
+		}
+
+		return rule.Sender, nil
 	}
 
-	return err
+	return nil, nil
 }
 
 // NewSendNotificationUC new instance of this use case
-func NewSendNotificationUC(EmailService EmailServiceInterface) *SendNotificationUC {
+func NewSendNotificationUC(
+	EmailService EmailServiceInterface,
+	UnsubscribeLinkService UnsubscribeLinkServiceInterface,
+	SuppressionRepository SuppressionRepositoryInterface,
+	RateLimitRulesRepository RateLimitRulesRepositoryInterface,
+	AttachmentService AttachmentServiceInterface,
+	Senders internal.SenderConfig,
+) *SendNotificationUC {
 	return &SendNotificationUC{
-		EmailService: EmailService,
+		EmailService:             EmailService,
+		UnsubscribeLinkService:   UnsubscribeLinkService,
+		SuppressionRepository:    SuppressionRepository,
+		RateLimitRulesRepository: RateLimitRulesRepository,
+		AttachmentService:        AttachmentService,
+		Senders:                  Senders,
 	}
 }
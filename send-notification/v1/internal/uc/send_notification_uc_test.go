@@ -2,7 +2,10 @@
 package uc
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"modak/send-notification/v1/internal"
@@ -10,18 +13,95 @@ import (
 
 // mockEmailService Mock for email service
 type mockEmailService struct {
-	SendFunc func(recipient, subject, message string) error
+	SendFunc func(email internal.Email) (string, error)
 }
 
 // Send Mock for method send of email service
-func (m *mockEmailService) Send(recipient, subject, message string) error {
-	return m.SendFunc(recipient, subject, message)
+func (m *mockEmailService) Send(_ context.Context, email internal.Email) (string, error) {
+	return m.SendFunc(email)
+}
+
+// mockUnsubscribeLinkService Mock for unsubscribe link service
+type mockUnsubscribeLinkService struct {
+	VerifyFunc func(token string) (internal.Unsubscription, error)
+	// disabled the service has no base URL and builds no link
+	disabled bool
+}
+
+// URL Mock for method that builds the unsubscribe link
+func (m *mockUnsubscribeLinkService) URL(tenant, email, notificationType string) string {
+	if m.disabled {
+		return ""
+	}
+
+	return "https://example.com/unsubscribe?token=" + internal.TenantType(tenant, email) + "." + notificationType
+}
+
+// Verify Mock for method that verifies the unsubscribe token
+func (m *mockUnsubscribeLinkService) Verify(token string) (internal.Unsubscription, error) {
+	return m.VerifyFunc(token)
+}
+
+// mockAttachmentService Mock for attachment service
+type mockAttachmentService struct {
+	LoadFunc func(attachments []internal.Attachment) ([]internal.EmailAttachment, error)
+}
+
+// Load Mock for method that loads the attachments, without LoadFunc the notification has no attachments
+func (m *mockAttachmentService) Load(
+	_ context.Context,
+	attachments []internal.Attachment,
+) ([]internal.EmailAttachment, error) {
+	if m.LoadFunc == nil {
+		return nil, nil
+	}
+
+	return m.LoadFunc(attachments)
+}
+
+// MockSuppressionRepository Mock for suppression repository
+type MockSuppressionRepository struct {
+	GetByEmailFunc func(email string) (*internal.Suppression, error)
+	SaveFunc       func(suppression internal.Suppression) error
+	ListFunc       func(limit int, cursor string) ([]internal.Suppression, string, error)
+	DeleteFunc     func(email string) error
+}
+
+// GetByEmail Mock for method to get a suppressed address, nil when it is not suppressed
+func (m *MockSuppressionRepository) GetByEmail(_ context.Context, email string) (*internal.Suppression, error) {
+	if m.GetByEmailFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetByEmailFunc(email)
+}
+
+// Save Mock for method to add an address to the suppression list
+func (m *MockSuppressionRepository) Save(_ context.Context, suppression internal.Suppression) error {
+	return m.SaveFunc(suppression)
+}
+
+// List Mock for method to get a page of suppressed addresses
+func (m *MockSuppressionRepository) List(
+	_ context.Context,
+	limit int,
+	cursor string,
+) ([]internal.Suppression, string, error) {
+	return m.ListFunc(limit, cursor)
+}
+
+// Delete Mock for method to remove an address from the suppression list
+func (m *MockSuppressionRepository) Delete(_ context.Context, email string) error {
+	return m.DeleteFunc(email)
 }
 
 // TestSendNotificationUC_Handle test for this method
 func TestSendNotificationUC_Handle(t *testing.T) {
 	type fields struct {
-		emailService EmailServiceInterface
+		emailService             EmailServiceInterface
+		suppressionRepository    SuppressionRepositoryInterface
+		rateLimitRulesRepository RateLimitRulesRepositoryInterface
+		attachmentService        AttachmentServiceInterface
 	}
 
 	type args struct {
@@ -32,14 +112,27 @@ func TestSendNotificationUC_Handle(t *testing.T) {
 		name    string
 		fields  fields
 		args    args
+		want    internal.SendResult
 		wantErr bool
 	}{
 		{
 			name: "success",
 			fields: fields{
 				emailService: &mockEmailService{
-					SendFunc: func(recipient, subject, message string) error {
-						return nil
+					SendFunc: func(email internal.Email) (string, error) {
+						if email.UnsubscribeURL != "https://example.com/unsubscribe?token=acme:test@example.com.News" {
+							return "", errors.New("unexpected unsubscribe link")
+						}
+
+						if email.Type != "News" || email.Tenant != "acme" || email.RequestID != "request-1" {
+							return "", errors.New("unexpected tags")
+						}
+
+						if email.Sender != (internal.Sender{FromAddress: "notifications@example.com", ReplyTo: "help@example.com"}) {
+							return "", errors.New("unexpected sender")
+						}
+
+						return "message-1", nil
 					},
 				},
 			},
@@ -50,14 +143,231 @@ func TestSendNotificationUC_Handle(t *testing.T) {
 					Message:   "Notification about News",
 				},
 			},
+			want:    internal.SendResult{Sent: true, MessageID: "message-1"},
 			wantErr: false,
 		},
+		{
+			name: "suppressed recipient",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						return "", errors.New("suppressed recipient must not be sent")
+					},
+				},
+				suppressionRepository: &MockSuppressionRepository{
+					GetByEmailFunc: func(email string) (*internal.Suppression, error) {
+						return &internal.Suppression{Email: email, Reason: internal.SuppressionReasonBounce}, nil
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "bounce@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			want:    internal.SendResult{Reason: internal.RejectionReasonSuppressed},
+			wantErr: false,
+		},
+		{
+			name: "suppression repository error",
+			fields: fields{
+				emailService: &mockEmailService{},
+				suppressionRepository: &MockSuppressionRepository{
+					GetByEmailFunc: func(email string) (*internal.Suppression, error) {
+						return nil, errors.New("database error")
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sender of the type",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						want := internal.Sender{FromAddress: "news@mail.example.com", DisplayName: "News", ReplyTo: "help@example.com"}
+						if email.Sender != want {
+							return "", errors.New("unexpected sender")
+						}
+
+						return "message-1", nil
+					},
+				},
+				rateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return &internal.RateLimitRule{
+							Type:   notificationType,
+							Sender: &internal.Sender{FromAddress: "news@mail.example.com", DisplayName: "News"},
+						}, nil
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			want:    internal.SendResult{Sent: true, MessageID: "message-1"},
+			wantErr: false,
+		},
+		{
+			name: "sender not allowed",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						return "", errors.New("sender not allowed must not be sent")
+					},
+				},
+				rateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return &internal.RateLimitRule{
+							Type:   notificationType,
+							Sender: &internal.Sender{FromAddress: "news@other.com"},
+						}, nil
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			want:    internal.SendResult{Reason: internal.RejectionReasonSenderNotAllowed},
+			wantErr: false,
+		},
+		{
+			name: "rate limit rules repository error",
+			fields: fields{
+				emailService: &mockEmailService{},
+				rateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return nil, errors.New("database error")
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "attachments",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						want := []internal.EmailAttachment{{Filename: "invite.ics", ContentType: "text/calendar", Data: []byte("ICS")}}
+						if !reflect.DeepEqual(email.Attachments, want) {
+							return "", errors.New("unexpected attachments")
+						}
+
+						return "message-1", nil
+					},
+				},
+				attachmentService: &mockAttachmentService{
+					LoadFunc: func(attachments []internal.Attachment) ([]internal.EmailAttachment, error) {
+						return []internal.EmailAttachment{
+							{Filename: attachments[0].Filename, ContentType: attachments[0].ContentType, Data: []byte("ICS")},
+						}, nil
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:        "News",
+					Recipient:   "test@example.com",
+					Message:     "Notification about News",
+					Attachments: []internal.Attachment{{Filename: "invite.ics", ContentType: "text/calendar", Content: "SUNT"}},
+				},
+			},
+			want:    internal.SendResult{Sent: true, MessageID: "message-1"},
+			wantErr: false,
+		},
+		{
+			name: "invalid attachment",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						return "", errors.New("invalid attachment must not be sent")
+					},
+				},
+				attachmentService: &mockAttachmentService{
+					LoadFunc: func(attachments []internal.Attachment) ([]internal.EmailAttachment, error) {
+						return nil, fmt.Errorf("%w: missing object", internal.ErrInvalidAttachment)
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:        "News",
+					Recipient:   "test@example.com",
+					Message:     "Notification about News",
+					Attachments: []internal.Attachment{{Filename: "report.pdf", ObjectPath: "s3://reports/missing.pdf"}},
+				},
+			},
+			want:    internal.SendResult{Reason: internal.RejectionReasonInvalidAttachment},
+			wantErr: false,
+		},
+		{
+			name: "email too large for SES",
+			fields: fields{
+				emailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						return "", fmt.Errorf("%w: the email is too large", internal.ErrInvalidAttachment)
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			want:    internal.SendResult{Reason: internal.RejectionReasonInvalidAttachment},
+			wantErr: false,
+		},
+		{
+			name: "attachment service error",
+			fields: fields{
+				emailService: &mockEmailService{},
+				attachmentService: &mockAttachmentService{
+					LoadFunc: func(attachments []internal.Attachment) ([]internal.EmailAttachment, error) {
+						return nil, errors.New("throttled")
+					},
+				},
+			},
+			args: args{
+				notification: internal.Notification{
+					Type:      "News",
+					Recipient: "test@example.com",
+					Message:   "Notification about News",
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "send email error",
 			fields: fields{
 				emailService: &mockEmailService{
-					SendFunc: func(recipient, subject, message string) error {
-						return errors.New("send error")
+					SendFunc: func(email internal.Email) (string, error) {
+						return "", errors.New("send error")
 					},
 				},
 			},
@@ -74,17 +384,404 @@ func TestSendNotificationUC_Handle(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			suppressionRepository := tt.fields.suppressionRepository
+			if suppressionRepository == nil {
+				suppressionRepository = &MockSuppressionRepository{}
+			}
+
+			rateLimitRulesRepository := tt.fields.rateLimitRulesRepository
+			if rateLimitRulesRepository == nil {
+				rateLimitRulesRepository = &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return &internal.RateLimitRule{Type: notificationType}, nil
+					},
+				}
+			}
+
+			attachmentService := tt.fields.attachmentService
+			if attachmentService == nil {
+				attachmentService = &mockAttachmentService{}
+			}
+
 			ucInstance := &SendNotificationUC{
-				EmailService: tt.fields.emailService,
+				EmailService:             tt.fields.emailService,
+				UnsubscribeLinkService:   &mockUnsubscribeLinkService{},
+				SuppressionRepository:    suppressionRepository,
+				RateLimitRulesRepository: rateLimitRulesRepository,
+				AttachmentService:        attachmentService,
+				Senders: internal.SenderConfig{
+					Default: internal.Sender{
+						FromAddress: "notifications@example.com",
+						ReplyTo:     "help@example.com",
+					},
+					AllowedIdentities: []string{"mail.example.com"},
+				},
 			}
-			err := ucInstance.Handle(tt.args.notification)
+			ctx := internal.WithRequestMetadata(
+				context.Background(),
+				internal.RequestMetadata{RequestID: "request-1", Tenant: "acme"},
+			)
+
+			got, err := ucInstance.Handle(ctx, tt.args.notification)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SendNotificationUC.Handle() error = %v, wantErr %v", err, tt.wantErr)
 			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SendNotificationUC.Handle() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
 
+// TestSendNotificationUC_Handle_TenantSender test for the sender of the notifications of a tenant
+func TestSendNotificationUC_Handle_TenantSender(t *testing.T) {
+	globalRule := internal.RateLimitRule{Type: "News", Sender: &internal.Sender{FromAddress: "news@mail.example.com"}}
+	tenantRule := internal.RateLimitRule{Type: "acme:News", Sender: &internal.Sender{FromAddress: "news@acme.com"}}
+
+	tests := []struct {
+		name    string
+		tenant  string
+		rules   []internal.RateLimitRule
+		tenants map[string]internal.Sender
+		want    string
+	}{
+		{
+			name:    "sender of the rule of the tenant",
+			tenant:  "acme",
+			rules:   []internal.RateLimitRule{globalRule, tenantRule},
+			tenants: map[string]internal.Sender{"acme": {FromAddress: "alerts@acme.com"}},
+			want:    "news@acme.com",
+		},
+		{
+			name:    "sender of the tenant over the sender of the global rule",
+			tenant:  "acme",
+			rules:   []internal.RateLimitRule{globalRule},
+			tenants: map[string]internal.Sender{"acme": {FromAddress: "alerts@acme.com"}},
+			want:    "alerts@acme.com",
+		},
+		{
+			name:   "sender of the global rule for a tenant without sender",
+			tenant: "globex",
+			rules:  []internal.RateLimitRule{globalRule},
+			want:   "news@mail.example.com",
+		},
+		{
+			name:    "sender of the global rule without tenant",
+			rules:   []internal.RateLimitRule{globalRule, tenantRule},
+			tenants: map[string]internal.Sender{"acme": {FromAddress: "alerts@acme.com"}},
+			want:    "news@mail.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			ucInstance := &SendNotificationUC{
+				EmailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						got = email.Sender.FromAddress
+
+						return "message-1", nil
+					},
+				},
+				UnsubscribeLinkService: &mockUnsubscribeLinkService{},
+				SuppressionRepository:  &MockSuppressionRepository{},
+				RateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						for _, rule := range tt.rules {
+							if rule.Type == notificationType {
+								return &rule, nil
+							}
+						}
+
+						return nil, nil
+					},
+				},
+				AttachmentService: &mockAttachmentService{},
+				Senders: internal.SenderConfig{
+					Default:           internal.Sender{FromAddress: "notifications@example.com"},
+					Tenants:           tt.tenants,
+					AllowedIdentities: []string{"mail.example.com", "acme.com"},
+				},
+			}
+			ctx := internal.WithRequestMetadata(context.Background(), internal.RequestMetadata{Tenant: tt.tenant})
+
+			_, err := ucInstance.Handle(ctx, internal.Notification{
+				Type:      "News",
+				Recipient: "test@example.com",
+				Message:   "Notification about News",
+			})
+			if err != nil {
+				t.Fatalf("SendNotificationUC.Handle() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("SendNotificationUC.Handle() sender = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendNotificationUC_SenderAllowed test for this method
+func TestSendNotificationUC_SenderAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *internal.RateLimitRule
+		ruleErr error
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "sender of the rule allowed",
+			rule: &internal.RateLimitRule{Type: "News", Sender: &internal.Sender{FromAddress: "news@mail.example.com"}},
+			want: true,
+		},
+		{
+			name: "default sender",
+			want: true,
+		},
+		{
+			name: "sender of the rule not allowed",
+			rule: &internal.RateLimitRule{Type: "News", Sender: &internal.Sender{FromAddress: "news@other.com"}},
+		},
+		{
+			name:    "rate limit rules repository error",
+			ruleErr: errors.New("database error"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ucInstance := &SendNotificationUC{
+				RateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return tt.rule, tt.ruleErr
+					},
+				},
+				Senders: internal.SenderConfig{
+					Default:           internal.Sender{FromAddress: "notifications@example.com"},
+					AllowedIdentities: []string{"mail.example.com"},
+				},
+			}
+
+			got, err := ucInstance.SenderAllowed(context.Background(), "News")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendNotificationUC.SenderAllowed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("SendNotificationUC.SenderAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendNotificationUC_Handle_Recipients test for the notifications to the lists to, cc and bcc
+func TestSendNotificationUC_Handle_Recipients(t *testing.T) {
+	to := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		to = append(to, fmt.Sprintf("user%d@example.com", i))
+	}
+
+	notification := internal.Notification{
+		Type:    "News",
+		To:      to,
+		Cc:      []string{"cc1@example.com", "bounce@example.com"},
+		Bcc:     []string{"bcc1@example.com", "bcc2@example.com", "bcc3@example.com"},
+		Message: "Notification about News",
+	}
+
+	tests := []struct {
+		name          string
+		failBatch     int
+		wantErr       bool
+		wantSent      bool
+		wantBatches   []int
+		wantProcessed int
+	}{
+		{
+			name:          "batches of at most 50 destinations",
+			failBatch:     -1,
+			wantSent:      true,
+			wantBatches:   []int{50, 50, 4},
+			wantProcessed: 104,
+		},
+		{
+			name:          "later batch not processed",
+			failBatch:     2,
+			wantSent:      true,
+			wantBatches:   []int{50, 50, 4},
+			wantProcessed: 100,
+		},
+		{
+			name:        "first batch error",
+			failBatch:   0,
+			wantErr:     true,
+			wantBatches: []int{50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches []internal.Email
+
+			ucInstance := &SendNotificationUC{
+				EmailService: &mockEmailService{
+					SendFunc: func(email internal.Email) (string, error) {
+						batches = append(batches, email)
+						if len(batches)-1 == tt.failBatch {
+							return "", errors.New("send error")
+						}
+
+						return fmt.Sprintf("message-%d", len(batches)), nil
+					},
+				},
+				UnsubscribeLinkService: &mockUnsubscribeLinkService{disabled: true},
+				AttachmentService:      &mockAttachmentService{},
+				SuppressionRepository: &MockSuppressionRepository{
+					GetByEmailFunc: func(email string) (*internal.Suppression, error) {
+						if email == "bounce@example.com" {
+							return &internal.Suppression{Email: email, Reason: internal.SuppressionReasonBounce}, nil
+						}
+
+						return nil, nil
+					},
+				},
+				RateLimitRulesRepository: &MockRateLimitRulesRepository{
+					GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+						return &internal.RateLimitRule{Type: notificationType}, nil
+					},
+				},
+				Senders: internal.SenderConfig{Default: internal.Sender{FromAddress: "notifications@example.com"}},
+			}
+
+			got, err := ucInstance.Handle(context.Background(), notification)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendNotificationUC.Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var sizes []int
+			for _, email := range batches {
+				sizes = append(sizes, email.DestinationCount())
+
+				if email.UnsubscribeURL != "" {
+					t.Errorf("SendNotificationUC.Handle() unsubscribe link %s, want none", email.UnsubscribeURL)
+				}
+			}
+
+			if !reflect.DeepEqual(sizes, tt.wantBatches) {
+				t.Errorf("SendNotificationUC.Handle() batches = %v, want %v", sizes, tt.wantBatches)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			// The last batch has the cc and the bcc that were not suppressed
+			last := batches[len(batches)-1]
+			if !reflect.DeepEqual(last.Cc, []string{"cc1@example.com"}) || len(last.Bcc) != 3 {
+				t.Errorf("SendNotificationUC.Handle() last batch cc = %v, bcc = %v", last.Cc, last.Bcc)
+			}
+
+			if got.Sent != tt.wantSent || got.MessageID != "message-1" || len(got.Recipients) != 105 {
+				t.Fatalf("SendNotificationUC.Handle() = %v, %s with %d recipients", got.Sent, got.MessageID, len(got.Recipients))
+			}
+
+			sent := 0
+			for _, result := range got.Recipients {
+				switch {
+				case result.Sent:
+					sent++
+				case result.Address == "bounce@example.com" && result.Reason != internal.RejectionReasonSuppressed,
+					result.Address != "bounce@example.com" && result.Reason != internal.RejectionReasonNotProcessed:
+					t.Errorf("SendNotificationUC.Handle() recipient %s reason %s", result.Address, result.Reason)
+				}
+			}
+
+			if sent != tt.wantProcessed {
+				t.Errorf("SendNotificationUC.Handle() sent to %d recipients, want %d", sent, tt.wantProcessed)
+			}
+		})
+	}
+}
+
+// TestSendNotificationUC_Handle_UnsubscribeLinks test that every recipient gets its own email with its own link
+func TestSendNotificationUC_Handle_UnsubscribeLinks(t *testing.T) {
+	notification := internal.Notification{
+		Type:    "News",
+		To:      []string{"to1@example.com", "to2@example.com"},
+		Cc:      []string{"cc1@example.com"},
+		Bcc:     []string{"bcc1@example.com"},
+		Message: "Notification about News",
+	}
+
+	var emails []internal.Email
+
+	ucInstance := &SendNotificationUC{
+		EmailService: &mockEmailService{
+			SendFunc: func(email internal.Email) (string, error) {
+				emails = append(emails, email)
+
+				return fmt.Sprintf("message-%d", len(emails)), nil
+			},
+		},
+		UnsubscribeLinkService: &mockUnsubscribeLinkService{},
+		AttachmentService:      &mockAttachmentService{},
+		SuppressionRepository: &MockSuppressionRepository{
+			GetByEmailFunc: func(email string) (*internal.Suppression, error) {
+				return nil, nil
+			},
+		},
+		RateLimitRulesRepository: &MockRateLimitRulesRepository{
+			GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+				return &internal.RateLimitRule{Type: notificationType}, nil
+			},
+		},
+		Senders: internal.SenderConfig{Default: internal.Sender{FromAddress: "notifications@example.com"}},
+	}
+
+	ctx := internal.WithRequestMetadata(context.Background(), internal.RequestMetadata{Tenant: "acme"})
+
+	got, err := ucInstance.Handle(ctx, notification)
+	if err != nil {
+		t.Fatalf("SendNotificationUC.Handle() error = %v", err)
+	}
+
+	link := "https://example.com/unsubscribe?token=acme:"
+	want := []internal.Email{
+		{To: []string{"to1@example.com"}, UnsubscribeURL: link + "to1@example.com.News"},
+		{To: []string{"to2@example.com"}, UnsubscribeURL: link + "to2@example.com.News"},
+		{Cc: []string{"cc1@example.com"}, UnsubscribeURL: link + "cc1@example.com.News"},
+		{Bcc: []string{"bcc1@example.com"}, UnsubscribeURL: link + "bcc1@example.com.News"},
+	}
+
+	if len(emails) != len(want) {
+		t.Fatalf("SendNotificationUC.Handle() sent %d emails, want %d", len(emails), len(want))
+	}
+
+	for i, email := range emails {
+		if !reflect.DeepEqual(email.To, want[i].To) || !reflect.DeepEqual(email.Cc, want[i].Cc) ||
+			!reflect.DeepEqual(email.Bcc, want[i].Bcc) || email.UnsubscribeURL != want[i].UnsubscribeURL {
+			t.Errorf("SendNotificationUC.Handle() email %d = %v %v %v %s, want %v", i, email.To, email.Cc, email.Bcc,
+				email.UnsubscribeURL, want[i])
+		}
+	}
+
+	if !got.Sent || got.MessageID != "message-1" || len(got.Recipients) != 4 {
+		t.Errorf("SendNotificationUC.Handle() = %v, %s with %d recipients", got.Sent, got.MessageID, len(got.Recipients))
+	}
+
+	for i, result := range got.Recipients {
+		if wantID := fmt.Sprintf("message-%d", i+1); !result.Sent || result.MessageID != wantID {
+			t.Errorf("SendNotificationUC.Handle() recipient %s = %v %s, want %s", result.Address, result.Sent,
+				result.MessageID, wantID)
+		}
+	}
+}
+
 // TestNewSendNotificationUC Test for this method
 func TestNewSendNotificationUC(t *testing.T) {
 	type args struct {
@@ -109,8 +806,16 @@ func TestNewSendNotificationUC(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewSendNotificationUC(tt.args.emailService); got.EmailService == nil {
-				t.Errorf("NewSendNotificationUC().EmailService is nil, want not nil")
+			if got := NewSendNotificationUC(
+				tt.args.emailService,
+				&mockUnsubscribeLinkService{},
+				&MockSuppressionRepository{},
+				&MockRateLimitRulesRepository{},
+				&mockAttachmentService{},
+				internal.SenderConfig{},
+			); got.EmailService == nil || got.UnsubscribeLinkService == nil || got.SuppressionRepository == nil ||
+				got.RateLimitRulesRepository == nil || got.AttachmentService == nil {
+				t.Errorf("NewSendNotificationUC() services are nil, want not nil")
 			}
 		})
 	}
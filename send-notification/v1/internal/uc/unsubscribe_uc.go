@@ -0,0 +1,59 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"net/http"
+
+	"modak/send-notification/v1/internal"
+)
+
+// UnsubscribeUC struct for this use case
+type UnsubscribeUC struct {
+	unsubscribeLinkService         UnsubscribeLinkServiceInterface
+	recipientPreferencesRepository RecipientPreferencesRepositoryInterface
+	recipientNormalizer            internal.RecipientNormalizer
+}
+
+// Handle main method with the logic to record the opt-out of a signed unsubscribe link
+func (uc *UnsubscribeUC) Handle(ctx context.Context, token string) error {
+	unsubscription, err := uc.unsubscribeLinkService.Verify(token)
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeUnsubscribeError,
+			ID:            internal.IDUnsubscribeTokenInvalid,
+			Message:       "Invalid unsubscribe link",
+			StatusCode:    http.StatusBadRequest,
+			OriginalError: err,
+		}
+	}
+
+	// The opt-out is saved with the same key that the validation of the notifications reads
+	email := uc.recipientNormalizer.Normalize(unsubscription.Email)
+
+	err = uc.recipientPreferencesRepository.OptOut(ctx, unsubscription.Tenant, email, unsubscription.Type)
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error saving in recipient preferences repository (OptOut)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	return nil
+}
+
+// NewUnsubscribeUC new instance of this use case
+func NewUnsubscribeUC(
+	unsubscribeLinkService UnsubscribeLinkServiceInterface,
+	recipientPreferencesRepository RecipientPreferencesRepositoryInterface,
+	recipientNormalizer internal.RecipientNormalizer,
+) *UnsubscribeUC {
+	return &UnsubscribeUC{
+		unsubscribeLinkService:         unsubscribeLinkService,
+		recipientPreferencesRepository: recipientPreferencesRepository,
+		recipientNormalizer:            recipientNormalizer,
+	}
+}
@@ -0,0 +1,81 @@
+// Package uc contains all the main logic related to use case layer
+package uc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"modak/send-notification/v1/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUnsubscribeUC_Handle test for this method
+func TestUnsubscribeUC_Handle(t *testing.T) {
+	tests := []struct {
+		name           string
+		verifyErr      error
+		optOutErr      error
+		wantOptOut     bool
+		wantStatusCode int
+	}{
+		{
+			name:       "success",
+			wantOptOut: true,
+		},
+		{
+			name:           "invalid token",
+			verifyErr:      errors.New("invalid token"),
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "error saving the opt-out",
+			optOutErr:      errors.New("database error"),
+			wantOptOut:     true,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optedOut := false
+
+			linkService := &mockUnsubscribeLinkService{
+				VerifyFunc: func(token string) (internal.Unsubscription, error) {
+					return internal.Unsubscription{
+						Tenant: "acme",
+						Email:  "Test.User+promo@Gmail.com",
+						Type:   "Marketing",
+					}, tt.verifyErr
+				},
+			}
+			preferencesRepo := &MockRecipientPreferencesRepository{
+				OptOutFunc: func(tenant, email, notificationType string) error {
+					// The opt-out is saved for the mailbox of the recipient in the tenant of the link
+					optedOut = tenant == "acme" && email == "testuser@gmail.com" && notificationType == "Marketing"
+
+					return tt.optOutErr
+				},
+			}
+
+			normalizer, err := internal.ParseRecipientNormalizer(internal.DefaultRecipientNormalization)
+			assert.NoError(t, err)
+
+			err = NewUnsubscribeUC(linkService, preferencesRepo, normalizer).Handle(context.Background(), "token")
+			assert.Equal(t, tt.wantOptOut, optedOut)
+
+			if tt.wantStatusCode == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var generalError *internal.GeneralError
+			if assert.ErrorAs(t, err, &generalError) {
+				assert.Equal(t, tt.wantStatusCode, generalError.StatusCode)
+			}
+		})
+	}
+}
@@ -2,132 +2,791 @@
 package uc
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
 	"modak/send-notification/v1/internal"
 
 	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
 )
 
+// recordSentConcurrency maximum number of notifications of a request recorded in the cache at the same time
+const recordSentConcurrency = 10
+
 // RateLimitRulesRepositoryInterface struct for this repository related to rules
 type RateLimitRulesRepositoryInterface interface {
-	GetByType(notificationType string) (*internal.RateLimitRule, error)
+	GetByType(ctx context.Context, notificationType string) (*internal.RateLimitRule, error)
+	GetByTypes(ctx context.Context, notificationTypes []string) (map[string]internal.RateLimitRule, error)
+	List(ctx context.Context) ([]internal.RateLimitRule, error)
+	Create(ctx context.Context, rule internal.RateLimitRule) error
+	Update(ctx context.Context, rule internal.RateLimitRule, previous internal.RateLimitRuleHistory) error
+	Delete(ctx context.Context, previous internal.RateLimitRuleHistory) error
+	History(ctx context.Context, notificationType string) ([]internal.RateLimitRuleHistory, error)
 }
 
 // RateLimitCacheRepositoryInterface struct for this repository related to cache
 type RateLimitCacheRepositoryInterface interface {
 	SetNotificationSentTimestamp(
+		ctx context.Context,
 		notificationType, email, timestamp, uuid string,
 		ttl int64,
 	) error
-	CountNotificationsWithinInterval(notificationType, email string, intervalInMinutes int) (int, error)
+	CountNotificationsWithinInterval(
+		ctx context.Context,
+		notificationType, email string,
+		windowStart time.Time,
+		limit int,
+	) (int, error)
+	CountNotificationsGrouped(ctx context.Context, queries []internal.NotificationCountQuery) (map[string]int, error)
+	DeleteNotifications(ctx context.Context, notificationType, email string) (int, error)
+	DeleteNotification(ctx context.Context, notificationType, email, timestamp, uuid string) error
+	GetRecordedContents(ctx context.Context, contentKeys []string, now int64) (map[string]bool, error)
+	RecordContent(ctx context.Context, contentKey string, now, ttl int64) (bool, error)
+	DeleteContent(ctx context.Context, contentKey string) error
+}
+
+// RecipientProfileRepositoryInterface struct for this repository related to recipients
+type RecipientProfileRepositoryInterface interface {
+	GetByEmail(ctx context.Context, email string) (*internal.RecipientProfile, error)
+}
+
+// RecipientPreferencesRepositoryInterface struct for this repository related to the recipients choices, the
+// choices of a recipient are kept per tenant
+type RecipientPreferencesRepositoryInterface interface {
+	GetByEmail(ctx context.Context, tenant, email string) (*internal.RecipientPreferences, error)
+	OptOut(ctx context.Context, tenant, email, notificationType string) error
+}
+
+// LoggerInterface logger of the problems that the use case works around without failing the request
+type LoggerInterface interface {
+	Errorf(message string, args ...interface{})
 }
 
 // ValidateRateLimitUC struct for this use case
 type ValidateRateLimitUC struct {
-	rateLimitRulesRepository RateLimitRulesRepositoryInterface
-	rateLimitCacheRepository RateLimitCacheRepositoryInterface
+	rateLimitRulesRepository       RateLimitRulesRepositoryInterface
+	rateLimitCacheRepository       RateLimitCacheRepositoryInterface
+	recipientProfileRepository     RecipientProfileRepositoryInterface
+	recipientPreferencesRepository RecipientPreferencesRepositoryInterface
+	defaultQuietHours              *internal.QuietHours
+	recipientNormalizer            internal.RecipientNormalizer
+	recipientCap                   internal.RecipientCap
+	logger                         LoggerInterface
+	now                            func() time.Time
 }
 
 // Handle main method with the logic to validate the rules of rate limit
-func (uc *ValidateRateLimitUC) Handle(notification internal.Notification) (bool, error) {
-	// Get the rules for the current notification
-	rule, err := uc.rateLimitRulesRepository.GetByType(notification.Type)
+func (uc *ValidateRateLimitUC) Handle(
+	ctx context.Context,
+	notification internal.Notification,
+) (internal.ValidationResult, error) {
+	results, err := uc.HandleBatch(ctx, []internal.Notification{notification})
+	if err != nil {
+		return internal.ValidationResult{}, err
+	}
+
+	return results[0], nil
+}
+
+// HandleBatch validate the rules of rate limit of every notification of a request, the results are in the order
+// of the notifications. The work is planned first so every rule, recipient and cache partition is read once,
+// the messages already sent are rejected without using any quota, and the notifications to the same recipient
+// share the quota left in their window by priority and then in the order of the request
+func (uc *ValidateRateLimitUC) HandleBatch(
+	ctx context.Context,
+	notifications []internal.Notification,
+) ([]internal.ValidationResult, error) {
+	plan, err := uc.newValidationPlan(ctx, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]internal.ValidationResult, len(notifications))
+
+	for i, notification := range notifications {
+		result, pending, err := uc.validate(ctx, plan, notification)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = result
+
+		if pending != nil {
+			pending.index = i
+			plan.pending = append(plan.pending, *pending)
+		}
+	}
+
+	if err := uc.rejectDuplicates(ctx, plan, results); err != nil {
+		return nil, err
+	}
+
+	if len(plan.pending) == 0 {
+		return results, nil
+	}
+
+	used, err := uc.countPartitions(ctx, uc.countQueries(plan))
 	if err != nil {
-		return false, &internal.GeneralError{
+		return nil, err
+	}
+
+	// The quota left is given to the highest priorities first, the pending notifications are in the order of the
+	// request so the ones with the same priority keep it
+	sort.SliceStable(plan.pending, func(i, j int) bool {
+		return internal.PriorityRank(plan.pending[i].priority) > internal.PriorityRank(plan.pending[j].priority)
+	})
+
+	var allowed []pendingNotification
+
+	for _, pending := range plan.pending {
+		capKey := uc.capQuery(plan, pending.query.Email).PartitionKey()
+
+		// The critical notifications are never limited, they only use the quota of the next ones
+		if pending.priority != internal.PriorityCritical {
+			if used[pending.query.PartitionKey()] >= pending.limit ||
+				uc.recipientCap.Enabled() && used[capKey] >= uc.recipientCap.LimitOf(pending.priority) {
+				results[pending.index] = internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}
+
+				continue
+			}
+		}
+
+		used[pending.query.PartitionKey()]++
+		used[capKey]++
+
+		results[pending.index] = internal.ValidationResult{Allowed: true}
+		allowed = append(allowed, pending)
+	}
+
+	if err := uc.recordSent(ctx, plan, allowed, results); err != nil {
+		uc.releaseRecorded(ctx, results)
+
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// releaseRecorded release what was recorded for the notifications of a request that failed, the request returns
+// no result so the caller can not release it. The release is not stopped by the cancellation that failed it
+func (uc *ValidateRateLimitUC) releaseRecorded(ctx context.Context, results []internal.ValidationResult) {
+	var reservations []internal.Reservation
+
+	for _, result := range results {
+		if result.Reservation != nil {
+			reservations = append(reservations, *result.Reservation)
+		}
+	}
+
+	if err := uc.Release(context.WithoutCancel(ctx), reservations); err != nil {
+		uc.logger.Errorf("error releasing the quota recorded before the error: %v", err)
+	}
+}
+
+// countPartitions notifications sent in the partitions of the queries, each partition is counted once for every
+// notification of the request that goes to it
+func (uc *ValidateRateLimitUC) countPartitions(
+	ctx context.Context,
+	queries []internal.NotificationCountQuery,
+) (map[string]int, error) {
+	used := make(map[string]int, len(queries))
+
+	// The requests with only critical notifications do not count anything
+	if len(queries) == 0 {
+		return used, nil
+	}
+
+	counts, err := uc.rateLimitCacheRepository.CountNotificationsGrouped(ctx, queries)
+	if err != nil {
+		return nil, &internal.GeneralError{
 			Code:          internal.CodeGeneralError,
 			ID:            internal.IDGeneralError,
-			Message:       "Error getting from rule repository (GetByType)",
+			Message:       "Error getting from cache repository (CountNotificationsGrouped)",
 			StatusCode:    http.StatusInternalServerError,
 			OriginalError: err,
 		}
 	}
 
-	// If the notification rule does not exist we return an alert error
-	if rule == nil {
-		return false, &internal.GeneralError{
-			Code:          internal.CodeNotificationError,
-			ID:            internal.IDNotificationTypeNotImplemented,
-			Message:       fmt.Sprintf("Notification type '%s' not implemented", notification.Type),
+	for partitionKey, count := range counts {
+		used[partitionKey] = count
+	}
+
+	return used, nil
+}
+
+// validationPlan state shared by the notifications of one request so the rules and the recipients are read once
+type validationPlan struct {
+	now         time.Time
+	tenant      string
+	rules       map[string]internal.RateLimitRule
+	preferences map[string]*internal.RecipientPreferences
+	profiles    map[string]*internal.RecipientProfile
+	pending     []pendingNotification
+}
+
+// pendingNotification notification that only needs the count of its partitions to be validated
+type pendingNotification struct {
+	index    int
+	priority string
+	// limit notifications of the type that the notification can use in the window, the borrow included
+	limit  int
+	query  internal.NotificationCountQuery
+	window internal.RateLimitWindow
+	// contentKey key of the message sent to the recipient, empty when the type is not deduplicated
+	contentKey       string
+	contentExpiresAt time.Time
+}
+
+// newValidationPlan read the rules of every type of the request in one batch, the rule of the tenant of the
+// request or else the global rule. Every type must have a rule
+func (uc *ValidateRateLimitUC) newValidationPlan(
+	ctx context.Context,
+	notifications []internal.Notification,
+) (*validationPlan, error) {
+	tenant := internal.RequestMetadataFromContext(ctx).Tenant
+
+	types := make([]string, 0, len(notifications))
+	keys := make([]string, 0, 2*len(notifications))
+
+	for _, notification := range notifications {
+		types = append(types, notification.Type)
+		keys = append(keys, notification.Type)
+
+		if tenant != "" {
+			keys = append(keys, internal.TenantType(tenant, notification.Type))
+		}
+	}
+
+	stored, err := uc.rateLimitRulesRepository.GetByTypes(ctx, keys)
+	if err != nil {
+		return nil, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from rule repository (GetByTypes)",
 			StatusCode:    http.StatusInternalServerError,
 			OriginalError: err,
 		}
 	}
 
+	rules := make(map[string]internal.RateLimitRule, len(types))
+
+	// If the notification rule does not exist we return an alert error
+	for _, notificationType := range types {
+		rule, ok := stored[internal.TenantType(tenant, notificationType)]
+		if !ok {
+			rule, ok = stored[notificationType]
+		}
+
+		if !ok {
+			return nil, &internal.GeneralError{
+				Code:       internal.CodeNotificationError,
+				ID:         internal.IDNotificationTypeNotImplemented,
+				Message:    fmt.Sprintf("Notification type '%s' not implemented", notificationType),
+				StatusCode: http.StatusInternalServerError,
+			}
+		}
+
+		rules[notificationType] = rule
+	}
+
+	return &validationPlan{
+		now:         uc.now(),
+		tenant:      tenant,
+		rules:       rules,
+		preferences: map[string]*internal.RecipientPreferences{},
+		profiles:    map[string]*internal.RecipientProfile{},
+	}, nil
+}
+
+// validate check every rule of a notification except the count, the notifications that pass every check
+// are returned as pending of the count of their partition
+func (uc *ValidateRateLimitUC) validate(
+	ctx context.Context,
+	plan *validationPlan,
+	notification internal.Notification,
+) (internal.ValidationResult, *pendingNotification, error) {
+	rule := plan.rules[notification.Type]
+	priority := rule.PriorityOf(notification.Priority)
+	critical := priority == internal.PriorityCritical
+
 	// If the notification rule about limit is zero we can't send any notification due to rate limit
-	if rule.NotificationsLimit <= 0 {
-		return false, nil
+	if rule.NotificationsLimit <= 0 && !critical {
+		return internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}, nil, nil
 	}
 
-	// We check if the notification can be sent
-	isAllowedToSendNotification, err := uc.CanSend(notification, *rule)
+	// The addresses of one mailbox share the quota and the choices of the recipient
+	email := uc.recipientNormalizer.Normalize(notification.Recipient)
+
+	// The recipient choices are checked before using any quota
+	preferences, err := uc.preferencesOf(ctx, plan, email)
 	if err != nil {
-		return false, err
+		return internal.ValidationResult{}, nil, err
 	}
 
-	// Check if is possible to send the notification
-	if isAllowedToSendNotification {
-		currentTimestamp := time.Now().Unix()
+	if preferences != nil {
+		if reason := preferences.RejectionReason(notification.Type); reason != "" {
+			return internal.ValidationResult{Reason: reason}, nil, nil
+		}
+	}
 
-		// Update the timestamp in the cache to know that this user already received a message
-		err := uc.rateLimitCacheRepository.SetNotificationSentTimestamp(
-			notification.Type,
-			notification.Recipient,
-			strconv.FormatInt(currentTimestamp, 10),
-			fmt.Sprintf("%s", uuid.New()),
-			currentTimestamp+int64(rule.IntervalInMinutes*int(time.Minute/time.Second)),
-		)
+	quietHours := rule.QuietHours
+	if quietHours == nil {
+		quietHours = uc.defaultQuietHours
+	}
+
+	// The critical notifications like the security alerts can not wait for the end of the quiet hours
+	if rule.QuietHoursExempt || critical {
+		quietHours = nil
+	}
+
+	// The recipient timezone is only needed for the quiet hours and the calendar windows
+	if quietHours != nil || rule.WindowAlignment == internal.WindowAlignmentCalendarDay ||
+		rule.WindowAlignment == internal.WindowAlignmentCalendarWeek {
+		profile, err := uc.profileOf(ctx, plan, notification.Recipient)
 		if err != nil {
-			return false, &internal.GeneralError{
-				Code:          internal.CodeGeneralError,
-				ID:            internal.IDGeneralError,
-				Message:       "Error saving in rule repository (SetNotificationSentTimestamp)",
+			return internal.ValidationResult{}, nil, err
+		}
+
+		// The recipient timezone has precedence over the timezone of the rule
+		if profile != nil && profile.Timezone != "" {
+			rule.Timezone = uc.profileTimezone(profile, rule.Timezone)
+		}
+	}
+
+	// Quiet hours are checked before the count so the notification does not use any quota
+	if quietHours != nil {
+		nextAllowedAt, err := uc.nextAllowedAt(*quietHours, rule, plan.now)
+		if err != nil {
+			return internal.ValidationResult{}, nil, &internal.GeneralError{
+				Code:          internal.CodeNotificationError,
+				ID:            internal.IDNotificationRuleInvalid,
+				Message:       fmt.Sprintf("Invalid quiet hours for notification type '%s'", notification.Type),
 				StatusCode:    http.StatusInternalServerError,
 				OriginalError: err,
 			}
 		}
 
-		// Notification sent successfully
-		return true, nil
+		if nextAllowedAt != nil {
+			return internal.ValidationResult{
+				Reason:        internal.RejectionReasonQuietHours,
+				NextAllowedAt: nextAllowedAt,
+			}, nil, nil
+		}
+	}
+
+	// Get the window where the notifications sent are counted against the limit and the window where the same
+	// message is not sent twice
+	var dedupWindow time.Duration
+
+	window, err := rule.Window(plan.now)
+	if err == nil {
+		dedupWindow, err = rule.DedupWindowDuration()
 	}
 
-	// Rate limit exceeded, no errors, no notifications sent
-	return false, nil
+	if err != nil {
+		return internal.ValidationResult{}, nil, &internal.GeneralError{
+			Code:          internal.CodeNotificationError,
+			ID:            internal.IDNotificationRuleInvalid,
+			Message:       fmt.Sprintf("Invalid rate limit rule for notification type '%s'", notification.Type),
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	borrow := 0
+	if rule.Priority != nil {
+		borrow = rule.Priority.Borrow
+	}
+
+	limit := rule.NotificationsLimit
+	if priority == internal.PriorityHigh {
+		limit += borrow
+	}
+
+	// The variants of an address of the same mailbox share its quota, the partition is counted up to the borrow
+	// so the high priority notifications know the quota left. Each tenant has its own partitions even when it
+	// uses the global rule, and each alignment of the windows too
+	tenantType := internal.TenantType(plan.tenant, notification.Type)
+	pending := &pendingNotification{
+		priority: priority,
+		limit:    limit,
+		query: internal.NotificationCountQuery{
+			Type:        rule.WindowType(tenantType),
+			Email:       email,
+			WindowStart: window.Start,
+			Limit:       rule.NotificationsLimit + borrow,
+		},
+		window: window,
+	}
+
+	if dedupWindow > 0 {
+		pending.contentKey = internal.ContentKey(tenantType, email, notification.Message)
+		pending.contentExpiresAt = plan.now.Add(dedupWindow)
+	}
+
+	return internal.ValidationResult{}, pending, nil
 }
 
-// CanSend check if the notification can be sent following the rules of rate limit
-func (uc *ValidateRateLimitUC) CanSend(notification internal.Notification, rule internal.RateLimitRule) (bool, error) {
-	// If we are within the interval, we need to check how many notifications were sent in this interval
-	count, err := uc.rateLimitCacheRepository.CountNotificationsWithinInterval(
-		notification.Type,
-		notification.Recipient,
-		rule.IntervalInMinutes,
+// rejectDuplicates reject the pending notifications whose message was already sent to the recipient inside the
+// dedup window of its type, or is repeated in the request, so the copies sent by mistake do not use any quota
+func (uc *ValidateRateLimitUC) rejectDuplicates(
+	ctx context.Context,
+	plan *validationPlan,
+	results []internal.ValidationResult,
+) error {
+	var contentKeys []string
+
+	for _, pending := range plan.pending {
+		if pending.contentKey != "" {
+			contentKeys = append(contentKeys, pending.contentKey)
+		}
+	}
+
+	if len(contentKeys) == 0 {
+		return nil
+	}
+
+	recorded, err := uc.rateLimitCacheRepository.GetRecordedContents(ctx, contentKeys, plan.now.Unix())
+	if err != nil {
+		return &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from cache repository (GetRecordedContents)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	seen := map[string]bool{}
+	pendingNotifications := plan.pending[:0]
+
+	for _, pending := range plan.pending {
+		if pending.contentKey != "" {
+			if recorded[pending.contentKey] || seen[pending.contentKey] {
+				results[pending.index] = internal.ValidationResult{Reason: internal.RejectionReasonDuplicate}
+
+				continue
+			}
+
+			seen[pending.contentKey] = true
+		}
+
+		pendingNotifications = append(pendingNotifications, pending)
+	}
+
+	plan.pending = pendingNotifications
+
+	return nil
+}
+
+// countQueries partitions that must be counted to validate the pending notifications, each partition once. The
+// critical notifications are not limited so they do not need any count
+func (uc *ValidateRateLimitUC) countQueries(plan *validationPlan) []internal.NotificationCountQuery {
+	var queries []internal.NotificationCountQuery
+
+	counted := map[string]bool{}
+
+	for _, pending := range plan.pending {
+		if pending.priority == internal.PriorityCritical {
+			continue
+		}
+
+		partitions := []internal.NotificationCountQuery{pending.query}
+		if uc.recipientCap.Enabled() {
+			partitions = append(partitions, uc.capQuery(plan, pending.query.Email))
+		}
+
+		for _, query := range partitions {
+			if !counted[query.PartitionKey()] {
+				counted[query.PartitionKey()] = true
+				queries = append(queries, query)
+			}
+		}
+	}
+
+	return queries
+}
+
+// capQuery query of the partition where every notification of the tenant to the recipient is counted for the cap
+func (uc *ValidateRateLimitUC) capQuery(plan *validationPlan, email string) internal.NotificationCountQuery {
+	return internal.NotificationCountQuery{
+		Type:        internal.TenantType(plan.tenant, internal.RecipientCapType),
+		Email:       email,
+		WindowStart: plan.now.Add(-uc.recipientCap.Window),
+		Limit:       uc.recipientCap.Limit,
+	}
+}
+
+// preferencesOf get the preferences of a recipient in the tenant of the request, read once per request
+func (uc *ValidateRateLimitUC) preferencesOf(
+	ctx context.Context,
+	plan *validationPlan,
+	email string,
+) (*internal.RecipientPreferences, error) {
+	if preferences, ok := plan.preferences[email]; ok {
+		return preferences, nil
+	}
+
+	preferences, err := uc.recipientPreferencesRepository.GetByEmail(ctx, plan.tenant, email)
+	if err != nil {
+		return nil, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from recipient preferences repository (GetByEmail)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	plan.preferences[email] = preferences
+
+	return preferences, nil
+}
+
+// profileOf get the profile of a recipient, read once per request
+func (uc *ValidateRateLimitUC) profileOf(
+	ctx context.Context,
+	plan *validationPlan,
+	email string,
+) (*internal.RecipientProfile, error) {
+	if profile, ok := plan.profiles[email]; ok {
+		return profile, nil
+	}
+
+	profile, err := uc.recipientProfileRepository.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, &internal.GeneralError{
+			Code:          internal.CodeGeneralError,
+			ID:            internal.IDGeneralError,
+			Message:       "Error getting from recipient profile repository (GetByEmail)",
+			StatusCode:    http.StatusInternalServerError,
+			OriginalError: err,
+		}
+	}
+
+	plan.profiles[email] = profile
+
+	return profile, nil
+}
+
+// profileTimezone timezone of the profile of a recipient, or the timezone of the rule when the one of the profile
+// is not valid, so one wrong profile does not block the notifications of the request
+func (uc *ValidateRateLimitUC) profileTimezone(profile *internal.RecipientProfile, ruleTimezone string) string {
+	if _, err := time.LoadLocation(profile.Timezone); err != nil {
+		uc.logger.Errorf("invalid timezone of the profile of %s, using the timezone of the rule: %v", profile.Email, err)
+
+		return ruleTimezone
+	}
+
+	return profile.Timezone
+}
+
+// recordSent save in the cache the notifications allowed so they count against the limit of the next ones, the
+// critical notifications are recorded too so they are visible and the cap of the recipient includes them. The
+// notifications whose message was recorded by another request since it was checked are rejected as duplicates.
+// The result of each notification recorded has its reservation, so it can be released when it is not sent
+func (uc *ValidateRateLimitUC) recordSent(
+	ctx context.Context,
+	plan *validationPlan,
+	allowed []pendingNotification,
+	results []internal.ValidationResult,
+) error {
+	now := plan.now
+
+	// The first error stops the writes that did not start yet
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	group.SetLimit(recordSentConcurrency)
+
+	for _, pending := range allowed {
+		pending := pending
+
+		group.Go(func() error {
+			// Each goroutine only writes the result of its own notification
+			reservation := &internal.Reservation{}
+			results[pending.index].Reservation = reservation
+
+			if pending.contentKey != "" {
+				recorded, err := uc.rateLimitCacheRepository.RecordContent(
+					groupCtx, pending.contentKey, now.Unix(), pending.contentExpiresAt.Unix(),
+				)
+				if err != nil {
+					return &internal.GeneralError{
+						Code:          internal.CodeGeneralError,
+						ID:            internal.IDGeneralError,
+						Message:       "Error saving in cache repository (RecordContent)",
+						StatusCode:    http.StatusInternalServerError,
+						OriginalError: err,
+					}
+				}
+
+				if !recorded {
+					results[pending.index] = internal.ValidationResult{Reason: internal.RejectionReasonDuplicate}
+
+					return nil
+				}
+
+				reservation.ContentKey = pending.contentKey
+			}
+
+			// Update the timestamp in the cache to know that this user already received a message
+			record, err := uc.setSentTimestamp(
+				groupCtx, now, pending.query.Type, pending.query.Email, pending.window.ExpiresAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			reservation.Records = append(reservation.Records, record)
+
+			if !uc.recipientCap.Enabled() {
+				return nil
+			}
+
+			capQuery := uc.capQuery(plan, pending.query.Email)
+
+			record, err = uc.setSentTimestamp(
+				groupCtx, now, capQuery.Type, capQuery.Email, now.Add(uc.recipientCap.Window),
+			)
+			if err != nil {
+				return err
+			}
+
+			reservation.Records = append(reservation.Records, record)
+
+			return nil
+		})
+	}
+
+	return group.Wait()
+}
+
+// setSentTimestamp save a notification sent in the partition of the type and the recipient until expiresAt
+func (uc *ValidateRateLimitUC) setSentTimestamp(
+	ctx context.Context,
+	now time.Time,
+	notificationType, email string,
+	expiresAt time.Time,
+) (internal.SentRecord, error) {
+	record := internal.SentRecord{
+		Type:      notificationType,
+		Email:     email,
+		Timestamp: strconv.FormatInt(now.Unix(), 10),
+		UUID:      uuid.New().String(),
+	}
+
+	err := uc.rateLimitCacheRepository.SetNotificationSentTimestamp(
+		ctx,
+		record.Type,
+		record.Email,
+		record.Timestamp,
+		record.UUID,
+		expiresAt.Unix(),
 	)
 	if err != nil {
-		return false, &internal.GeneralError{
+		return internal.SentRecord{}, &internal.GeneralError{
 			Code:          internal.CodeGeneralError,
 			ID:            internal.IDGeneralError,
-			Message:       "Error getting from cache repository (CountNotificationsWithinInterval)",
+			Message:       "Error saving in rule repository (SetNotificationSentTimestamp)",
 			StatusCode:    http.StatusInternalServerError,
 			OriginalError: err,
 		}
 	}
 
-	// If the count of notifications sent is less than the allowed limit, we can send another one
-	// Otherwise, we have exceeded the rate limit
-	return count < rule.NotificationsLimit, nil
+	return record, nil
+}
+
+// Release remove from the cache the quota and the messages recorded for notifications that were not sent, so the
+// recipients do not lose the quota and the notifications can be sent again. Every reservation is released even
+// when another one fails, the first error is returned
+func (uc *ValidateRateLimitUC) Release(ctx context.Context, reservations []internal.Reservation) error {
+	var group errgroup.Group
+
+	group.SetLimit(recordSentConcurrency)
+
+	for _, reservation := range reservations {
+		reservation := reservation
+
+		group.Go(func() error {
+			return uc.release(ctx, reservation)
+		})
+	}
+
+	return group.Wait()
+}
+
+// release remove the message and then the notifications of one reservation, the message first so a retry of the
+// notification is never rejected as a duplicate
+func (uc *ValidateRateLimitUC) release(ctx context.Context, reservation internal.Reservation) error {
+	if reservation.ContentKey != "" {
+		if err := uc.rateLimitCacheRepository.DeleteContent(ctx, reservation.ContentKey); err != nil {
+			return &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error deleting from cache repository (DeleteContent)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+	}
+
+	for _, record := range reservation.Records {
+		err := uc.rateLimitCacheRepository.DeleteNotification(
+			ctx, record.Type, record.Email, record.Timestamp, record.UUID,
+		)
+		if err != nil {
+			return &internal.GeneralError{
+				Code:          internal.CodeGeneralError,
+				ID:            internal.IDGeneralError,
+				Message:       "Error deleting from cache repository (DeleteNotification)",
+				StatusCode:    http.StatusInternalServerError,
+				OriginalError: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// nextAllowedAt check the quiet hours in the timezone of the recipient, nil when the notification can be sent now
+func (uc *ValidateRateLimitUC) nextAllowedAt(
+	quietHours internal.QuietHours,
+	rule internal.RateLimitRule,
+	now time.Time,
+) (*time.Time, error) {
+	location, err := rule.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	return quietHours.NextAllowedAt(now, location)
 }
 
 // NewValidateRateLimitUC new instance of this use case
 func NewValidateRateLimitUC(
 	rateLimitRulesRepository RateLimitRulesRepositoryInterface,
 	rateLimitCacheRepository RateLimitCacheRepositoryInterface,
+	recipientProfileRepository RecipientProfileRepositoryInterface,
+	recipientPreferencesRepository RecipientPreferencesRepositoryInterface,
+	defaultQuietHours *internal.QuietHours,
+	recipientNormalizer internal.RecipientNormalizer,
+	recipientCap internal.RecipientCap,
+	logger LoggerInterface,
 ) *ValidateRateLimitUC {
 	return &ValidateRateLimitUC{
-		rateLimitRulesRepository: rateLimitRulesRepository,
-		rateLimitCacheRepository: rateLimitCacheRepository,
+		rateLimitRulesRepository:       rateLimitRulesRepository,
+		rateLimitCacheRepository:       rateLimitCacheRepository,
+		recipientProfileRepository:     recipientProfileRepository,
+		recipientPreferencesRepository: recipientPreferencesRepository,
+		defaultQuietHours:              defaultQuietHours,
+		recipientNormalizer:            recipientNormalizer,
+		recipientCap:                   recipientCap,
+		logger:                         logger,
+		now:                            time.Now,
 	}
 }
@@ -2,8 +2,13 @@
 package uc
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"modak/send-notification/v1/internal"
 
@@ -12,22 +17,188 @@ import (
 
 // MockRateLimitRulesRepository mock for repository with rate limit rules
 type MockRateLimitRulesRepository struct {
-	GetByTypeFunc func(notificationType string) (*internal.RateLimitRule, error)
+	GetByTypeFunc  func(notificationType string) (*internal.RateLimitRule, error)
+	GetByTypesFunc func(notificationTypes []string) (map[string]internal.RateLimitRule, error)
+	ListFunc       func() ([]internal.RateLimitRule, error)
+	CreateFunc     func(rule internal.RateLimitRule) error
+	UpdateFunc     func(rule internal.RateLimitRule, previous internal.RateLimitRuleHistory) error
+	DeleteFunc     func(previous internal.RateLimitRuleHistory) error
+	HistoryFunc    func(notificationType string) ([]internal.RateLimitRuleHistory, error)
 }
 
 // GetByType mock for the method that get the rules about rate limit
-func (m *MockRateLimitRulesRepository) GetByType(notificationType string) (*internal.RateLimitRule, error) {
+func (m *MockRateLimitRulesRepository) GetByType(
+	_ context.Context,
+	notificationType string,
+) (*internal.RateLimitRule, error) {
 	return m.GetByTypeFunc(notificationType)
 }
 
+// GetByTypes mock for the method that get the rules of several types, without GetByTypesFunc it uses GetByTypeFunc
+func (m *MockRateLimitRulesRepository) GetByTypes(
+	_ context.Context,
+	notificationTypes []string,
+) (map[string]internal.RateLimitRule, error) {
+	if m.GetByTypesFunc != nil {
+		return m.GetByTypesFunc(notificationTypes)
+	}
+
+	rules := map[string]internal.RateLimitRule{}
+
+	for _, notificationType := range notificationTypes {
+		rule, err := m.GetByTypeFunc(notificationType)
+		if err != nil {
+			return nil, err
+		}
+
+		if rule != nil {
+			rules[notificationType] = *rule
+		}
+	}
+
+	return rules, nil
+}
+
+// List mock for the method that get every rule
+func (m *MockRateLimitRulesRepository) List(_ context.Context) ([]internal.RateLimitRule, error) {
+	return m.ListFunc()
+}
+
+// Create mock for the method that save a new rule
+func (m *MockRateLimitRulesRepository) Create(_ context.Context, rule internal.RateLimitRule) error {
+	return m.CreateFunc(rule)
+}
+
+// Update mock for the method that replace a rule keeping the previous version
+func (m *MockRateLimitRulesRepository) Update(
+	_ context.Context,
+	rule internal.RateLimitRule,
+	previous internal.RateLimitRuleHistory,
+) error {
+	return m.UpdateFunc(rule, previous)
+}
+
+// Delete mock for the method that remove a rule keeping the previous version
+func (m *MockRateLimitRulesRepository) Delete(_ context.Context, previous internal.RateLimitRuleHistory) error {
+	return m.DeleteFunc(previous)
+}
+
+// History mock for the method that get the previous versions of a rule
+func (m *MockRateLimitRulesRepository) History(
+	_ context.Context,
+	notificationType string,
+) ([]internal.RateLimitRuleHistory, error) {
+	return m.HistoryFunc(notificationType)
+}
+
 // MockRateLimitCacheRepository mock for repository with the cache of notifications
 type MockRateLimitCacheRepository struct {
 	SetNotificationSentTimestampFunc     func(notificationType, email, timestamp, uuid string, ttl int64) error
-	CountNotificationsWithinIntervalFunc func(notificationType, email string, intervalInMinutes int) (int, error)
+	CountNotificationsWithinIntervalFunc func(notificationType, email string, windowStart time.Time, limit int) (int, error)
+	CountNotificationsGroupedFunc        func(queries []internal.NotificationCountQuery) (map[string]int, error)
+	DeleteNotificationsFunc              func(notificationType, email string) (int, error)
+	GetRecordedContentsFunc              func(contentKeys []string, now int64) (map[string]bool, error)
+	RecordContentFunc                    func(contentKey string, now, ttl int64) (bool, error)
+	DeleteNotificationFunc               func(notificationType, email, timestamp, uuid string) error
+	DeleteContentFunc                    func(contentKey string) error
+}
+
+// decisions results without their reservations, whose records have random uuids
+func decisions(results []internal.ValidationResult) []internal.ValidationResult {
+	decided := make([]internal.ValidationResult, 0, len(results))
+
+	for _, result := range results {
+		result.Reservation = nil
+		decided = append(decided, result)
+	}
+
+	return decided
+}
+
+// CountNotificationsGrouped Mock for the method that count the notifications of several partitions,
+// without CountNotificationsGroupedFunc it uses CountNotificationsWithinIntervalFunc
+func (m *MockRateLimitCacheRepository) CountNotificationsGrouped(
+	_ context.Context,
+	queries []internal.NotificationCountQuery,
+) (map[string]int, error) {
+	if m.CountNotificationsGroupedFunc != nil {
+		return m.CountNotificationsGroupedFunc(queries)
+	}
+
+	counts := map[string]int{}
+
+	for _, query := range queries {
+		count, err := m.CountNotificationsWithinIntervalFunc(query.Type, query.Email, query.WindowStart, query.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[query.PartitionKey()] = count
+	}
+
+	return counts, nil
+}
+
+// DeleteNotifications Mock for the method that remove the notifications sent to a user
+func (m *MockRateLimitCacheRepository) DeleteNotifications(
+	_ context.Context, notificationType,
+	email string,
+) (int, error) {
+	return m.DeleteNotificationsFunc(notificationType, email)
+}
+
+// GetRecordedContents Mock for the method that check the messages already sent, without GetRecordedContentsFunc
+// no message was sent
+func (m *MockRateLimitCacheRepository) GetRecordedContents(
+	_ context.Context,
+	contentKeys []string,
+	now int64,
+) (map[string]bool, error) {
+	if m.GetRecordedContentsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetRecordedContentsFunc(contentKeys, now)
+}
+
+// RecordContent Mock for the method that save a message sent, without RecordContentFunc every message is recorded
+func (m *MockRateLimitCacheRepository) RecordContent(
+	_ context.Context,
+	contentKey string,
+	now, ttl int64,
+) (bool, error) {
+	if m.RecordContentFunc == nil {
+		return true, nil
+	}
+
+	return m.RecordContentFunc(contentKey, now, ttl)
+}
+
+// DeleteNotification Mock for the method that remove one notification sent, without DeleteNotificationFunc it is
+// removed
+func (m *MockRateLimitCacheRepository) DeleteNotification(
+	_ context.Context,
+	notificationType, email, timestamp, uuid string,
+) error {
+	if m.DeleteNotificationFunc == nil {
+		return nil
+	}
+
+	return m.DeleteNotificationFunc(notificationType, email, timestamp, uuid)
+}
+
+// DeleteContent Mock for the method that remove a message recorded, without DeleteContentFunc it is removed
+func (m *MockRateLimitCacheRepository) DeleteContent(_ context.Context, contentKey string) error {
+	if m.DeleteContentFunc == nil {
+		return nil
+	}
+
+	return m.DeleteContentFunc(contentKey)
 }
 
 // SetNotificationSentTimestamp Mock for the method that save into the cache
 func (m *MockRateLimitCacheRepository) SetNotificationSentTimestamp(
+	_ context.Context,
 	notificationType,
 	email,
 	timestamp,
@@ -39,11 +210,50 @@ func (m *MockRateLimitCacheRepository) SetNotificationSentTimestamp(
 
 // CountNotificationsWithinInterval Mock for the method that count the number of notifications sent to a user
 func (m *MockRateLimitCacheRepository) CountNotificationsWithinInterval(
+	_ context.Context,
 	notificationType,
 	email string,
-	intervalInMinutes int,
+	windowStart time.Time,
+	limit int,
 ) (int, error) {
-	return m.CountNotificationsWithinIntervalFunc(notificationType, email, intervalInMinutes)
+	return m.CountNotificationsWithinIntervalFunc(notificationType, email, windowStart, limit)
+}
+
+// MockRecipientProfileRepository mock for repository with the recipients profile
+type MockRecipientProfileRepository struct {
+	GetByEmailFunc func(email string) (*internal.RecipientProfile, error)
+}
+
+// GetByEmail mock for the method that get the profile of a recipient
+func (m *MockRecipientProfileRepository) GetByEmail(
+	_ context.Context,
+	email string,
+) (*internal.RecipientProfile, error) {
+	return m.GetByEmailFunc(email)
+}
+
+// MockRecipientPreferencesRepository mock for repository with the recipients choices
+type MockRecipientPreferencesRepository struct {
+	GetByEmailFunc func(tenant, email string) (*internal.RecipientPreferences, error)
+	OptOutFunc     func(tenant, email, notificationType string) error
+}
+
+// GetByEmail mock for the method that get the preferences of a recipient
+func (m *MockRecipientPreferencesRepository) GetByEmail(
+	_ context.Context,
+	tenant string,
+	email string,
+) (*internal.RecipientPreferences, error) {
+	if m.GetByEmailFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetByEmailFunc(tenant, email)
+}
+
+// OptOut mock for the method that records the opt-out of a recipient
+func (m *MockRecipientPreferencesRepository) OptOut(_ context.Context, tenant, email, notificationType string) error {
+	return m.OptOutFunc(tenant, email, notificationType)
 }
 
 // TestValidateRateLimitUC_Handle Test for this method
@@ -80,7 +290,8 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 					CountNotificationsWithinIntervalFunc: func(
 						notificationType,
 						email string,
-						intervalInMinutes int,
+						windowStart time.Time,
+						limit int,
 					) (int, error) {
 						return 3, nil
 					},
@@ -162,7 +373,8 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 					CountNotificationsWithinIntervalFunc: func(
 						notificationType,
 						email string,
-						intervalInMinutes int,
+						windowStart time.Time,
+						limit int,
 					) (int, error) {
 						return 5, nil
 					},
@@ -188,7 +400,8 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 					CountNotificationsWithinIntervalFunc: func(
 						notificationType,
 						email string,
-						intervalInMinutes int,
+						windowStart time.Time,
+						limit int,
 					) (int, error) {
 						return 0, errors.New("cache retrieval error")
 					},
@@ -214,7 +427,8 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 					CountNotificationsWithinIntervalFunc: func(
 						notificationType,
 						email string,
-						intervalInMinutes int,
+						windowStart time.Time,
+						limit int,
 					) (int, error) {
 						return 3, nil
 					},
@@ -239,8 +453,17 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 			rulesRepo := tt.rulesRepoFunc()
 			cacheRepo := tt.cacheRepoFunc()
 
-			ucInstance := NewValidateRateLimitUC(rulesRepo, cacheRepo)
-			result, err := ucInstance.Handle(notification)
+			ucInstance := NewValidateRateLimitUC(
+				rulesRepo,
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+			result, err := ucInstance.Handle(context.Background(), notification)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -248,7 +471,1321 @@ func TestValidateRateLimitUC_Handle(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			assert.Equal(t, tt.want, result)
+			assert.Equal(t, tt.want, result.Allowed)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_Handle_Window test that the window of the rule defines the count range and the TTL
+func TestValidateRateLimitUC_Handle_Window(t *testing.T) {
+	now := time.Date(2023, 10, 15, 13, 45, 0, 0, time.UTC)
+
+	notification := internal.Notification{
+		Type:      "News",
+		Recipient: "test@example.com",
+		Message:   "Hello",
+	}
+
+	tests := []struct {
+		name            string
+		rule            *internal.RateLimitRule
+		wantType        string
+		wantWindowStart time.Time
+		wantTTL         int64
+		wantErr         bool
+	}{
+		{
+			name:            "sub-minute rolling window",
+			rule:            &internal.RateLimitRule{NotificationsLimit: 5, Interval: "10s"},
+			wantType:        "News",
+			wantWindowStart: now.Add(-10 * time.Second),
+			wantTTL:         now.Add(10 * time.Second).Unix(),
+		},
+		{
+			name: "calendar day window",
+			rule: &internal.RateLimitRule{
+				NotificationsLimit: 1,
+				WindowAlignment:    internal.WindowAlignmentCalendarDay,
+			},
+			wantType:        "News#calendar_day",
+			wantWindowStart: time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC),
+			wantTTL:         time.Date(2023, 10, 16, 0, 0, 0, 0, time.UTC).Unix(),
+		},
+		{
+			name:    "invalid interval",
+			rule:    &internal.RateLimitRule{NotificationsLimit: 1, Interval: "soon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotType string
+
+			var gotWindowStart time.Time
+
+			var gotTTL int64
+
+			rulesRepo := &MockRateLimitRulesRepository{
+				GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+					return tt.rule, nil
+				},
+			}
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsWithinIntervalFunc: func(
+					notificationType,
+					email string,
+					windowStart time.Time,
+					limit int,
+				) (int, error) {
+					gotType = notificationType
+					gotWindowStart = windowStart
+
+					return 0, nil
+				},
+				SetNotificationSentTimestampFunc: func(
+					notificationType,
+					email,
+					timestamp,
+					uuid string,
+					ttl int64,
+				) error {
+					gotTTL = ttl
+
+					return nil
+				},
+			}
+
+			profileRepo := &MockRecipientProfileRepository{
+				GetByEmailFunc: func(email string) (*internal.RecipientProfile, error) {
+					return nil, nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				rulesRepo,
+				cacheRepo,
+				profileRepo,
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			result, err := ucInstance.Handle(context.Background(), notification)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, result.Allowed)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.True(t, tt.wantWindowStart.Equal(gotWindowStart))
+			assert.Equal(t, tt.wantTTL, gotTTL)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_Handle_QuietHours test the quiet hours in the recipient timezone
+func TestValidateRateLimitUC_Handle_QuietHours(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	// 03:00 UTC is 22:00 of the previous day in Bogota
+	now := time.Date(2023, 10, 15, 3, 0, 0, 0, time.UTC)
+	nightQuietHours := &internal.QuietHours{Start: "21:00", End: "08:00"}
+	bogotaProfile := &internal.RecipientProfile{Email: "test@example.com", Timezone: "America/Bogota"}
+
+	tests := []struct {
+		name              string
+		rule              *internal.RateLimitRule
+		defaultQuietHours *internal.QuietHours
+		profile           *internal.RecipientProfile
+		profileErr        error
+		want              internal.ValidationResult
+		wantCount         bool
+		wantLogged        bool
+		wantErr           bool
+	}{
+		{
+			name:              "default quiet hours in the recipient timezone",
+			rule:              &internal.RateLimitRule{NotificationsLimit: 3, IntervalInMinutes: 60},
+			defaultQuietHours: nightQuietHours,
+			profile:           bogotaProfile,
+			want: internal.ValidationResult{
+				Reason:        internal.RejectionReasonQuietHours,
+				NextAllowedAt: timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, bogota)),
+			},
+		},
+		{
+			name: "quiet hours of the rule override the default",
+			rule: &internal.RateLimitRule{
+				NotificationsLimit: 3,
+				IntervalInMinutes:  60,
+				QuietHours:         &internal.QuietHours{Start: "23:00", End: "06:00"},
+			},
+			defaultQuietHours: nightQuietHours,
+			profile:           bogotaProfile,
+			want:              internal.ValidationResult{Allowed: true},
+			wantCount:         true,
+		},
+		{
+			name: "exempt type is sent inside quiet hours",
+			rule: &internal.RateLimitRule{
+				NotificationsLimit: 3,
+				IntervalInMinutes:  60,
+				QuietHoursExempt:   true,
+			},
+			defaultQuietHours: nightQuietHours,
+			profile:           bogotaProfile,
+			want:              internal.ValidationResult{Allowed: true},
+			wantCount:         true,
+		},
+		{
+			name:              "recipient without profile uses UTC",
+			rule:              &internal.RateLimitRule{NotificationsLimit: 3, IntervalInMinutes: 60},
+			defaultQuietHours: nightQuietHours,
+			profile:           nil,
+			want: internal.ValidationResult{
+				Reason:        internal.RejectionReasonQuietHours,
+				NextAllowedAt: timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			name: "invalid timezone of the profile uses the timezone of the rule",
+			rule: &internal.RateLimitRule{
+				NotificationsLimit: 3,
+				IntervalInMinutes:  60,
+				Timezone:           "America/Bogota",
+			},
+			defaultQuietHours: nightQuietHours,
+			profile:           &internal.RecipientProfile{Email: "test@example.com", Timezone: "Mars/Olympus"},
+			want: internal.ValidationResult{
+				Reason:        internal.RejectionReasonQuietHours,
+				NextAllowedAt: timePointer(time.Date(2023, 10, 15, 8, 0, 0, 0, bogota)),
+			},
+			wantLogged: true,
+		},
+		{
+			name:              "error getting the profile",
+			rule:              &internal.RateLimitRule{NotificationsLimit: 3, IntervalInMinutes: 60},
+			defaultQuietHours: nightQuietHours,
+			profileErr:        errors.New("database error"),
+			wantErr:           true,
+		},
+		{
+			name: "invalid quiet hours",
+			rule: &internal.RateLimitRule{
+				NotificationsLimit: 3,
+				IntervalInMinutes:  60,
+				QuietHours:         &internal.QuietHours{Start: "late", End: "08:00"},
+			},
+			profile: bogotaProfile,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted := false
+
+			rulesRepo := &MockRateLimitRulesRepository{
+				GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+					return tt.rule, nil
+				},
+			}
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsWithinIntervalFunc: func(
+					notificationType,
+					email string,
+					windowStart time.Time,
+					limit int,
+				) (int, error) {
+					counted = true
+
+					return 0, nil
+				},
+				SetNotificationSentTimestampFunc: func(
+					notificationType,
+					email,
+					timestamp,
+					uuid string,
+					ttl int64,
+				) error {
+					return nil
+				},
+			}
+			profileRepo := &MockRecipientProfileRepository{
+				GetByEmailFunc: func(email string) (*internal.RecipientProfile, error) {
+					return tt.profile, tt.profileErr
+				},
+			}
+
+			logger := &mockLogger{}
+			ucInstance := NewValidateRateLimitUC(
+				rulesRepo,
+				cacheRepo,
+				profileRepo,
+				&MockRecipientPreferencesRepository{},
+				tt.defaultQuietHours,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				logger,
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			result, err := ucInstance.Handle(context.Background(), internal.Notification{
+				Type:      "Marketing",
+				Recipient: "test@example.com",
+				Message:   "Hello",
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.Allowed, result.Allowed)
+			assert.Equal(t, tt.want.Reason, result.Reason)
+			assert.Equal(t, tt.wantCount, counted)
+			assert.Equal(t, tt.wantLogged, len(logger.errors) > 0)
+
+			if tt.want.NextAllowedAt == nil {
+				assert.Nil(t, result.NextAllowedAt)
+			} else if assert.NotNil(t, result.NextAllowedAt) {
+				assert.True(t, tt.want.NextAllowedAt.Equal(*result.NextAllowedAt))
+			}
+		})
+	}
+}
+
+// mockLogger Mock for the logger, it keeps the errors logged
+type mockLogger struct {
+	errors []string
+}
+
+// Errorf Mock for method to log an error
+func (m *mockLogger) Errorf(format string, args ...interface{}) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
+
+// TestValidateRateLimitUC_Handle_Preferences test that the recipient choices are checked before using quota
+func TestValidateRateLimitUC_Handle_Preferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		preferences    *internal.RecipientPreferences
+		preferencesErr error
+		want           internal.ValidationResult
+		wantCount      bool
+		wantErr        bool
+	}{
+		{
+			name:      "without preferences",
+			want:      internal.ValidationResult{Allowed: true},
+			wantCount: true,
+		},
+		{
+			name:        "opted out of other type",
+			preferences: &internal.RecipientPreferences{OptedOutTypes: []string{"News"}},
+			want:        internal.ValidationResult{Allowed: true},
+			wantCount:   true,
+		},
+		{
+			name:        "opted out of the type",
+			preferences: &internal.RecipientPreferences{OptedOutTypes: []string{"Marketing"}},
+			want:        internal.ValidationResult{Reason: internal.RejectionReasonOptedOut},
+		},
+		{
+			name:        "opted out of the email channel",
+			preferences: &internal.RecipientPreferences{Channels: []string{"sms"}},
+			want:        internal.ValidationResult{Reason: internal.RejectionReasonOptedOut},
+		},
+		{
+			name:        "global unsubscribe",
+			preferences: &internal.RecipientPreferences{Unsubscribed: true},
+			want:        internal.ValidationResult{Reason: internal.RejectionReasonUnsubscribed},
+		},
+		{
+			name:           "error getting the preferences",
+			preferencesErr: errors.New("database error"),
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted := false
+
+			rulesRepo := &MockRateLimitRulesRepository{
+				GetByTypeFunc: func(notificationType string) (*internal.RateLimitRule, error) {
+					return &internal.RateLimitRule{NotificationsLimit: 3, IntervalInMinutes: 60}, nil
+				},
+			}
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsWithinIntervalFunc: func(
+					notificationType,
+					email string,
+					windowStart time.Time,
+					limit int,
+				) (int, error) {
+					counted = true
+
+					return 0, nil
+				},
+				SetNotificationSentTimestampFunc: func(
+					notificationType,
+					email,
+					timestamp,
+					uuid string,
+					ttl int64,
+				) error {
+					return nil
+				},
+			}
+			preferencesRepo := &MockRecipientPreferencesRepository{
+				GetByEmailFunc: func(tenant, email string) (*internal.RecipientPreferences, error) {
+					// The choices are read for the mailbox of the recipient in the tenant of the request
+					if tenant != "acme" || email != "testuser@gmail.com" {
+						return nil, fmt.Errorf("unexpected recipient %s of tenant %s", email, tenant)
+					}
+
+					return tt.preferences, tt.preferencesErr
+				},
+			}
+
+			normalizer, err := internal.ParseRecipientNormalizer(internal.DefaultRecipientNormalization)
+			assert.NoError(t, err)
+
+			ucInstance := NewValidateRateLimitUC(
+				rulesRepo,
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				preferencesRepo,
+				nil,
+				normalizer,
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+
+			ctx := internal.WithRequestMetadata(context.Background(), internal.RequestMetadata{Tenant: "acme"})
+			result, err := ucInstance.Handle(ctx, internal.Notification{
+				Type:      "Marketing",
+				Recipient: "Test.User+promo@Gmail.com",
+				Message:   "Hello",
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decisions([]internal.ValidationResult{result})[0])
+			assert.Equal(t, tt.wantCount, counted)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch Test for this method
+func TestValidateRateLimitUC_HandleBatch(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"News":   {Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60},
+		"Status": {Type: "Status", NotificationsLimit: 1, IntervalInMinutes: 60},
+	}
+
+	notification := func(notificationType, recipient string) internal.Notification {
+		return internal.Notification{Type: notificationType, Recipient: recipient, Message: "Hello"}
+	}
+
+	allowed := internal.ValidationResult{Allowed: true}
+	rateLimited := internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}
+
+	tests := []struct {
+		name           string
+		notifications  []internal.Notification
+		counts         map[string]int
+		want           []internal.ValidationResult
+		wantPartitions int
+		wantRecorded   int
+	}{
+		{
+			name: "notifications to the same recipient share the quota left in order",
+			notifications: []internal.Notification{
+				notification("News", "a@example.com"),
+				notification("News", "a@example.com"),
+				notification("News", "a@example.com"),
+			},
+			counts:         map[string]int{"News#a@example.com": 1},
+			want:           []internal.ValidationResult{allowed, rateLimited, rateLimited},
+			wantPartitions: 1,
+			wantRecorded:   1,
+		},
+		{
+			name: "every partition has its own quota",
+			notifications: []internal.Notification{
+				notification("News", "a@example.com"),
+				notification("Status", "a@example.com"),
+				notification("News", "b@example.com"),
+				notification("Status", "a@example.com"),
+				notification("News", "a@example.com"),
+				notification("News", "b@example.com"),
+				notification("News", "b@example.com"),
+			},
+			counts: map[string]int{},
+			want: []internal.ValidationResult{
+				allowed, allowed, allowed, rateLimited, allowed, allowed, rateLimited,
+			},
+			wantPartitions: 3,
+			wantRecorded:   5,
+		},
+		{
+			name: "partition already at the limit",
+			notifications: []internal.Notification{
+				notification("Status", "a@example.com"),
+				notification("Status", "a@example.com"),
+			},
+			counts:         map[string]int{"Status#a@example.com": 1},
+			want:           []internal.ValidationResult{rateLimited, rateLimited},
+			wantPartitions: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			ruleReads := 0
+			preferenceReads := 0
+			recorded := 0
+
+			rulesRepo := &MockRateLimitRulesRepository{
+				GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+					ruleReads++
+
+					return rules, nil
+				},
+			}
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+					assert.Len(t, queries, tt.wantPartitions)
+
+					return tt.counts, nil
+				},
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					recorded++
+
+					return nil
+				},
+			}
+
+			preferencesRepo := &MockRecipientPreferencesRepository{
+				GetByEmailFunc: func(tenant, email string) (*internal.RecipientPreferences, error) {
+					preferenceReads++
+
+					return nil, nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				rulesRepo,
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				preferencesRepo,
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+
+			got, err := ucInstance.HandleBatch(context.Background(), tt.notifications)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decisions(got))
+			assert.Equal(t, 1, ruleReads)
+			assert.LessOrEqual(t, preferenceReads, 2)
+			assert.Equal(t, tt.wantRecorded, recorded)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch_NormalizedRecipients test that the variants of an address share one quota
+func TestValidateRateLimitUC_HandleBatch_NormalizedRecipients(t *testing.T) {
+	normalizer, err := internal.ParseRecipientNormalizer(internal.DefaultRecipientNormalization)
+	assert.NoError(t, err)
+
+	allowed := internal.ValidationResult{Allowed: true}
+	rateLimited := internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}
+
+	tests := []struct {
+		name         string
+		recipients   []string
+		want         []internal.ValidationResult
+		wantRecorded map[string]int
+	}{
+		{
+			name:         "case of the address",
+			recipients:   []string{"User@Example.com", "user@example.com", "USER@EXAMPLE.COM"},
+			want:         []internal.ValidationResult{allowed, allowed, rateLimited},
+			wantRecorded: map[string]int{"News#user@example.com": 2},
+		},
+		{
+			name:         "sub addresses and dots of a provider that ignores them",
+			recipients:   []string{"User.Name+news@GMAIL.com", "username@gmail.com", "u.s.e.r.n.a.m.e+x@gmail.com"},
+			want:         []internal.ValidationResult{allowed, allowed, rateLimited},
+			wantRecorded: map[string]int{"News#username@gmail.com": 2},
+		},
+		{
+			name:         "sub addresses of a provider that does not ignore them",
+			recipients:   []string{"user+a@example.com", "user+b@example.com", "user+a@example.com", "user+a@example.com"},
+			want:         []internal.ValidationResult{allowed, allowed, allowed, rateLimited},
+			wantRecorded: map[string]int{"News#user+a@example.com": 2, "News#user+b@example.com": 1},
+		},
+		{
+			name:         "unicode and punycode domains",
+			recipients:   []string{"user@bücher.example", "user@xn--bcher-kva.example", "user@BÜCHER.example"},
+			want:         []internal.ValidationResult{allowed, allowed, rateLimited},
+			wantRecorded: map[string]int{"News#user@xn--bcher-kva.example": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			// The cache of the notifications sent, every notification is validated in its own request
+			recorded := map[string]int{}
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsWithinIntervalFunc: func(
+					notificationType, email string, windowStart time.Time, limit int,
+				) (int, error) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					return recorded[notificationType+"#"+email], nil
+				},
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					recorded[notificationType+"#"+email]++
+
+					return nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{
+					GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+						return map[string]internal.RateLimitRule{
+							"News": {Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60},
+						}, nil
+					},
+				},
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{
+					GetByEmailFunc: func(tenant, email string) (*internal.RecipientPreferences, error) {
+						return nil, nil
+					},
+				},
+				nil,
+				normalizer,
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+
+			got := make([]internal.ValidationResult, 0, len(tt.recipients))
+
+			for _, recipient := range tt.recipients {
+				result, err := ucInstance.Handle(context.Background(), internal.Notification{
+					Type:      "News",
+					Recipient: recipient,
+					Message:   "Hello",
+				})
+				assert.NoError(t, err)
+
+				got = append(got, result)
+			}
+
+			assert.Equal(t, tt.want, decisions(got))
+			assert.Equal(t, tt.wantRecorded, recorded)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch_Priority test that the priorities bypass, borrow and give up the quota
+func TestValidateRateLimitUC_HandleBatch_Priority(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"Security": {
+			Type:               "Security",
+			NotificationsLimit: 1,
+			IntervalInMinutes:  60,
+			Priority:           &internal.PriorityPolicy{Default: internal.PriorityCritical},
+		},
+		"Marketing": {
+			Type:               "Marketing",
+			NotificationsLimit: 5,
+			IntervalInMinutes:  60,
+			Priority:           &internal.PriorityPolicy{Default: internal.PriorityLow},
+		},
+		"Account": {
+			Type:               "Account",
+			NotificationsLimit: 1,
+			IntervalInMinutes:  60,
+			Priority:           &internal.PriorityPolicy{Max: internal.PriorityHigh, Borrow: 1},
+		},
+		"News": {Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60},
+	}
+
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+	endOfQuietHours := time.Date(2023, 10, 2, 13, 0, 0, 0, time.UTC)
+
+	notification := func(notificationType, priority string) internal.Notification {
+		return internal.Notification{
+			Type:      notificationType,
+			Recipient: "a@example.com",
+			Message:   "Hello",
+			Priority:  priority,
+		}
+	}
+
+	allowed := internal.ValidationResult{Allowed: true}
+	rateLimited := internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}
+	recipientCap := internal.RecipientCap{Limit: 5, Window: 24 * time.Hour, LowPriorityPercent: 80}
+
+	tests := []struct {
+		name           string
+		notifications  []internal.Notification
+		recipientCap   internal.RecipientCap
+		quietHours     *internal.QuietHours
+		counts         map[string]int
+		want           []internal.ValidationResult
+		wantPartitions int
+		wantRecorded   map[string]int
+	}{
+		{
+			name:           "critical skips the limit of the type and the cap but is recorded",
+			notifications:  []internal.Notification{notification("Security", ""), notification("Security", "")},
+			recipientCap:   recipientCap,
+			counts:         map[string]int{"Security#a@example.com": 1, "*#a@example.com": 5},
+			want:           []internal.ValidationResult{allowed, allowed},
+			wantPartitions: 0,
+			wantRecorded:   map[string]int{"Security#a@example.com": 2, "*#a@example.com": 2},
+		},
+		{
+			name:          "critical skips the quiet hours",
+			notifications: []internal.Notification{notification("Security", ""), notification("News", "")},
+			quietHours:    &internal.QuietHours{Start: "11:00", End: "13:00"},
+			counts:        map[string]int{},
+			want: []internal.ValidationResult{
+				allowed,
+				{Reason: internal.RejectionReasonQuietHours, NextAllowedAt: &endOfQuietHours},
+			},
+			wantPartitions: 0,
+			wantRecorded:   map[string]int{"Security#a@example.com": 1},
+		},
+		{
+			name: "low is rejected first when the cap is close to full",
+			notifications: []internal.Notification{
+				notification("Marketing", ""),
+				notification("News", ""),
+				notification("Marketing", ""),
+			},
+			recipientCap:   recipientCap,
+			counts:         map[string]int{"*#a@example.com": 3},
+			want:           []internal.ValidationResult{rateLimited, allowed, rateLimited},
+			wantPartitions: 3,
+			wantRecorded:   map[string]int{"News#a@example.com": 1, "*#a@example.com": 1},
+		},
+		{
+			name: "critical uses the cap before the others",
+			notifications: []internal.Notification{
+				notification("News", ""),
+				notification("Security", ""),
+			},
+			recipientCap:   recipientCap,
+			counts:         map[string]int{"*#a@example.com": 4},
+			want:           []internal.ValidationResult{rateLimited, allowed},
+			wantPartitions: 2,
+			wantRecorded:   map[string]int{"Security#a@example.com": 1, "*#a@example.com": 1},
+		},
+		{
+			name: "high borrows over the limit of the type before normal",
+			notifications: []internal.Notification{
+				notification("Account", ""),
+				notification("Account", internal.PriorityHigh),
+				notification("Account", internal.PriorityHigh),
+			},
+			counts:         map[string]int{"Account#a@example.com": 1},
+			want:           []internal.ValidationResult{rateLimited, allowed, rateLimited},
+			wantPartitions: 1,
+			wantRecorded:   map[string]int{"Account#a@example.com": 1},
+		},
+		{
+			name:           "priorities over the max of the type are lowered",
+			notifications:  []internal.Notification{notification("News", internal.PriorityCritical)},
+			counts:         map[string]int{"News#a@example.com": 2},
+			want:           []internal.ValidationResult{rateLimited},
+			wantPartitions: 1,
+			wantRecorded:   map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			recorded := map[string]int{}
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+					assert.Len(t, queries, tt.wantPartitions)
+
+					return tt.counts, nil
+				},
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					recorded[notificationType+"#"+email]++
+
+					return nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{
+					GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+						return rules, nil
+					},
+				},
+				cacheRepo,
+				&MockRecipientProfileRepository{
+					GetByEmailFunc: func(email string) (*internal.RecipientProfile, error) {
+						return nil, nil
+					},
+				},
+				&MockRecipientPreferencesRepository{},
+				tt.quietHours,
+				internal.RecipientNormalizer{},
+				tt.recipientCap,
+				&mockLogger{},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			got, err := ucInstance.HandleBatch(context.Background(), tt.notifications)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decisions(got))
+			assert.Equal(t, tt.wantRecorded, recorded)
 		})
 	}
 }
+
+// TestValidateRateLimitUC_HandleBatch_Duplicates test that the same message is not sent twice inside the dedup window
+func TestValidateRateLimitUC_HandleBatch_Duplicates(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"Status": {Type: "Status", NotificationsLimit: 5, IntervalInMinutes: 60, DedupWindow: "10m"},
+		"News":   {Type: "News", NotificationsLimit: 5, IntervalInMinutes: 60},
+	}
+
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	notification := func(notificationType, recipient, message string) internal.Notification {
+		return internal.Notification{Type: notificationType, Recipient: recipient, Message: message}
+	}
+
+	statusKey := internal.ContentKey("Status", "a@example.com", "Order shipped")
+
+	allowed := internal.ValidationResult{Allowed: true}
+	duplicate := internal.ValidationResult{Reason: internal.RejectionReasonDuplicate}
+
+	tests := []struct {
+		name          string
+		notifications []internal.Notification
+		recorded      map[string]bool
+		concurrent    map[string]bool
+		want          []internal.ValidationResult
+		wantChecked   int
+		wantRecorded  map[string]int
+	}{
+		{
+			name: "message already sent inside the window",
+			notifications: []internal.Notification{
+				notification("Status", "a@example.com", "Order shipped"),
+				notification("Status", "a@example.com", "Order delivered"),
+			},
+			recorded:     map[string]bool{statusKey: true},
+			want:         []internal.ValidationResult{duplicate, allowed},
+			wantChecked:  2,
+			wantRecorded: map[string]int{"Status#a@example.com": 1},
+		},
+		{
+			name: "message repeated in the request",
+			notifications: []internal.Notification{
+				notification("Status", "a@example.com", "Order shipped"),
+				notification("Status", "a@example.com", "Order shipped"),
+				notification("Status", "b@example.com", "Order shipped"),
+			},
+			want:         []internal.ValidationResult{allowed, duplicate, allowed},
+			wantChecked:  3,
+			wantRecorded: map[string]int{"Status#a@example.com": 1, "Status#b@example.com": 1},
+		},
+		{
+			name: "types without dedup window",
+			notifications: []internal.Notification{
+				notification("News", "a@example.com", "Weekly news"),
+				notification("News", "a@example.com", "Weekly news"),
+			},
+			want:         []internal.ValidationResult{allowed, allowed},
+			wantRecorded: map[string]int{"News#a@example.com": 2},
+		},
+		{
+			name:          "message recorded by another request since it was checked",
+			notifications: []internal.Notification{notification("Status", "a@example.com", "Order shipped")},
+			concurrent:    map[string]bool{statusKey: true},
+			want:          []internal.ValidationResult{duplicate},
+			wantChecked:   1,
+			wantRecorded:  map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			checked := 0
+			recorded := map[string]int{}
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+					return map[string]int{}, nil
+				},
+				GetRecordedContentsFunc: func(contentKeys []string, unix int64) (map[string]bool, error) {
+					checked += len(contentKeys)
+					assert.Equal(t, now.Unix(), unix)
+
+					return tt.recorded, nil
+				},
+				RecordContentFunc: func(contentKey string, unix, ttl int64) (bool, error) {
+					assert.Equal(t, now.Add(10*time.Minute).Unix(), ttl)
+
+					return !tt.concurrent[contentKey], nil
+				},
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					recorded[notificationType+"#"+email]++
+
+					return nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{
+					GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+						return rules, nil
+					},
+				},
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			got, err := ucInstance.HandleBatch(context.Background(), tt.notifications)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, decisions(got))
+			assert.Equal(t, tt.wantChecked, checked)
+			assert.Equal(t, tt.wantRecorded, recorded)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch_Reservations test that the notifications allowed have the records and the
+// message saved for them, so they can be released
+func TestValidateRateLimitUC_HandleBatch_Reservations(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"Status": {Type: "Status", NotificationsLimit: 5, IntervalInMinutes: 60, DedupWindow: "10m"},
+		"News":   {Type: "News", NotificationsLimit: 5, IntervalInMinutes: 60},
+	}
+
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		notification internal.Notification
+		recipientCap internal.RecipientCap
+		concurrent   bool
+		want         *internal.Reservation
+	}{
+		{
+			name:         "type with dedup window",
+			notification: internal.Notification{Type: "Status", Recipient: "a@example.com", Message: "Order shipped"},
+			want: &internal.Reservation{
+				Records:    []internal.SentRecord{{Type: "Status", Email: "a@example.com", Timestamp: "1696248000"}},
+				ContentKey: internal.ContentKey("Status", "a@example.com", "Order shipped"),
+			},
+		},
+		{
+			name:         "type and cap of the recipient",
+			notification: internal.Notification{Type: "News", Recipient: "a@example.com", Message: "Weekly news"},
+			recipientCap: internal.RecipientCap{Limit: 10, Window: 24 * time.Hour},
+			want: &internal.Reservation{
+				Records: []internal.SentRecord{
+					{Type: "News", Email: "a@example.com", Timestamp: "1696248000"},
+					{Type: "*", Email: "a@example.com", Timestamp: "1696248000"},
+				},
+			},
+		},
+		{
+			name:         "message recorded by another request has no reservation",
+			notification: internal.Notification{Type: "Status", Recipient: "a@example.com", Message: "Order shipped"},
+			concurrent:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uuids []string
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+					return map[string]int{}, nil
+				},
+				RecordContentFunc: func(contentKey string, unix, ttl int64) (bool, error) {
+					return !tt.concurrent, nil
+				},
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					uuids = append(uuids, uuid)
+
+					return nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{
+					GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+						return rules, nil
+					},
+				},
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				tt.recipientCap,
+				&mockLogger{},
+			)
+			ucInstance.now = func() time.Time { return now }
+
+			got, err := ucInstance.Handle(context.Background(), tt.notification)
+			assert.NoError(t, err)
+
+			// The records have the uuids saved in the cache
+			if tt.want != nil {
+				for i := range tt.want.Records {
+					tt.want.Records[i].UUID = uuids[i]
+				}
+			}
+
+			assert.Equal(t, tt.want, got.Reservation)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch_RecordError test that what was recorded before an error of the cache is
+// released, even when the error is the cancellation of the request, and that an error releasing it does not hide
+// the error of the cache
+func TestValidateRateLimitUC_HandleBatch_RecordError(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"Status": {Type: "Status", NotificationsLimit: 5, IntervalInMinutes: 60, DedupWindow: "10m"},
+	}
+
+	tests := []struct {
+		name        string
+		releaseErr  error
+		wantDeleted []string
+	}{
+		{
+			name:        "the records are released",
+			wantDeleted: []string{"Status#a@example.com"},
+		},
+		{
+			// The records are kept when the message could not be deleted, so the dedup keeps working
+			name:       "error releasing the records",
+			releaseErr: errors.New("AccessDeniedException: not authorized to perform dynamodb:DeleteItem"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var deleted []string
+
+			var contents []string
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+					return map[string]int{}, nil
+				},
+				// The cap partition is not saved because the request is cancelled
+				SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+					if notificationType == internal.RecipientCapType {
+						cancel()
+
+						return context.Canceled
+					}
+
+					return nil
+				},
+				DeleteNotificationFunc: func(notificationType, email, timestamp, uuid string) error {
+					deleted = append(deleted, notificationType+"#"+email)
+
+					return tt.releaseErr
+				},
+				DeleteContentFunc: func(contentKey string) error {
+					contents = append(contents, contentKey)
+
+					return tt.releaseErr
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{
+					GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+						return rules, nil
+					},
+				},
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{Limit: 10, Window: 24 * time.Hour},
+				&mockLogger{},
+			)
+
+			_, err := ucInstance.HandleBatch(ctx, []internal.Notification{
+				{Type: "Status", Recipient: "a@example.com", Message: "Order shipped"},
+			})
+
+			var generalError *internal.GeneralError
+			if assert.ErrorAs(t, err, &generalError) {
+				assert.ErrorIs(t, generalError.OriginalError, context.Canceled)
+			}
+
+			assert.Equal(t, []string{internal.ContentKey("Status", "a@example.com", "Order shipped")}, contents)
+			assert.Equal(t, tt.wantDeleted, deleted)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_Release test that the records and the messages of the reservations are removed
+func TestValidateRateLimitUC_Release(t *testing.T) {
+	reservations := []internal.Reservation{
+		{
+			Records: []internal.SentRecord{
+				{Type: "Status", Email: "a@example.com", Timestamp: "1696248000", UUID: "uuid-1"},
+				{Type: "*", Email: "a@example.com", Timestamp: "1696248000", UUID: "uuid-2"},
+			},
+			ContentKey: "DEDUP#a",
+		},
+		{
+			Records: []internal.SentRecord{
+				{Type: "News", Email: "b@example.com", Timestamp: "1696248000", UUID: "uuid-3"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		failedContent string
+		wantErr       bool
+		wantDeleted   []string
+		wantContents  []string
+	}{
+		{
+			name: "every record and message",
+			wantDeleted: []string{
+				"*#a@example.com#uuid-2", "News#b@example.com#uuid-3", "Status#a@example.com#uuid-1",
+			},
+			wantContents: []string{"DEDUP#a"},
+		},
+		{
+			name:          "the other reservations are released when one fails",
+			failedContent: "DEDUP#a",
+			wantErr:       true,
+			wantDeleted:   []string{"News#b@example.com#uuid-3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+
+			var deleted []string
+
+			var contents []string
+
+			cacheRepo := &MockRateLimitCacheRepository{
+				DeleteNotificationFunc: func(notificationType, email, timestamp, uuid string) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					assert.Equal(t, "1696248000", timestamp)
+					deleted = append(deleted, notificationType+"#"+email+"#"+uuid)
+
+					return nil
+				},
+				DeleteContentFunc: func(contentKey string) error {
+					if contentKey == tt.failedContent {
+						return errors.New("delete error")
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+
+					contents = append(contents, contentKey)
+
+					return nil
+				},
+			}
+
+			ucInstance := NewValidateRateLimitUC(
+				&MockRateLimitRulesRepository{},
+				cacheRepo,
+				&MockRecipientProfileRepository{},
+				&MockRecipientPreferencesRepository{},
+				nil,
+				internal.RecipientNormalizer{},
+				internal.RecipientCap{},
+				&mockLogger{},
+			)
+
+			err := ucInstance.Release(context.Background(), reservations)
+			assert.Equal(t, tt.wantErr, err != nil)
+
+			sort.Strings(deleted)
+			assert.Equal(t, tt.wantDeleted, deleted)
+			assert.Equal(t, tt.wantContents, contents)
+		})
+	}
+}
+
+// TestValidateRateLimitUC_HandleBatch_Tenants test that the tenants have their own rules and quotas of the same type
+func TestValidateRateLimitUC_HandleBatch_Tenants(t *testing.T) {
+	rules := map[string]internal.RateLimitRule{
+		"News":        {Type: "News", NotificationsLimit: 2, IntervalInMinutes: 60},
+		"globex:News": {Type: "globex:News", NotificationsLimit: 1, IntervalInMinutes: 60},
+	}
+
+	now := time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	allowed := internal.ValidationResult{Allowed: true}
+	rateLimited := internal.ValidationResult{Reason: internal.RejectionReasonRateLimited}
+
+	// The requests are sent in order and share the cache, so each request sees the notifications of the previous ones
+	requests := []struct {
+		tenant string
+		want   []internal.ValidationResult
+	}{
+		{tenant: "acme", want: []internal.ValidationResult{allowed, allowed}},
+		{tenant: "acme", want: []internal.ValidationResult{rateLimited, rateLimited}},
+		{tenant: "initech", want: []internal.ValidationResult{allowed, allowed}},
+		{tenant: "globex", want: []internal.ValidationResult{allowed, rateLimited}},
+		{tenant: "", want: []internal.ValidationResult{allowed, allowed}},
+	}
+
+	var mu sync.Mutex
+
+	recorded := map[string]int{}
+
+	cacheRepo := &MockRateLimitCacheRepository{
+		CountNotificationsGroupedFunc: func(queries []internal.NotificationCountQuery) (map[string]int, error) {
+			counts := map[string]int{}
+			for _, query := range queries {
+				counts[query.PartitionKey()] = recorded[query.PartitionKey()]
+			}
+
+			return counts, nil
+		},
+		SetNotificationSentTimestampFunc: func(notificationType, email, timestamp, uuid string, ttl int64) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			recorded[notificationType+"#"+email]++
+
+			return nil
+		},
+	}
+
+	ucInstance := NewValidateRateLimitUC(
+		&MockRateLimitRulesRepository{
+			GetByTypesFunc: func(notificationTypes []string) (map[string]internal.RateLimitRule, error) {
+				found := map[string]internal.RateLimitRule{}
+				for _, notificationType := range notificationTypes {
+					if rule, ok := rules[notificationType]; ok {
+						found[notificationType] = rule
+					}
+				}
+
+				return found, nil
+			},
+		},
+		cacheRepo,
+		&MockRecipientProfileRepository{},
+		&MockRecipientPreferencesRepository{},
+		nil,
+		internal.RecipientNormalizer{},
+		internal.RecipientCap{Limit: 10, Window: 24 * time.Hour, LowPriorityPercent: 100},
+		&mockLogger{},
+	)
+	ucInstance.now = func() time.Time { return now }
+
+	notifications := []internal.Notification{
+		{Type: "News", Recipient: "a@example.com", Message: "Weekly news"},
+		{Type: "News", Recipient: "a@example.com", Message: "Weekly news"},
+	}
+
+	for i, request := range requests {
+		ctx := internal.WithRequestMetadata(context.Background(), internal.RequestMetadata{Tenant: request.tenant})
+
+		got, err := ucInstance.HandleBatch(ctx, notifications)
+		assert.NoError(t, err)
+		assert.Equal(t, request.want, decisions(got), "request %d of tenant '%s'", i, request.tenant)
+	}
+
+	assert.Equal(t, map[string]int{
+		"acme:News#a@example.com":    2,
+		"acme:*#a@example.com":       2,
+		"initech:News#a@example.com": 2,
+		"initech:*#a@example.com":    2,
+		"globex:News#a@example.com":  1,
+		"globex:*#a@example.com":     1,
+		"News#a@example.com":         2,
+		"*#a@example.com":            2,
+	}, recorded)
+}
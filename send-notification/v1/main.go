@@ -8,9 +8,9 @@ import (
 )
 
 func main() {
-	handler, err := di.Initialize()
+	router, err := di.Initialize()
 	if err != nil {
 		panic("fatal err: " + err.Error())
 	}
-	lambda.Start(handler.Handle)
+	lambda.Start(router.Handle)
 }